@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/analysis"
+	"github.com/plonxyz/triagectl/internal/correlate"
+	"github.com/plonxyz/triagectl/internal/output"
+)
+
+// runAnalyze re-runs the analyzers over an existing artifacts.db, so new
+// IOCs or analyzer changes can be applied without re-collecting
+func runAnalyze(args []string) int {
+	fs := flag.NewFlagSet("analyze", flag.ExitOnError)
+	casePath := caseFlag(fs)
+	analyzerOpts := analyzerFlags(fs)
+	enableHTML := fs.Bool("html", false, "Regenerate report.html")
+	enableTimeline := fs.Bool("timeline", false, "Regenerate timeline.csv (Timesketch format)")
+	timelineFmt := fs.String("timeline-format", "csv", "Timeline format: csv, or jsonl for Timesketch JSONL")
+	enableJSONL := fs.Bool("jsonl", false, "Rewrite artifacts.jsonl with the analysis results")
+	gzipJSONL := fs.Bool("gzip", false, "Gzip-compress the JSON Lines output")
+	fs.Parse(args)
+
+	kase, ok := openCase(fs, caseArg(fs, *casePath))
+	if !ok {
+		return 2
+	}
+
+	db, err := output.OpenSQLite(kase.DBPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	if *analyzerOpts.yaraPath != "" && kase.Manifest.Root == "" {
+		// Live cases refer to files on the host they were collected on
+		if host, _ := os.Hostname(); host != kase.Manifest.Hostname {
+			fmt.Fprintf(os.Stderr, "Warning: skipping YARA scan, case was collected live on %s, not this host (%s)\n", kase.Manifest.Hostname, host)
+			*analyzerOpts.yaraPath = ""
+		}
+	}
+	suppressions, ok := loadAnalyzers(analyzerOpts, kase.Manifest.Root)
+	if !ok {
+		return 1
+	}
+
+	artifacts, err := db.LoadArtifacts(false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading artifacts: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Loaded %d artifacts from %s\n", len(artifacts), kase.DBPath())
+
+	// Cases collected with --no-analysis were written without process
+	// context on their connections; SaveAnalysis stores what this adds
+	correlate.Enrich(artifacts)
+	fmt.Println("\nRunning analysis...")
+	artifacts = analysis.RunAll(artifacts)
+	suppressedCount := 0
+	if suppressions != nil {
+		suppressedCount = suppressions.Apply(artifacts, time.Now())
+	}
+
+	if err := db.SaveAnalysis(artifacts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing analysis results: %v\n", err)
+		return 1
+	}
+	if err := db.WriteEntities(correlate.Entities(artifacts)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing process entities: %v\n", err)
+	}
+
+	findingsCount := 0
+	for _, a := range artifacts {
+		if a.IsFinding() {
+			findingsCount++
+		}
+	}
+	fmt.Printf("  Analysis complete: %d findings detected\n", findingsCount)
+	if suppressedCount > 0 {
+		fmt.Printf("  Suppressed: %d findings\n", suppressedCount)
+	}
+
+	kase.Manifest.Analyses = append(kase.Manifest.Analyses, analyzerOpts.run(findingsCount, suppressedCount))
+	if err := kase.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing case manifest: %v\n", err)
+	}
+
+	writeAttackLayer(kase, artifacts)
+	if *enableTimeline {
+		writeTimeline(kase, artifacts, *timelineFmt)
+	}
+	if *enableJSONL {
+		writeJSONL(kase, artifacts, *gzipJSONL)
+	}
+	if *enableHTML {
+		if err := writeReport(kase.ReportPath(), kase, db, artifacts); err != nil {
+			return 1
+		}
+	}
+
+	fmt.Println()
+	fmt.Println("Analysis complete!")
+	return 0
+}
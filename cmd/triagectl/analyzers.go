@@ -0,0 +1,141 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/analysis"
+	"github.com/plonxyz/triagectl/internal/attack"
+	"github.com/plonxyz/triagectl/internal/casedir"
+	"github.com/plonxyz/triagectl/internal/suppress"
+)
+
+// analyzerOptions are the flags that configure analysis, shared by collect
+// and analyze
+type analyzerOptions struct {
+	iocFile      *string
+	sigmaPath    *string
+	yaraPath     *string
+	baselineFile *string
+	attackMap    *string
+	weightsFile  *string
+	suppressFile *string
+}
+
+func analyzerFlags(fs *flag.FlagSet) *analyzerOptions {
+	return &analyzerOptions{
+		iocFile:      fs.String("ioc-file", "", "Path to IOC file: one indicator per line, typed CSV, STIX 2.1 bundle or MISP event JSON"),
+		sigmaPath:    fs.String("sigma", "", "Sigma rule file or directory of rules"),
+		yaraPath:     fs.String("yara", "", "YARA rule file or directory of rules to scan referenced files with"),
+		baselineFile: fs.String("baseline", "", "Path to a baseline file built with 'triagectl baseline'"),
+		attackMap:    fs.String("attack-map", "", "YAML file of ATT&CK technique mappings to add to the built-in ones"),
+		weightsFile:  fs.String("weights", "", "YAML file of risk score weights per rule or analyzer"),
+		suppressFile: fs.String("suppress", "", "YAML file of suppression rules for expected findings"),
+	}
+}
+
+// run records an analysis with these options for the case manifest
+func (o *analyzerOptions) run(findings, suppressed int) casedir.AnalysisRun {
+	return casedir.AnalysisRun{
+		At:           time.Now(),
+		Version:      version,
+		IOCFile:      *o.iocFile,
+		Sigma:        *o.sigmaPath,
+		YARA:         *o.yaraPath,
+		Baseline:     *o.baselineFile,
+		AttackMap:    *o.attackMap,
+		Weights:      *o.weightsFile,
+		Suppressions: *o.suppressFile,
+		Findings:     findings,
+		Suppressed:   suppressed,
+	}
+}
+
+// loadAnalyzers registers the analyzers given by opts and loads their
+// score weights, suppressions and ATT&CK mapping. YARA resolves referenced
+// files under root, or on this host when root is empty. Errors are printed;
+// ok is false if any file failed to load.
+func loadAnalyzers(opts *analyzerOptions, root string) (suppressions *suppress.Rules, ok bool) {
+	if *opts.iocFile != "" {
+		iocMatcher, err := analysis.NewIOCMatcher(*opts.iocFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading IOC file: %v\n", err)
+			return nil, false
+		}
+		analysis.RegisterAnalyzer(iocMatcher)
+		active, expired := iocMatcher.Indicators()
+		fmt.Printf("Loaded %d indicators (%d expired, skipped): %s\n", active, expired, *opts.iocFile)
+		printSkipped("indicators that could not be used", iocMatcher.Skipped())
+	}
+	if *opts.sigmaPath != "" {
+		sigmaAnalyzer, err := analysis.NewSigmaAnalyzer(*opts.sigmaPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading Sigma rules: %v\n", err)
+			return nil, false
+		}
+		analysis.RegisterAnalyzer(sigmaAnalyzer)
+		loaded, applicable := sigmaAnalyzer.Rules()
+		fmt.Printf("Loaded %d Sigma rules (%d apply to collected artifacts): %s\n", loaded, applicable, *opts.sigmaPath)
+		printSkipped("Sigma rules that failed to load", sigmaAnalyzer.Skipped())
+	}
+	if *opts.yaraPath != "" {
+		yaraAnalyzer, err := analysis.NewYaraAnalyzer(*opts.yaraPath, root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading YARA rules: %v\n", err)
+			return nil, false
+		}
+		analysis.RegisterAnalyzer(yaraAnalyzer)
+		fmt.Printf("Loaded %d YARA rules: %s\n", yaraAnalyzer.Rules(), *opts.yaraPath)
+	}
+	if *opts.baselineFile != "" {
+		baseline, err := analysis.NewBaselineAnalyzer(*opts.baselineFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading baseline: %v\n", err)
+			return nil, false
+		}
+		analysis.RegisterAnalyzer(baseline)
+		fmt.Printf("Loaded baseline: %s\n", *opts.baselineFile)
+	}
+	if *opts.weightsFile != "" {
+		weights, err := analysis.LoadScoreWeights(*opts.weightsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading score weights: %v\n", err)
+			return nil, false
+		}
+		analysis.SetScoreWeights(weights)
+		fmt.Printf("Loaded score weights: %s\n", *opts.weightsFile)
+	}
+	if *opts.suppressFile != "" {
+		rules, err := suppress.Load(*opts.suppressFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading suppressions: %v\n", err)
+			return nil, false
+		}
+		suppressions = rules
+		active, expired := suppressions.Count(time.Now())
+		fmt.Printf("Loaded %d suppressions (%d expired, skipped): %s\n", active, expired, *opts.suppressFile)
+	}
+	if *opts.attackMap != "" {
+		mapping, err := attack.Load(*opts.attackMap)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading ATT&CK mapping: %v\n", err)
+			return nil, false
+		}
+		analysis.SetAttackMapping(mapping)
+		fmt.Printf("Loaded ATT&CK mapping: %s\n", *opts.attackMap)
+	}
+	return suppressions, true
+}
+
+// printSkipped lists the rules or indicators that were skipped on loading
+func printSkipped(what string, skipped []error) {
+	if len(skipped) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: skipped %d %s:\n", len(skipped), what)
+	for _, err := range skipped {
+		fmt.Fprintf(os.Stderr, "  %v\n", err)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/plonxyz/triagectl/internal/analysis"
+	"github.com/plonxyz/triagectl/internal/attack"
+	"github.com/plonxyz/triagectl/internal/casedir"
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// writeAttackLayer exports the case's techniques as an ATT&CK Navigator layer
+func writeAttackLayer(kase *casedir.Case, artifacts []models.Artifact) {
+	name := "triagectl"
+	if kase.Manifest.Hostname != "" {
+		name += " " + kase.Manifest.Hostname
+	}
+	var metadata []attack.LayerMetadata
+	if kase.Manifest.Hostname != "" {
+		metadata = append(metadata, attack.LayerMetadata{Name: "hostname", Value: kase.Manifest.Hostname})
+	}
+	if !kase.Manifest.CollectedAt.IsZero() {
+		metadata = append(metadata, attack.LayerMetadata{Name: "collected_at", Value: kase.Manifest.CollectedAt.Format("2006-01-02 15:04:05 MST")})
+	}
+	metadata = append(metadata, attack.LayerMetadata{Name: "triagectl", Value: version})
+
+	layer := attack.NewLayer(name, metadata, artifacts, analysis.AttackMapping())
+	if err := layer.WriteJSON(kase.AttackLayerPath()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing ATT&CK layer: %v\n", err)
+		return
+	}
+	fmt.Printf("  ATT&CK Navigator layer: %s\n", kase.AttackLayerPath())
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/plonxyz/triagectl/internal/analysis"
+	"github.com/plonxyz/triagectl/internal/output"
+)
+
+// runBaseline builds a baseline file from cases collected on known-good
+// machines, for use with --baseline on collect and analyze
+func runBaseline(args []string) int {
+	fs := flag.NewFlagSet("baseline", flag.ExitOnError)
+	outPath := fs.String("output", "baseline.json", "Where to write the baseline file")
+	merge := fs.String("merge", "", "Existing baseline file to extend instead of starting empty")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: triagectl baseline [flags] <case>...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	// Allow flags after the cases
+	var paths []string
+	for fs.NArg() > 0 {
+		paths = append(paths, fs.Arg(0))
+		fs.Parse(fs.Args()[1:])
+	}
+	if len(paths) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: baseline requires at least one case")
+		fs.Usage()
+		return 2
+	}
+
+	baseline := analysis.NewBaseline()
+	if *merge != "" {
+		var err error
+		if baseline, err = analysis.LoadBaseline(*merge); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading baseline: %v\n", err)
+			return 1
+		}
+	}
+
+	for _, path := range paths {
+		kase, ok := openCase(fs, path)
+		if !ok {
+			return 2
+		}
+		db, err := output.OpenSQLiteReadOnly(kase.DBPath())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+			return 1
+		}
+		artifacts, err := db.LoadArtifacts(false)
+		db.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading artifacts: %v\n", err)
+			return 1
+		}
+		source := kase.Dir
+		if kase.Manifest.Hostname != "" {
+			source = kase.Manifest.Hostname + " (" + kase.Dir + ")"
+		}
+		baseline.AddArtifacts(source, artifacts)
+		fmt.Printf("Added %s: %d artifacts\n", kase.Dir, len(artifacts))
+	}
+
+	fmt.Println()
+	for _, category := range []string{
+		analysis.BaselineHashes, analysis.BaselineLaunchdLabels, analysis.BaselineLoginItems,
+		analysis.BaselineAppBundleIDs, analysis.BaselineKextIDs, analysis.BaselineTCCGrants,
+		analysis.BaselineProcessPaths, analysis.BaselineCronEntries,
+	} {
+		fmt.Printf("  %-20s %6d\n", category, baseline.Count(category))
+	}
+
+	if err := baseline.Save(*outPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing baseline: %v\n", err)
+		return 1
+	}
+	fmt.Printf("\n  Baseline: %s\n", *outPath)
+	return 0
+}
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/analysis"
+	"github.com/plonxyz/triagectl/internal/casedir"
+	"github.com/plonxyz/triagectl/internal/collectors"
+	"github.com/plonxyz/triagectl/internal/correlate"
+	"github.com/plonxyz/triagectl/internal/hashing"
+	"github.com/plonxyz/triagectl/internal/models"
+	"github.com/plonxyz/triagectl/internal/output"
+	"github.com/plonxyz/triagectl/internal/progress"
+	"github.com/plonxyz/triagectl/internal/report"
+	"github.com/plonxyz/triagectl/internal/suppress"
+)
+
+// runCollect collects artifacts from the live system or a mounted image
+// into a new case directory, analyzing them unless --no-analysis is given
+func runCollect(args []string) int {
+	fs := flag.NewFlagSet("collect", flag.ExitOnError)
+	outputDir := fs.String("output", "./triagectl-output", "Output directory for collected artifacts")
+	listCollectors := fs.Bool("list", false, "List available collectors and exit (same as 'triagectl list')")
+	showVersion := fs.Bool("version", false, "Show version and exit (same as 'triagectl version')")
+	timeout := fs.Int("timeout", 300, "Global timeout in seconds for collection")
+	collectorFilter := fs.String("collectors", "", "Comma-separated collector IDs to run (default: all)")
+	collectorTimeout := fs.Int("collector-timeout", 60, "Per-collector timeout in seconds")
+	concurrency := fs.Int("concurrency", 4, "Maximum number of collectors to run concurrently")
+	hashMaxSize := fs.Int64("hash-max-size", hashing.DefaultMaxSize>>20, "Largest file to hash in MB (0 disables hashing)")
+	hashWorkers := fs.Int("hash-workers", hashing.DefaultWorkers, "Maximum number of files hashed at once")
+	enableCSV := fs.Bool("csv", false, "Enable CSV output")
+	enableJSONL := fs.Bool("jsonl", false, "Enable JSON Lines output (one artifact per line; streamed as collected with --no-analysis)")
+	gzipJSONL := fs.Bool("gzip", false, "Gzip-compress the JSON Lines output")
+	enableHTML := fs.Bool("html", false, "Generate HTML report")
+	enableTimeline := fs.Bool("timeline", false, "Generate timeline.csv (Timesketch format)")
+	timelineFmt := fs.String("timeline-format", "csv", "Timeline format: csv, or jsonl for Timesketch JSONL")
+	analyzerOpts := analyzerFlags(fs)
+	rootPath := fs.String("root", "", "Collect offline from a mounted disk image or extracted filesystem at this path")
+	hostnameFlag := fs.String("hostname", "", "Hostname to record on artifacts (default: live hostname, or root directory name with --root)")
+	noAnalysis := fs.Bool("no-analysis", false, "Only collect, writing each collector's artifacts as it finishes instead of holding them in memory; run 'triagectl analyze' on the case later")
+	shipOpts := shipFlags(fs)
+	fs.Parse(args)
+
+	if *rootPath != "" {
+		info, err := os.Stat(*rootPath)
+		if err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: --root %s is not a directory\n", *rootPath)
+			return 1
+		}
+		collectors.SetRoot(*rootPath)
+	}
+	if *hostnameFlag != "" {
+		collectors.SetHostname(*hostnameFlag)
+	}
+	collectors.SetHashing(*hashMaxSize<<20, *hashWorkers)
+
+	if *showVersion {
+		fmt.Printf("triagectl v%s\n", version)
+		return 0
+	}
+
+	if *listCollectors {
+		printCollectors()
+		return 0
+	}
+
+	banner := fmt.Sprintf("triagectl v%s", version)
+	const boxWidth = 39
+	pad := boxWidth - len(banner)
+	left := pad / 2
+	right := pad - left
+	fmt.Println("╔═══════════════════════════════════════╗")
+	fmt.Printf("║%s%s%s║\n", strings.Repeat(" ", left), banner, strings.Repeat(" ", right))
+	fmt.Println("╚═══════════════════════════════════════╝")
+	fmt.Println()
+
+	// 1. Create the case directory
+	kase, err := casedir.Create(*outputDir, collectors.TargetHostname(), time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating output directory: %v\n", err)
+		return 1
+	}
+	kase.Manifest.Version = version
+	kase.Manifest.Root = collectors.Root()
+
+	fmt.Printf("Output directory: %s\n", kase.Dir)
+	if collectors.Offline() {
+		fmt.Printf("Offline root: %s (live-only collectors will be skipped)\n", collectors.Root())
+	}
+	fmt.Println()
+
+	// 2. Init writers: SQLite + optionally CSV and JSONL → MultiWriter
+	sqlitePath := kase.DBPath()
+
+	sqliteWriter, err := output.NewSQLiteWriter(sqlitePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating SQLite writer: %v\n", err)
+		return 1
+	}
+	defer sqliteWriter.Close()
+
+	writers := []output.Writer{sqliteWriter}
+
+	var csvPath string
+	if *enableCSV {
+		csvPath = kase.CSVPath()
+		csvWriter, err := output.NewCSVWriter(csvPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating CSV writer: %v\n", err)
+			return 1
+		}
+		defer csvWriter.Close()
+		writers = append(writers, csvWriter)
+	}
+
+	var jsonlPath string
+	if *enableJSONL {
+		jsonlPath = jsonlOutputPath(kase, *gzipJSONL)
+		jsonlWriter, err := output.NewJSONLWriter(jsonlPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating JSONL writer: %v\n", err)
+			return 1
+		}
+		defer jsonlWriter.Close()
+		writers = append(writers, jsonlWriter)
+	}
+
+	// Network writers go last so an unreachable endpoint cannot hold up
+	// the local outputs
+	shipTargets, err := shipOpts.targets(kase)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	for _, t := range shipTargets {
+		writers = append(writers, t.writer)
+	}
+
+	// Without analysis nothing needs the whole collection, so each
+	// collector's artifacts are written as they arrive and then dropped.
+	// Analysis and process correlation relate artifacts to each other, so
+	// with analysis they are held and written once scored.
+	streaming := *noAnalysis
+	multiWriter := output.NewMultiWriter(writers...)
+	defer multiWriter.Close()
+
+	// 3. Filter collectors via --collectors
+	activeCollectors := filterCollectors(*collectorFilter)
+
+	// 4. Load IOCs if --ioc-file provided
+	var suppressions *suppress.Rules
+	if !*noAnalysis {
+		var ok bool
+		if suppressions, ok = loadAnalyzers(analyzerOpts, *rootPath); !ok {
+			return 1
+		}
+	}
+
+	// 5. Start progress tracker
+	tracker := progress.NewTracker(len(activeCollectors))
+
+	// 6. Launch collectors with semaphore + per-collector timeouts
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(*timeout)*time.Second)
+	defer cancel()
+
+	startTime := time.Now()
+	fmt.Println("Starting artifact collection...")
+	fmt.Println()
+
+	semaphore := make(chan struct{}, *concurrency)
+	var wg sync.WaitGroup
+	resultsCh := make(chan models.CollectionResult, len(activeCollectors))
+
+	for _, collector := range activeCollectors {
+		wg.Add(1)
+		go func(c collectors.Collector) {
+			defer wg.Done()
+
+			// Live-only collectors would report the analysis host, not the image
+			if collectors.IsLiveOnly(c) {
+				resultsCh <- models.CollectionResult{
+					CollectorID: c.ID(),
+					StartedAt:   time.Now(),
+					SkipReason:  "live-only",
+				}
+				return
+			}
+
+			semaphore <- struct{}{}        // acquire
+			defer func() { <-semaphore }() // release
+
+			tracker.Start(c.Name())
+
+			collectorCtx, collectorCancel := context.WithTimeout(ctx, time.Duration(*collectorTimeout)*time.Second)
+			defer collectorCancel()
+
+			start := time.Now()
+			artifacts, err := c.Collect(collectorCtx)
+			dur := time.Since(start)
+
+			result := models.CollectionResult{
+				CollectorID: c.ID(),
+				Artifacts:   artifacts,
+				Error:       err,
+				Duration:    dur,
+				StartedAt:   start,
+			}
+
+			resultsCh <- result
+		}(collector)
+	}
+
+	go func() {
+		wg.Wait()
+		close(resultsCh)
+	}()
+
+	// 7. Process results: write to MultiWriter, update progress
+	var allArtifacts []models.Artifact
+	var allResults []models.CollectionResult
+	totalArtifacts := 0
+	successfulCollectors := 0
+	failedCollectors := 0
+	skippedCollectors := 0
+
+	for result := range resultsCh {
+		if streaming {
+			if err := sqliteWriter.WriteResults([]models.CollectionResult{result}); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing collector results: %v\n", err)
+			}
+		}
+		artifacts := result.Artifacts
+		count := len(artifacts)
+		if streaming {
+			result.Artifacts = nil
+		}
+		allResults = append(allResults, result)
+
+		if result.SkipReason != "" {
+			tracker.Skip(result.CollectorID, result.SkipReason)
+			skippedCollectors++
+			continue
+		}
+
+		// Partial results from a failed collector are dropped, as in analysis mode
+		if result.Error != nil {
+			tracker.Fail(result.CollectorID, result.Error)
+			failedCollectors++
+			continue
+		}
+
+		if streaming {
+			if err := multiWriter.WriteMany(artifacts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing %s artifacts: %v\n", result.CollectorID, err)
+			}
+		} else {
+			allArtifacts = append(allArtifacts, artifacts...)
+		}
+		totalArtifacts += count
+		tracker.Success(result.CollectorID, count)
+		successfulCollectors++
+	}
+
+	tracker.Finish()
+	duration := time.Since(startTime)
+
+	suppressedCount := 0
+	if !streaming {
+		// 8. Number artifacts with the row IDs the new artifacts.db will give
+		// them, so analyzer-created artifacts can link to their source.
+		// Attach process context to connections, then run cross-artifact analyzers.
+		for i := range allArtifacts {
+			allArtifacts[i].ID = int64(i + 1)
+		}
+		correlate.Enrich(allArtifacts)
+		fmt.Println("\nRunning analysis...")
+		allArtifacts = analysis.RunAll(allArtifacts)
+		if suppressions != nil {
+			suppressedCount = suppressions.Apply(allArtifacts, time.Now())
+		}
+
+		// 9. Write analyzed artifacts to all output formats
+		if err := multiWriter.WriteMany(allArtifacts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing artifacts: %v\n", err)
+		}
+		if err := sqliteWriter.WriteResults(allResults); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing collector results: %v\n", err)
+		}
+		if err := sqliteWriter.WriteEntities(correlate.Entities(allArtifacts)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing process entities: %v\n", err)
+		}
+	} else if *enableTimeline || *enableHTML {
+		// The timeline and report need the whole collection after all
+		var err error
+		if allArtifacts, err = sqliteWriter.LoadArtifacts(false); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading artifacts: %v\n", err)
+		}
+		if allResults, err = sqliteWriter.LoadResults(allArtifacts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading collector results: %v\n", err)
+		}
+	}
+	finishShipping(shipTargets)
+
+	// Update severity counts
+	findingsCount := 0
+	for _, a := range allArtifacts {
+		if a.IsFinding() {
+			findingsCount++
+		}
+	}
+	if !*noAnalysis {
+		fmt.Printf("  Analysis complete: %d findings detected\n", findingsCount)
+		if suppressedCount > 0 {
+			fmt.Printf("  Suppressed: %d findings\n", suppressedCount)
+		}
+	}
+
+	kase.Manifest.Duration = duration.Round(time.Millisecond).String()
+	kase.Manifest.Artifacts = totalArtifacts
+	for _, c := range activeCollectors {
+		kase.Manifest.Collectors = append(kase.Manifest.Collectors, c.ID())
+	}
+	if !*noAnalysis {
+		kase.Manifest.Analyses = append(kase.Manifest.Analyses, analyzerOpts.run(findingsCount, suppressedCount))
+	}
+	if err := kase.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing case manifest: %v\n", err)
+	}
+
+	if !*noAnalysis {
+		writeAttackLayer(kase, allArtifacts)
+	}
+
+	// 9. If --timeline: generate timeline.csv or timeline.jsonl
+	if *enableTimeline {
+		writeTimeline(kase, allArtifacts, *timelineFmt)
+	}
+
+	// 10. If --html: generate report.html
+	if *enableHTML {
+		reportPath := kase.ReportPath()
+		if err := report.GenerateHTMLReport(reportPath, allArtifacts, allResults, duration); err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating HTML report: %v\n", err)
+		} else {
+			fmt.Printf("  HTML Report: %s\n", reportPath)
+		}
+	}
+
+	// 11. Print summary
+	fmt.Println()
+	fmt.Println("═══════════════════════════════════════")
+	fmt.Println("Collection Summary")
+	fmt.Println("═══════════════════════════════════════")
+	fmt.Printf("Duration: %v\n", duration.Round(time.Millisecond))
+	fmt.Printf("Total Artifacts: %d\n", totalArtifacts)
+	fmt.Printf("Successful Collectors: %d\n", successfulCollectors)
+	fmt.Printf("Failed Collectors: %d\n", failedCollectors)
+	if skippedCollectors > 0 {
+		fmt.Printf("Skipped Collectors (live-only): %d\n", skippedCollectors)
+	}
+
+	if findingsCount > 0 {
+		fmt.Printf("Findings (risk >= medium): %d\n", findingsCount)
+	}
+
+	fmt.Println()
+
+	// Print detailed stats from SQLite
+	if err := sqliteWriter.PrintStats(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error printing stats: %v\n", err)
+	}
+
+	fmt.Println()
+	fmt.Println("Output files:")
+	fmt.Printf("  - SQLite: %s\n", sqlitePath)
+	if *enableCSV {
+		fmt.Printf("  - CSV:    %s\n", csvPath)
+	}
+	if *enableJSONL {
+		fmt.Printf("  - JSONL:  %s\n", jsonlPath)
+	}
+	if *enableTimeline {
+		if _, timelinePath, err := timelineFormat(kase, *timelineFmt); err == nil {
+			fmt.Printf("  - Timeline: %s\n", timelinePath)
+		}
+	}
+	if *enableHTML {
+		fmt.Printf("  - Report: %s\n", kase.ReportPath())
+	}
+	if !*noAnalysis {
+		fmt.Printf("  - ATT&CK layer: %s\n", kase.AttackLayerPath())
+	}
+	fmt.Println()
+	fmt.Println("Collection complete!")
+	return 0
+}
+
+func filterCollectors(filter string) []collectors.Collector {
+	if filter == "" {
+		return collectors.Registry
+	}
+
+	allowed := make(map[string]bool)
+	for _, id := range strings.Split(filter, ",") {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			allowed[id] = true
+		}
+	}
+
+	var filtered []collectors.Collector
+	for _, c := range collectors.Registry {
+		if allowed[c.ID()] {
+			filtered = append(filtered, c)
+		}
+	}
+
+	if len(filtered) == 0 {
+		fmt.Fprintf(os.Stderr, "Warning: no collectors matched filter '%s', running all\n", filter)
+		return collectors.Registry
+	}
+
+	return filtered
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/plonxyz/triagectl/internal/casedir"
+	"github.com/plonxyz/triagectl/internal/diff"
+	"github.com/plonxyz/triagectl/internal/report"
+)
+
+// runDiff compares an earlier case with a later one, writing diff.json into
+// the later case and optionally a report with a Changes section
+func runDiff(args []string) int {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	beforePath := fs.String("before", "", "Earlier case directory or artifacts.db")
+	afterPath := fs.String("after", "", "Later case directory or artifacts.db")
+	jsonPath := fs.String("json", "", "Where to write the JSON diff (default: diff.json in the later case)")
+	enableHTML := fs.Bool("html", false, "Regenerate the later case's report.html with a Changes section")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: triagectl diff [flags] <before-case> <after-case>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	// Positional form: diff <before> <after> [flags]
+	for fs.NArg() > 0 && (*beforePath == "" || *afterPath == "") {
+		if *beforePath == "" {
+			*beforePath = fs.Arg(0)
+		} else {
+			*afterPath = fs.Arg(0)
+		}
+		fs.Parse(fs.Args()[1:])
+	}
+	if *beforePath == "" || *afterPath == "" {
+		fmt.Fprintln(os.Stderr, "Error: diff requires two cases")
+		fs.Usage()
+		return 2
+	}
+
+	before, ok := openCase(fs, *beforePath)
+	if !ok {
+		return 2
+	}
+	after, ok := openCase(fs, *afterPath)
+	if !ok {
+		return 2
+	}
+
+	beforeDB, beforeArtifacts, ok := loadAnalyzed(before)
+	if !ok {
+		return 1
+	}
+	defer beforeDB.Close()
+	afterDB, afterArtifacts, ok := loadAnalyzed(after)
+	if !ok {
+		return 1
+	}
+	defer afterDB.Close()
+
+	if h1, h2 := before.Manifest.Hostname, after.Manifest.Hostname; h1 != "" && h2 != "" && h1 != h2 {
+		fmt.Fprintf(os.Stderr, "Warning: comparing different hosts (%s vs %s)\n", h1, h2)
+	}
+
+	result := diff.Compare(beforeArtifacts, afterArtifacts, report.Summarize)
+	result.Before = diffSide(before, len(beforeArtifacts))
+	result.After = diffSide(after, len(afterArtifacts))
+
+	fmt.Printf("Before: %s (%d artifacts)\n", before.Dir, len(beforeArtifacts))
+	fmt.Printf("After:  %s (%d artifacts)\n\n", after.Dir, len(afterArtifacts))
+	fmt.Printf("  %-30s %8s %8s %8s\n", "ARTIFACT TYPE", "ADDED", "REMOVED", "CHANGED")
+	for _, td := range result.Types {
+		fmt.Printf("  %-30s %8d %8d %8d\n", td.ArtifactType, len(td.Added), len(td.Removed), len(td.Changed))
+	}
+	fmt.Printf("  %-30s %8d %8d %8d\n\n", "TOTAL", result.Added, result.Removed, result.Changed)
+
+	out := after.Path(casedir.DiffFile)
+	if *jsonPath != "" {
+		out = *jsonPath
+	}
+	if err := result.WriteJSON(out); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing diff: %v\n", err)
+		return 1
+	}
+	fmt.Printf("  JSON diff: %s\n", out)
+
+	if *enableHTML {
+		results, err := afterDB.LoadResults(afterArtifacts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading collector results: %v\n", err)
+			return 1
+		}
+		reportPath := after.ReportPath()
+		if err := report.GenerateHTMLReportWithDiff(reportPath, afterArtifacts, results, caseDuration(after, results), result); err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating HTML report: %v\n", err)
+			return 1
+		}
+		fmt.Printf("  HTML Report: %s\n", reportPath)
+	}
+	return 0
+}
+
+func diffSide(kase *casedir.Case, count int) diff.Side {
+	side := diff.Side{Path: kase.Dir, Hostname: kase.Manifest.Hostname, Artifacts: count}
+	if !kase.Manifest.CollectedAt.IsZero() {
+		t := kase.Manifest.CollectedAt
+		side.CollectedAt = &t
+	}
+	return side
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/plonxyz/triagectl/internal/normalize"
+	"github.com/plonxyz/triagectl/internal/output"
+	"github.com/plonxyz/triagectl/internal/report"
+)
+
+// runExport writes a case's artifacts normalized to ECS or OCSF as bulk
+// NDJSON, ready for the Elasticsearch or OpenSearch _bulk API
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	casePath := caseFlag(fs)
+	format := fs.String("format", normalize.SchemaECS, "Schema to normalize to: ecs or ocsf")
+	outPath := fs.String("output", "", "File to write, or - for stdout (default: artifacts.<format>.ndjson in the case directory)")
+	index := fs.String("index", "", "Index named in the bulk actions (default: triagectl-<format>)")
+	compress := fs.Bool("gzip", false, "Gzip-compress the export")
+	fs.Parse(args)
+
+	kase, ok := openCase(fs, caseArg(fs, *casePath))
+	if !ok {
+		return 2
+	}
+	mapper, err := normalize.NewMapper(*format, version, report.Summarize)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if *index == "" {
+		*index = "triagectl-" + mapper.Schema()
+	}
+
+	db, artifacts, ok := loadAnalyzed(kase)
+	if !ok {
+		return 1
+	}
+	defer db.Close()
+
+	var w *output.BulkWriter
+	path := *outPath
+	if path == "-" {
+		w = output.NewBulkStream(os.Stdout, *compress, *index, mapper)
+	} else {
+		if path == "" {
+			path = kase.ExportPath(mapper.Schema())
+		}
+		if *compress && !strings.HasSuffix(path, ".gz") {
+			path += ".gz"
+		}
+		if w, err = output.NewBulkWriter(path, *index, mapper); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating export: %v\n", err)
+			return 1
+		}
+	}
+	err = w.WriteMany(artifacts)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing export: %v\n", err)
+		return 1
+	}
+	if path != "-" {
+		fmt.Printf("Exported %d artifacts as %s: %s\n", len(artifacts), mapper.Schema(), path)
+	}
+	return 0
+}
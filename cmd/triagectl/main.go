@@ -1,264 +1,163 @@
 package main
 
 import (
-	"context"
 	"flag"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
-	"sync"
-	"time"
 
-	"github.com/plonxyz/triagectl/internal/analysis"
+	"github.com/plonxyz/triagectl/internal/casedir"
 	"github.com/plonxyz/triagectl/internal/collectors"
-	"github.com/plonxyz/triagectl/internal/models"
-	"github.com/plonxyz/triagectl/internal/output"
-	"github.com/plonxyz/triagectl/internal/progress"
-	"github.com/plonxyz/triagectl/internal/report"
 )
 
 const version = "0.2.0"
 
-func main() {
-	// Command line flags
-	outputDir := flag.String("output", "./triagectl-output", "Output directory for collected artifacts")
-	listCollectors := flag.Bool("list", false, "List available collectors and exit")
-	showVersion := flag.Bool("version", false, "Show version and exit")
-	timeout := flag.Int("timeout", 300, "Global timeout in seconds for collection")
-	collectorFilter := flag.String("collectors", "", "Comma-separated collector IDs to run (default: all)")
-	collectorTimeout := flag.Int("collector-timeout", 60, "Per-collector timeout in seconds")
-	concurrency := flag.Int("concurrency", 4, "Maximum number of collectors to run concurrently")
-	enableCSV := flag.Bool("csv", false, "Enable CSV output")
-	enableHTML := flag.Bool("html", false, "Generate HTML report")
-	enableTimeline := flag.Bool("timeline", false, "Generate timeline.csv (Timesketch format)")
-	iocFile := flag.String("ioc-file", "", "Path to IOC file (one indicator per line)")
-	flag.Parse()
-
-	if *showVersion {
-		fmt.Printf("triagectl v%s\n", version)
-		os.Exit(0)
-	}
+// command is a CLI verb. Every verb except collect and list operates on an
+// existing case directory, so collection and analysis can happen on
+// different machines.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int
+}
 
-	if *listCollectors {
-		printCollectors()
-		os.Exit(0)
+var commands []command
+
+func init() {
+	commands = []command{
+		{"collect", "Collect artifacts into a new case directory", runCollect},
+		{"analyze", "Re-run analyzers over a case and store scores and tags", runAnalyze},
+		{"report", "Generate the HTML report for a case", runReport},
+		{"timeline", "Generate the Timesketch timeline for a case", runTimeline},
+		{"export", "Export a case as ECS or OCSF NDJSON for Elasticsearch/OpenSearch", runExport},
+		{"ship", "Send a case to Elasticsearch/OpenSearch or Splunk HEC", runShip},
+		{"diff", "Compare two cases of the same host", runDiff},
+		{"baseline", "Build a known-good baseline file from one or more cases", runBaseline},
+		{"query", "Run a read-only SQL query against a case database", runQuery},
+		{"list", "List collectors, or the cases in an output directory", runList},
+		{"version", "Show version and exit", runVersion},
 	}
+}
 
-	banner := fmt.Sprintf("triagectl v%s", version)
-	const boxWidth = 39
-	pad := boxWidth - len(banner)
-	left := pad / 2
-	right := pad - left
-	fmt.Println("╔═══════════════════════════════════════╗")
-	fmt.Printf("║%s%s%s║\n", strings.Repeat(" ", left), banner, strings.Repeat(" ", right))
-	fmt.Println("╚═══════════════════════════════════════╝")
-	fmt.Println()
-
-	// 1. Create output directory
-	ts := time.Now().Format("20060102-150405")
-	hostname, _ := os.Hostname()
-	collectionDir := filepath.Join(*outputDir, fmt.Sprintf("%s-%s", hostname, ts))
-
-	if err := os.MkdirAll(collectionDir, 0755); err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating output directory: %v\n", err)
-		os.Exit(1)
+func main() {
+	// Bare flags keep the pre-subcommand behaviour: collect
+	if len(os.Args) < 2 || strings.HasPrefix(os.Args[1], "-") {
+		if len(os.Args) >= 2 && (os.Args[1] == "-h" || os.Args[1] == "--help" || os.Args[1] == "-help") {
+			usage()
+			os.Exit(0)
+		}
+		os.Exit(runCollect(os.Args[1:]))
 	}
 
-	fmt.Printf("Output directory: %s\n\n", collectionDir)
-
-	// 2. Init writers: SQLite + optionally CSV → MultiWriter
-	sqlitePath := filepath.Join(collectionDir, "artifacts.db")
-
-	sqliteWriter, err := output.NewSQLiteWriter(sqlitePath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating SQLite writer: %v\n", err)
-		os.Exit(1)
+	name := os.Args[1]
+	if name == "help" {
+		usage()
+		os.Exit(0)
 	}
-	defer sqliteWriter.Close()
-
-	writers := []output.Writer{sqliteWriter}
-
-	var csvPath string
-	if *enableCSV {
-		csvPath = filepath.Join(collectionDir, "artifacts.csv")
-		csvWriter, err := output.NewCSVWriter(csvPath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error creating CSV writer: %v\n", err)
-			os.Exit(1)
+	for _, c := range commands {
+		if c.name == name {
+			os.Exit(c.run(os.Args[2:]))
 		}
-		defer csvWriter.Close()
-		writers = append(writers, csvWriter)
 	}
 
-	multiWriter := output.NewMultiWriter(writers...)
-	defer multiWriter.Close()
-
-	// 3. Filter collectors via --collectors
-	activeCollectors := filterCollectors(*collectorFilter)
+	fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
 
-	// 4. Load IOCs if --ioc-file provided
-	if *iocFile != "" {
-		iocMatcher, err := analysis.NewIOCMatcher(*iocFile)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error loading IOC file: %v\n", err)
-			os.Exit(1)
-		}
-		analysis.RegisterAnalyzer(iocMatcher)
-		fmt.Printf("Loaded IOC file: %s\n\n", *iocFile)
+func usage() {
+	fmt.Fprintf(os.Stderr, "triagectl v%s - macOS forensic triage\n\n", version)
+	fmt.Fprintln(os.Stderr, "Usage: triagectl <command> [flags]")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.summary)
 	}
+	fmt.Fprintln(os.Stderr, "\nRun 'triagectl <command> -h' for command flags. With no command, triagectl collects.")
+}
 
-	// 5. Start progress tracker
-	tracker := progress.NewTracker(len(activeCollectors))
-
-	// 6. Launch collectors with semaphore + per-collector timeouts
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(*timeout)*time.Second)
-	defer cancel()
-
-	startTime := time.Now()
-	fmt.Println("Starting artifact collection...")
-	fmt.Println()
-
-	semaphore := make(chan struct{}, *concurrency)
-	var wg sync.WaitGroup
-	resultsCh := make(chan models.CollectionResult, len(activeCollectors))
-
-	for _, collector := range activeCollectors {
-		wg.Add(1)
-		go func(c collectors.Collector) {
-			defer wg.Done()
-
-			semaphore <- struct{}{}        // acquire
-			defer func() { <-semaphore }() // release
-
-			tracker.Start(c.Name())
-
-			collectorCtx, collectorCancel := context.WithTimeout(ctx, time.Duration(*collectorTimeout)*time.Second)
-			defer collectorCancel()
-
-			start := time.Now()
-			artifacts, err := c.Collect(collectorCtx)
-			dur := time.Since(start)
-
-			result := models.CollectionResult{
-				CollectorID: c.ID(),
-				Artifacts:   artifacts,
-				Error:       err,
-				Duration:    dur,
-				StartedAt:   start,
-			}
+// caseFlag registers the --case flag shared by the verbs that read a case.
+// --db is accepted as an alias since it predates case directories.
+func caseFlag(fs *flag.FlagSet) *string {
+	path := fs.String("case", "", "Case directory or its artifacts.db (required)")
+	fs.StringVar(path, "db", "", "Alias for --case")
+	return path
+}
 
-			resultsCh <- result
-		}(collector)
+// caseArg lets the case be given positionally ("triagectl report <dir>"),
+// parsing any flags that follow it
+func caseArg(fs *flag.FlagSet, path string) string {
+	if path == "" && fs.NArg() > 0 {
+		path = fs.Arg(0)
+		fs.Parse(fs.Args()[1:])
 	}
+	return path
+}
 
-	go func() {
-		wg.Wait()
-		close(resultsCh)
-	}()
-
-	// 7. Process results: write to MultiWriter, update progress
-	var allArtifacts []models.Artifact
-	var allResults []models.CollectionResult
-	totalArtifacts := 0
-	successfulCollectors := 0
-	failedCollectors := 0
-
-	for result := range resultsCh {
-		allResults = append(allResults, result)
-
-		if result.Error != nil {
-			tracker.Fail(result.CollectorID, result.Error)
-			failedCollectors++
-			continue
-		}
-
-		if len(result.Artifacts) > 0 {
-			allArtifacts = append(allArtifacts, result.Artifacts...)
-			totalArtifacts += len(result.Artifacts)
-			tracker.Success(result.CollectorID, len(result.Artifacts))
-		} else {
-			tracker.Success(result.CollectorID, 0)
-		}
-		successfulCollectors++
+// openCase resolves the --case flag, printing an error on failure
+func openCase(fs *flag.FlagSet, path string) (*casedir.Case, bool) {
+	if path == "" {
+		fmt.Fprintf(os.Stderr, "Error: %s requires --case\n", fs.Name())
+		fs.Usage()
+		return nil, false
 	}
+	kase, err := casedir.Open(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening case: %v\n", err)
+		return nil, false
+	}
+	return kase, true
+}
 
-	tracker.Finish()
-	duration := time.Since(startTime)
-
-	// 8. Run cross-artifact analyzers
-	fmt.Println("\nRunning analysis...")
-	allArtifacts = analysis.RunAll(allArtifacts)
+func runVersion(args []string) int {
+	fmt.Printf("triagectl v%s\n", version)
+	return 0
+}
 
-	// 9. Write analyzed artifacts to all output formats
-	if err := multiWriter.WriteMany(allArtifacts); err != nil {
-		fmt.Fprintf(os.Stderr, "Error writing artifacts: %v\n", err)
+// runList prints the collector registry, or with "cases" the case
+// directories found under --output
+func runList(args []string) int {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	outputDir := fs.String("output", "./triagectl-output", "Output directory to search for cases")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: triagectl list [collectors|cases] [--output dir]")
+		fs.PrintDefaults()
 	}
+	fs.Parse(args)
 
-	// Update severity counts
-	findingsCount := 0
-	for _, a := range allArtifacts {
-		if a.RiskScore >= 40 {
-			findingsCount++
-		}
+	what := "collectors"
+	if fs.NArg() > 0 {
+		what = fs.Arg(0)
+		// Allow flags after the positional argument
+		fs.Parse(fs.Args()[1:])
 	}
-	fmt.Printf("  Analysis complete: %d findings detected\n", findingsCount)
 
-	// 9. If --timeline: generate timeline.csv
-	if *enableTimeline {
-		timelinePath := filepath.Join(collectionDir, "timeline.csv")
-		if err := output.GenerateTimeline(allArtifacts, timelinePath, report.Summarize); err != nil {
-			fmt.Fprintf(os.Stderr, "Error generating timeline: %v\n", err)
-		} else {
-			fmt.Printf("  Timeline: %s\n", timelinePath)
+	switch what {
+	case "collectors":
+		printCollectors()
+	case "cases":
+		cases, err := casedir.List(*outputDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error listing cases: %v\n", err)
+			return 1
 		}
-	}
-
-	// 10. If --html: generate report.html
-	if *enableHTML {
-		reportPath := filepath.Join(collectionDir, "report.html")
-		if err := report.GenerateHTMLReport(reportPath, allArtifacts, allResults, duration); err != nil {
-			fmt.Fprintf(os.Stderr, "Error generating HTML report: %v\n", err)
-		} else {
-			fmt.Printf("  HTML Report: %s\n", reportPath)
+		if len(cases) == 0 {
+			fmt.Printf("No cases found in %s\n", *outputDir)
+			return 0
 		}
+		fmt.Printf("%-45s %-25s %-20s %10s %9s\n", "CASE", "HOSTNAME", "COLLECTED", "ARTIFACTS", "ANALYSES")
+		for _, c := range cases {
+			m := c.Manifest
+			collected := "-"
+			if !m.CollectedAt.IsZero() {
+				collected = m.CollectedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%-45s %-25s %-20s %10d %9d\n", c.Dir, m.Hostname, collected, m.Artifacts, len(m.Analyses))
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown list target %q (want collectors or cases)\n", what)
+		return 2
 	}
-
-	// 11. Print summary
-	fmt.Println()
-	fmt.Println("═══════════════════════════════════════")
-	fmt.Println("Collection Summary")
-	fmt.Println("═══════════════════════════════════════")
-	fmt.Printf("Duration: %v\n", duration.Round(time.Millisecond))
-	fmt.Printf("Total Artifacts: %d\n", totalArtifacts)
-	fmt.Printf("Successful Collectors: %d\n", successfulCollectors)
-	fmt.Printf("Failed Collectors: %d\n", failedCollectors)
-
-	if findingsCount > 0 {
-		fmt.Printf("Findings (risk >= medium): %d\n", findingsCount)
-	}
-
-	fmt.Println()
-
-	// Print detailed stats from SQLite
-	if err := sqliteWriter.PrintStats(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error printing stats: %v\n", err)
-	}
-
-	fmt.Println()
-	fmt.Println("Output files:")
-	fmt.Printf("  - SQLite: %s\n", sqlitePath)
-	if *enableCSV {
-		fmt.Printf("  - CSV:    %s\n", csvPath)
-	}
-	if *enableTimeline {
-		fmt.Printf("  - Timeline: %s\n", filepath.Join(collectionDir, "timeline.csv"))
-	}
-	if *enableHTML {
-		fmt.Printf("  - Report: %s\n", filepath.Join(collectionDir, "report.html"))
-	}
-	fmt.Println()
-	fmt.Println("Collection complete!")
+	return 0
 }
 
 func printCollectors() {
@@ -269,35 +168,10 @@ func printCollectors() {
 		if collector.RequiresRoot() {
 			rootRequired = " [REQUIRES ROOT]"
 		}
+		if collector.LiveOnly() {
+			rootRequired += " [LIVE ONLY]"
+		}
 		fmt.Printf("  %-25s - %s%s\n", collector.ID(), collector.Description(), rootRequired)
 	}
 	fmt.Printf("\nTotal: %d collectors\n", len(collectors.Registry))
 }
-
-func filterCollectors(filter string) []collectors.Collector {
-	if filter == "" {
-		return collectors.Registry
-	}
-
-	allowed := make(map[string]bool)
-	for _, id := range strings.Split(filter, ",") {
-		id = strings.TrimSpace(id)
-		if id != "" {
-			allowed[id] = true
-		}
-	}
-
-	var filtered []collectors.Collector
-	for _, c := range collectors.Registry {
-		if allowed[c.ID()] {
-			filtered = append(filtered, c)
-		}
-	}
-
-	if len(filtered) == 0 {
-		fmt.Fprintf(os.Stderr, "Warning: no collectors matched filter '%s', running all\n", filter)
-		return collectors.Registry
-	}
-
-	return filtered
-}
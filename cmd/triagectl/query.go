@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/plonxyz/triagectl/internal/output"
+)
+
+// runQuery runs a SQL statement against a case database opened read-only
+func runQuery(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	casePath := caseFlag(fs)
+	format := fs.String("format", "table", "Output format: table, csv or json")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: triagectl query [--case] <dir> [--format table|csv|json] \"SELECT ...\"")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	path := caseArg(fs, *casePath)
+
+	switch *format {
+	case "table", "csv", "json":
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", *format)
+		return 2
+	}
+
+	kase, ok := openCase(fs, path)
+	if !ok {
+		return 2
+	}
+	query := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if query == "" {
+		fmt.Fprintln(os.Stderr, "Error: query requires a SQL statement")
+		fs.Usage()
+		return 2
+	}
+
+	db, err := output.OpenSQLiteReadOnly(kase.DBPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	rows, err := db.Query(query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running query: %v\n", err)
+		return 1
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading columns: %v\n", err)
+		return 1
+	}
+
+	var records [][]string
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading row: %v\n", err)
+			return 1
+		}
+		record := make([]string, len(columns))
+		for i, v := range values {
+			record[i] = queryValue(v)
+		}
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading rows: %v\n", err)
+		return 1
+	}
+
+	switch *format {
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		w.Write(columns)
+		w.WriteAll(records)
+	case "json":
+		out := make([]map[string]string, 0, len(records))
+		for _, rec := range records {
+			row := make(map[string]string, len(columns))
+			for i, col := range columns {
+				row[col] = rec[i]
+			}
+			out = append(out, row)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(out)
+	case "table":
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(columns, "\t")))
+		for _, rec := range records {
+			fmt.Fprintln(tw, strings.Join(rec, "\t"))
+		}
+		tw.Flush()
+		fmt.Printf("\n(%d rows)\n", len(records))
+	}
+	return 0
+}
+
+func queryValue(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(t)
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
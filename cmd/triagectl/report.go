@@ -0,0 +1,200 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/analysis"
+	"github.com/plonxyz/triagectl/internal/attack"
+	"github.com/plonxyz/triagectl/internal/casedir"
+	"github.com/plonxyz/triagectl/internal/models"
+	"github.com/plonxyz/triagectl/internal/output"
+	"github.com/plonxyz/triagectl/internal/report"
+)
+
+// runReport regenerates report.html from a case's stored artifacts and analysis
+func runReport(args []string) int {
+	fs := flag.NewFlagSet("report", flag.ExitOnError)
+	casePath := caseFlag(fs)
+	outPath := fs.String("output", "", "Report file to write (default: report.html in the case directory)")
+	attackMap := fs.String("attack-map", "", "YAML file of ATT&CK technique mappings to re-map stored tags with")
+	fs.Parse(args)
+
+	kase, ok := openCase(fs, caseArg(fs, *casePath))
+	if !ok {
+		return 2
+	}
+
+	db, artifacts, ok := loadAnalyzed(kase)
+	if !ok {
+		return 1
+	}
+	defer db.Close()
+
+	if *attackMap != "" {
+		mapping, err := attack.Load(*attackMap)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading ATT&CK mapping: %v\n", err)
+			return 1
+		}
+		analysis.SetAttackMapping(mapping)
+		analysis.MapTechniques(artifacts)
+	}
+
+	path := kase.ReportPath()
+	if *outPath != "" {
+		path = *outPath
+	}
+	if err := writeReport(path, kase, db, artifacts); err != nil {
+		return 1
+	}
+	return 0
+}
+
+// runTimeline regenerates timeline.csv or timeline.jsonl from a case's
+// stored artifacts
+func runTimeline(args []string) int {
+	fs := flag.NewFlagSet("timeline", flag.ExitOnError)
+	casePath := caseFlag(fs)
+	format := fs.String("format", "csv", "Timeline format: csv, or jsonl for Timesketch JSONL")
+	outPath := fs.String("output", "", "Timeline file to write (default: timeline.csv or timeline.jsonl in the case directory)")
+	fs.Parse(args)
+
+	kase, ok := openCase(fs, caseArg(fs, *casePath))
+	if !ok {
+		return 2
+	}
+	generate, path, err := timelineFormat(kase, *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	db, artifacts, ok := loadAnalyzed(kase)
+	if !ok {
+		return 1
+	}
+	defer db.Close()
+
+	if *outPath != "" {
+		path = *outPath
+	}
+	if err := generate(artifacts, path, report.Summarize); err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating timeline: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Timeline: %s\n", path)
+	return 0
+}
+
+// timelineFormat returns the generator and case file of a timeline format
+func timelineFormat(kase *casedir.Case, format string) (func([]models.Artifact, string, func(models.Artifact) string) error, string, error) {
+	switch format {
+	case "csv":
+		return output.GenerateTimeline, kase.TimelinePath(), nil
+	case "jsonl":
+		return output.GenerateTimelineJSONL, kase.TimelineJSONLPath(), nil
+	}
+	return nil, "", fmt.Errorf("unknown timeline format %q (want csv or jsonl)", format)
+}
+
+// loadAnalyzed opens a case database and loads its artifacts with the
+// scores and tags from the last analysis
+func loadAnalyzed(kase *casedir.Case) (*output.SQLiteWriter, []models.Artifact, bool) {
+	db, err := output.OpenSQLite(kase.DBPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		return nil, nil, false
+	}
+	artifacts, err := db.LoadArtifacts(true)
+	if err != nil {
+		db.Close()
+		fmt.Fprintf(os.Stderr, "Error loading artifacts: %v\n", err)
+		return nil, nil, false
+	}
+	if len(kase.Manifest.Analyses) == 0 {
+		fmt.Fprintln(os.Stderr, "Warning: case has not been analyzed; run 'triagectl analyze' for risk scores")
+	}
+	return db, artifacts, true
+}
+
+func writeTimeline(kase *casedir.Case, artifacts []models.Artifact, format string) {
+	generate, timelinePath, err := timelineFormat(kase, format)
+	if err == nil {
+		err = generate(artifacts, timelinePath, report.Summarize)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating timeline: %v\n", err)
+	} else {
+		fmt.Printf("  Timeline: %s\n", timelinePath)
+	}
+}
+
+// jsonlOutputPath is artifacts.jsonl in the case, or artifacts.jsonl.gz
+func jsonlOutputPath(kase *casedir.Case, compress bool) string {
+	if compress {
+		return kase.JSONLPath() + ".gz"
+	}
+	return kase.JSONLPath()
+}
+
+// writeJSONL writes every artifact to the case's JSON Lines file
+func writeJSONL(kase *casedir.Case, artifacts []models.Artifact, compress bool) {
+	path := jsonlOutputPath(kase, compress)
+	w, err := output.NewJSONLWriter(path)
+	if err == nil {
+		err = w.WriteMany(artifacts)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing JSONL: %v\n", err)
+		return
+	}
+	fmt.Printf("  JSONL: %s\n", path)
+}
+
+// writeReport renders the HTML report, reusing the recorded collector results
+func writeReport(path string, kase *casedir.Case, db *output.SQLiteWriter, artifacts []models.Artifact) error {
+	results, err := db.LoadResults(artifacts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading collector results: %v\n", err)
+		return err
+	}
+	if err := report.GenerateHTMLReport(path, artifacts, results, caseDuration(kase, results)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating HTML report: %v\n", err)
+		return err
+	}
+	fmt.Printf("  HTML Report: %s\n", path)
+	return nil
+}
+
+// caseDuration returns the recorded collection time, falling back to an
+// estimate for cases without a manifest
+func caseDuration(kase *casedir.Case, results []models.CollectionResult) time.Duration {
+	if d, err := time.ParseDuration(kase.Manifest.Duration); err == nil {
+		return d
+	}
+	return collectionDuration(results)
+}
+
+// collectionDuration approximates the original wall-clock collection time
+// from the recorded per-collector start times and durations
+func collectionDuration(results []models.CollectionResult) time.Duration {
+	var first, last time.Time
+	for _, r := range results {
+		if r.StartedAt.IsZero() {
+			continue
+		}
+		if first.IsZero() || r.StartedAt.Before(first) {
+			first = r.StartedAt
+		}
+		if end := r.StartedAt.Add(r.Duration); end.After(last) {
+			last = end
+		}
+	}
+	return last.Sub(first)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/plonxyz/triagectl/internal/casedir"
+	"github.com/plonxyz/triagectl/internal/normalize"
+	"github.com/plonxyz/triagectl/internal/output"
+	"github.com/plonxyz/triagectl/internal/report"
+)
+
+// shipOptions are the flags of the Elasticsearch/OpenSearch and Splunk HEC
+// writers, shared by collect and ship. Secrets may come from the
+// environment instead, keeping them out of the process list.
+type shipOptions struct {
+	esURL      *string
+	esIndex    *string
+	esFormat   *string
+	esAPIKey   *string
+	esUser     *string
+	esPassword *string
+
+	splunkURL   *string
+	splunkToken *string
+	splunkIndex *string
+
+	batch      *int
+	retries    *int
+	caCert     *string
+	clientCert *string
+	clientKey  *string
+	insecure   *bool
+}
+
+func shipFlags(fs *flag.FlagSet) *shipOptions {
+	return &shipOptions{
+		esURL:      fs.String("es-url", "", "Elasticsearch/OpenSearch URL to ship artifacts to (e.g. https://es:9200)"),
+		esIndex:    fs.String("es-index", "", "Index to ship to (default: triagectl-<format>)"),
+		esFormat:   fs.String("es-format", normalize.SchemaECS, "Schema of shipped documents: ecs or ocsf"),
+		esAPIKey:   fs.String("es-api-key", "", "Elasticsearch API key (base64 id:key; or $TRIAGECTL_ES_API_KEY)"),
+		esUser:     fs.String("es-user", "", "Elasticsearch/OpenSearch user for basic authentication"),
+		esPassword: fs.String("es-password", "", "Password for --es-user (or $TRIAGECTL_ES_PASSWORD)"),
+
+		splunkURL:   fs.String("splunk-url", "", "Splunk HTTP Event Collector URL to ship artifacts to (e.g. https://splunk:8088)"),
+		splunkToken: fs.String("splunk-token", "", "HEC token (or $TRIAGECTL_SPLUNK_TOKEN)"),
+		splunkIndex: fs.String("splunk-index", "", "Splunk index (default: the token's default index)"),
+
+		batch:      fs.Int("ship-batch", 500, "Artifacts per shipping request"),
+		retries:    fs.Int("ship-retries", 5, "Retries per failed shipping request, with exponential backoff"),
+		caCert:     fs.String("ship-ca-cert", "", "PEM file of CAs to trust when shipping"),
+		clientCert: fs.String("ship-client-cert", "", "PEM client certificate for shipping"),
+		clientKey:  fs.String("ship-client-key", "", "PEM key of --ship-client-cert"),
+		insecure:   fs.Bool("ship-insecure", false, "Skip TLS certificate verification when shipping"),
+	}
+}
+
+// shipWriter is a network writer that reports what it delivered
+type shipWriter interface {
+	output.Writer
+	Stats() output.ShipStats
+}
+
+type shipTarget struct {
+	name   string
+	url    string
+	spool  string
+	writer shipWriter
+}
+
+func envDefault(v *string, name string) string {
+	if *v != "" {
+		return *v
+	}
+	return os.Getenv(name)
+}
+
+// targets creates a writer per configured endpoint, spooling into the case
+func (o *shipOptions) targets(kase *casedir.Case) ([]*shipTarget, error) {
+	var targets []*shipTarget
+	httpConfig := func(url, spool string) output.HTTPConfig {
+		return output.HTTPConfig{
+			URL:        url,
+			BatchSize:  *o.batch,
+			MaxRetries: *o.retries,
+			CACert:     *o.caCert,
+			ClientCert: *o.clientCert,
+			ClientKey:  *o.clientKey,
+			Insecure:   *o.insecure,
+			SpoolPath:  spool,
+		}
+	}
+
+	if *o.esURL != "" {
+		mapper, err := normalize.NewMapper(*o.esFormat, version, report.Summarize)
+		if err != nil {
+			return nil, err
+		}
+		cfg := output.ElasticConfig{
+			HTTPConfig: httpConfig(*o.esURL, kase.Path(casedir.ElasticSpoolFile)),
+			APIKey:     envDefault(o.esAPIKey, "TRIAGECTL_ES_API_KEY"),
+			Username:   *o.esUser,
+			Password:   envDefault(o.esPassword, "TRIAGECTL_ES_PASSWORD"),
+		}
+		cfg.Index = *o.esIndex
+		w, err := output.NewElasticWriter(cfg, mapper)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, &shipTarget{"Elasticsearch", *o.esURL, cfg.SpoolPath, w})
+	}
+
+	if *o.splunkURL != "" {
+		cfg := output.SplunkConfig{
+			HTTPConfig: httpConfig(*o.splunkURL, kase.Path(casedir.SplunkSpoolFile)),
+			Token:      envDefault(o.splunkToken, "TRIAGECTL_SPLUNK_TOKEN"),
+		}
+		if cfg.Token == "" {
+			return nil, fmt.Errorf("--splunk-url requires --splunk-token")
+		}
+		cfg.Index = *o.splunkIndex
+		w, err := output.NewSplunkWriter(cfg)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, &shipTarget{"Splunk HEC", *o.splunkURL, cfg.SpoolPath, w})
+	}
+	return targets, nil
+}
+
+// finishShipping sends what the targets still queue and reports the
+// outcome, returning false if anything was spooled, rejected or lost
+func finishShipping(targets []*shipTarget) bool {
+	ok := true
+	for _, t := range targets {
+		err := t.writer.Close()
+		stats := t.writer.Stats()
+		fmt.Printf("  %s: shipped %d artifacts to %s\n", t.name, stats.Sent, t.url)
+		if stats.Spooled > 0 {
+			fmt.Printf("  %s: spooled %d artifacts to %s (%v)\n", t.name, stats.Spooled, t.spool, stats.LastErr)
+			ok = false
+		}
+		if stats.Rejected > 0 {
+			fmt.Printf("  %s: %d artifacts rejected, saved to %s.rejected (%v)\n", t.name, stats.Rejected, t.spool, stats.LastErr)
+			ok = false
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error shipping to %s: %v\n", t.name, err)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// runShip sends a case's stored artifacts and analysis to the configured
+// SIEM endpoints
+func runShip(args []string) int {
+	fs := flag.NewFlagSet("ship", flag.ExitOnError)
+	casePath := caseFlag(fs)
+	opts := shipFlags(fs)
+	fs.Parse(args)
+
+	kase, ok := openCase(fs, caseArg(fs, *casePath))
+	if !ok {
+		return 2
+	}
+	if *opts.esURL == "" && *opts.splunkURL == "" {
+		fmt.Fprintln(os.Stderr, "Error: ship requires --es-url or --splunk-url")
+		fs.Usage()
+		return 2
+	}
+	targets, err := opts.targets(kase)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	db, artifacts, ok := loadAnalyzed(kase)
+	if !ok {
+		return 1
+	}
+	defer db.Close()
+
+	for _, t := range targets {
+		if err := t.writer.WriteMany(artifacts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error shipping to %s: %v\n", t.name, err)
+		}
+	}
+	if !finishShipping(targets) {
+		return 1
+	}
+	return 0
+}
@@ -1,6 +1,9 @@
 package analysis
 
-import "github.com/plonxyz/triagectl/internal/models"
+import (
+	"github.com/plonxyz/triagectl/internal/attack"
+	"github.com/plonxyz/triagectl/internal/models"
+)
 
 // Analyzer inspects artifacts and enriches them with risk scores, severity, and tags
 type Analyzer interface {
@@ -14,11 +17,25 @@ var analyzers []Analyzer
 func init() {
 	analyzers = []Analyzer{
 		&SuspiciousProcessAnalyzer{},
+		&ProcessTreeAnalyzer{},
 		&NetworkAnomalyAnalyzer{},
 		&PersistenceAnomalyAnalyzer{},
 	}
 }
 
+// attackMapping maps tags to ATT&CK techniques once the analyzers have run
+var attackMapping = attack.Default()
+
+// SetAttackMapping replaces the embedded ATT&CK mapping (used for --attack-map)
+func SetAttackMapping(m *attack.Mapping) {
+	attackMapping = m
+}
+
+// AttackMapping returns the ATT&CK mapping findings are tagged with
+func AttackMapping() *attack.Mapping {
+	return attackMapping
+}
+
 // RegisterAnalyzer adds an analyzer to the pipeline (used for IOC matcher)
 func RegisterAnalyzer(a Analyzer) {
 	analyzers = append(analyzers, a)
@@ -35,5 +52,16 @@ func RunAll(artifacts []models.Artifact) []models.Artifact {
 			artifacts[i].Severity = models.SeverityFromScore(artifacts[i].RiskScore)
 		}
 	}
+	MapTechniques(artifacts)
 	return artifacts
 }
+
+// MapTechniques sets each artifact's ATT&CK techniques from its tags, and
+// from its type if it scored as a finding. Suppression does not count: a
+// suppressed artifact keeps its techniques.
+func MapTechniques(artifacts []models.Artifact) {
+	for i := range artifacts {
+		a := &artifacts[i]
+		a.Techniques = attackMapping.TechniquesFor(a.ArtifactType, a.Tags, a.RiskScore >= models.FindingScore)
+	}
+}
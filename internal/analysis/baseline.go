@@ -0,0 +1,253 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// Baseline categories. Each maps to the identities of one kind of entity
+// seen on known-good machines.
+const (
+	BaselineHashes        = "hashes"
+	BaselineLaunchdLabels = "launchd_labels"
+	BaselineLoginItems    = "login_items"
+	BaselineAppBundleIDs  = "app_bundle_ids"
+	BaselineKextIDs       = "kext_ids"
+	BaselineTCCGrants     = "tcc_grants"
+	BaselineProcessPaths  = "process_paths"
+	BaselineCronEntries   = "cron_entries"
+)
+
+// Baseline is the set of entities present on known-good collections
+type Baseline struct {
+	Version   int                 `json:"version"`
+	CreatedAt time.Time           `json:"created_at"`
+	Sources   []string            `json:"sources,omitempty"`
+	Entries   map[string][]string `json:"entries"`
+
+	sets map[string]map[string]bool
+}
+
+// NewBaseline returns an empty baseline
+func NewBaseline() *Baseline {
+	return &Baseline{
+		Version:   1,
+		CreatedAt: time.Now().UTC(),
+		Entries:   make(map[string][]string),
+		sets:      make(map[string]map[string]bool),
+	}
+}
+
+// LoadBaseline reads a baseline file written by Save
+func LoadBaseline(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	b := NewBaseline()
+	if err := json.Unmarshal(data, b); err != nil {
+		return nil, fmt.Errorf("parsing baseline %s: %w", path, err)
+	}
+	for category, values := range b.Entries {
+		for _, v := range values {
+			b.add(category, v)
+		}
+	}
+	return b, nil
+}
+
+// AddArtifacts records the identities of every artifact in a known-good
+// collection; source names the collection in the baseline file
+func (b *Baseline) AddArtifacts(source string, artifacts []models.Artifact) {
+	b.Sources = append(b.Sources, source)
+	for _, a := range artifacts {
+		ids, hashes := baselineKeys(a)
+		for _, id := range ids {
+			b.add(id.category, id.value)
+		}
+		for _, h := range hashes {
+			b.add(BaselineHashes, h)
+		}
+	}
+}
+
+// Count returns the number of entries in a category
+func (b *Baseline) Count(category string) int { return len(b.sets[category]) }
+
+// Save writes the baseline as JSON with sorted entries
+func (b *Baseline) Save(path string) error {
+	b.Entries = make(map[string][]string, len(b.sets))
+	for category, set := range b.sets {
+		values := make([]string, 0, len(set))
+		for v := range set {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		b.Entries[category] = values
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func (b *Baseline) add(category, value string) {
+	if value == "" {
+		return
+	}
+	if b.sets[category] == nil {
+		b.sets[category] = make(map[string]bool)
+	}
+	b.sets[category][value] = true
+}
+
+func (b *Baseline) has(category, value string) bool { return b.sets[category][value] }
+
+type baselineID struct {
+	category string
+	value    string
+}
+
+// baselineKeys extracts the identities an artifact is baselined by, plus
+// the hashes of the file(s) it refers to
+func baselineKeys(a models.Artifact) ([]baselineID, []string) {
+	var ids []baselineID
+	id := func(category, value string) {
+		if value != "" {
+			ids = append(ids, baselineID{category, value})
+		}
+	}
+
+	switch a.ArtifactType {
+	case "user_launch_agent", "system_launch_agent", "system_launch_daemon":
+		label := GetString(a.Data, "label")
+		if label == "" {
+			label = GetString(a.Data, "name")
+		}
+		id(BaselineLaunchdLabels, label)
+	case "login_item_btm":
+		item := GetString(a.Data, "bundle_id")
+		if item == "" {
+			item = GetString(a.Data, "identifier")
+		}
+		if item == "" {
+			item = GetString(a.Data, "Name")
+		}
+		id(BaselineLoginItems, item)
+	case "login_item_backgrounditems":
+		id(BaselineLoginItems, GetString(a.Data, "path"))
+	case "system_application", "user_application":
+		bundleID := GetString(a.Data, "bundle_id")
+		if bundleID == "" {
+			bundleID = GetString(a.Data, "path")
+		}
+		id(BaselineAppBundleIDs, bundleID)
+	case "kernel_extension", "library_extension":
+		id(BaselineKextIDs, GetString(a.Data, "name"))
+	case "system_extension":
+		id(BaselineKextIDs, GetString(a.Data, "identifier"))
+	case "tcc_permission":
+		// Only grants; denied entries are not a risk to baseline
+		switch fmt.Sprint(a.Data["auth_value"]) {
+		case "2", "3":
+			id(BaselineTCCGrants, GetString(a.Data, "service")+"|"+GetString(a.Data, "client"))
+		}
+	case "running_process":
+		id(BaselineProcessPaths, GetString(a.Data, "exe"))
+	case "user_crontab", "system_cron":
+		id(BaselineCronEntries, GetString(a.Data, "entry"))
+	}
+
+	var hashes []string
+	for _, h := range []string{GetString(a.Data, "target_sha256"), a.Metadata.FileHash} {
+		if h != "" {
+			hashes = append(hashes, strings.ToLower(h))
+		}
+	}
+	return ids, hashes
+}
+
+// BaselineAnalyzer compares artifacts against a known-good baseline: entities
+// seen on the baseline machines are scored down, entities absent from it are
+// scored up, and a known entity whose binary hash differs is flagged
+type BaselineAnalyzer struct {
+	baseline *Baseline
+}
+
+func (a *BaselineAnalyzer) Name() string { return "baseline" }
+
+// NewBaselineAnalyzer loads the baseline file at path
+func NewBaselineAnalyzer(path string) (*BaselineAnalyzer, error) {
+	b, err := LoadBaseline(path)
+	if err != nil {
+		return nil, err
+	}
+	return &BaselineAnalyzer{baseline: b}, nil
+}
+
+func (a *BaselineAnalyzer) Analyze(artifacts []models.Artifact) []models.Artifact {
+	b := a.baseline
+	for i, art := range artifacts {
+		ids, hashes := baselineKeys(art)
+		if len(ids) == 0 {
+			continue
+		}
+		// A category absent from the baseline says nothing about this artifact
+		if b.Count(ids[0].category) == 0 {
+			continue
+		}
+
+		known := true
+		for _, id := range ids {
+			if !b.has(id.category, id.value) {
+				known = false
+				break
+			}
+		}
+
+		hashKnown := true
+		if b.Count(BaselineHashes) > 0 {
+			for _, h := range hashes {
+				if !b.has(BaselineHashes, h) {
+					hashKnown = false
+				}
+			}
+		}
+
+		switch {
+		case known && !hashKnown:
+			score(&artifacts[i], a.Name(), hit("baseline_hash_mismatch", 25, ids[0].category, ids[0].value))
+			artifacts[i].Tags = AppendUnique(artifacts[i].Tags, "baseline_hash_mismatch")
+		case known:
+			// Never talk down an IOC or Sigma hit
+			if hasTagPrefix(art.Tags, "ioc_match") || hasTagPrefix(art.Tags, "sigma:") {
+				continue
+			}
+			artifacts[i].Tags = AppendUnique(artifacts[i].Tags, "baseline_known")
+			// Only scored artifacts have points to take off
+			if art.RiskScore > 0 {
+				score(&artifacts[i], a.Name(), hit("baseline_known", -20, ids[0].category, ids[0].value))
+			}
+		default:
+			score(&artifacts[i], a.Name(), hit("baseline_absent", 15, ids[0].category, ids[0].value))
+			artifacts[i].Tags = AppendUnique(artifacts[i].Tags, "baseline_absent")
+		}
+	}
+	return artifacts
+}
+
+func hasTagPrefix(tags []string, prefix string) bool {
+	for _, t := range tags {
+		if strings.HasPrefix(t, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package analysis
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+func TestBaselineKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		art    models.Artifact
+		ids    []baselineID
+		hashes []string
+	}{
+		{
+			name:   "launch agent by label, with target hash",
+			art:    fixture("user_launch_agent", map[string]interface{}{"label": "com.x", "name": "x.plist", "target_sha256": "ABCD"}),
+			ids:    []baselineID{{BaselineLaunchdLabels, "com.x"}},
+			hashes: []string{"abcd"},
+		},
+		{
+			name: "launch daemon falls back to name",
+			art:  fixture("system_launch_daemon", map[string]interface{}{"name": "x.plist"}),
+			ids:  []baselineID{{BaselineLaunchdLabels, "x.plist"}},
+		},
+		{
+			name: "btm login item by bundle ID",
+			art:  fixture("login_item_btm", map[string]interface{}{"bundle_id": "com.b", "identifier": "id", "Name": "n"}),
+			ids:  []baselineID{{BaselineLoginItems, "com.b"}},
+		},
+		{
+			name: "btm login item falls back to name",
+			art:  fixture("login_item_btm", map[string]interface{}{"Name": "n"}),
+			ids:  []baselineID{{BaselineLoginItems, "n"}},
+		},
+		{
+			name: "background item by path",
+			art:  fixture("login_item_backgrounditems", map[string]interface{}{"path": "/Applications/X.app"}),
+			ids:  []baselineID{{BaselineLoginItems, "/Applications/X.app"}},
+		},
+		{
+			name: "app without bundle ID by path",
+			art:  fixture("user_application", map[string]interface{}{"path": "/Users/a/Applications/Y.app"}),
+			ids:  []baselineID{{BaselineAppBundleIDs, "/Users/a/Applications/Y.app"}},
+		},
+		{
+			name: "kext by name",
+			art:  fixture("kernel_extension", map[string]interface{}{"name": "com.k"}),
+			ids:  []baselineID{{BaselineKextIDs, "com.k"}},
+		},
+		{
+			name: "system extension by identifier",
+			art:  fixture("system_extension", map[string]interface{}{"identifier": "com.s"}),
+			ids:  []baselineID{{BaselineKextIDs, "com.s"}},
+		},
+		{
+			name: "TCC grant",
+			art:  fixture("tcc_permission", map[string]interface{}{"auth_value": 2, "service": "kTCCServiceCamera", "client": "com.c"}),
+			ids:  []baselineID{{BaselineTCCGrants, "kTCCServiceCamera|com.c"}},
+		},
+		{
+			name: "TCC grant loaded from a database",
+			art:  fixture("tcc_permission", map[string]interface{}{"auth_value": float64(3), "service": "s", "client": "c"}),
+			ids:  []baselineID{{BaselineTCCGrants, "s|c"}},
+		},
+		{
+			name: "TCC denial not baselined",
+			art:  fixture("tcc_permission", map[string]interface{}{"auth_value": 0, "service": "s", "client": "c"}),
+		},
+		{
+			name: "process by executable, with file hash",
+			art: models.Artifact{
+				ArtifactType: "running_process",
+				Data:         map[string]interface{}{"exe": "/bin/x"},
+				Metadata:     models.ArtifactMetadata{FileHash: "EF01"},
+			},
+			ids:    []baselineID{{BaselineProcessPaths, "/bin/x"}},
+			hashes: []string{"ef01"},
+		},
+		{
+			name: "cron entry",
+			art:  fixture("user_crontab", map[string]interface{}{"entry": "* * * * * /bin/x"}),
+			ids:  []baselineID{{BaselineCronEntries, "* * * * * /bin/x"}},
+		},
+		{
+			name: "missing identity",
+			art:  fixture("user_launch_agent", map[string]interface{}{}),
+		},
+		{
+			name: "type not baselined",
+			art:  fixture("network_connection", map[string]interface{}{"remote_addr": "10.0.0.1"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids, hashes := baselineKeys(tt.art)
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("ids = %v, want %v", ids, tt.ids)
+			}
+			if !reflect.DeepEqual(hashes, tt.hashes) {
+				t.Errorf("hashes = %v, want %v", hashes, tt.hashes)
+			}
+		})
+	}
+}
+
+func TestBaselineSaveLoad(t *testing.T) {
+	b := NewBaseline()
+	b.AddArtifacts("gold-1", []models.Artifact{
+		fixture("user_launch_agent", map[string]interface{}{"label": "com.b", "target_sha256": "AA"}),
+		fixture("user_launch_agent", map[string]interface{}{"label": "com.a"}),
+	})
+	b.AddArtifacts("gold-2", []models.Artifact{
+		fixture("user_launch_agent", map[string]interface{}{"label": "com.a"}),
+		fixture("running_process", map[string]interface{}{"exe": "/bin/x"}),
+	})
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := b.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Sources, []string{"gold-1", "gold-2"}) {
+		t.Errorf("sources = %v", loaded.Sources)
+	}
+	wantEntries := map[string][]string{
+		BaselineLaunchdLabels: {"com.a", "com.b"},
+		BaselineHashes:        {"aa"},
+		BaselineProcessPaths:  {"/bin/x"},
+	}
+	if !reflect.DeepEqual(loaded.Entries, wantEntries) {
+		t.Errorf("entries = %v, want %v", loaded.Entries, wantEntries)
+	}
+	if loaded.Count(BaselineLaunchdLabels) != 2 || !loaded.has(BaselineLaunchdLabels, "com.b") {
+		t.Errorf("loaded baseline does not know com.b")
+	}
+	if !loaded.CreatedAt.Equal(b.CreatedAt) {
+		t.Errorf("created_at = %v, want %v", loaded.CreatedAt, b.CreatedAt)
+	}
+}
+
+func TestBaselineAnalyzer(t *testing.T) {
+	b := NewBaseline()
+	b.AddArtifacts("gold", []models.Artifact{
+		fixture("user_launch_agent", map[string]interface{}{"label": "com.known", "target_sha256": "aaaa"}),
+	})
+	analyzer := &BaselineAnalyzer{baseline: b}
+	withWeights(t, &ScoreWeights{})
+
+	agent := func(label, sha string, score int, tags ...string) models.Artifact {
+		a := fixture("user_launch_agent", map[string]interface{}{"label": label, "target_sha256": sha})
+		if score > 0 {
+			a.Contributions = []models.ScoreContribution{{Analyzer: "persistence", Rule: "r", Points: score}}
+			a.RiskScore = score
+		}
+		a.Tags = tags
+		return a
+	}
+	tests := []struct {
+		name  string
+		art   models.Artifact
+		score int
+		tags  []string
+		rules []string // baseline contributions
+	}{
+		{"known and scored", agent("com.known", "aaaa", 50), 30, []string{"baseline_known"}, []string{"baseline_known"}},
+		{"known at score 0", agent("com.known", "aaaa", 0), 0, []string{"baseline_known"}, nil},
+		{"known, small score clamped", agent("com.known", "aaaa", 10), 0, []string{"baseline_known"}, []string{"baseline_known"}},
+		{"absent", agent("com.new", "aaaa", 0), 15, []string{"baseline_absent"}, []string{"baseline_absent"}},
+		{"hash mismatch", agent("com.known", "bbbb", 0), 25, []string{"baseline_hash_mismatch"}, []string{"baseline_hash_mismatch"}},
+		{"IOC hit not reduced", agent("com.known", "aaaa", 60, "ioc_match"), 60, []string{"ioc_match"}, nil},
+		{"Sigma hit not reduced", agent("com.known", "aaaa", 60, "sigma:abc"), 60, []string{"sigma:abc"}, nil},
+		{"category not in baseline", fixture("running_process", map[string]interface{}{"exe": "/bin/x"}), 0, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := analyzer.Analyze([]models.Artifact{tt.art})[0]
+			if got.RiskScore != tt.score {
+				t.Errorf("score = %d, want %d", got.RiskScore, tt.score)
+			}
+			if !reflect.DeepEqual(got.Tags, tt.tags) {
+				t.Errorf("tags = %v, want %v", got.Tags, tt.tags)
+			}
+			var rules []string
+			for _, c := range got.Contributions {
+				if c.Analyzer == "baseline" {
+					rules = append(rules, c.Rule)
+				}
+			}
+			if !reflect.DeepEqual(rules, tt.rules) {
+				t.Errorf("baseline contributions = %v, want %v", rules, tt.rules)
+			}
+		})
+	}
+}
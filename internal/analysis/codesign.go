@@ -0,0 +1,44 @@
+package analysis
+
+import "github.com/plonxyz/triagectl/internal/models"
+
+// riskyEntitlements weaken the hardened runtime or let other processes
+// attach to or inject into the binary
+var riskyEntitlements = []string{
+	"com.apple.security.get-task-allow",
+	"com.apple.security.cs.disable-library-validation",
+	"com.apple.security.cs.allow-dyld-environment-variables",
+	"com.apple.security.cs.allow-unsigned-executable-memory",
+	"com.apple.security.cs.disable-executable-page-protection",
+}
+
+// scoreCodeSignature scores the code signature collectors attached under
+// prefix, tagging with tagPrefix. Artifacts without a signature (not a
+// Mach-O binary, or the file was missing) score nothing.
+func scoreCodeSignature(data map[string]interface{}, prefix, tagPrefix string) ([]models.ScoreContribution, []string) {
+	signed, ok := data[prefix+"signed"].(bool)
+	if !ok {
+		return nil, nil
+	}
+	if !signed {
+		return []models.ScoreContribution{hit(tagPrefix+"_unsigned", 25, prefix+"signed", "false")},
+			[]string{tagPrefix + "_unsigned"}
+	}
+
+	var hits []models.ScoreContribution
+	var tags []string
+	if adhoc, _ := data[prefix+"adhoc_signed"].(bool); adhoc {
+		hits = append(hits, hit(tagPrefix+"_adhoc_signed", 15, prefix+"adhoc_signed", "true"))
+		tags = append(tags, tagPrefix+"_adhoc_signed")
+	}
+	if ents, ok := data[prefix+"entitlements"].(map[string]interface{}); ok {
+		for _, ent := range riskyEntitlements {
+			if v, _ := ents[ent].(bool); v {
+				hits = append(hits, hit(tagPrefix+"_risky_entitlements", 10, prefix+"entitlements", ent))
+				tags = append(tags, tagPrefix+"_risky_entitlements")
+				break
+			}
+		}
+	}
+	return hits, tags
+}
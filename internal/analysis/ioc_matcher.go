@@ -1,120 +1,101 @@
 package analysis
 
 import (
-	"bufio"
-	"net"
-	"os"
-	"regexp"
+	"fmt"
 	"strings"
+	"time"
 
+	"github.com/plonxyz/triagectl/internal/ioc"
 	"github.com/plonxyz/triagectl/internal/models"
 )
 
 // IOCMatcher matches artifacts against a list of Indicators of Compromise
 type IOCMatcher struct {
-	ips     map[string]bool
-	domains map[string]bool
-	hashes  map[string]bool
-	paths   map[string]bool
+	matcher *ioc.Matcher
+	expired int
+	skipped []error
 }
 
 func (m *IOCMatcher) Name() string { return "ioc_matcher" }
 
-// NewIOCMatcher loads IOCs from a file (one per line, auto-detects type)
+// NewIOCMatcher loads IOCs from a flat list (one per line, auto-detects
+// type), a typed CSV, a STIX 2.1 bundle or a MISP event export. Indicators
+// past their valid_until are dropped.
 func NewIOCMatcher(filePath string) (*IOCMatcher, error) {
-	f, err := os.Open(filePath)
+	inds, skipped, err := ioc.Load(filePath)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
 
-	m := &IOCMatcher{
-		ips:     make(map[string]bool),
-		domains: make(map[string]bool),
-		hashes:  make(map[string]bool),
-		paths:   make(map[string]bool),
-	}
-
-	hashRegex := regexp.MustCompile(`^[0-9a-fA-F]{32,128}$`)
-	domainRegex := regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)+$`)
-
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" || strings.HasPrefix(line, "#") {
+	m := &IOCMatcher{skipped: skipped}
+	now := time.Now()
+	active := inds[:0]
+	for _, ind := range inds {
+		if ind.Expired(now) {
+			m.expired++
 			continue
 		}
-
-		switch {
-		case net.ParseIP(line) != nil:
-			m.ips[line] = true
-		case hashRegex.MatchString(line):
-			m.hashes[strings.ToLower(line)] = true
-		case strings.HasPrefix(line, "/"):
-			m.paths[line] = true
-		case domainRegex.MatchString(line):
-			m.domains[strings.ToLower(line)] = true
-		default:
-			// Try as domain anyway
-			m.domains[strings.ToLower(line)] = true
-		}
+		active = append(active, ind)
+	}
+	if m.matcher, err = ioc.NewMatcher(active); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
 	}
+	return m, nil
+}
 
-	return m, scanner.Err()
+// Indicators returns the number of active indicators and of those dropped
+// as expired
+func (m *IOCMatcher) Indicators() (active, expired int) {
+	return m.matcher.Len(), m.expired
 }
 
+// Skipped returns an error for each indicator that could not be loaded
+func (m *IOCMatcher) Skipped() []error { return m.skipped }
+
 func (m *IOCMatcher) Analyze(artifacts []models.Artifact) []models.Artifact {
 	for i, art := range artifacts {
-		matched := false
-		var tags []string
-
-		// Check all string values in Data for matches
-		for _, v := range art.Data {
-			s, ok := v.(string)
-			if !ok {
-				continue
-			}
-
-			// IP match
-			for ip := range m.ips {
-				if strings.Contains(s, ip) {
-					matched = true
-					tags = append(tags, "ioc_match:ip:"+ip)
-				}
-			}
-
-			// Domain match
-			sLower := strings.ToLower(s)
-			for domain := range m.domains {
-				if strings.Contains(sLower, domain) {
-					matched = true
-					tags = append(tags, "ioc_match:domain:"+domain)
-				}
-			}
+		hits := m.matcher.Match(art.Data, art.Metadata.FileHash)
+		if len(hits) == 0 {
+			continue
+		}
 
-			// Hash match
-			for hash := range m.hashes {
-				if strings.Contains(sLower, hash) {
-					matched = true
-					tags = append(tags, "ioc_match:hash:"+hash)
-				}
+		var tags, values []string
+		for _, h := range hits {
+			ind := h.Indicator
+			tags = append(tags, "ioc_match:"+ind.Type+":"+ind.Value)
+			if ind.Source != "" {
+				tags = append(tags, "ioc_source:"+ind.Source)
 			}
-
-			// Path match
-			for path := range m.paths {
-				if strings.Contains(s, path) {
-					matched = true
-					tags = append(tags, "ioc_match:path:"+path)
-				}
+			if ind.Event != "" {
+				tags = append(tags, "ioc_event:"+ind.Event)
 			}
+			artifacts[i].IOCMatches = append(artifacts[i].IOCMatches, iocMatch(ind, h.Field))
+			values = append(values, ind.Value)
 		}
 
-		if matched {
-			artifacts[i].RiskScore = 90
-			artifacts[i].Tags = appendUnique(artifacts[i].Tags, "ioc_match")
-			artifacts[i].Tags = appendUnique(artifacts[i].Tags, tags...)
-		}
+		// One contribution however many indicators hit; ioc_matches has each
+		score(&artifacts[i], m.Name(), hit("ioc_match", 90, hits[0].Field, strings.Join(values, ", ")))
+		artifacts[i].Tags = AppendUnique(artifacts[i].Tags, "ioc_match")
+		artifacts[i].Tags = AppendUnique(artifacts[i].Tags, tags...)
 	}
 
 	return artifacts
 }
+
+func iocMatch(ind ioc.Indicator, field string) models.IOCMatch {
+	m := models.IOCMatch{
+		Type:       ind.Type,
+		Value:      ind.Value,
+		Field:      field,
+		Name:       ind.Name,
+		Source:     ind.Source,
+		Event:      ind.Event,
+		EventID:    ind.EventID,
+		Confidence: ind.Confidence,
+	}
+	if !ind.ValidUntil.IsZero() {
+		t := ind.ValidUntil
+		m.ValidUntil = &t
+	}
+	return m
+}
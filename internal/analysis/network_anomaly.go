@@ -31,7 +31,7 @@ func (a *NetworkAnomalyAnalyzer) Analyze(artifacts []models.Artifact) []models.A
 	pidConnCount := make(map[string]int)
 	for _, art := range artifacts {
 		if art.ArtifactType == "network_connection" || art.ArtifactType == "open_network_file" {
-			pid := getString(art.Data, "pid")
+			pid := GetString(art.Data, "pid")
 			if pid != "" {
 				pidConnCount[pid]++
 			}
@@ -43,20 +43,20 @@ func (a *NetworkAnomalyAnalyzer) Analyze(artifacts []models.Artifact) []models.A
 			continue
 		}
 
-		score := 0
+		var hits []models.ScoreContribution
 		var tags []string
 
 		remotePort := getPort(art.Data, "remote_port")
 		if remotePort == 0 {
 			// Try extracting from remote_addr for open_files
-			if addr := getString(art.Data, "remote_addr"); addr != "" {
+			if addr := GetString(art.Data, "remote_addr"); addr != "" {
 				if idx := strings.LastIndex(addr, ":"); idx >= 0 {
 					remotePort, _ = strconv.Atoi(addr[idx+1:])
 				}
 			}
 		}
 
-		remoteAddr := getString(art.Data, "remote_addr")
+		remoteAddr := GetString(art.Data, "remote_addr")
 		isExternal := remoteAddr != "" &&
 			!strings.HasPrefix(remoteAddr, "127.") &&
 			!strings.HasPrefix(remoteAddr, "::1") &&
@@ -65,33 +65,36 @@ func (a *NetworkAnomalyAnalyzer) Analyze(artifacts []models.Artifact) []models.A
 
 		// Common C2 ports
 		if remotePort > 0 && c2Ports[remotePort] && isExternal {
-			score += 30
+			hits = append(hits, hit("c2_port", 30, "remote_port", strconv.Itoa(remotePort)))
 			tags = append(tags, fmt.Sprintf("c2_port:%d", remotePort))
 		}
 
 		// IRC ports to external IPs
 		if remotePort > 0 && ircPorts[remotePort] && isExternal {
-			score += 20
+			hits = append(hits, hit("irc_connection", 20, "remote_port", strconv.Itoa(remotePort)))
 			tags = append(tags, "irc_connection")
 		}
 
 		// Tor ports (check both remote and local — Tor listens locally on SOCKS port)
 		localPort := getPort(art.Data, "local_port")
-		if (remotePort > 0 && torPorts[remotePort]) || (localPort > 0 && torPorts[localPort]) {
-			score += 20
+		if remotePort > 0 && torPorts[remotePort] {
+			hits = append(hits, hit("tor_connection", 20, "remote_port", strconv.Itoa(remotePort)))
+			tags = append(tags, "tor_connection")
+		} else if localPort > 0 && torPorts[localPort] {
+			hits = append(hits, hit("tor_connection", 20, "local_port", strconv.Itoa(localPort)))
 			tags = append(tags, "tor_connection")
 		}
 
 		// High connection count from single PID
-		pid := getString(art.Data, "pid")
+		pid := GetString(art.Data, "pid")
 		if pid != "" && pidConnCount[pid] > 50 {
-			score += 10
+			hits = append(hits, hit("high_conn_count", 10, "pid", pid))
 			tags = append(tags, "high_conn_count")
 		}
 
-		if score > 0 {
-			artifacts[i].RiskScore += score
-			artifacts[i].Tags = appendUnique(artifacts[i].Tags, tags...)
+		if len(hits) > 0 {
+			score(&artifacts[i], a.Name(), hits...)
+			artifacts[i].Tags = AppendUnique(artifacts[i].Tags, tags...)
 		}
 	}
 
@@ -99,7 +102,7 @@ func (a *NetworkAnomalyAnalyzer) Analyze(artifacts []models.Artifact) []models.A
 }
 
 func getPort(d map[string]interface{}, key string) int {
-	v := getString(d, key)
+	v := GetString(d, key)
 	if v == "" {
 		return 0
 	}
@@ -16,116 +16,201 @@ func (a *PersistenceAnomalyAnalyzer) Analyze(artifacts []models.Artifact) []mode
 	now := time.Now()
 
 	for i, art := range artifacts {
-		score := 0
+		var hits []models.ScoreContribution
 		var tags []string
 
 		switch art.ArtifactType {
 		case "user_launch_agent", "system_launch_agent", "system_launch_daemon":
-			score, tags = a.analyzeLaunchAgent(art, now)
+			hits, tags = a.analyzeLaunchAgent(art, now)
 		case "user_crontab", "system_cron":
-			score, tags = a.analyzeCron(art)
+			hits, tags = a.analyzeCron(art)
 		case "login_item_btm", "login_item_backgrounditems":
-			score, tags = a.analyzeLoginItem(art)
+			hits, tags = a.analyzeLoginItem(art)
 		case "library_extension":
-			score, tags = a.analyzeExtension(art, now)
+			hits, tags = a.analyzeExtension(art, now)
 		}
 
-		if score > 0 {
-			artifacts[i].RiskScore += score
-			artifacts[i].Tags = appendUnique(artifacts[i].Tags, tags...)
+		if len(hits) > 0 {
+			score(&artifacts[i], a.Name(), hits...)
+			artifacts[i].Tags = AppendUnique(artifacts[i].Tags, tags...)
 		}
 	}
 
 	return artifacts
 }
 
-func (a *PersistenceAnomalyAnalyzer) analyzeLaunchAgent(art models.Artifact, now time.Time) (int, []string) {
-	score := 0
+func (a *PersistenceAnomalyAnalyzer) analyzeLaunchAgent(art models.Artifact, now time.Time) ([]models.ScoreContribution, []string) {
+	var hits []models.ScoreContribution
 	var tags []string
 
-	path := getString(art.Data, "path")
-	modTimeStr := getString(art.Data, "mod_time")
+	modTimeStr := GetString(art.Data, "mod_time")
 
 	// Modified in last 24h
 	if modTimeStr != "" {
 		if modTime, err := time.Parse(time.RFC3339, modTimeStr); err == nil {
 			if now.Sub(modTime) < 24*time.Hour {
-				score += 20
+				hits = append(hits, hit("recently_modified", 20, "mod_time", modTimeStr))
 				tags = append(tags, "recently_modified")
 			}
 		}
 	}
 
-	// Binary target in /tmp or user-writable path
-	if strings.Contains(path, "/tmp/") || strings.Contains(path, "/var/tmp/") ||
-		strings.Contains(path, "/private/tmp/") {
-		score += 35
-		tags = append(tags, "plist_in_tmp")
+	// Score on where the job's target binary lives, not where the plist is
+	if target := GetString(art.Data, "target_path"); target != "" {
+		switch {
+		case isTmpPath(target):
+			hits = append(hits, hit("target_in_tmp", 35, "target_path", target))
+			tags = append(tags, "target_in_tmp")
+		case strings.HasPrefix(target, "/Users/Shared/"):
+			hits = append(hits, hit("target_in_users_shared", 25, "target_path", target))
+			tags = append(tags, "target_in_users_shared")
+		case hasHiddenComponent(target):
+			hits = append(hits, hit("target_in_hidden_dir", 25, "target_path", target))
+			tags = append(tags, "target_in_hidden_dir")
+		case strings.HasPrefix(target, "/Users/") && art.ArtifactType != "user_launch_agent":
+			hits = append(hits, hit("system_job_user_target", 20, "target_path", target))
+			tags = append(tags, "system_job_user_target")
+		}
+
+		if exists, ok := art.Data["target_exists"].(bool); ok && !exists {
+			hits = append(hits, hit("target_missing", 10, "target_exists", "false"))
+			tags = append(tags, "target_missing")
+		}
+
+		sigHits, sigTags := scoreCodeSignature(art.Data, "target_", "target")
+		hits = append(hits, sigHits...)
+		tags = append(tags, sigTags...)
 	}
 
 	// Non-Apple plist in system directories
-	name := getString(art.Data, "name")
+	nameField := "label"
+	name := GetString(art.Data, "label")
+	if name == "" {
+		nameField, name = "name", GetString(art.Data, "name")
+	}
 	if (art.ArtifactType == "system_launch_agent" || art.ArtifactType == "system_launch_daemon") &&
 		!strings.HasPrefix(name, "com.apple.") {
-		score += 10
+		hits = append(hits, hit("non_apple_system_plist", 10, nameField, name))
 		tags = append(tags, "non_apple_system_plist")
 	}
 
-	return score, tags
+	return hits, tags
+}
+
+// isTmpPath reports whether p is under a world-writable temp directory
+func isTmpPath(p string) bool {
+	for _, prefix := range []string{"/tmp/", "/var/tmp/", "/private/tmp/", "/private/var/tmp/"} {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasHiddenComponent reports whether any directory in p starts with a dot
+func hasHiddenComponent(p string) bool {
+	parts := strings.Split(p, "/")
+	for _, part := range parts[:len(parts)-1] {
+		if strings.HasPrefix(part, ".") && part != "." && part != ".." {
+			return true
+		}
+	}
+	return false
 }
 
-func (a *PersistenceAnomalyAnalyzer) analyzeCron(art models.Artifact) (int, []string) {
-	score := 0
+func (a *PersistenceAnomalyAnalyzer) analyzeCron(art models.Artifact) ([]models.ScoreContribution, []string) {
+	var hits []models.ScoreContribution
 	var tags []string
 
-	entry := getString(art.Data, "entry")
+	entry := GetString(art.Data, "entry")
 
 	// curl|sh or wget patterns
 	entryLower := strings.ToLower(entry)
 	if (strings.Contains(entryLower, "curl") || strings.Contains(entryLower, "wget")) &&
 		(strings.Contains(entryLower, "| sh") || strings.Contains(entryLower, "|sh") ||
 			strings.Contains(entryLower, "| bash") || strings.Contains(entryLower, "|bash")) {
-		score += 30
+		hits = append(hits, hit("cron_curl_pipe_sh", 30, "entry", entry))
 		tags = append(tags, "cron_curl_pipe_sh")
 	}
 
 	// Cron pointing to /tmp
 	if strings.Contains(entry, "/tmp/") || strings.Contains(entry, "/var/tmp/") {
-		score += 20
+		hits = append(hits, hit("cron_tmp_path", 20, "entry", entry))
 		tags = append(tags, "cron_tmp_path")
 	}
 
-	return score, tags
+	return hits, tags
 }
 
-func (a *PersistenceAnomalyAnalyzer) analyzeLoginItem(art models.Artifact) (int, []string) {
-	score := 0
+func (a *PersistenceAnomalyAnalyzer) analyzeLoginItem(art models.Artifact) ([]models.ScoreContribution, []string) {
+	var hits []models.ScoreContribution
 	var tags []string
 
-	path := getString(art.Data, "path")
-	content := getString(art.Data, "content")
+	// Every location the item can launch from, by key; sfltool output uses
+	// its own key names
+	var paths [][2]string
+	for _, key := range []string{"path", "app_path", "executable_path", "launchd_target", "URL", "Executable Path"} {
+		if p := strings.TrimPrefix(GetString(art.Data, key), "file://"); p != "" {
+			paths = append(paths, [2]string{key, p})
+		}
+	}
 
-	if strings.Contains(path, "/tmp/") || strings.Contains(content, "/tmp/") {
-		score += 25
+	// Score the worst location once rather than once per key
+	if key, p, ok := findPath(paths, func(p string) bool { return isTmpPath(p) || strings.Contains(p, "/tmp/") }); ok {
+		hits = append(hits, hit("login_item_tmp_path", 25, key, p))
 		tags = append(tags, "login_item_tmp_path")
+	} else if key, p, ok := findPath(paths, func(p string) bool { return strings.HasPrefix(p, "/Users/Shared/") }); ok {
+		hits = append(hits, hit("login_item_users_shared", 20, key, p))
+		tags = append(tags, "login_item_users_shared")
+	} else if key, p, ok := findPath(paths, hasHiddenComponent); ok {
+		hits = append(hits, hit("login_item_hidden_dir", 20, key, p))
+		tags = append(tags, "login_item_hidden_dir")
+	}
+
+	// Enabled, non-Apple item with no signing team
+	enabled, _ := art.Data["enabled"].(bool)
+	if _, decoded := art.Data["team_id"]; decoded && enabled && GetString(art.Data, "team_id") == "" &&
+		!strings.HasPrefix(GetString(art.Data, "identifier"), "com.apple.") &&
+		!strings.Contains(GetString(art.Data, "identifier"), ".com.apple.") {
+		hits = append(hits, hit("login_item_no_team_id", 10, "identifier", GetString(art.Data, "identifier")))
+		tags = append(tags, "login_item_no_team_id")
+	}
+
+	if exists, ok := art.Data["launchd_target_exists"].(bool); ok && !exists {
+		hits = append(hits, hit("login_item_target_missing", 10, "launchd_target", GetString(art.Data, "launchd_target")))
+		tags = append(tags, "login_item_target_missing")
 	}
 
-	return score, tags
+	return hits, tags
+}
+
+// findPath returns the first of the keyed paths that matches
+func findPath(paths [][2]string, match func(string) bool) (key, path string, ok bool) {
+	for _, p := range paths {
+		if match(p[1]) {
+			return p[0], p[1], true
+		}
+	}
+	return "", "", false
 }
 
-func (a *PersistenceAnomalyAnalyzer) analyzeExtension(art models.Artifact, now time.Time) (int, []string) {
-	score := 0
+func (a *PersistenceAnomalyAnalyzer) analyzeExtension(art models.Artifact, now time.Time) ([]models.ScoreContribution, []string) {
+	var hits []models.ScoreContribution
 	var tags []string
 
-	modTimeStr := getString(art.Data, "mod_time")
+	modTimeStr := GetString(art.Data, "mod_time")
 	if modTimeStr != "" {
 		if modTime, err := time.Parse(time.RFC3339, modTimeStr); err == nil {
 			if now.Sub(modTime) < 24*time.Hour {
-				score += 15
+				hits = append(hits, hit("recently_installed_extension", 15, "mod_time", modTimeStr))
 				tags = append(tags, "recently_installed_extension")
 			}
 		}
 	}
 
-	return score, tags
+	sigHits, sigTags := scoreCodeSignature(art.Data, "executable_", "extension")
+	hits = append(hits, sigHits...)
+	tags = append(tags, sigTags...)
+
+	return hits, tags
 }
@@ -0,0 +1,287 @@
+package analysis
+
+import (
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// ProcessNode is a running_process artifact linked to its parent and children
+type ProcessNode struct {
+	// Index is the position of the process in the artifact slice
+	Index    int
+	PID      int
+	PPID     int
+	Name     string
+	Exe      string
+	Cmdline  string
+	Created  time.Time
+	Parent   *ProcessNode
+	Children []*ProcessNode
+	// ParentReused is set when a process with the parent's PID exists but
+	// started after this one, so the PID was reused and the real parent exited
+	ParentReused bool
+}
+
+// ProcessTree links the running processes of a collection by ppid
+type ProcessTree struct {
+	// Roots are launchd, kernel_task and processes whose parent is gone,
+	// sorted by PID; children are sorted the same way
+	Roots []*ProcessNode
+	// Nodes are all processes in artifact order
+	Nodes []*ProcessNode
+}
+
+// BuildProcessTree links running_process artifacts into a tree. A ppid is
+// resolved to the process with that PID that started no later than the
+// child; PIDs are reused, so a process that started after the child can't
+// be its parent.
+func BuildProcessTree(artifacts []models.Artifact) *ProcessTree {
+	tree := &ProcessTree{}
+	byPID := make(map[int][]*ProcessNode)
+	for i, art := range artifacts {
+		if art.ArtifactType != "running_process" {
+			continue
+		}
+		n := &ProcessNode{
+			Index:   i,
+			PID:     getInt(art.Data, "pid"),
+			PPID:    getInt(art.Data, "ppid"),
+			Name:    GetString(art.Data, "name"),
+			Exe:     GetString(art.Data, "exe"),
+			Cmdline: GetString(art.Data, "cmdline"),
+		}
+		if t, err := time.Parse(time.RFC3339, GetString(art.Data, "create_time")); err == nil {
+			n.Created = t
+		}
+		tree.Nodes = append(tree.Nodes, n)
+		byPID[n.PID] = append(byPID[n.PID], n)
+	}
+
+	for _, n := range tree.Nodes {
+		if n.PPID != n.PID {
+			n.Parent, n.ParentReused = resolveParent(n, byPID[n.PPID])
+		}
+		// Guard against cycles from inconsistent snapshots
+		if n.Parent != nil && n.Parent.descendsFrom(n) {
+			n.Parent = nil
+		}
+		if n.Parent != nil {
+			n.Parent.Children = append(n.Parent.Children, n)
+		} else {
+			tree.Roots = append(tree.Roots, n)
+		}
+	}
+
+	sortNodes(tree.Roots)
+	for _, n := range tree.Nodes {
+		sortNodes(n.Children)
+	}
+	return tree
+}
+
+// resolveParent picks the latest-started candidate that started no later
+// than n. Creation times have one-second resolution, so ties are accepted.
+func resolveParent(n *ProcessNode, candidates []*ProcessNode) (parent *ProcessNode, reused bool) {
+	for _, c := range candidates {
+		if c == n {
+			continue
+		}
+		if !n.Created.IsZero() && !c.Created.IsZero() && c.Created.After(n.Created) {
+			reused = true
+			continue
+		}
+		if parent == nil || c.Created.After(parent.Created) {
+			parent = c
+		}
+	}
+	if parent != nil {
+		reused = false
+	}
+	return parent, reused
+}
+
+func (n *ProcessNode) descendsFrom(ancestor *ProcessNode) bool {
+	seen := make(map[*ProcessNode]bool)
+	for p := n; p != nil && !seen[p]; p = p.Parent {
+		if p == ancestor {
+			return true
+		}
+		seen[p] = true
+	}
+	return false
+}
+
+func sortNodes(nodes []*ProcessNode) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].PID < nodes[j].PID })
+}
+
+// Ancestors returns the chain of parents, nearest first
+func (n *ProcessNode) Ancestors() []*ProcessNode {
+	var chain []*ProcessNode
+	for p := n.Parent; p != nil; p = p.Parent {
+		chain = append(chain, p)
+	}
+	return chain
+}
+
+// Lineage renders the ancestry chain root first, e.g.
+// "launchd(1) > Microsoft Word(812) > sh(990)"
+func (n *ProcessNode) Lineage() string {
+	ancestors := n.Ancestors()
+	parts := make([]string, 0, len(ancestors)+1)
+	for i := len(ancestors) - 1; i >= 0; i-- {
+		parts = append(parts, ancestors[i].label())
+	}
+	parts = append(parts, n.label())
+	return strings.Join(parts, " > ")
+}
+
+func (n *ProcessNode) label() string {
+	return n.DisplayName() + "(" + strconv.Itoa(n.PID) + ")"
+}
+
+// DisplayName is the executable's base name, falling back to the process
+// name, which macOS truncates
+func (n *ProcessNode) DisplayName() string {
+	if n.Exe != "" {
+		return filepath.Base(n.Exe)
+	}
+	return n.Name
+}
+
+// ProcessTreeAnalyzer flags suspicious parent/child relationships that are
+// invisible when each process is scored on its own
+type ProcessTreeAnalyzer struct{}
+
+func (a *ProcessTreeAnalyzer) Name() string { return "process_tree" }
+
+var shellNames = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true,
+	"tcsh": true, "csh": true, "fish": true,
+}
+
+var interpreterNames = map[string]bool{
+	"osascript": true, "python": true, "python2": true, "python3": true,
+	"perl": true, "perl5": true, "ruby": true, "node": true, "php": true,
+	"tclsh": true, "swift": true, "lua": true,
+}
+
+// spawnedToolNames are processes that have no business being started by
+// documents, web content or mail
+var spawnedToolNames = map[string]bool{
+	"curl": true, "wget": true, "nc": true, "ncat": true, "socat": true,
+	"base64": true, "openssl": true,
+}
+
+// Applications handling untrusted content, matched by name prefix so their
+// helper processes are covered too
+var lineageFamilies = []struct {
+	family   string
+	prefixes []string
+}{
+	{"office", []string{"Microsoft Word", "Microsoft Excel", "Microsoft PowerPoint", "Pages", "Numbers", "Keynote", "soffice", "LibreOffice"}},
+	{"mail", []string{"Mail", "Microsoft Outlook", "Thunderbird", "Spark", "Airmail"}},
+	{"browser", []string{"Safari", "com.apple.WebKit", "Google Chrome", "Chromium", "Firefox", "firefox", "plugin-container", "Microsoft Edge", "Brave Browser", "Opera", "Arc", "Vivaldi"}},
+}
+
+func (a *ProcessTreeAnalyzer) Analyze(artifacts []models.Artifact) []models.Artifact {
+	tree := BuildProcessTree(artifacts)
+
+	for _, n := range tree.Nodes {
+		art := &artifacts[n.Index]
+		var hits []models.ScoreContribution
+		var tags []string
+		name := baseName(n)
+
+		// Shell, interpreter or download tool descending from a document,
+		// browser or mail app through nothing but other shells
+		if shellNames[name] || interpreterNames[name] || spawnedToolNames[name] {
+			for _, p := range n.Ancestors() {
+				if family := lineageFamily(p); family != "" {
+					hits = append(hits, hit("suspicious_lineage", 40, "ppid", n.Lineage()))
+					tags = append(tags, "suspicious_lineage:"+family, "suspicious_parent:"+p.DisplayName())
+					break
+				}
+				if pn := baseName(p); !shellNames[pn] && !interpreterNames[pn] {
+					break
+				}
+			}
+		}
+
+		// Shell reparented to launchd that holds network connections:
+		// typical of a detached reverse shell
+		if (shellNames[name] || interpreterNames[name]) && (n.PPID == 1 || n.Parent == nil) &&
+			getInt(art.Data, "num_connections") > 0 {
+			hits = append(hits, hit("orphaned_shell_network", 35, "num_connections", GetString(art.Data, "num_connections")))
+			tags = append(tags, "orphaned_shell_network")
+		}
+
+		// Interpreter running a script out of a temp directory
+		if shellNames[name] || interpreterNames[name] {
+			if script := scriptArg(n.Cmdline); script != "" && isTmpPath(script) {
+				hits = append(hits, hit("interpreter_tmp_script", 30, "cmdline", script))
+				tags = append(tags, "interpreter_tmp_script")
+			}
+		}
+
+		if len(hits) > 0 {
+			score(art, a.Name(), hits...)
+			art.Tags = AppendUnique(art.Tags, tags...)
+		}
+	}
+
+	return artifacts
+}
+
+// baseName is the lowercase command name used to classify a process; the
+// version suffix of python3.11 and the like is dropped
+func baseName(n *ProcessNode) string {
+	name := strings.ToLower(n.DisplayName())
+	if i := strings.IndexByte(name, '.'); i > 0 && strings.Trim(name[i:], ".0123456789") == "" {
+		name = name[:i]
+	}
+	return name
+}
+
+func lineageFamily(n *ProcessNode) string {
+	names := []string{n.DisplayName(), n.Name}
+	for _, f := range lineageFamilies {
+		for _, prefix := range f.prefixes {
+			for _, name := range names {
+				if name == prefix || strings.HasPrefix(name, prefix+" ") || strings.HasPrefix(name, prefix+".") {
+					return f.family
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// scriptArg returns the script file an interpreter command line runs, or ""
+// for inline code (-c, -e) and interactive shells
+func scriptArg(cmdline string) string {
+	args := strings.Fields(cmdline)
+	if len(args) < 2 {
+		return ""
+	}
+	for _, arg := range args[1:] {
+		switch {
+		case arg == "-c" || arg == "-e" || arg == "-m":
+			return ""
+		case strings.HasPrefix(arg, "-"):
+			continue
+		}
+		return arg
+	}
+	return ""
+}
+
+func getInt(d map[string]interface{}, key string) int {
+	n, _ := strconv.Atoi(GetString(d, key))
+	return n
+}
@@ -0,0 +1,308 @@
+package analysis
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+var treeStart = time.Date(2026, 2, 6, 9, 0, 0, 0, time.UTC)
+
+// proc is a running_process artifact started offset seconds after
+// treeStart; a negative offset leaves create_time out
+func proc(pid, ppid int, exe, cmdline string, offset int) models.Artifact {
+	data := map[string]interface{}{"pid": pid, "ppid": ppid, "exe": exe, "cmdline": cmdline}
+	if exe != "" && exe[0] != '/' {
+		data = map[string]interface{}{"pid": pid, "ppid": ppid, "name": exe, "cmdline": cmdline}
+	}
+	if offset >= 0 {
+		data["create_time"] = treeStart.Add(time.Duration(offset) * time.Second).Format(time.RFC3339)
+	}
+	return fixture("running_process", data)
+}
+
+func TestBuildProcessTree(t *testing.T) {
+	tests := []struct {
+		name    string
+		procs   []models.Artifact
+		roots   []int
+		lineage map[int]string // by PID
+		reused  []int          // PIDs whose parent's PID was reused
+	}{
+		{
+			name: "parent and child",
+			procs: []models.Artifact{
+				proc(1, 0, "/sbin/launchd", "", 0),
+				proc(10, 1, "/bin/zsh", "", 10),
+				proc(11, 10, "/usr/bin/curl", "", 20),
+			},
+			roots:   []int{1},
+			lineage: map[int]string{11: "launchd(1) > zsh(10) > curl(11)"},
+		},
+		{
+			name: "parent started after the child",
+			procs: []models.Artifact{
+				proc(1, 0, "/sbin/launchd", "", 0),
+				proc(20, 30, "/bin/sh", "", 10),
+				proc(30, 1, "/usr/bin/vim", "", 50),
+			},
+			roots:   []int{1, 20},
+			lineage: map[int]string{20: "sh(20)"},
+			reused:  []int{20},
+		},
+		{
+			name: "latest candidate started before the child",
+			procs: []models.Artifact{
+				proc(30, 0, "/old", "", 0),
+				proc(30, 0, "/new", "", 40),
+				proc(30, 0, "/newest", "", 90),
+				proc(31, 30, "/bin/sh", "", 60),
+			},
+			roots:   []int{30, 30, 30},
+			lineage: map[int]string{31: "new(30) > sh(31)"},
+		},
+		{
+			name: "same second accepted",
+			procs: []models.Artifact{
+				proc(40, 0, "/bin/bash", "", 5),
+				proc(41, 40, "/bin/sleep", "", 5),
+			},
+			roots:   []int{40},
+			lineage: map[int]string{41: "bash(40) > sleep(41)"},
+		},
+		{
+			name: "no creation times",
+			procs: []models.Artifact{
+				proc(50, 0, "/bin/bash", "", -1),
+				proc(51, 50, "/bin/sleep", "", -1),
+			},
+			roots:   []int{50},
+			lineage: map[int]string{51: "bash(50) > sleep(51)"},
+		},
+		{
+			name: "cycle broken",
+			procs: []models.Artifact{
+				proc(5, 6, "/a", "", -1),
+				proc(6, 5, "/b", "", -1),
+			},
+			roots:   []int{6},
+			lineage: map[int]string{5: "b(6) > a(5)"},
+		},
+		{
+			name: "own parent",
+			procs: []models.Artifact{
+				proc(0, 0, "kernel_task", "", -1),
+			},
+			roots:   []int{0},
+			lineage: map[int]string{0: "kernel_task(0)"},
+		},
+		{
+			name: "parent gone",
+			procs: []models.Artifact{
+				proc(70, 69, "/bin/sh", "", -1),
+			},
+			roots: []int{70},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := BuildProcessTree(tt.procs)
+			var roots []int
+			for _, r := range tree.Roots {
+				roots = append(roots, r.PID)
+			}
+			if !reflect.DeepEqual(roots, tt.roots) {
+				t.Errorf("roots = %v, want %v", roots, tt.roots)
+			}
+			var reused []int
+			for _, n := range tree.Nodes {
+				if want, ok := tt.lineage[n.PID]; ok {
+					if got := n.Lineage(); got != want {
+						t.Errorf("lineage of %d = %q, want %q", n.PID, got, want)
+					}
+				}
+				if n.ParentReused {
+					reused = append(reused, n.PID)
+				}
+				for _, c := range n.Children {
+					if c.Parent != n {
+						t.Errorf("child %d of %d has parent %v", c.PID, n.PID, c.Parent)
+					}
+				}
+			}
+			if !reflect.DeepEqual(reused, tt.reused) {
+				t.Errorf("parent reused for %v, want %v", reused, tt.reused)
+			}
+		})
+	}
+}
+
+func TestScriptArg(t *testing.T) {
+	tests := []struct {
+		cmdline string
+		want    string
+	}{
+		{"python3 /tmp/x.py", "/tmp/x.py"},
+		{"/bin/bash -x /tmp/s.sh arg", "/tmp/s.sh"},
+		{"perl -w -T /var/tmp/p.pl", "/var/tmp/p.pl"},
+		{"bash -c /tmp/s.sh", ""},
+		{"ruby -e 'puts 1'", ""},
+		{"python3 -m http.server", ""},
+		{"zsh", ""},
+		{"zsh -l", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := scriptArg(tt.cmdline); got != tt.want {
+			t.Errorf("scriptArg(%q) = %q, want %q", tt.cmdline, got, tt.want)
+		}
+	}
+}
+
+func TestBaseName(t *testing.T) {
+	tests := []struct {
+		exe, name string
+		want      string
+	}{
+		{"/usr/local/bin/python3.11", "", "python3"},
+		{"/usr/bin/perl5.34", "", "perl5"},
+		{"/opt/homebrew/bin/node", "", "node"},
+		{"/bin/ZSH", "", "zsh"},
+		{"", "Python", "python"},
+		{"", "com.apple.WebKit.Networking", "com.apple.webkit.networking"},
+		{"/Applications/x/bash.old", "", "bash.old"},
+		{"/tmp/.hidden", "", ".hidden"},
+		{"/usr/bin/python3.", "", "python3"},
+	}
+	for _, tt := range tests {
+		n := &ProcessNode{Exe: tt.exe, Name: tt.name}
+		if got := baseName(n); got != tt.want {
+			t.Errorf("baseName(%q, %q) = %q, want %q", tt.exe, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestProcessTreeRules(t *testing.T) {
+	word := proc(10, 1, "/Applications/Microsoft Word.app/Contents/MacOS/Microsoft Word", "", 0)
+	withConns := func(a models.Artifact, n interface{}) models.Artifact {
+		a.Data["num_connections"] = n
+		return a
+	}
+	tests := []struct {
+		name  string
+		procs []models.Artifact
+		pid   int
+		tags  []string
+	}{
+		{
+			name:  "shell under office app",
+			procs: []models.Artifact{word, proc(11, 10, "/bin/sh", "sh", 1)},
+			pid:   11,
+			tags:  []string{"suspicious_lineage:office", "suspicious_parent:Microsoft Word"},
+		},
+		{
+			name:  "tool under shells under office app",
+			procs: []models.Artifact{word, proc(11, 10, "/bin/zsh", "zsh", 1), proc(12, 11, "/usr/bin/python3.11", "python3", 2), proc(13, 12, "/usr/bin/curl", "curl", 3)},
+			pid:   13,
+			tags:  []string{"suspicious_lineage:office", "suspicious_parent:Microsoft Word"},
+		},
+		{
+			name:  "chain broken by another app",
+			procs: []models.Artifact{word, proc(11, 10, "/usr/bin/xcodebuild", "", 1), proc(12, 11, "/bin/sh", "sh", 2)},
+			pid:   12,
+		},
+		{
+			name:  "browser helper by name prefix",
+			procs: []models.Artifact{proc(20, 1, "Google Chrome Helper (Renderer)", "", 0), proc(21, 20, "/bin/bash", "bash", 1)},
+			pid:   21,
+			tags:  []string{"suspicious_lineage:browser", "suspicious_parent:Google Chrome Helper (Renderer)"},
+		},
+		{
+			name:  "WebKit process by dotted prefix",
+			procs: []models.Artifact{proc(20, 1, "com.apple.WebKit.WebContent", "", 0), proc(21, 20, "/usr/bin/osascript", "osascript", 1)},
+			pid:   21,
+			tags:  []string{"suspicious_lineage:browser", "suspicious_parent:com.apple.WebKit.WebContent"},
+		},
+		{
+			name:  "mail app",
+			procs: []models.Artifact{proc(30, 1, "/System/Applications/Mail.app/Contents/MacOS/Mail", "", 0), proc(31, 30, "/usr/bin/base64", "base64", 1)},
+			pid:   31,
+			tags:  []string{"suspicious_lineage:mail", "suspicious_parent:Mail"},
+		},
+		{
+			name:  "name only sharing a prefix",
+			procs: []models.Artifact{proc(30, 1, "/Applications/Mailspring.app/Contents/MacOS/Mailspring", "", 0), proc(31, 30, "/bin/sh", "sh", 1)},
+			pid:   31,
+		},
+		{
+			name:  "unrelated process under office app",
+			procs: []models.Artifact{word, proc(11, 10, "/usr/bin/mdworker", "", 1)},
+			pid:   11,
+		},
+		{
+			name:  "orphaned shell with connections",
+			procs: []models.Artifact{proc(1, 0, "/sbin/launchd", "", 0), withConns(proc(40, 1, "/bin/bash", "bash -i", 1), 1)},
+			pid:   40,
+			tags:  []string{"orphaned_shell_network"},
+		},
+		{
+			name:  "orphaned shell whose parent is gone",
+			procs: []models.Artifact{withConns(proc(40, 39, "/usr/bin/python3", "python3", 1), float64(2))},
+			pid:   40,
+			tags:  []string{"orphaned_shell_network"},
+		},
+		{
+			name:  "orphaned shell without connections",
+			procs: []models.Artifact{proc(1, 0, "/sbin/launchd", "", 0), withConns(proc(40, 1, "/bin/bash", "bash", 1), 0)},
+			pid:   40,
+		},
+		{
+			name:  "shell with connections under a terminal",
+			procs: []models.Artifact{proc(1, 0, "/sbin/launchd", "", 0), proc(39, 1, "/System/Applications/Utilities/Terminal.app/Contents/MacOS/Terminal", "", 0), withConns(proc(40, 39, "/bin/zsh", "zsh", 1), 1)},
+			pid:   40,
+		},
+		{
+			name:  "interpreter running a temp script",
+			procs: []models.Artifact{proc(50, 49, "/usr/bin/python3", "python3 -u /private/tmp/x.py", 0)},
+			pid:   50,
+			tags:  []string{"interpreter_tmp_script"},
+		},
+		{
+			name:  "interpreter running inline code",
+			procs: []models.Artifact{proc(50, 49, "/bin/sh", "sh -c /tmp/x.sh", 0)},
+			pid:   50,
+		},
+		{
+			name:  "interpreter running a script elsewhere",
+			procs: []models.Artifact{proc(50, 49, "/usr/bin/ruby", "ruby /Users/a/x.rb", 0)},
+			pid:   50,
+		},
+		{
+			name:  "non-interpreter with a temp argument",
+			procs: []models.Artifact{proc(50, 49, "/usr/bin/open", "open /tmp/x.pdf", 0)},
+			pid:   50,
+		},
+	}
+	withWeights(t, &ScoreWeights{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arts := (&ProcessTreeAnalyzer{}).Analyze(append([]models.Artifact(nil), tt.procs...))
+			for _, a := range arts {
+				if a.Data["pid"] != tt.pid {
+					continue
+				}
+				tags := append([]string(nil), a.Tags...)
+				sort.Strings(tags)
+				if !reflect.DeepEqual(tags, tt.tags) {
+					t.Errorf("tags = %v, want %v", tags, tt.tags)
+				}
+				if (a.RiskScore > 0) != (len(tt.tags) > 0) {
+					t.Errorf("score = %d with tags %v", a.RiskScore, tags)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// MaxRiskScore is the highest risk score an artifact can reach
+const MaxRiskScore = 100
+
+// ScoreWeights adjusts the points analyzer rules contribute. Rules maps a
+// rule, or analyzer/rule, to the points it is worth instead of its built-in
+// points (0 disables it); Analyzers multiplies every rule of an analyzer.
+type ScoreWeights struct {
+	Rules     map[string]int     `yaml:"rules"`
+	Analyzers map[string]float64 `yaml:"analyzers"`
+}
+
+// scoreWeights applies to every contribution recorded by score
+var scoreWeights = &ScoreWeights{}
+
+// LoadScoreWeights reads a weights file
+func LoadScoreWeights(path string) (*ScoreWeights, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &ScoreWeights{}
+	if err := yaml.Unmarshal(data, w); err != nil {
+		return nil, fmt.Errorf("parsing weights %s: %w", path, err)
+	}
+	for a, m := range w.Analyzers {
+		if m < 0 {
+			return nil, fmt.Errorf("%s: negative multiplier for %s", path, a)
+		}
+	}
+	return w, nil
+}
+
+// SetScoreWeights replaces the built-in rule points (used for --weights)
+func SetScoreWeights(w *ScoreWeights) {
+	scoreWeights = w
+}
+
+// points returns the weighted points for a rule
+func (w *ScoreWeights) points(analyzer, rule string, points int) int {
+	if p, ok := w.Rules[analyzer+"/"+rule]; ok {
+		points = p
+	} else if p, ok := w.Rules[rule]; ok {
+		points = p
+	}
+	if m, ok := w.Analyzers[analyzer]; ok {
+		points = int(math.Round(float64(points) * m))
+	}
+	return points
+}
+
+// hit is a rule that fired, before weighting
+func hit(rule string, points int, field, value string) models.ScoreContribution {
+	return models.ScoreContribution{Rule: rule, Points: points, Field: field, Value: value}
+}
+
+// score records the rules an analyzer fired on an artifact, with weights
+// applied, and recomputes its risk score. Rules weighted down to nothing
+// are dropped.
+func score(art *models.Artifact, analyzer string, hits ...models.ScoreContribution) {
+	for _, h := range hits {
+		h.Analyzer = analyzer
+		if h.Points = scoreWeights.points(analyzer, h.Rule, h.Points); h.Points == 0 {
+			continue
+		}
+		art.Contributions = append(art.Contributions, h)
+	}
+	art.RiskScore = totalScore(art.Contributions)
+}
+
+// totalScore sums contributions into a risk score between 0 and
+// MaxRiskScore
+func totalScore(contributions []models.ScoreContribution) int {
+	total := 0
+	for _, c := range contributions {
+		total += c.Points
+	}
+	switch {
+	case total < 0:
+		return 0
+	case total > MaxRiskScore:
+		return MaxRiskScore
+	}
+	return total
+}
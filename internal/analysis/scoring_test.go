@@ -0,0 +1,126 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// withWeights sets the score weights for the rest of the test
+func withWeights(t *testing.T, w *ScoreWeights) {
+	t.Helper()
+	prev := scoreWeights
+	SetScoreWeights(w)
+	t.Cleanup(func() { SetScoreWeights(prev) })
+}
+
+func TestScore(t *testing.T) {
+	weights := &ScoreWeights{
+		Rules:     map[string]int{"shell": 5, "net/shell": 7, "disabled": 0},
+		Analyzers: map[string]float64{"net": 1.5, "quiet": 0},
+	}
+	tests := []struct {
+		name     string
+		analyzer string
+		hits     []models.ScoreContribution
+		score    int
+		points   []int // the recorded contributions' points
+	}{
+		{"sum", "proc", []models.ScoreContribution{hit("a", 20, "", ""), hit("b", 30, "", "")}, 50, []int{20, 30}},
+		{"clamped at 100", "proc", []models.ScoreContribution{hit("a", 70, "", ""), hit("b", 60, "", "")}, 100, []int{70, 60}},
+		{"clamped at 0", "proc", []models.ScoreContribution{hit("a", 10, "", ""), hit("known", -20, "", "")}, 0, []int{10, -20}},
+		{"negative contribution reduces", "proc", []models.ScoreContribution{hit("a", 50, "", ""), hit("known", -20, "", "")}, 30, []int{50, -20}},
+		{"rule weight", "proc", []models.ScoreContribution{hit("shell", 40, "", "")}, 5, []int{5}},
+		{"analyzer/rule weight wins", "net", []models.ScoreContribution{hit("shell", 40, "", "")}, 11, []int{11}},
+		{"analyzer multiplier rounds", "net", []models.ScoreContribution{hit("port", 25, "", "")}, 38, []int{38}},
+		{"rule weighted to zero dropped", "proc", []models.ScoreContribution{hit("disabled", 40, "", ""), hit("a", 10, "", "")}, 10, []int{10}},
+		{"zero-point hit dropped", "proc", []models.ScoreContribution{hit("a", 0, "", "")}, 0, nil},
+		{"analyzer multiplied to zero", "quiet", []models.ScoreContribution{hit("a", 40, "", "")}, 0, nil},
+	}
+	withWeights(t, weights)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var art models.Artifact
+			score(&art, tt.analyzer, tt.hits...)
+			if art.RiskScore != tt.score {
+				t.Errorf("score = %d, want %d", art.RiskScore, tt.score)
+			}
+			var points []int
+			for _, c := range art.Contributions {
+				if c.Analyzer != tt.analyzer {
+					t.Errorf("contribution %+v not attributed to %s", c, tt.analyzer)
+				}
+				points = append(points, c.Points)
+			}
+			if !reflect.DeepEqual(points, tt.points) {
+				t.Errorf("contributions = %v, want %v", points, tt.points)
+			}
+		})
+	}
+}
+
+func TestScoreAccumulatesAcrossAnalyzers(t *testing.T) {
+	withWeights(t, &ScoreWeights{})
+	var art models.Artifact
+	score(&art, "first", hit("a", 30, "name", "x"))
+	score(&art, "second", hit("b", 20, "path", "/tmp/x"))
+	if art.RiskScore != 50 || len(art.Contributions) != 2 {
+		t.Fatalf("score = %d with %d contributions, want 50 with 2", art.RiskScore, len(art.Contributions))
+	}
+	want := models.ScoreContribution{Analyzer: "second", Rule: "b", Points: 20, Field: "path", Value: "/tmp/x"}
+	if art.Contributions[1] != want {
+		t.Errorf("contribution = %+v, want %+v", art.Contributions[1], want)
+	}
+}
+
+func TestLoadScoreWeights(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    *ScoreWeights
+		err     string
+	}{
+		{
+			name:    "rules and analyzers",
+			content: "rules:\n  shell: 5\n  net/c2_port: 0\nanalyzers:\n  sigma: 0.5\n",
+			want: &ScoreWeights{
+				Rules:     map[string]int{"shell": 5, "net/c2_port": 0},
+				Analyzers: map[string]float64{"sigma": 0.5},
+			},
+		},
+		{name: "empty", content: "", want: &ScoreWeights{}},
+		{name: "negative multiplier", content: "analyzers:\n  sigma: -1\n", err: "negative multiplier"},
+		{name: "points not a number", content: "rules:\n  shell: many\n", err: "parsing weights"},
+		{name: "not a mapping", content: "- shell\n", err: "parsing weights"},
+		{name: "bad YAML", content: "rules: [\n", err: "parsing weights"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "weights.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadScoreWeights(path)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("err = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("weights = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := LoadScoreWeights(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("missing file loaded")
+	}
+}
@@ -0,0 +1,81 @@
+package analysis
+
+import (
+	"github.com/plonxyz/triagectl/internal/models"
+	"github.com/plonxyz/triagectl/internal/sigma"
+)
+
+// sigmaLevelScores is the risk added for a match, by rule level
+var sigmaLevelScores = map[string]int{
+	"informational": 0,
+	"low":           10,
+	"medium":        25,
+	"high":          50,
+	"critical":      75,
+}
+
+// SigmaAnalyzer evaluates Sigma rules against the artifact types their
+// logsource maps to
+type SigmaAnalyzer struct {
+	rules   []*sigma.Rule
+	skipped []error
+}
+
+func (a *SigmaAnalyzer) Name() string { return "sigma" }
+
+// NewSigmaAnalyzer loads the rules in a YAML file or directory, skipping
+// those that fail to compile
+func NewSigmaAnalyzer(path string) (*SigmaAnalyzer, error) {
+	rules, skipped, err := sigma.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &SigmaAnalyzer{rules: rules, skipped: skipped}, nil
+}
+
+// Skipped returns an error naming the file for each rule that was skipped
+func (a *SigmaAnalyzer) Skipped() []error { return a.skipped }
+
+// Rules returns the number of loaded rules and how many of them map to
+// artifact types triagectl collects
+func (a *SigmaAnalyzer) Rules() (loaded, applicable int) {
+	for _, r := range a.rules {
+		if len(r.ArtifactTypes()) > 0 {
+			applicable++
+		}
+	}
+	return len(a.rules), applicable
+}
+
+func (a *SigmaAnalyzer) Analyze(artifacts []models.Artifact) []models.Artifact {
+	// Rules applicable to each artifact type, resolved once per type
+	byType := make(map[string][]*sigma.Rule)
+
+	for i, art := range artifacts {
+		rules, ok := byType[art.ArtifactType]
+		if !ok {
+			for _, r := range a.rules {
+				if r.AppliesTo(art.ArtifactType) {
+					rules = append(rules, r)
+				}
+			}
+			byType[art.ArtifactType] = rules
+		}
+
+		for _, r := range rules {
+			if !r.Match(art) {
+				continue
+			}
+			score(&artifacts[i], a.Name(), hit(r.ID, sigmaLevelScores[r.Level], "level", r.Level))
+			artifacts[i].Tags = AppendUnique(artifacts[i].Tags, "sigma:"+r.ID)
+			artifacts[i].Tags = AppendUnique(artifacts[i].Tags, "sigma_title:"+r.Title)
+			if r.Level != "" {
+				artifacts[i].Tags = AppendUnique(artifacts[i].Tags, "sigma_level:"+r.Level)
+			}
+			for _, t := range r.AttackTags() {
+				artifacts[i].Tags = AppendUnique(artifacts[i].Tags, t)
+			}
+		}
+	}
+	return artifacts
+}
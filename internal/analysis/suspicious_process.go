@@ -25,18 +25,18 @@ func (a *SuspiciousProcessAnalyzer) Analyze(artifacts []models.Artifact) []model
 			continue
 		}
 
-		score := 0
+		var hits []models.ScoreContribution
 		var tags []string
 
-		exe := getString(art.Data, "exe")
-		name := getString(art.Data, "name")
-		username := getString(art.Data, "username")
-		cwd := getString(art.Data, "cwd")
+		exe := GetString(art.Data, "exe")
+		name := GetString(art.Data, "name")
+		username := GetString(art.Data, "username")
+		cwd := GetString(art.Data, "cwd")
 
 		// Exe in /tmp or /var/tmp
 		if strings.HasPrefix(exe, "/tmp/") || strings.HasPrefix(exe, "/var/tmp/") ||
 			strings.HasPrefix(exe, "/private/tmp/") || strings.HasPrefix(exe, "/private/var/tmp/") {
-			score += 30
+			hits = append(hits, hit("exe_in_tmp", 30, "exe", exe))
 			tags = append(tags, "exe_in_tmp")
 		}
 
@@ -46,45 +46,56 @@ func (a *SuspiciousProcessAnalyzer) Analyze(artifacts []models.Artifact) []model
 			baseName = name[idx+1:]
 		}
 		if suspiciousNames[strings.ToLower(baseName)] {
-			score += 20
+			hits = append(hits, hit("suspicious_name", 20, "name", name))
 			tags = append(tags, fmt.Sprintf("suspicious_name:%s", baseName))
 		}
 
 		// No exe path
 		if exe == "" && name != "" {
-			score += 15
+			hits = append(hits, hit("no_exe_path", 15, "name", name))
 			tags = append(tags, "no_exe_path")
 		}
 
 		// Root process in user directories
-		if username == "root" && (strings.HasPrefix(cwd, "/Users/") || strings.HasPrefix(exe, "/Users/")) {
-			score += 25
+		if username == "root" && strings.HasPrefix(exe, "/Users/") {
+			hits = append(hits, hit("root_in_user_dir", 25, "exe", exe))
+			tags = append(tags, "root_in_user_dir")
+		} else if username == "root" && strings.HasPrefix(cwd, "/Users/") {
+			hits = append(hits, hit("root_in_user_dir", 25, "cwd", cwd))
 			tags = append(tags, "root_in_user_dir")
 		}
 
 		// Hidden process name (starts with .)
 		if strings.HasPrefix(baseName, ".") {
-			score += 20
+			hits = append(hits, hit("hidden_process", 20, "name", name))
 			tags = append(tags, "hidden_process")
 		}
 
-		if score > 0 {
-			artifacts[i].RiskScore += score
-			artifacts[i].Tags = appendUnique(artifacts[i].Tags, tags...)
+		// Unsigned or ad-hoc signed executable
+		sigHits, sigTags := scoreCodeSignature(art.Data, "exe_", "exe")
+		hits = append(hits, sigHits...)
+		tags = append(tags, sigTags...)
+
+		if len(hits) > 0 {
+			score(&artifacts[i], a.Name(), hits...)
+			artifacts[i].Tags = AppendUnique(artifacts[i].Tags, tags...)
 		}
 	}
 
 	return artifacts
 }
 
-func getString(d map[string]interface{}, key string) string {
-	if v, ok := d[key]; ok {
+// GetString returns an artifact Data value as text, or "" if it is
+// missing or nil
+func GetString(d map[string]interface{}, key string) string {
+	if v, ok := d[key]; ok && v != nil {
 		return fmt.Sprintf("%v", v)
 	}
 	return ""
 }
 
-func appendUnique(existing []string, items ...string) []string {
+// AppendUnique appends the items not already in existing, such as tags
+func AppendUnique(existing []string, items ...string) []string {
 	seen := make(map[string]bool)
 	for _, s := range existing {
 		seen[s] = true
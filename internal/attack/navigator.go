@@ -0,0 +1,200 @@
+package attack
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// Navigator layer format versions written by NewLayer
+const (
+	navigatorVersion = "4.9.1"
+	layerVersion     = "4.5"
+	attackVersion    = "15"
+)
+
+// Hit summarizes the artifacts placed under one technique
+type Hit struct {
+	Technique
+	Count    int
+	MaxScore int
+	Types    []string
+	Tags     []string
+}
+
+// Summarize returns a hit per technique found on artifacts, by ID.
+// Suppressed artifacts are not counted.
+func Summarize(artifacts []models.Artifact, m *Mapping) []Hit {
+	byID := make(map[string]*Hit)
+	for _, a := range artifacts {
+		if a.Suppression != nil {
+			continue
+		}
+		for _, id := range a.Techniques {
+			h, ok := byID[id]
+			if !ok {
+				h = &Hit{Technique: m.Technique(id)}
+				byID[id] = h
+			}
+			h.Count++
+			if a.RiskScore > h.MaxScore {
+				h.MaxScore = a.RiskScore
+			}
+			if !contains(h.Types, a.ArtifactType) {
+				h.Types = append(h.Types, a.ArtifactType)
+			}
+			for _, tag := range a.Tags {
+				if contains(m.tagTechniques(tag), id) && !contains(h.Tags, tag) {
+					h.Tags = append(h.Tags, tag)
+				}
+			}
+		}
+	}
+
+	hits := make([]Hit, 0, len(byID))
+	for _, h := range byID {
+		sort.Strings(h.Types)
+		sort.Strings(h.Tags)
+		hits = append(hits, *h)
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].ID < hits[j].ID })
+	return hits
+}
+
+// Layer is an ATT&CK Navigator layer document
+type Layer struct {
+	Name         string            `json:"name"`
+	Versions     LayerVersions     `json:"versions"`
+	Domain       string            `json:"domain"`
+	Description  string            `json:"description"`
+	Techniques   []LayerTechnique  `json:"techniques"`
+	Gradient     LayerGradient     `json:"gradient"`
+	Metadata     []LayerMetadata   `json:"metadata,omitempty"`
+	LegendItems  []LayerLegendItem `json:"legendItems"`
+	HideDisabled bool              `json:"hideDisabled"`
+}
+
+// LayerVersions are the ATT&CK, Navigator and layer format versions
+type LayerVersions struct {
+	Attack    string `json:"attack"`
+	Navigator string `json:"navigator"`
+	Layer     string `json:"layer"`
+}
+
+// LayerTechnique scores one technique in a layer
+type LayerTechnique struct {
+	TechniqueID       string `json:"techniqueID"`
+	Score             *int   `json:"score,omitempty"`
+	Comment           string `json:"comment,omitempty"`
+	Enabled           bool   `json:"enabled"`
+	ShowSubtechniques bool   `json:"showSubtechniques,omitempty"`
+}
+
+// LayerGradient colors scores between MinValue and MaxValue
+type LayerGradient struct {
+	Colors   []string `json:"colors"`
+	MinValue int      `json:"minValue"`
+	MaxValue int      `json:"maxValue"`
+}
+
+// LayerMetadata is a name/value pair shown with the layer
+type LayerMetadata struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// LayerLegendItem labels a color in the layer legend
+type LayerLegendItem struct {
+	Label string `json:"label"`
+	Color string `json:"color"`
+}
+
+// NewLayer builds a Navigator layer scoring each technique found on
+// artifacts with the highest risk score among them. Parents of scored
+// sub-techniques are expanded so the sub-techniques show.
+func NewLayer(name string, metadata []LayerMetadata, artifacts []models.Artifact, m *Mapping) *Layer {
+	hits := Summarize(artifacts, m)
+	layer := &Layer{
+		Name: name,
+		Versions: LayerVersions{
+			Attack:    attackVersion,
+			Navigator: navigatorVersion,
+			Layer:     layerVersion,
+		},
+		Domain:      "enterprise-attack",
+		Description: fmt.Sprintf("%d techniques observed by triagectl", len(hits)),
+		Techniques:  []LayerTechnique{},
+		Gradient: LayerGradient{
+			Colors:   []string{"#fff3b0", "#ff9f43", "#d63031"},
+			MinValue: 0,
+			MaxValue: 100,
+		},
+		Metadata: metadata,
+		LegendItems: []LayerLegendItem{
+			{Label: "low risk", Color: "#fff3b0"},
+			{Label: "critical risk", Color: "#d63031"},
+		},
+	}
+
+	expanded := make(map[string]bool)
+	for _, h := range hits {
+		score := h.MaxScore
+		comment := fmt.Sprintf("%d artifacts (%s)", h.Count, strings.Join(h.Types, ", "))
+		if h.Count == 1 {
+			comment = fmt.Sprintf("1 artifact (%s)", h.Types[0])
+		}
+		if len(h.Tags) > 0 {
+			comment += ": " + strings.Join(h.Tags, ", ")
+		}
+		layer.Techniques = append(layer.Techniques, LayerTechnique{
+			TechniqueID: h.ID,
+			Score:       &score,
+			Comment:     comment,
+			Enabled:     true,
+		})
+		if parent, _, ok := strings.Cut(h.ID, "."); ok {
+			expanded[parent] = true
+		}
+	}
+	for i, t := range layer.Techniques {
+		if expanded[t.TechniqueID] {
+			layer.Techniques[i].ShowSubtechniques = true
+			delete(expanded, t.TechniqueID)
+		}
+	}
+	parents := make([]string, 0, len(expanded))
+	for id := range expanded {
+		parents = append(parents, id)
+	}
+	sort.Strings(parents)
+	for _, id := range parents {
+		layer.Techniques = append(layer.Techniques, LayerTechnique{
+			TechniqueID:       id,
+			Enabled:           true,
+			ShowSubtechniques: true,
+		})
+	}
+	return layer
+}
+
+// WriteJSON writes the layer as an indented JSON document
+func (l *Layer) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package btm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Bookmark holds the fields of a CFURL bookmark ("book" blob) that matter for
+// locating the target on disk
+type Bookmark struct {
+	Path       string
+	VolumePath string
+	VolumeName string
+	VolumeUUID string
+	Created    time.Time
+}
+
+// Bookmark TOC keys
+const (
+	bmkPathComponents = 0x1004
+	bmkCreationDate   = 0x1040
+	bmkVolumePath     = 0x2002
+	bmkVolumeURL      = 0x2005
+	bmkVolumeName     = 0x2010
+	bmkVolumeUUID     = 0x2011
+)
+
+// Bookmark record types
+const (
+	bmkTypeString = 0x0101
+	bmkTypeData   = 0x0201
+	bmkTypeDate   = 0x0400
+	bmkTypeArray  = 0x0601
+	bmkTypeURL    = 0x0901
+)
+
+var errNotBookmark = errors.New("btm: not a bookmark")
+
+// bmkEpoch is the reference date for bookmark dates (2001-01-01 UTC)
+var bmkEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// ParseBookmark decodes a CFURL bookmark blob
+//
+// Layout: a header ("book", total size, version, header size) followed by a
+// data area whose first uint32 is the offset of the first table of contents.
+// Each TOC lists (key, record offset) pairs; offsets are relative to the data area.
+func ParseBookmark(data []byte) (*Bookmark, error) {
+	if len(data) < 16 || (string(data[:4]) != "book" && string(data[:4]) != "alis") {
+		return nil, errNotBookmark
+	}
+	if string(data[:4]) == "alis" {
+		return nil, fmt.Errorf("btm: legacy alias records are not supported")
+	}
+
+	headerSize := binary.LittleEndian.Uint32(data[12:16])
+	if headerSize < 16 || uint64(headerSize)+4 > uint64(len(data)) {
+		return nil, fmt.Errorf("btm: bookmark header size %d out of range", headerSize)
+	}
+	p := &bookmarkParser{body: data[headerSize:]}
+
+	tocOffset, ok := p.u32(0)
+	if !ok {
+		return nil, fmt.Errorf("btm: truncated bookmark")
+	}
+
+	bm := &Bookmark{}
+	// TOCs form a linked list; cap the walk so a corrupt chain cannot loop
+	for i := 0; tocOffset != 0 && i < 32; i++ {
+		next, err := p.toc(tocOffset, bm)
+		if err != nil {
+			return nil, err
+		}
+		tocOffset = next
+	}
+	return bm, nil
+}
+
+type bookmarkParser struct {
+	body []byte
+}
+
+func (p *bookmarkParser) u32(off uint32) (uint32, bool) {
+	if uint64(off)+4 > uint64(len(p.body)) {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(p.body[off:]), true
+}
+
+// toc reads one table of contents into bm and returns the next TOC offset
+func (p *bookmarkParser) toc(off uint32, bm *Bookmark) (uint32, error) {
+	magic, ok1 := p.u32(off + 4)
+	next, ok2 := p.u32(off + 12)
+	count, ok3 := p.u32(off + 16)
+	if !ok1 || !ok2 || !ok3 {
+		return 0, fmt.Errorf("btm: bookmark TOC at %d truncated", off)
+	}
+	if magic != 0xfffffffe {
+		return 0, fmt.Errorf("btm: bad bookmark TOC magic 0x%x", magic)
+	}
+	if uint64(count)*12 > uint64(len(p.body)) {
+		return 0, fmt.Errorf("btm: bookmark TOC count %d out of range", count)
+	}
+
+	for i := uint32(0); i < count; i++ {
+		entry := off + 20 + i*12
+		key, ok1 := p.u32(entry)
+		recOff, ok2 := p.u32(entry + 4)
+		if !ok1 || !ok2 {
+			return 0, fmt.Errorf("btm: bookmark TOC entry %d truncated", i)
+		}
+		// The high bit marks keys whose record is a string naming the key
+		key &^= 0x80000000
+
+		v := p.record(recOff, 0)
+		switch key {
+		case bmkPathComponents:
+			if parts, ok := v.([]interface{}); ok {
+				var comps []string
+				for _, part := range parts {
+					if s, ok := part.(string); ok {
+						comps = append(comps, s)
+					}
+				}
+				bm.Path = "/" + strings.Join(comps, "/")
+			}
+		case bmkCreationDate:
+			if t, ok := v.(time.Time); ok {
+				bm.Created = t
+			}
+		case bmkVolumePath, bmkVolumeURL:
+			if s, ok := v.(string); ok && bm.VolumePath == "" {
+				bm.VolumePath = strings.TrimPrefix(s, "file://")
+			}
+		case bmkVolumeName:
+			if s, ok := v.(string); ok {
+				bm.VolumeName = s
+			}
+		case bmkVolumeUUID:
+			if s, ok := v.(string); ok {
+				bm.VolumeUUID = s
+			}
+		}
+	}
+	return next, nil
+}
+
+// record decodes the typed record at off; unknown types yield nil
+func (p *bookmarkParser) record(off uint32, depth int) interface{} {
+	length, ok1 := p.u32(off)
+	typ, ok2 := p.u32(off + 4)
+	if !ok1 || !ok2 || depth > 4 {
+		return nil
+	}
+	start := uint64(off) + 8
+	end := start + uint64(length)
+	if end > uint64(len(p.body)) {
+		return nil
+	}
+	b := p.body[start:end]
+
+	switch typ {
+	case bmkTypeString, bmkTypeURL:
+		return string(b)
+	case bmkTypeData:
+		return b
+	case bmkTypeDate:
+		if len(b) != 8 {
+			return nil
+		}
+		// Dates are the one big-endian field in the format
+		secs := math.Float64frombits(binary.BigEndian.Uint64(b))
+		whole, frac := math.Modf(secs)
+		return bmkEpoch.Add(time.Duration(whole) * time.Second).Add(time.Duration(frac * float64(time.Second))).UTC()
+	case bmkTypeArray:
+		var arr []interface{}
+		for i := 0; i+4 <= len(b); i += 4 {
+			arr = append(arr, p.record(binary.LittleEndian.Uint32(b[i:]), depth+1))
+		}
+		return arr
+	}
+	return nil
+}
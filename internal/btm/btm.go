@@ -0,0 +1,302 @@
+// Package btm decodes Background Task Management databases: the per-user
+// backgrounditems.btm used up to macOS 12 and the system-wide
+// BackgroundItems-v*.btm store used from macOS 13. Both are NSKeyedArchiver
+// property lists; decoding needs no macOS APIs, so it works on mounted
+// images and non-macOS analysis hosts.
+package btm
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/plonxyz/triagectl/internal/plist"
+)
+
+// Item type bits (ItemRecord.type)
+const (
+	TypeUserItem  = 0x1
+	TypeApp       = 0x2
+	TypeLoginItem = 0x4
+	TypeAgent     = 0x8
+	TypeDaemon    = 0x10
+	TypeDeveloper = 0x20
+	TypeSpotlight = 0x40
+	TypeQuicklook = 0x800
+	TypeLegacy    = 0x10000
+	TypeCurated   = 0x80000
+)
+
+// Disposition bits (ItemRecord.disposition)
+const (
+	DispositionEnabled  = 0x1
+	DispositionAllowed  = 0x2
+	DispositionHidden   = 0x4
+	DispositionNotified = 0x8
+)
+
+var typeNames = []struct {
+	bit  int64
+	name string
+}{
+	{TypeUserItem, "user item"},
+	{TypeApp, "app"},
+	{TypeLoginItem, "login item"},
+	{TypeAgent, "agent"},
+	{TypeDaemon, "daemon"},
+	{TypeDeveloper, "developer"},
+	{TypeSpotlight, "spotlight"},
+	{TypeQuicklook, "quicklook"},
+	{TypeLegacy, "legacy"},
+	{TypeCurated, "curated"},
+}
+
+// Item is a single background task entry
+type Item struct {
+	UUID                string
+	Name                string
+	DeveloperName       string
+	TeamID              string
+	Type                int64
+	Disposition         int64
+	Identifier          string
+	URL                 string
+	ExecutablePath      string
+	BundleID            string
+	AssociatedBundleIDs []string
+	ParentIdentifier    string
+	EmbeddedIdentifiers []string
+	Generation          int64
+	// UserID is the itemsByUserIdentifier key the item was stored under
+	UserID string
+	// Path is the item's location on disk, from its URL or bookmark
+	Path     string
+	Bookmark *Bookmark
+	// Legacy is set for entries read from a pre-macOS 13 backgrounditems.btm
+	Legacy bool
+}
+
+func (it *Item) Enabled() bool  { return it.Disposition&DispositionEnabled != 0 }
+func (it *Item) Allowed() bool  { return it.Disposition&DispositionAllowed != 0 }
+func (it *Item) Hidden() bool   { return it.Disposition&DispositionHidden != 0 }
+func (it *Item) Notified() bool { return it.Disposition&DispositionNotified != 0 }
+
+// TypeNames lists the names of the type bits set on the item
+func (it *Item) TypeNames() []string {
+	var names []string
+	for _, t := range typeNames {
+		if it.Type&t.bit != 0 {
+			names = append(names, t.name)
+		}
+	}
+	return names
+}
+
+// LaunchdPlist returns the launchd property list backing an agent or daemon
+// item, or "" if the item is not launchd-managed
+func (it *Item) LaunchdPlist() string {
+	if strings.HasSuffix(it.Path, ".plist") {
+		return it.Path
+	}
+	return ""
+}
+
+// ParseFile decodes the BTM database at path
+func ParseFile(path string) ([]Item, error) {
+	root, err := plist.UnarchiveFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Items(root), nil
+}
+
+// Parse decodes an in-memory BTM database
+func Parse(data []byte) ([]Item, error) {
+	v, err := plist.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	root, err := plist.Unarchive(v)
+	if err != nil {
+		return nil, err
+	}
+	return Items(root), nil
+}
+
+// Items extracts the entries from an unarchived BTM object graph. Both layouts
+// are handled by walking the graph: modern stores keep ItemRecord objects
+// under store.itemsByUserIdentifier, legacy files keep name/bookmark pairs
+// under AllContainers[].internalItems.
+func Items(root interface{}) []Item {
+	w := &walker{seen: make(map[string]bool), visited: make(map[node]bool)}
+	w.walk(root, "", 0)
+	return w.items
+}
+
+type walker struct {
+	items   []Item
+	seen    map[string]bool
+	visited map[node]bool
+}
+
+// node is a map or slice of the graph walked for a user. Unarchive returns
+// an object referenced from several places as the same map or slice, so a
+// crafted archive can reference one subtree an exponential number of times;
+// each is walked once.
+type node struct {
+	ptr  uintptr
+	len  int
+	user string
+}
+
+func (w *walker) walk(v interface{}, user string, depth int) {
+	if depth > 32 {
+		return
+	}
+	switch v.(type) {
+	case []interface{}, map[string]interface{}:
+		rv := reflect.ValueOf(v)
+		n := node{rv.Pointer(), rv.Len(), user}
+		if w.visited[n] {
+			return
+		}
+		w.visited[n] = true
+	}
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			w.walk(e, user, depth+1)
+		}
+	case map[string]interface{}:
+		if byUser, ok := t["itemsByUserIdentifier"].(map[string]interface{}); ok {
+			for _, uid := range sortedKeys(byUser) {
+				w.walk(byUser[uid], uid, depth+1)
+			}
+			return
+		}
+		if item, ok := itemFromDict(t); ok {
+			item.UserID = user
+			key := fmt.Sprintf("%s|%s|%s|%s|%s", user, item.UUID, item.Identifier, item.Name, item.Path)
+			if !w.seen[key] {
+				w.seen[key] = true
+				w.items = append(w.items, item)
+			}
+			return
+		}
+		for _, k := range sortedKeys(t) {
+			w.walk(t[k], user, depth+1)
+		}
+	}
+}
+
+func itemFromDict(d map[string]interface{}) (Item, bool) {
+	_, hasDisposition := d["disposition"]
+	_, hasBookmark := d["bookmark"]
+	if !hasDisposition && !hasBookmark {
+		return Item{}, false
+	}
+
+	it := Item{
+		UUID:                str(d["uuid"]),
+		Name:                str(d["name"]),
+		DeveloperName:       str(d["developerName"]),
+		TeamID:              str(d["teamIdentifier"]),
+		Type:                num(d["type"]),
+		Disposition:         num(d["disposition"]),
+		Identifier:          str(d["identifier"]),
+		URL:                 str(d["url"]),
+		ExecutablePath:      str(d["executablePath"]),
+		BundleID:            str(d["bundleIdentifier"]),
+		AssociatedBundleIDs: strs(d["associatedBundleIdentifiers"]),
+		ParentIdentifier:    str(d["parentIdentifier"]),
+		EmbeddedIdentifiers: strs(d["embeddedItemIdentifiers"]),
+		Generation:          num(d["generation"]),
+		Legacy:              !hasDisposition,
+	}
+
+	if it.URL != "" {
+		it.Path = urlPath(it.URL)
+	}
+
+	// Legacy items wrap the bookmark blob in an object with a "data" field
+	var blob []byte
+	switch b := d["bookmark"].(type) {
+	case []byte:
+		blob = b
+	case map[string]interface{}:
+		blob, _ = b["data"].([]byte)
+	}
+	if len(blob) > 0 {
+		if bm, err := ParseBookmark(blob); err == nil {
+			it.Bookmark = bm
+			if it.Path == "" {
+				it.Path = bm.Path
+			}
+		}
+	}
+
+	return it, true
+}
+
+// urlPath converts a file:// URL to a path, leaving other strings as-is
+func urlPath(s string) string {
+	if !strings.HasPrefix(s, "file://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return strings.TrimPrefix(s, "file://")
+	}
+	p := u.Path
+	if len(p) > 1 {
+		p = strings.TrimSuffix(p, "/")
+	}
+	return p
+}
+
+func str(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case nil:
+		return ""
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func num(v interface{}) int64 {
+	switch t := v.(type) {
+	case int64:
+		return t
+	case uint64:
+		return int64(t)
+	case float64:
+		return int64(t)
+	}
+	return 0
+}
+
+func strs(v interface{}) []string {
+	arr, ok := v.([]interface{})
+	if !ok {
+		return nil
+	}
+	out := make([]string, 0, len(arr))
+	for _, e := range arr {
+		if s, ok := e.(string); ok && s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
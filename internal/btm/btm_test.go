@@ -0,0 +1,48 @@
+package btm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sharedArchive is an XML NSKeyedArchiver document whose arrays each hold
+// the next one twice, down to a single item: 2^depth paths to the item
+func sharedArchive(depth int) []byte {
+	uid := func(n int) string {
+		return fmt.Sprintf("<dict><key>CF$UID</key><integer>%d</integer></dict>", n)
+	}
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><plist version="1.0"><dict>`)
+	b.WriteString(`<key>$archiver</key><string>NSKeyedArchiver</string>`)
+	b.WriteString(`<key>$top</key><dict><key>root</key>` + uid(2) + `</dict>`)
+	b.WriteString(`<key>$objects</key><array><string>$null</string>`)
+	b.WriteString(`<dict><key>$classname</key><string>NSArray</string></dict>`)
+	for i := 0; i < depth; i++ {
+		next := uid(i + 3)
+		b.WriteString(`<dict><key>$class</key>` + uid(1) + `<key>NS.objects</key><array>` + next + next + `</array></dict>`)
+	}
+	b.WriteString(`<dict><key>disposition</key><integer>3</integer><key>name</key><string>Agent</string></dict>`)
+	b.WriteString(`</array></dict></plist>`)
+	return []byte(b.String())
+}
+
+func TestParseSharedDAG(t *testing.T) {
+	done := make(chan []Item, 1)
+	go func() {
+		items, err := Parse(sharedArchive(30))
+		if err != nil {
+			t.Error(err)
+		}
+		done <- items
+	}()
+	select {
+	case items := <-done:
+		if len(items) != 1 || items[0].Name != "Agent" {
+			t.Errorf("items = %+v, want the one shared item", items)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Parse did not finish")
+	}
+}
@@ -0,0 +1,158 @@
+// Package casedir defines the on-disk layout of a collection ("case"): one
+// directory per collection holding artifacts.db, a case.json manifest and any
+// generated outputs. Every CLI verb locates its inputs and outputs through it,
+// so a case collected on an endpoint can be analyzed and reported elsewhere.
+package casedir
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// File names inside a case directory
+const (
+	ManifestFile    = "case.json"
+	DBFile          = "artifacts.db"
+	CSVFile         = "artifacts.csv"
+	JSONLFile       = "artifacts.jsonl"
+	ReportFile      = "report.html"
+	TimelineFile    = "timeline.csv"
+	DiffFile        = "diff.json"
+	AttackLayerFile = "attack_layer.json"
+	// TimelineJSONLFile is written instead of TimelineFile with
+	// --timeline-format jsonl
+	TimelineJSONLFile = "timeline.jsonl"
+	// Artifacts that could not be shipped, ready to post to the endpoint
+	ElasticSpoolFile = "spool.elasticsearch.ndjson"
+	SplunkSpoolFile  = "spool.splunk.json"
+)
+
+// Manifest records how and when a case was collected and processed
+type Manifest struct {
+	Version     string        `json:"version"`
+	Hostname    string        `json:"hostname"`
+	Root        string        `json:"root,omitempty"`
+	CollectedAt time.Time     `json:"collected_at"`
+	Duration    string        `json:"duration,omitempty"`
+	Collectors  []string      `json:"collectors,omitempty"`
+	Artifacts   int           `json:"artifacts"`
+	Analyses    []AnalysisRun `json:"analyses,omitempty"`
+}
+
+// AnalysisRun records one pass of the analyzers over the case
+type AnalysisRun struct {
+	At           time.Time `json:"at"`
+	Version      string    `json:"version"`
+	IOCFile      string    `json:"ioc_file,omitempty"`
+	Sigma        string    `json:"sigma,omitempty"`
+	YARA         string    `json:"yara,omitempty"`
+	Baseline     string    `json:"baseline,omitempty"`
+	AttackMap    string    `json:"attack_map,omitempty"`
+	Weights      string    `json:"weights,omitempty"`
+	Suppressions string    `json:"suppressions,omitempty"`
+	Findings     int       `json:"findings"`
+	// Suppressed is the number of findings suppression rules left out of
+	// Findings
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// Case is a collection directory
+type Case struct {
+	Dir      string
+	Manifest Manifest
+}
+
+// Create makes a new case directory named <hostname>-<timestamp> under outputDir
+func Create(outputDir, hostname string, now time.Time) (*Case, error) {
+	dir := filepath.Join(outputDir, fmt.Sprintf("%s-%s", hostname, now.Format("20060102-150405")))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Case{
+		Dir:      dir,
+		Manifest: Manifest{Hostname: hostname, CollectedAt: now},
+	}, nil
+}
+
+// Open locates a case from either its directory or its artifacts.db path.
+// Cases collected before manifests existed open with an empty Manifest.
+func Open(path string) (*Case, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	dir := path
+	if !info.IsDir() {
+		dir = filepath.Dir(path)
+	}
+
+	c := &Case{Dir: dir}
+	if _, err := os.Stat(c.DBPath()); err != nil {
+		return nil, fmt.Errorf("%s is not a case directory: %w", dir, err)
+	}
+
+	data, err := os.ReadFile(c.Path(ManifestFile))
+	if err == nil {
+		if err := json.Unmarshal(data, &c.Manifest); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", ManifestFile, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return c, nil
+}
+
+// List returns the cases directly under outputDir, oldest first
+func List(outputDir string) ([]*Case, error) {
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return nil, err
+	}
+	var cases []*Case
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		c, err := Open(filepath.Join(outputDir, e.Name()))
+		if err != nil {
+			continue
+		}
+		cases = append(cases, c)
+	}
+	sort.Slice(cases, func(i, j int) bool {
+		ti, tj := cases[i].Manifest.CollectedAt, cases[j].Manifest.CollectedAt
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return cases[i].Dir < cases[j].Dir
+	})
+	return cases, nil
+}
+
+// Path returns the path of a file inside the case directory
+func (c *Case) Path(name string) string { return filepath.Join(c.Dir, name) }
+
+func (c *Case) DBPath() string            { return c.Path(DBFile) }
+func (c *Case) CSVPath() string           { return c.Path(CSVFile) }
+func (c *Case) JSONLPath() string         { return c.Path(JSONLFile) }
+func (c *Case) ReportPath() string        { return c.Path(ReportFile) }
+func (c *Case) TimelinePath() string      { return c.Path(TimelineFile) }
+func (c *Case) TimelineJSONLPath() string { return c.Path(TimelineJSONLFile) }
+func (c *Case) AttackLayerPath() string   { return c.Path(AttackLayerFile) }
+
+// ExportPath returns the path of the bulk NDJSON export in a schema
+// (artifacts.ecs.ndjson)
+func (c *Case) ExportPath(schema string) string { return c.Path("artifacts." + schema + ".ndjson") }
+
+// Save writes the manifest to case.json
+func (c *Case) Save() error {
+	data, err := json.MarshalIndent(c.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.Path(ManifestFile), append(data, '\n'), 0644)
+}
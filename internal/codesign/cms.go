@@ -0,0 +1,92 @@
+package codesign
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+)
+
+// cmsSigners returns the common names of the certificates in a CMS
+// SignedData blob, leaf first:
+//
+//	ContentInfo ::= SEQUENCE { contentType OID, content [0] EXPLICIT SignedData }
+//	SignedData ::= SEQUENCE { version, digestAlgorithms, encapContentInfo,
+//	                          certificates [0] IMPLICIT SET OF Certificate OPTIONAL, ... }
+func cmsSigners(der []byte) []string {
+	var ci struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue `asn1:"explicit,tag:0"`
+	}
+	if _, err := asn1.Unmarshal(der, &ci); err != nil {
+		return nil
+	}
+	var signedData asn1.RawValue
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &signedData); err != nil {
+		return nil
+	}
+
+	for rest := signedData.Bytes; len(rest) > 0; {
+		var field asn1.RawValue
+		var err error
+		if rest, err = asn1.Unmarshal(rest, &field); err != nil {
+			return nil
+		}
+		if field.Class != asn1.ClassContextSpecific || field.Tag != 0 {
+			continue
+		}
+		certs, err := x509.ParseCertificates(field.Bytes)
+		if err != nil {
+			return nil
+		}
+		var names []string
+		for _, c := range chainOrder(certs) {
+			names = append(names, c.Subject.CommonName)
+		}
+		return names
+	}
+	return nil
+}
+
+// chainOrder sorts certificates from the leaf (the one that issued no other)
+// up through its issuers; certificates outside that chain follow
+func chainOrder(certs []*x509.Certificate) []*x509.Certificate {
+	issuer := func(c *x509.Certificate) *x509.Certificate {
+		for _, p := range certs {
+			if p != c && bytes.Equal(p.RawSubject, c.RawIssuer) {
+				return p
+			}
+		}
+		return nil
+	}
+
+	var leaf *x509.Certificate
+	for _, c := range certs {
+		issuesOther := false
+		for _, o := range certs {
+			if o != c && bytes.Equal(o.RawIssuer, c.RawSubject) && !bytes.Equal(o.RawSubject, o.RawIssuer) {
+				issuesOther = true
+				break
+			}
+		}
+		if !issuesOther {
+			leaf = c
+			break
+		}
+	}
+	if leaf == nil {
+		return certs
+	}
+
+	seen := make(map[*x509.Certificate]bool)
+	var ordered []*x509.Certificate
+	for c := leaf; c != nil && !seen[c]; c = issuer(c) {
+		seen[c] = true
+		ordered = append(ordered, c)
+	}
+	for _, c := range certs {
+		if !seen[c] {
+			ordered = append(ordered, c)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,107 @@
+package codesign
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+)
+
+// CodeDirectory hash types
+const (
+	hashSHA1        = 1
+	hashSHA256      = 2
+	hashSHA256Trunc = 3
+	hashSHA384      = 4
+)
+
+const (
+	cdHashLen = 20
+	// cdMinHeaderLength covers the fields up to spare2
+	cdMinHeaderLength = 44
+	// cdVersionTeamID is the first version with teamOffset
+	cdVersionTeamID = 0x20200
+)
+
+type codeDirectory struct {
+	raw        []byte
+	flags      uint32
+	hashType   uint8
+	identifier string
+	teamID     string
+}
+
+// parseCodeDirectory reads a CodeDirectory blob:
+//
+//	magic, length, version, flags, hashOffset, identOffset,
+//	nSpecialSlots, nCodeSlots, codeLimit          uint32
+//	hashSize, hashType, platform, pageSize        uint8
+//	spare2                                        uint32
+//	scatterOffset                                 uint32 (>= 0x20100)
+//	teamOffset                                    uint32 (>= 0x20200)
+func parseCodeDirectory(b []byte) (*codeDirectory, error) {
+	be := binary.BigEndian
+	if len(b) < cdMinHeaderLength || be.Uint32(b) != magicCodeDirectory {
+		return nil, fmt.Errorf("bad code directory")
+	}
+	cd := &codeDirectory{
+		raw:        b,
+		flags:      be.Uint32(b[12:]),
+		hashType:   b[37],
+		identifier: cString(b, int(be.Uint32(b[20:]))),
+	}
+	if version := be.Uint32(b[8:]); version >= cdVersionTeamID && len(b) >= 52 {
+		cd.teamID = cString(b, int(be.Uint32(b[48:])))
+	}
+	return cd, nil
+}
+
+// strength orders hash types so the SHA-256 directory is preferred over the
+// SHA-1 one kept for old systems, as the kernel does
+func (cd *codeDirectory) strength() int {
+	switch cd.hashType {
+	case hashSHA1:
+		return 1
+	case hashSHA256Trunc:
+		return 2
+	case hashSHA256:
+		return 3
+	case hashSHA384:
+		return 4
+	}
+	return 0
+}
+
+func (cd *codeDirectory) hashName() string {
+	switch cd.hashType {
+	case hashSHA1:
+		return "sha1"
+	case hashSHA256:
+		return "sha256"
+	case hashSHA256Trunc:
+		return "sha256-truncated"
+	case hashSHA384:
+		return "sha384"
+	}
+	return fmt.Sprintf("unknown-%d", cd.hashType)
+}
+
+// cdhash is the directory's own hash, truncated to 20 bytes
+func (cd *codeDirectory) cdhash() string {
+	var h hash.Hash
+	switch cd.hashType {
+	case hashSHA1:
+		h = sha1.New()
+	case hashSHA256, hashSHA256Trunc:
+		h = sha256.New()
+	case hashSHA384:
+		h = sha512.New384()
+	default:
+		return ""
+	}
+	h.Write(cd.raw)
+	return hex.EncodeToString(h.Sum(nil)[:cdHashLen])
+}
@@ -0,0 +1,289 @@
+// Package codesign reads the embedded code signature of Mach-O binaries
+// without calling codesign: the CodeDirectory (identifier, team ID, flags,
+// cdhash), the CMS signer certificate chain and the entitlements, for thin
+// and universal binaries.
+//
+// Signatures are read, not verified: page hashes and the CMS signature are
+// not checked, so a tampered binary still reports its original signer.
+package codesign
+
+import (
+	"bytes"
+	"debug/macho"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/plonxyz/triagectl/internal/plist"
+)
+
+// Code signing flags from the CodeDirectory (CS_* in xnu's cs_blobs.h)
+const (
+	FlagAdhoc             = 0x00000002
+	FlagHard              = 0x00000100
+	FlagKill              = 0x00000200
+	FlagCheckExpiration   = 0x00000400
+	FlagRestrict          = 0x00000800
+	FlagEnforcement       = 0x00001000
+	FlagLibraryValidation = 0x00002000
+	FlagRuntime           = 0x00010000
+	FlagLinkerSigned      = 0x00020000
+)
+
+// flagNames are the flags in the order and spelling codesign -dv prints them
+var flagNames = []struct {
+	flag uint32
+	name string
+}{
+	{FlagAdhoc, "adhoc"},
+	{FlagHard, "hard"},
+	{FlagKill, "kill"},
+	{FlagCheckExpiration, "expires"},
+	{FlagRestrict, "restrict"},
+	{FlagEnforcement, "enforcement"},
+	{FlagLibraryValidation, "library-validation"},
+	{FlagRuntime, "runtime"},
+	{FlagLinkerSigned, "linker-signed"},
+}
+
+// ErrNotMachO is returned for files that are not Mach-O binaries
+var ErrNotMachO = errors.New("not a Mach-O binary")
+
+// Signature is the code signature of one architecture slice
+type Signature struct {
+	Arch string
+	// Signed is false when the slice has no LC_CODE_SIGNATURE
+	Signed     bool
+	Identifier string
+	TeamID     string
+	// CDHash is the hex cdhash of the strongest CodeDirectory
+	CDHash   string
+	HashType string
+	Flags    uint32
+	// Signers are the common names of the CMS certificates, leaf first;
+	// empty for ad-hoc signatures
+	Signers      []string
+	Entitlements map[string]interface{}
+}
+
+// Adhoc reports whether the slice is signed without a certificate
+func (s Signature) Adhoc() bool {
+	return s.Signed && (s.Flags&FlagAdhoc != 0 || len(s.Signers) == 0)
+}
+
+// HardenedRuntime reports whether the hardened runtime is enabled
+func (s Signature) HardenedRuntime() bool { return s.Flags&FlagRuntime != 0 }
+
+// LinkerSigned reports whether the signature was made by the linker
+func (s Signature) LinkerSigned() bool { return s.Flags&FlagLinkerSigned != 0 }
+
+// FlagNames returns the names of the set flags
+func (s Signature) FlagNames() []string {
+	var names []string
+	for _, f := range flagNames {
+		if s.Flags&f.flag != 0 {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// ParseFile reads the signatures of every architecture in a Mach-O file
+func ParseFile(path string) ([]Signature, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads the signatures of every architecture in a thin or universal
+// Mach-O binary
+func Parse(r io.ReaderAt) ([]Signature, error) {
+	fat, err := macho.NewFatFile(r)
+	if err == nil {
+		defer fat.Close()
+		var sigs []Signature
+		for _, arch := range fat.Arches {
+			sig, err := parseSlice(r, arch.File, int64(arch.Offset))
+			if err != nil {
+				return nil, fmt.Errorf("%s slice: %w", archName(arch.Cpu), err)
+			}
+			sigs = append(sigs, sig)
+		}
+		return sigs, nil
+	}
+	if !errors.Is(err, macho.ErrNotFat) {
+		return nil, notMachO(err)
+	}
+
+	f, err := macho.NewFile(r)
+	if err != nil {
+		return nil, notMachO(err)
+	}
+	defer f.Close()
+	sig, err := parseSlice(r, f, 0)
+	if err != nil {
+		return nil, err
+	}
+	return []Signature{sig}, nil
+}
+
+// notMachO maps debug/macho's bad magic errors to ErrNotMachO
+func notMachO(err error) error {
+	var ferr *macho.FormatError
+	if errors.As(err, &ferr) {
+		return ErrNotMachO
+	}
+	return err
+}
+
+// loadCodeSignature is LC_CODE_SIGNATURE, which debug/macho leaves raw
+const loadCodeSignature = 0x1d
+
+// maxSignatureSize bounds the signature blob read from a slice
+const maxSignatureSize = 16 << 20
+
+func parseSlice(r io.ReaderAt, f *macho.File, offset int64) (Signature, error) {
+	sig := Signature{Arch: archName(f.Cpu)}
+	for _, l := range f.Loads {
+		raw := l.Raw()
+		if len(raw) < 16 || f.ByteOrder.Uint32(raw) != loadCodeSignature {
+			continue
+		}
+		dataOff := int64(f.ByteOrder.Uint32(raw[8:]))
+		dataSize := int64(f.ByteOrder.Uint32(raw[12:]))
+		if dataSize > maxSignatureSize {
+			return sig, fmt.Errorf("code signature of %d bytes is too large", dataSize)
+		}
+		blob := make([]byte, dataSize)
+		if _, err := r.ReadAt(blob, offset+dataOff); err != nil {
+			return sig, fmt.Errorf("reading code signature: %w", err)
+		}
+		if err := parseSuperBlob(blob, &sig); err != nil {
+			return sig, err
+		}
+		sig.Signed = true
+		break
+	}
+	return sig, nil
+}
+
+// Blob magics and superblob slot types
+const (
+	magicEmbeddedSignature = 0xfade0cc0
+	magicCodeDirectory     = 0xfade0c02
+	magicEntitlements      = 0xfade7171
+	magicBlobWrapper       = 0xfade0b01
+
+	slotCodeDirectory       = 0
+	slotEntitlements        = 5
+	slotAlternateCodeDirLow = 0x1000
+	slotAlternateCodeDirHi  = 0x1004
+	slotSignature           = 0x10000
+)
+
+// parseSuperBlob reads the embedded signature superblob. All blob fields
+// are big-endian regardless of the binary's byte order.
+func parseSuperBlob(b []byte, sig *Signature) error {
+	be := binary.BigEndian
+	if len(b) < 12 || be.Uint32(b) != magicEmbeddedSignature {
+		return fmt.Errorf("bad embedded signature magic")
+	}
+	count := int(be.Uint32(b[8:]))
+	if 12+count*8 > len(b) {
+		return fmt.Errorf("truncated embedded signature index")
+	}
+
+	var best *codeDirectory
+	for i := 0; i < count; i++ {
+		typ := be.Uint32(b[12+i*8:])
+		off := int(be.Uint32(b[16+i*8:]))
+		blob, err := subBlob(b, off)
+		if err != nil {
+			return fmt.Errorf("slot %#x: %w", typ, err)
+		}
+
+		switch {
+		case typ == slotCodeDirectory || typ >= slotAlternateCodeDirLow && typ <= slotAlternateCodeDirHi:
+			cd, err := parseCodeDirectory(blob)
+			if err != nil {
+				return err
+			}
+			if best == nil || cd.strength() > best.strength() {
+				best = cd
+			}
+		case typ == slotEntitlements && be.Uint32(blob) == magicEntitlements:
+			sig.Entitlements = parseEntitlements(blob[8:])
+		case typ == slotSignature && be.Uint32(blob) == magicBlobWrapper:
+			if len(blob) > 8 {
+				sig.Signers = cmsSigners(blob[8:])
+			}
+		}
+	}
+	if best == nil {
+		return fmt.Errorf("embedded signature has no code directory")
+	}
+
+	sig.Identifier = best.identifier
+	sig.TeamID = best.teamID
+	sig.Flags = best.flags
+	sig.CDHash = best.cdhash()
+	sig.HashType = best.hashName()
+	return nil
+}
+
+// parseEntitlements decodes the XML plist of an entitlements blob
+func parseEntitlements(b []byte) map[string]interface{} {
+	v, err := plist.Decode(b)
+	if err != nil {
+		return nil
+	}
+	ents, _ := v.(map[string]interface{})
+	return ents
+}
+
+// subBlob returns the blob at off, bounded by its own length field
+func subBlob(b []byte, off int) ([]byte, error) {
+	if off < 0 || off+8 > len(b) {
+		return nil, fmt.Errorf("blob offset %d out of range", off)
+	}
+	length := int(binary.BigEndian.Uint32(b[off+4:]))
+	if length < 8 || off+length > len(b) {
+		return nil, fmt.Errorf("blob length %d out of range", length)
+	}
+	return b[off : off+length], nil
+}
+
+// cString reads a NUL-terminated string at off
+func cString(b []byte, off int) string {
+	if off <= 0 || off >= len(b) {
+		return ""
+	}
+	s := b[off:]
+	if i := bytes.IndexByte(s, 0); i >= 0 {
+		s = s[:i]
+	}
+	return string(s)
+}
+
+func archName(cpu macho.Cpu) string {
+	switch cpu {
+	case macho.CpuAmd64:
+		return "x86_64"
+	case macho.CpuArm64:
+		return "arm64"
+	case macho.Cpu386:
+		return "i386"
+	case macho.CpuArm:
+		return "arm"
+	case macho.CpuPpc:
+		return "ppc"
+	case macho.CpuPpc64:
+		return "ppc64"
+	}
+	return fmt.Sprintf("cpu_%d", uint32(cpu))
+}
@@ -0,0 +1,324 @@
+package codesign
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var be = binary.BigEndian
+
+// blob prefixes a payload with a blob magic and length
+func blob(magic uint32, payload []byte) []byte {
+	b := be.AppendUint32(nil, magic)
+	b = be.AppendUint32(b, uint32(8+len(payload)))
+	return append(b, payload...)
+}
+
+// codeDir builds a version 0x20400 CodeDirectory
+func codeDir(ident, team string, flags uint32, hashType uint8) []byte {
+	const header = 88
+	b := make([]byte, header)
+	be.PutUint32(b, magicCodeDirectory)
+	be.PutUint32(b[8:], 0x20400)
+	be.PutUint32(b[12:], flags)
+	be.PutUint32(b[20:], header)
+	b[36] = 32
+	b[37] = hashType
+	be.PutUint32(b[48:], uint32(header+len(ident)+1))
+	b = append(b, ident...)
+	b = append(b, 0)
+	b = append(b, team...)
+	b = append(b, 0)
+	be.PutUint32(b[4:], uint32(len(b)))
+	return b
+}
+
+type slot struct {
+	typ  uint32
+	blob []byte
+}
+
+// superBlob builds an embedded signature holding slots in order
+func superBlob(slots ...slot) []byte {
+	off := 12 + 8*len(slots)
+	var index, blobs []byte
+	for _, s := range slots {
+		index = be.AppendUint32(index, s.typ)
+		index = be.AppendUint32(index, uint32(off+len(blobs)))
+		blobs = append(blobs, s.blob...)
+	}
+	b := be.AppendUint32(nil, magicEmbeddedSignature)
+	b = be.AppendUint32(b, uint32(off+len(blobs)))
+	b = be.AppendUint32(b, uint32(len(slots)))
+	return append(append(b, index...), blobs...)
+}
+
+// thinMachO builds a 64-bit little-endian Mach-O whose only load command is
+// LC_CODE_SIGNATURE pointing at sig, or that has no load commands if sig is nil
+func thinMachO(cpu uint32, sig []byte) []byte {
+	le := binary.LittleEndian
+	ncmds, sizeofcmds := 0, 0
+	if sig != nil {
+		ncmds, sizeofcmds = 1, 16
+	}
+	b := le.AppendUint32(nil, 0xfeedfacf)
+	b = le.AppendUint32(b, cpu)
+	b = le.AppendUint32(b, 0)
+	b = le.AppendUint32(b, 2) // MH_EXECUTE
+	b = le.AppendUint32(b, uint32(ncmds))
+	b = le.AppendUint32(b, uint32(sizeofcmds))
+	b = le.AppendUint32(b, 0)
+	b = le.AppendUint32(b, 0)
+	if sig != nil {
+		b = le.AppendUint32(b, loadCodeSignature)
+		b = le.AppendUint32(b, 16)
+		b = le.AppendUint32(b, uint32(len(b)+8))
+		b = le.AppendUint32(b, uint32(len(sig)))
+		b = append(b, sig...)
+	}
+	return b
+}
+
+// fatMachO wraps slices, each aligned to 4096 bytes, in a universal binary
+func fatMachO(cpus []uint32, slices [][]byte) []byte {
+	const align = 4096
+	b := be.AppendUint32(nil, 0xcafebabe)
+	b = be.AppendUint32(b, uint32(len(slices)))
+	off := align
+	var body []byte
+	for i, s := range slices {
+		b = be.AppendUint32(b, cpus[i])
+		b = be.AppendUint32(b, 0)
+		b = be.AppendUint32(b, uint32(off))
+		b = be.AppendUint32(b, uint32(len(s)))
+		b = be.AppendUint32(b, 12)
+		body = append(body, s...)
+		body = append(body, make([]byte, align-len(s)%align)...)
+		off += len(s) + align - len(s)%align
+	}
+	b = append(b, make([]byte, align-len(b))...)
+	return append(b, body...)
+}
+
+const (
+	cpuAmd64 = 0x01000007
+	cpuArm64 = 0x0100000c
+)
+
+func TestParseThin(t *testing.T) {
+	cd1 := codeDir("com.example.tool", "ABCDE12345", FlagRuntime, hashSHA1)
+	cd256 := codeDir("com.example.tool", "ABCDE12345", FlagRuntime, hashSHA256)
+	ents := blob(magicEntitlements, []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict><key>com.apple.security.get-task-allow</key><true/></dict></plist>`))
+	bin := thinMachO(cpuArm64, superBlob(
+		slot{slotCodeDirectory, cd1},
+		slot{slotEntitlements, ents},
+		slot{slotAlternateCodeDirLow, cd256},
+	))
+
+	sigs, err := Parse(bytes.NewReader(bin))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sigs) != 1 {
+		t.Fatalf("got %d signatures, want 1", len(sigs))
+	}
+	sig := sigs[0]
+	sum := sha256.Sum256(cd256)
+	want := Signature{
+		Arch:         "arm64",
+		Signed:       true,
+		Identifier:   "com.example.tool",
+		TeamID:       "ABCDE12345",
+		CDHash:       hex.EncodeToString(sum[:cdHashLen]),
+		HashType:     "sha256",
+		Flags:        FlagRuntime,
+		Entitlements: map[string]interface{}{"com.apple.security.get-task-allow": true},
+	}
+	if !reflect.DeepEqual(sig, want) {
+		t.Errorf("Parse = %+v, want %+v", sig, want)
+	}
+	if !sig.Adhoc() || !sig.HardenedRuntime() || sig.LinkerSigned() {
+		t.Errorf("Adhoc/HardenedRuntime/LinkerSigned = %v/%v/%v", sig.Adhoc(), sig.HardenedRuntime(), sig.LinkerSigned())
+	}
+	if got := sig.FlagNames(); !reflect.DeepEqual(got, []string{"runtime"}) {
+		t.Errorf("FlagNames = %v", got)
+	}
+}
+
+func TestParseUniversal(t *testing.T) {
+	x86 := thinMachO(cpuAmd64, superBlob(slot{slotCodeDirectory, codeDir("a.out", "", FlagAdhoc|FlagLinkerSigned, hashSHA256)}))
+	arm := thinMachO(cpuArm64, nil)
+	sigs, err := Parse(bytes.NewReader(fatMachO([]uint32{cpuAmd64, cpuArm64}, [][]byte{x86, arm})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sigs) != 2 {
+		t.Fatalf("got %d signatures, want 2", len(sigs))
+	}
+	if s := sigs[0]; s.Arch != "x86_64" || !s.Signed || s.Identifier != "a.out" || s.TeamID != "" ||
+		!s.Adhoc() || !s.LinkerSigned() {
+		t.Errorf("x86_64 slice = %+v", s)
+	}
+	if s := sigs[1]; s.Arch != "arm64" || s.Signed {
+		t.Errorf("arm64 slice = %+v, want unsigned", s)
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	cd := codeDir("x", "", 0, hashSHA256)
+	tests := []struct {
+		name    string
+		sig     []byte
+		wantErr string
+	}{
+		{"bad magic", blob(0xdeadbeef, make([]byte, 8)), "bad embedded signature magic"},
+		{"short", []byte{0xfa, 0xde}, "bad embedded signature magic"},
+		{"huge count", func() []byte {
+			b := superBlob(slot{slotCodeDirectory, cd})
+			be.PutUint32(b[8:], 0xffffffff)
+			return b
+		}(), "truncated embedded signature index"},
+		{"blob offset", func() []byte {
+			b := superBlob(slot{slotCodeDirectory, cd})
+			be.PutUint32(b[16:], 0xfffffff0)
+			return b
+		}(), "out of range"},
+		{"blob length", func() []byte {
+			b := superBlob(slot{slotCodeDirectory, cd})
+			be.PutUint32(b[20+4:], 0xffffffff)
+			return b
+		}(), "blob length"},
+		{"short code directory", superBlob(slot{slotCodeDirectory, blob(magicCodeDirectory, make([]byte, 8))}), "bad code directory"},
+		{"no code directory", superBlob(slot{slotEntitlements, blob(magicEntitlements, nil)}), "no code directory"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(bytes.NewReader(thinMachO(cpuArm64, tt.sig)))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if _, err := Parse(bytes.NewReader([]byte("#!/bin/sh\necho hi\n"))); !errors.Is(err, ErrNotMachO) {
+		t.Errorf("script: err = %v, want ErrNotMachO", err)
+	}
+}
+
+func TestCodeDirectoryFields(t *testing.T) {
+	// Out-of-range string offsets and old versions without a team ID
+	b := codeDir("id", "TEAM", 0, hashSHA1)
+	be.PutUint32(b[20:], 0xffffff)
+	be.PutUint32(b[8:], 0x20100)
+	cd, err := parseCodeDirectory(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cd.identifier != "" || cd.teamID != "" {
+		t.Errorf("identifier, team = %q, %q; want both empty", cd.identifier, cd.teamID)
+	}
+	sum := sha1.Sum(b)
+	if cd.cdhash() != hex.EncodeToString(sum[:]) || cd.hashName() != "sha1" {
+		t.Errorf("cdhash = %s (%s)", cd.cdhash(), cd.hashName())
+	}
+	cd.hashType = 9
+	if cd.cdhash() != "" || cd.hashName() != "unknown-9" || cd.strength() != 0 {
+		t.Errorf("unknown hash type: %q %q %d", cd.cdhash(), cd.hashName(), cd.strength())
+	}
+}
+
+func TestCMSSigners(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newCert := func(serial int64, cn string, parent *x509.Certificate) *x509.Certificate {
+		tmpl := &x509.Certificate{
+			SerialNumber:          big.NewInt(serial),
+			Subject:               pkix.Name{CommonName: cn},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  parent == nil || cn != "Developer ID Application: Example",
+			BasicConstraintsValid: true,
+		}
+		if parent == nil {
+			parent = tmpl
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	root := newCert(1, "Apple Root CA", nil)
+	inter := newCert(2, "Developer ID Certification Authority", root)
+	leaf := newCert(3, "Developer ID Application: Example", inter)
+
+	// Certificates stored out of chain order, as codesign does not guarantee one
+	var certs []byte
+	for _, c := range []*x509.Certificate{root, leaf, inter} {
+		certs = append(certs, c.Raw...)
+	}
+	mustMarshal := func(v interface{}) []byte {
+		b, err := asn1.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	version := mustMarshal(1)
+	digests := mustMarshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true})
+	encap := mustMarshal(struct{ Type asn1.ObjectIdentifier }{asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}})
+	certSet := mustMarshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certs})
+	signedData := mustMarshal(asn1.RawValue{
+		Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true,
+		Bytes: bytes.Join([][]byte{version, digests, encap, certSet}, nil),
+	})
+	contentType := mustMarshal(asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2})
+	content := mustMarshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signedData})
+	der := mustMarshal(asn1.RawValue{
+		Class: asn1.ClassUniversal, Tag: asn1.TagSequence, IsCompound: true,
+		Bytes: append(contentType, content...),
+	})
+
+	want := []string{"Developer ID Application: Example", "Developer ID Certification Authority", "Apple Root CA"}
+	if got := cmsSigners(der); !reflect.DeepEqual(got, want) {
+		t.Errorf("cmsSigners = %v, want %v", got, want)
+	}
+
+	bin := thinMachO(cpuArm64, superBlob(
+		slot{slotCodeDirectory, codeDir("com.example", "ABCDE12345", 0, hashSHA256)},
+		slot{slotSignature, blob(magicBlobWrapper, der)},
+	))
+	sigs, err := Parse(bytes.NewReader(bin))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sigs[0].Signers, want) || sigs[0].Adhoc() {
+		t.Errorf("Signers = %v, adhoc %v", sigs[0].Signers, sigs[0].Adhoc())
+	}
+
+	for _, bad := range [][]byte{nil, {0x30, 0x80}, der[:len(der)/2]} {
+		if got := cmsSigners(bad); got != nil {
+			t.Errorf("cmsSigners(%x) = %v, want nil", bad, got)
+		}
+	}
+}
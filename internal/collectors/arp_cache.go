@@ -2,7 +2,6 @@ package collectors
 
 import (
 	"context"
-	"os"
 	"os/exec"
 	"regexp"
 	"strings"
@@ -17,9 +16,10 @@ func (c *ARPCacheCollector) ID() string          { return "arp_cache" }
 func (c *ARPCacheCollector) Name() string        { return "ARP Cache" }
 func (c *ARPCacheCollector) Description() string { return "Collects ARP cache entries for network mapping" }
 func (c *ARPCacheCollector) RequiresRoot() bool  { return false }
+func (c *ARPCacheCollector) LiveOnly() bool      { return true }
 
 func (c *ARPCacheCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 	var artifacts []models.Artifact
 
 	out, err := exec.CommandContext(ctx, "arp", "-a").CombinedOutput()
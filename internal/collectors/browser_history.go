@@ -17,30 +17,29 @@ func (c *BrowserHistoryCollector) ID() string          { return "browser_history
 func (c *BrowserHistoryCollector) Name() string        { return "Browser History" }
 func (c *BrowserHistoryCollector) Description() string { return "Collects browser history from Safari and Chrome" }
 func (c *BrowserHistoryCollector) RequiresRoot() bool  { return false }
+func (c *BrowserHistoryCollector) LiveOnly() bool      { return false }
 
 func (c *BrowserHistoryCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
-	}
+	hostname := TargetHostname()
 
 	var artifacts []models.Artifact
 
-	// Safari History
-	safariHistory := filepath.Join(homeDir, "Library/Safari/History.db")
-	safariArtifacts := c.collectSafariHistory(safariHistory, hostname)
-	artifacts = append(artifacts, safariArtifacts...)
+	for _, home := range userHomes() {
+		// Safari History
+		safariHistory := filepath.Join(home.path, "Library/Safari/History.db")
+		safariArtifacts := c.collectSafariHistory(safariHistory, home.user, hostname)
+		artifacts = append(artifacts, safariArtifacts...)
 
-	// Chrome History
-	chromeHistory := filepath.Join(homeDir, "Library/Application Support/Google/Chrome/Default/History")
-	chromeArtifacts := c.collectChromeHistory(chromeHistory, hostname)
-	artifacts = append(artifacts, chromeArtifacts...)
+		// Chrome History
+		chromeHistory := filepath.Join(home.path, "Library/Application Support/Google/Chrome/Default/History")
+		chromeArtifacts := c.collectChromeHistory(chromeHistory, home.user, hostname)
+		artifacts = append(artifacts, chromeArtifacts...)
+	}
 
 	return artifacts, nil
 }
 
-func (c *BrowserHistoryCollector) collectSafariHistory(dbPath string, hostname string) []models.Artifact {
+func (c *BrowserHistoryCollector) collectSafariHistory(dbPath, user, hostname string) []models.Artifact {
 	var artifacts []models.Artifact
 
 	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
@@ -100,6 +99,7 @@ func (c *BrowserHistoryCollector) collectSafariHistory(dbPath string, hostname s
 				"title":       title,
 				"visit_time":  visitDateTime.Format(time.RFC3339),
 				"visit_count": visitCount,
+				"user":        user,
 			},
 			Metadata: models.ArtifactMetadata{
 				Success:      true,
@@ -108,6 +108,7 @@ func (c *BrowserHistoryCollector) collectSafariHistory(dbPath string, hostname s
 				CollectedAt:  time.Now().Format(time.RFC3339),
 			},
 		}
+		artifact.AddTimestamp(models.TimeAccessed, "", visitDateTime)
 
 		artifacts = append(artifacts, artifact)
 	}
@@ -115,7 +116,7 @@ func (c *BrowserHistoryCollector) collectSafariHistory(dbPath string, hostname s
 	return artifacts
 }
 
-func (c *BrowserHistoryCollector) collectChromeHistory(dbPath string, hostname string) []models.Artifact {
+func (c *BrowserHistoryCollector) collectChromeHistory(dbPath, user, hostname string) []models.Artifact {
 	var artifacts []models.Artifact
 
 	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
@@ -176,6 +177,7 @@ func (c *BrowserHistoryCollector) collectChromeHistory(dbPath string, hostname s
 				"title":            title,
 				"visit_count":      visitCount,
 				"last_visit_time":  visitDateTime.Format(time.RFC3339),
+				"user":             user,
 			},
 			Metadata: models.ArtifactMetadata{
 				Success:      true,
@@ -184,6 +186,7 @@ func (c *BrowserHistoryCollector) collectChromeHistory(dbPath string, hostname s
 				CollectedAt:  time.Now().Format(time.RFC3339),
 			},
 		}
+		artifact.AddTimestamp(models.TimeAccessed, "", visitDateTime)
 
 		artifacts = append(artifacts, artifact)
 	}
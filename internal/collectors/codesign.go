@@ -0,0 +1,115 @@
+package collectors
+
+import (
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/plonxyz/triagectl/internal/codesign"
+)
+
+// signatures caches parsed code signatures by path and file version, as one
+// binary usually backs many processes
+var signatures = struct {
+	sync.Mutex
+	m map[signatureKey]signatureResult
+}{m: make(map[signatureKey]signatureResult)}
+
+type signatureKey struct {
+	path  string
+	size  int64
+	mtime int64
+}
+
+type signatureResult struct {
+	sigs []codesign.Signature
+	err  error
+}
+
+// addCodeSignature reads the embedded code signature of the Mach-O binary at
+// hostPath into data under prefix. Files that are missing or not Mach-O add
+// nothing; a universal binary counts as signed only when every slice is.
+func addCodeSignature(data map[string]interface{}, prefix, hostPath string) {
+	sigs, err := parseSignature(hostPath)
+	if errors.Is(err, codesign.ErrNotMachO) || errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		data[prefix+"code_signature_error"] = err.Error()
+		return
+	}
+	if len(sigs) == 0 {
+		return
+	}
+
+	signed := true
+	adhoc := false
+	hardened := true
+	var primary *codesign.Signature
+	var archs []string
+	cdhashes := make(map[string]interface{})
+	for i := range sigs {
+		s := &sigs[i]
+		archs = append(archs, s.Arch)
+		if !s.Signed {
+			signed = false
+			hardened = false
+			continue
+		}
+		if primary == nil {
+			primary = s
+		}
+		adhoc = adhoc || s.Adhoc()
+		hardened = hardened && s.HardenedRuntime()
+		cdhashes[s.Arch] = s.CDHash
+	}
+
+	data[prefix+"archs"] = archs
+	data[prefix+"signed"] = signed
+	if primary == nil {
+		return
+	}
+	data[prefix+"adhoc_signed"] = adhoc
+	data[prefix+"hardened_runtime"] = hardened
+	data[prefix+"signing_id"] = primary.Identifier
+	data[prefix+"cdhash"] = primary.CDHash
+	if len(cdhashes) > 1 {
+		data[prefix+"cdhashes"] = cdhashes
+	}
+	if primary.TeamID != "" {
+		data[prefix+"team_id"] = primary.TeamID
+	}
+	if flags := primary.FlagNames(); len(flags) > 0 {
+		data[prefix+"code_signature_flags"] = flags
+	}
+	if len(primary.Signers) > 0 {
+		data[prefix+"signers"] = primary.Signers
+	}
+	if len(primary.Entitlements) > 0 {
+		data[prefix+"entitlements"] = primary.Entitlements
+	}
+}
+
+func parseSignature(path string) ([]codesign.Signature, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, codesign.ErrNotMachO
+	}
+	key := signatureKey{path: path, size: info.Size(), mtime: info.ModTime().UnixNano()}
+
+	signatures.Lock()
+	r, ok := signatures.m[key]
+	signatures.Unlock()
+	if ok {
+		return r.sigs, r.err
+	}
+
+	r.sigs, r.err = codesign.ParseFile(path)
+	signatures.Lock()
+	signatures.m[key] = r
+	signatures.Unlock()
+	return r.sigs, r.err
+}
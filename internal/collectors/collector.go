@@ -19,6 +19,10 @@ type Collector interface {
 	// RequiresRoot indicates if root privileges are needed
 	RequiresRoot() bool
 
+	// LiveOnly indicates the collector inspects the running system and
+	// cannot be pointed at an offline root
+	LiveOnly() bool
+
 	// Collect performs the artifact collection
 	Collect(ctx context.Context) ([]models.Artifact, error)
 }
@@ -15,6 +15,7 @@ func (c *EnvironmentCollector) ID() string          { return "environment" }
 func (c *EnvironmentCollector) Name() string        { return "Environment Variables" }
 func (c *EnvironmentCollector) Description() string { return "Collects environment variables and flags suspicious ones" }
 func (c *EnvironmentCollector) RequiresRoot() bool  { return false }
+func (c *EnvironmentCollector) LiveOnly() bool      { return true }
 
 // suspiciousVars are environment variables commonly abused for persistence or injection
 var suspiciousVars = map[string]string{
@@ -32,7 +33,7 @@ var suspiciousVars = map[string]string{
 }
 
 func (c *EnvironmentCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 	var artifacts []models.Artifact
 
 	for _, envVar := range os.Environ() {
@@ -17,16 +17,20 @@ func (c *ExtensionsCollector) ID() string          { return "extensions" }
 func (c *ExtensionsCollector) Name() string        { return "System/Kernel Extensions" }
 func (c *ExtensionsCollector) Description() string { return "Collects system extensions, kernel extensions, and third-party extensions" }
 func (c *ExtensionsCollector) RequiresRoot() bool  { return false }
+func (c *ExtensionsCollector) LiveOnly() bool      { return false }
 
 func (c *ExtensionsCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 	var artifacts []models.Artifact
 
-	// System extensions
-	artifacts = append(artifacts, c.collectSystemExtensions(ctx, hostname)...)
+	// Loaded system and kernel extensions can only be listed on the live system
+	if !Offline() {
+		// System extensions
+		artifacts = append(artifacts, c.collectSystemExtensions(ctx, hostname)...)
 
-	// Kernel extensions
-	artifacts = append(artifacts, c.collectKernelExtensions(ctx, hostname)...)
+		// Kernel extensions
+		artifacts = append(artifacts, c.collectKernelExtensions(ctx, hostname)...)
+	}
 
 	// /Library/Extensions directory
 	artifacts = append(artifacts, c.collectLibraryExtensions(hostname)...)
@@ -143,7 +147,7 @@ func (c *ExtensionsCollector) collectKernelExtensions(ctx context.Context, hostn
 func (c *ExtensionsCollector) collectLibraryExtensions(hostname string) []models.Artifact {
 	var artifacts []models.Artifact
 
-	extDir := "/Library/Extensions"
+	extDir := resolvePath("/Library/Extensions")
 	entries, err := os.ReadDir(extDir)
 	if err != nil {
 		return artifacts
@@ -156,14 +160,14 @@ func (c *ExtensionsCollector) collectLibraryExtensions(hostname string) []models
 			continue
 		}
 
-		artifacts = append(artifacts, models.Artifact{
+		artifact := models.Artifact{
 			Timestamp:    time.Now(),
 			CollectorID:  c.ID(),
 			ArtifactType: "library_extension",
 			Hostname:     hostname,
 			Data: map[string]interface{}{
 				"name":     entry.Name(),
-				"path":     fullPath,
+				"path":     targetPath(fullPath),
 				"is_dir":   entry.IsDir(),
 				"size":     info.Size(),
 				"mod_time": info.ModTime().Format(time.RFC3339),
@@ -173,7 +177,14 @@ func (c *ExtensionsCollector) collectLibraryExtensions(hostname string) []models
 				SourcePath: fullPath,
 				CollectedAt: time.Now().Format(time.RFC3339),
 			},
-		})
+		}
+		addFileTimes(&artifact, "", info)
+		if filepath.Ext(entry.Name()) == ".kext" {
+			executable := bundleExecutable(fullPath, "")
+			artifact.Metadata.FileHash = addFileHashes(artifact.Data, "executable_", executable)
+			addCodeSignature(artifact.Data, "executable_", executable)
+		}
+		artifacts = append(artifacts, artifact)
 	}
 
 	return artifacts
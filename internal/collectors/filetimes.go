@@ -0,0 +1,18 @@
+package collectors
+
+import (
+	"os"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// addFileTimes records the MACB times of a file on the artifact, the
+// modification time first. The birth time is only recorded where the
+// platform's stat reports one (macOS, not Linux).
+func addFileTimes(a *models.Artifact, source string, info os.FileInfo) {
+	atime, ctime, btime := statTimes(info)
+	a.AddTimestamp(models.TimeModified, source, info.ModTime())
+	a.AddTimestamp(models.TimeAccessed, source, atime)
+	a.AddTimestamp(models.TimeChanged, source, ctime)
+	a.AddTimestamp(models.TimeCreated, source, btime)
+}
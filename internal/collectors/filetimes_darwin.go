@@ -0,0 +1,18 @@
+//go:build darwin
+
+package collectors
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// statTimes returns a file's access, inode change and birth times
+func statTimes(info os.FileInfo) (atime, ctime, btime time.Time) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	return time.Unix(st.Atimespec.Unix()), time.Unix(st.Ctimespec.Unix()), time.Unix(st.Birthtimespec.Unix())
+}
@@ -0,0 +1,19 @@
+//go:build linux
+
+package collectors
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// statTimes returns a file's access and inode change times; Linux stat has
+// no birth time
+func statTimes(info os.FileInfo) (atime, ctime, btime time.Time) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return
+	}
+	return time.Unix(st.Atim.Unix()), time.Unix(st.Ctim.Unix()), time.Time{}
+}
@@ -0,0 +1,13 @@
+//go:build !darwin && !linux
+
+package collectors
+
+import (
+	"os"
+	"time"
+)
+
+// statTimes is unavailable here; only the modification time is recorded
+func statTimes(info os.FileInfo) (atime, ctime, btime time.Time) {
+	return
+}
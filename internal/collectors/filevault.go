@@ -2,7 +2,6 @@ package collectors
 
 import (
 	"context"
-	"os"
 	"os/exec"
 	"strings"
 	"time"
@@ -16,9 +15,10 @@ func (c *FileVaultCollector) ID() string          { return "filevault" }
 func (c *FileVaultCollector) Name() string        { return "FileVault Status" }
 func (c *FileVaultCollector) Description() string { return "Collects FileVault disk encryption status" }
 func (c *FileVaultCollector) RequiresRoot() bool  { return false }
+func (c *FileVaultCollector) LiveOnly() bool      { return true }
 
 func (c *FileVaultCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 	var artifacts []models.Artifact
 
 	// fdesetup status
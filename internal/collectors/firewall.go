@@ -2,7 +2,6 @@ package collectors
 
 import (
 	"context"
-	"os"
 	"os/exec"
 	"strings"
 	"time"
@@ -16,9 +15,10 @@ func (c *FirewallCollector) ID() string          { return "firewall" }
 func (c *FirewallCollector) Name() string        { return "Firewall Status" }
 func (c *FirewallCollector) Description() string { return "Collects macOS Application Firewall configuration" }
 func (c *FirewallCollector) RequiresRoot() bool  { return false }
+func (c *FirewallCollector) LiveOnly() bool      { return true }
 
 func (c *FirewallCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 	var artifacts []models.Artifact
 
 	fwPath := "/usr/libexec/ApplicationFirewall/socketfilterfw"
@@ -3,7 +3,6 @@ package collectors
 import (
 	"bufio"
 	"context"
-	"os"
 	"os/exec"
 	"strings"
 	"time"
@@ -17,9 +16,10 @@ func (c *FSEventsCollector) ID() string          { return "fsevents" }
 func (c *FSEventsCollector) Name() string        { return "FSEvents File System Changes" }
 func (c *FSEventsCollector) Description() string { return "Collects recent file system events using fs_usage" }
 func (c *FSEventsCollector) RequiresRoot() bool  { return true }
+func (c *FSEventsCollector) LiveOnly() bool      { return true }
 
 func (c *FSEventsCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 	var artifacts []models.Artifact
 
 	// Run fs_usage for 5 seconds to capture recent filesystem activity.
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/plonxyz/triagectl/internal/models"
+	"github.com/plonxyz/triagectl/internal/plist"
 )
 
 type GatekeeperCollector struct{}
@@ -16,19 +17,23 @@ func (c *GatekeeperCollector) ID() string          { return "gatekeeper" }
 func (c *GatekeeperCollector) Name() string        { return "Gatekeeper/XProtect/SIP" }
 func (c *GatekeeperCollector) Description() string { return "Collects Gatekeeper, XProtect, and SIP security status" }
 func (c *GatekeeperCollector) RequiresRoot() bool  { return false }
+func (c *GatekeeperCollector) LiveOnly() bool      { return false }
 
 func (c *GatekeeperCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 	var artifacts []models.Artifact
 
-	// Gatekeeper status
-	if a := c.collectGatekeeper(ctx, hostname); a != nil {
-		artifacts = append(artifacts, *a)
-	}
+	// Gatekeeper and SIP status can only be queried on the live system
+	if !Offline() {
+		// Gatekeeper status
+		if a := c.collectGatekeeper(ctx, hostname); a != nil {
+			artifacts = append(artifacts, *a)
+		}
 
-	// SIP status
-	if a := c.collectSIP(ctx, hostname); a != nil {
-		artifacts = append(artifacts, *a)
+		// SIP status
+		if a := c.collectSIP(ctx, hostname); a != nil {
+			artifacts = append(artifacts, *a)
+		}
 	}
 
 	// XProtect version
@@ -96,18 +101,18 @@ func (c *GatekeeperCollector) collectXProtect(hostname string) *models.Artifact
 	}
 
 	for _, plistPath := range xprotectPaths {
+		plistPath = resolvePath(plistPath)
 		if _, err := os.Stat(plistPath); err != nil {
 			continue
 		}
 
-		out, err := exec.Command("plutil", "-convert", "xml1", "-o", "-", plistPath).CombinedOutput()
+		info, err := plist.DecodeDictFile(plistPath)
 		if err != nil {
 			continue
 		}
 
-		// Extract version from plist content
-		content := string(out)
-		version := extractPlistValue(content, "CFBundleShortVersionString")
+		version, _ := info["CFBundleShortVersionString"].(string)
+		bundleVersion, _ := info["CFBundleVersion"].(string)
 
 		return &models.Artifact{
 			Timestamp:    time.Now(),
@@ -115,9 +120,9 @@ func (c *GatekeeperCollector) collectXProtect(hostname string) *models.Artifact
 			ArtifactType: "xprotect_version",
 			Hostname:     hostname,
 			Data: map[string]interface{}{
-				"plist_path": plistPath,
-				"version":    version,
-				"raw_plist":  content,
+				"plist_path":     targetPath(plistPath),
+				"version":        version,
+				"bundle_version": bundleVersion,
 			},
 			Metadata: models.ArtifactMetadata{
 				Success:    true,
@@ -129,17 +134,3 @@ func (c *GatekeeperCollector) collectXProtect(hostname string) *models.Artifact
 
 	return nil
 }
-
-func extractPlistValue(content, key string) string {
-	idx := strings.Index(content, "<key>"+key+"</key>")
-	if idx < 0 {
-		return ""
-	}
-	rest := content[idx:]
-	strStart := strings.Index(rest, "<string>")
-	strEnd := strings.Index(rest, "</string>")
-	if strStart < 0 || strEnd < 0 {
-		return ""
-	}
-	return rest[strStart+8 : strEnd]
-}
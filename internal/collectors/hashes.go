@@ -0,0 +1,48 @@
+package collectors
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/plonxyz/triagectl/internal/hashing"
+	"github.com/plonxyz/triagectl/internal/plist"
+)
+
+// hasher hashes the files artifacts refer to, shared by all collectors so a
+// binary referenced from several places is read once
+var hasher = hashing.New(hashing.DefaultMaxSize, hashing.DefaultWorkers)
+
+// SetHashing configures file hashing: files over maxSize bytes are not
+// hashed (0 disables hashing) and at most workers files are read at once
+func SetHashing(maxSize int64, workers int) {
+	hasher = hashing.New(maxSize, workers)
+}
+
+// addFileHashes hashes the file at hostPath into data as <prefix>md5,
+// <prefix>sha1 and <prefix>sha256 and returns the SHA-256, or "" when the
+// file can't be hashed (missing, unreadable, not regular or too large)
+func addFileHashes(data map[string]interface{}, prefix, hostPath string) string {
+	h, err := hasher.Hash(hostPath)
+	if err != nil {
+		return ""
+	}
+	data[prefix+"md5"] = h.MD5
+	data[prefix+"sha1"] = h.SHA1
+	data[prefix+"sha256"] = h.SHA256
+	return h.SHA256
+}
+
+// bundleExecutable returns the main executable of an .app or .kext bundle
+// on disk: Contents/MacOS/ plus CFBundleExecutable, or the bundle name when
+// Info.plist doesn't say
+func bundleExecutable(bundlePath, executable string) string {
+	if executable == "" {
+		if info, err := plist.DecodeDictFile(filepath.Join(bundlePath, "Contents", "Info.plist")); err == nil {
+			executable, _ = info["CFBundleExecutable"].(string)
+		}
+	}
+	if executable == "" {
+		executable = strings.TrimSuffix(filepath.Base(bundlePath), filepath.Ext(bundlePath))
+	}
+	return filepath.Join(bundlePath, "Contents", "MacOS", executable)
+}
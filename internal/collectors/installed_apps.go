@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/plonxyz/triagectl/internal/models"
+	"github.com/plonxyz/triagectl/internal/plist"
 )
 
 type InstalledAppsCollector struct{}
@@ -15,20 +16,20 @@ func (c *InstalledAppsCollector) ID() string          { return "installed_apps"
 func (c *InstalledAppsCollector) Name() string        { return "Installed Applications" }
 func (c *InstalledAppsCollector) Description() string { return "Collects installed applications" }
 func (c *InstalledAppsCollector) RequiresRoot() bool  { return false }
+func (c *InstalledAppsCollector) LiveOnly() bool      { return false }
 
 func (c *InstalledAppsCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 
 	var artifacts []models.Artifact
 
 	// System applications
-	systemApps := c.collectApps("/Applications", "system_application", hostname)
+	systemApps := c.collectApps(resolvePath("/Applications"), "system_application", hostname)
 	artifacts = append(artifacts, systemApps...)
 
 	// User applications
-	homeDir, err := os.UserHomeDir()
-	if err == nil {
-		userApps := c.collectApps(filepath.Join(homeDir, "Applications"), "user_application", hostname)
+	for _, home := range userHomes() {
+		userApps := c.collectApps(filepath.Join(home.path, "Applications"), "user_application", hostname)
 		artifacts = append(artifacts, userApps...)
 	}
 
@@ -61,7 +62,7 @@ func (c *InstalledAppsCollector) collectApps(appDir, appType, hostname string) [
 			Hostname:     hostname,
 			Data: map[string]interface{}{
 				"name":     entry.Name(),
-				"path":     fullPath,
+				"path":     targetPath(fullPath),
 				"mod_time": info.ModTime().Format(time.RFC3339),
 			},
 			Metadata: models.ArtifactMetadata{
@@ -71,9 +72,34 @@ func (c *InstalledAppsCollector) collectApps(appDir, appType, hostname string) [
 				CollectedAt:  time.Now().Format(time.RFC3339),
 			},
 		}
+		addFileTimes(&artifact, "", info)
+
+		c.readInfoPlist(fullPath, artifact.Data)
+		executable, _ := artifact.Data["executable"].(string)
+		mainExecutable := bundleExecutable(fullPath, executable)
+		artifact.Metadata.FileHash = addFileHashes(artifact.Data, "executable_", mainExecutable)
+		addCodeSignature(artifact.Data, "executable_", mainExecutable)
 
 		artifacts = append(artifacts, artifact)
 	}
 
 	return artifacts
 }
+
+// readInfoPlist adds the bundle identity from Contents/Info.plist
+func (c *InstalledAppsCollector) readInfoPlist(appPath string, data map[string]interface{}) {
+	info, err := plist.DecodeDictFile(filepath.Join(appPath, "Contents", "Info.plist"))
+	if err != nil {
+		return
+	}
+	for key, field := range map[string]string{
+		"CFBundleIdentifier":         "bundle_id",
+		"CFBundleShortVersionString": "version",
+		"CFBundleVersion":            "bundle_version",
+		"CFBundleExecutable":         "executable",
+	} {
+		if v, ok := info[key].(string); ok && v != "" {
+			data[field] = v
+		}
+	}
+}
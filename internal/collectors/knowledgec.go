@@ -17,21 +17,28 @@ func (c *KnowledgeCCollector) ID() string          { return "knowledgec" }
 func (c *KnowledgeCCollector) Name() string        { return "KnowledgeC Database" }
 func (c *KnowledgeCCollector) Description() string { return "Collects app usage and screen time data" }
 func (c *KnowledgeCCollector) RequiresRoot() bool  { return false }
+func (c *KnowledgeCCollector) LiveOnly() bool      { return false }
 
 func (c *KnowledgeCCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
+	hostname := TargetHostname()
+
+	var artifacts []models.Artifact
+
+	for _, home := range userHomes() {
+		artifacts = append(artifacts, c.collectKnowledgeDB(home, hostname)...)
 	}
 
+	return artifacts, nil
+}
+
+func (c *KnowledgeCCollector) collectKnowledgeDB(home userHome, hostname string) []models.Artifact {
 	var artifacts []models.Artifact
 
 	// KnowledgeC database location
-	knowledgeDB := filepath.Join(homeDir, "Library/Application Support/Knowledge/knowledgeC.db")
+	knowledgeDB := filepath.Join(home.path, "Library/Application Support/Knowledge/knowledgeC.db")
 
 	if _, err := os.Stat(knowledgeDB); os.IsNotExist(err) {
-		return artifacts, nil
+		return artifacts
 	}
 
 	// Copy database to avoid lock issues
@@ -40,16 +47,16 @@ func (c *KnowledgeCCollector) Collect(ctx context.Context) ([]models.Artifact, e
 
 	input, err := os.ReadFile(knowledgeDB)
 	if err != nil {
-		return artifacts, nil
+		return artifacts
 	}
 
 	if err := os.WriteFile(tmpDB, input, 0600); err != nil {
-		return artifacts, nil
+		return artifacts
 	}
 
 	db, err := sql.Open("sqlite3", tmpDB)
 	if err != nil {
-		return artifacts, nil
+		return artifacts
 	}
 	defer db.Close()
 
@@ -69,7 +76,7 @@ func (c *KnowledgeCCollector) Collect(ctx context.Context) ([]models.Artifact, e
 
 	rows, err := db.Query(query)
 	if err != nil {
-		return artifacts, nil
+		return artifacts
 	}
 	defer rows.Close()
 
@@ -88,13 +95,17 @@ func (c *KnowledgeCCollector) Collect(ctx context.Context) ([]models.Artifact, e
 
 		data := map[string]interface{}{
 			"app_name": appName.String,
+			"user":     home.user,
 		}
 
+		var start, end time.Time
 		if sd, ok := toSeconds(startDate); ok {
-			data["start_time"] = macEpoch.Add(time.Duration(sd) * time.Second).Format(time.RFC3339)
+			start = macEpoch.Add(time.Duration(sd) * time.Second)
+			data["start_time"] = start.Format(time.RFC3339)
 		}
 		if ed, ok := toSeconds(endDate); ok {
-			data["end_time"] = macEpoch.Add(time.Duration(ed) * time.Second).Format(time.RFC3339)
+			end = macEpoch.Add(time.Duration(ed) * time.Second)
+			data["end_time"] = end.Format(time.RFC3339)
 		}
 		if dur, ok := toSeconds(duration); ok && dur > 0 {
 			data["duration_seconds"] = dur
@@ -113,11 +124,13 @@ func (c *KnowledgeCCollector) Collect(ctx context.Context) ([]models.Artifact, e
 				CollectedAt:  time.Now().Format(time.RFC3339),
 			},
 		}
+		artifact.AddTimestamp(models.TimeStarted, "", start)
+		artifact.AddTimestamp(models.TimeEnded, "", end)
 
 		artifacts = append(artifacts, artifact)
 	}
 
-	return artifacts, nil
+	return artifacts
 }
 
 func toSeconds(v interface{}) (int64, bool) {
@@ -4,9 +4,11 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/plonxyz/triagectl/internal/models"
+	"github.com/plonxyz/triagectl/internal/plist"
 )
 
 type LaunchAgentsCollector struct{}
@@ -15,30 +17,32 @@ func (c *LaunchAgentsCollector) ID() string          { return "launch_agents" }
 func (c *LaunchAgentsCollector) Name() string        { return "Launch Agents/Daemons" }
 func (c *LaunchAgentsCollector) Description() string { return "Collects persistence mechanisms via Launch Agents and Daemons" }
 func (c *LaunchAgentsCollector) RequiresRoot() bool  { return false }
+func (c *LaunchAgentsCollector) LiveOnly() bool      { return false }
 
 func (c *LaunchAgentsCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 
-	// Standard locations for launch agents/daemons
-	locations := []struct {
-		path        string
-		itemType    string
+	type location struct {
+		path         string
+		itemType     string
 		requiresRoot bool
-	}{
-		{"/Library/LaunchAgents", "system_launch_agent", false},
-		{"/Library/LaunchDaemons", "system_launch_daemon", true},
-		{"/System/Library/LaunchAgents", "system_launch_agent", false},
-		{"/System/Library/LaunchDaemons", "system_launch_daemon", false},
+		user         string
+	}
+
+	// Standard locations for launch agents/daemons
+	locations := []location{
+		{"/Library/LaunchAgents", "system_launch_agent", false, ""},
+		{"/Library/LaunchDaemons", "system_launch_daemon", true, ""},
+		{"/System/Library/LaunchAgents", "system_launch_agent", false, ""},
+		{"/System/Library/LaunchDaemons", "system_launch_daemon", false, ""},
+	}
+	for i := range locations {
+		locations[i].path = resolvePath(locations[i].path)
 	}
 
 	// Add user-specific locations
-	homeDir, err := os.UserHomeDir()
-	if err == nil {
-		locations = append(locations, struct {
-			path        string
-			itemType    string
-			requiresRoot bool
-		}{filepath.Join(homeDir, "Library/LaunchAgents"), "user_launch_agent", false})
+	for _, home := range userHomes() {
+		locations = append(locations, location{filepath.Join(home.path, "Library/LaunchAgents"), "user_launch_agent", false, home.user})
 	}
 
 	var artifacts []models.Artifact
@@ -67,10 +71,10 @@ func (c *LaunchAgentsCollector) Collect(ctx context.Context) ([]models.Artifact,
 				Hostname:     hostname,
 				Data: map[string]interface{}{
 					"name":      item.Name(),
-					"path":      fullPath,
+					"path":      targetPath(fullPath),
 					"size":      info.Size(),
 					"mod_time":  info.ModTime().Format(time.RFC3339),
-					"directory": loc.path,
+					"directory": targetPath(loc.path),
 				},
 				Metadata: models.ArtifactMetadata{
 					Success:      true,
@@ -80,9 +84,151 @@ func (c *LaunchAgentsCollector) Collect(ctx context.Context) ([]models.Artifact,
 				},
 			}
 
+			if loc.user != "" {
+				artifact.Data["user"] = loc.user
+			}
+			artifact.Metadata.FileHash = addFileHashes(artifact.Data, "plist_", fullPath)
+			addFileTimes(&artifact, "plist", info)
+
+			if !strings.HasSuffix(item.Name(), ".plist") {
+				artifacts = append(artifacts, artifact)
+				continue
+			}
+
+			if target := c.parseLaunchdPlist(fullPath, artifact.Data); target != nil {
+				addFileTimes(&artifact, "target", target)
+			}
+
 			artifacts = append(artifacts, artifact)
 		}
 	}
 
 	return artifacts, nil
 }
+
+// launchdInterpreters run a script given as their first non-flag argument
+var launchdInterpreters = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "csh": true, "tcsh": true,
+	"python": true, "python2": true, "python3": true, "perl": true, "ruby": true,
+	"osascript": true, "node": true, "php": true,
+}
+
+// parseLaunchdPlist decodes a launchd job definition into data, including
+// the resolved target binary and its on-disk attributes. It returns the
+// target's file info when the target exists.
+func (c *LaunchAgentsCollector) parseLaunchdPlist(plistPath string, data map[string]interface{}) os.FileInfo {
+	job, err := plist.DecodeDictFile(plistPath)
+	if err != nil {
+		data["parse_error"] = err.Error()
+		return nil
+	}
+
+	if label, ok := job["Label"].(string); ok {
+		data["label"] = label
+	}
+	program, _ := job["Program"].(string)
+	if program != "" {
+		data["program"] = program
+	}
+	args := plistStrings(job["ProgramArguments"])
+	if len(args) > 0 {
+		data["program_arguments"] = args
+	}
+	if v, ok := job["RunAtLoad"].(bool); ok {
+		data["run_at_load"] = v
+	}
+	if v, ok := job["KeepAlive"]; ok {
+		data["keep_alive"] = v
+	}
+	if v, ok := job["StartInterval"]; ok {
+		data["start_interval"] = v
+	}
+	if v, ok := job["StartCalendarInterval"]; ok {
+		data["start_calendar_interval"] = v
+	}
+	if paths := plistStrings(job["WatchPaths"]); len(paths) > 0 {
+		data["watch_paths"] = paths
+	}
+	if v, ok := job["UserName"].(string); ok {
+		data["user_name"] = v
+	}
+	if env, ok := job["EnvironmentVariables"].(map[string]interface{}); ok {
+		data["environment_variables"] = env
+	}
+	if v, ok := job["Disabled"].(bool); ok {
+		data["disabled"] = v
+	}
+
+	target := launchdTarget(program, args)
+	if target == "" {
+		return nil
+	}
+	data["target_path"] = target
+
+	// Relative targets are looked up on PATH by launchd and can't be resolved here
+	if !filepath.IsAbs(target) {
+		return nil
+	}
+
+	hostPath := resolvePath(target)
+	info, err := os.Stat(hostPath)
+	if err != nil {
+		data["target_exists"] = false
+		return nil
+	}
+	data["target_exists"] = true
+	data["target_size"] = info.Size()
+	data["target_mod_time"] = info.ModTime().Format(time.RFC3339)
+	addFileHashes(data, "target_", hostPath)
+	addCodeSignature(data, "target_", hostPath)
+	return info
+}
+
+// launchdTarget picks the file a job executes: Program, else the first
+// argument, else the script handed to an interpreter
+func launchdTarget(program string, args []string) string {
+	target := program
+	if target == "" && len(args) > 0 {
+		target = args[0]
+	}
+	if !launchdInterpreters[filepath.Base(target)] {
+		return target
+	}
+
+	// Skip the interpreter itself when ProgramArguments[0] is it
+	rest := args
+	if len(rest) > 0 && rest[0] == target {
+		rest = rest[1:]
+	}
+	for _, arg := range rest {
+		if arg == "-c" || arg == "-e" {
+			// Inline script: the interpreter is the only file involved
+			return target
+		}
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		if filepath.IsAbs(arg) {
+			return arg
+		}
+		return target
+	}
+	return target
+}
+
+func plistStrings(v interface{}) []string {
+	arr, ok := v.([]interface{})
+	if !ok {
+		if s, ok := v.(string); ok {
+			return []string{s}
+		}
+		return nil
+	}
+	out := make([]string, 0, len(arr))
+	for _, item := range arr {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -2,36 +2,175 @@ package collectors
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/plonxyz/triagectl/internal/btm"
 	"github.com/plonxyz/triagectl/internal/models"
 )
 
 type LoginItemsCollector struct{}
 
-func (c *LoginItemsCollector) ID() string          { return "login_items" }
-func (c *LoginItemsCollector) Name() string        { return "Login Items" }
-func (c *LoginItemsCollector) Description() string { return "Collects login items and background task management entries" }
-func (c *LoginItemsCollector) RequiresRoot() bool  { return false }
+func (c *LoginItemsCollector) ID() string   { return "login_items" }
+func (c *LoginItemsCollector) Name() string { return "Login Items" }
+func (c *LoginItemsCollector) Description() string {
+	return "Collects login items and background task management entries"
+}
+func (c *LoginItemsCollector) RequiresRoot() bool { return false }
+func (c *LoginItemsCollector) LiveOnly() bool     { return false }
 
 func (c *LoginItemsCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 	var artifacts []models.Artifact
 
-	// Try sfltool dumpbtm (macOS 13+)
-	artifacts = append(artifacts, c.collectBTM(ctx, hostname)...)
+	// Decode the macOS 13+ BTM store directly; fall back to sfltool dumpbtm
+	// on a live system where the store isn't readable (it is root-owned)
+	btmItems, ok := c.collectBTMStore(hostname)
+	artifacts = append(artifacts, btmItems...)
+	if !ok && !Offline() {
+		artifacts = append(artifacts, c.collectSFLTool(ctx, hostname)...)
+	}
 
-	// Try backgrounditems.btm via plutil
+	// Decode backgrounditems.btm (macOS 10.13-12)
 	artifacts = append(artifacts, c.collectBackgroundItems(hostname)...)
 
 	return artifacts, nil
 }
 
-func (c *LoginItemsCollector) collectBTM(ctx context.Context, hostname string) []models.Artifact {
+// btmStoreDir holds BackgroundItems-v<N>.btm; older versions may linger after upgrades
+const btmStoreDir = "/private/var/db/com.apple.backgroundtaskmanagement"
+
+// collectBTMStore decodes the newest BackgroundItems-v*.btm, reporting
+// whether one could be read
+func (c *LoginItemsCollector) collectBTMStore(hostname string) ([]models.Artifact, bool) {
+	var artifacts []models.Artifact
+
+	matches, _ := filepath.Glob(filepath.Join(resolvePath(btmStoreDir), "BackgroundItems-v*.btm"))
+	storePath := ""
+	bestVersion := -1
+	for _, m := range matches {
+		v, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), "BackgroundItems-v"), ".btm"))
+		if err == nil && v > bestVersion {
+			bestVersion, storePath = v, m
+		}
+	}
+	if storePath == "" {
+		return artifacts, false
+	}
+
+	items, err := btm.ParseFile(storePath)
+	if err != nil {
+		return artifacts, false
+	}
+
+	byIdentifier := make(map[string]*btm.Item, len(items))
+	for i := range items {
+		if items[i].Identifier != "" {
+			byIdentifier[items[i].Identifier] = &items[i]
+		}
+	}
+
+	for i := range items {
+		item := &items[i]
+		data := map[string]interface{}{
+			"name":           item.Name,
+			"uuid":           item.UUID,
+			"identifier":     item.Identifier,
+			"developer_name": item.DeveloperName,
+			"team_id":        item.TeamID,
+			"bundle_id":      item.BundleID,
+			"type":           fmt.Sprintf("0x%x", item.Type),
+			"type_flags":     item.TypeNames(),
+			"disposition":    fmt.Sprintf("0x%x", item.Disposition),
+			"enabled":        item.Enabled(),
+			"allowed":        item.Allowed(),
+			"notified":       item.Notified(),
+			"hidden":         item.Hidden(),
+			"path":           item.Path,
+			"url":            item.URL,
+			"user_uuid":      item.UserID,
+			"btm_path":       targetPath(storePath),
+		}
+		if app := appBundlePath(item.Path); app != "" {
+			data["app_path"] = app
+		}
+		if item.ExecutablePath != "" {
+			data["executable_path"] = item.ExecutablePath
+		}
+		if len(item.AssociatedBundleIDs) > 0 {
+			data["associated_bundle_ids"] = item.AssociatedBundleIDs
+		}
+		if len(item.EmbeddedIdentifiers) > 0 {
+			data["embedded_identifiers"] = item.EmbeddedIdentifiers
+		}
+		if item.ParentIdentifier != "" {
+			data["parent_identifier"] = item.ParentIdentifier
+			if parent, ok := byIdentifier[item.ParentIdentifier]; ok {
+				data["parent_name"] = parent.Name
+				data["parent_path"] = parent.Path
+				if _, ok := data["app_path"]; !ok {
+					if app := appBundlePath(parent.Path); app != "" {
+						data["app_path"] = app
+					}
+				}
+			}
+		}
+		if plistPath := item.LaunchdPlist(); plistPath != "" {
+			c.describeLaunchdItem(plistPath, data)
+		}
+
+		artifacts = append(artifacts, models.Artifact{
+			Timestamp:    time.Now(),
+			CollectorID:  c.ID(),
+			ArtifactType: "login_item_btm",
+			Hostname:     hostname,
+			Data:         data,
+			Metadata: models.ArtifactMetadata{
+				Success:      true,
+				RequiresRoot: true,
+				SourcePath:   storePath,
+				CollectedAt:  time.Now().Format(time.RFC3339),
+			},
+		})
+	}
+
+	return artifacts, true
+}
+
+// describeLaunchdItem records the launchd job an agent/daemon item points at
+func (c *LoginItemsCollector) describeLaunchdItem(plistPath string, data map[string]interface{}) {
+	data["launchd_plist"] = plistPath
+
+	job := make(map[string]interface{})
+	(&LaunchAgentsCollector{}).parseLaunchdPlist(resolvePath(plistPath), job)
+	if label, ok := job["label"]; ok {
+		data["launchd_label"] = label
+	}
+	if target, ok := job["target_path"]; ok {
+		data["launchd_target"] = target
+	}
+	if exists, ok := job["target_exists"]; ok {
+		data["launchd_target_exists"] = exists
+	}
+}
+
+// appBundlePath returns the enclosing .app bundle of p, if any
+func appBundlePath(p string) string {
+	if i := strings.Index(p, ".app/"); i >= 0 {
+		return p[:i+4]
+	}
+	if strings.HasSuffix(p, ".app") {
+		return p
+	}
+	return ""
+}
+
+func (c *LoginItemsCollector) collectSFLTool(ctx context.Context, hostname string) []models.Artifact {
 	var artifacts []models.Artifact
 
 	out, err := exec.CommandContext(ctx, "sfltool", "dumpbtm").CombinedOutput()
@@ -97,40 +236,52 @@ func (c *LoginItemsCollector) collectBTM(ctx context.Context, hostname string) [
 func (c *LoginItemsCollector) collectBackgroundItems(hostname string) []models.Artifact {
 	var artifacts []models.Artifact
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return artifacts
-	}
-
-	btmPaths := []string{
-		filepath.Join(homeDir, "Library/Application Support/com.apple.backgroundtaskmanagementagent/backgrounditems.btm"),
-	}
-
-	for _, btmPath := range btmPaths {
+	for _, home := range userHomes() {
+		btmPath := filepath.Join(home.path, "Library/Application Support/com.apple.backgroundtaskmanagementagent/backgrounditems.btm")
 		if _, err := os.Stat(btmPath); err != nil {
 			continue
 		}
 
-		out, err := exec.Command("plutil", "-convert", "xml1", "-o", "-", btmPath).CombinedOutput()
+		items, err := btm.ParseFile(btmPath)
 		if err != nil {
 			continue
 		}
 
-		artifacts = append(artifacts, models.Artifact{
-			Timestamp:    time.Now(),
-			CollectorID:  c.ID(),
-			ArtifactType: "login_item_backgrounditems",
-			Hostname:     hostname,
-			Data: map[string]interface{}{
-				"path":    btmPath,
-				"content": string(out),
-			},
-			Metadata: models.ArtifactMetadata{
-				Success:    true,
-				SourcePath: btmPath,
-				CollectedAt: time.Now().Format(time.RFC3339),
-			},
-		})
+		for _, item := range items {
+			data := map[string]interface{}{
+				"name":     item.Name,
+				"path":     item.Path,
+				"btm_path": targetPath(btmPath),
+				"user":     home.user,
+			}
+			if app := appBundlePath(item.Path); app != "" {
+				data["app_path"] = app
+			}
+			if bm := item.Bookmark; bm != nil {
+				if bm.VolumePath != "" {
+					data["volume_path"] = bm.VolumePath
+				}
+				if bm.VolumeName != "" {
+					data["volume_name"] = bm.VolumeName
+				}
+				if !bm.Created.IsZero() {
+					data["target_created"] = bm.Created.Format(time.RFC3339)
+				}
+			}
+
+			artifacts = append(artifacts, models.Artifact{
+				Timestamp:    time.Now(),
+				CollectorID:  c.ID(),
+				ArtifactType: "login_item_backgrounditems",
+				Hostname:     hostname,
+				Data:         data,
+				Metadata: models.ArtifactMetadata{
+					Success:     true,
+					SourcePath:  btmPath,
+					CollectedAt: time.Now().Format(time.RFC3339),
+				},
+			})
+		}
 	}
 
 	return artifacts
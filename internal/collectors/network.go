@@ -2,7 +2,6 @@ package collectors
 
 import (
 	"context"
-	"os"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/net"
@@ -15,9 +14,10 @@ func (c *NetworkConnectionsCollector) ID() string          { return "network_con
 func (c *NetworkConnectionsCollector) Name() string        { return "Network Connections" }
 func (c *NetworkConnectionsCollector) Description() string { return "Collects active network connections" }
 func (c *NetworkConnectionsCollector) RequiresRoot() bool  { return false }
+func (c *NetworkConnectionsCollector) LiveOnly() bool      { return true }
 
 func (c *NetworkConnectionsCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 
 	connections, err := net.Connections("all")
 	if err != nil {
@@ -17,9 +17,10 @@ func (c *NetworkInterfacesCollector) ID() string          { return "network_inte
 func (c *NetworkInterfacesCollector) Name() string        { return "Network Interfaces" }
 func (c *NetworkInterfacesCollector) Description() string { return "Collects network interface details" }
 func (c *NetworkInterfacesCollector) RequiresRoot() bool  { return false }
+func (c *NetworkInterfacesCollector) LiveOnly() bool      { return true }
 
 func (c *NetworkInterfacesCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 	var artifacts []models.Artifact
 
 	interfaces, err := net.Interfaces()
@@ -2,7 +2,6 @@ package collectors
 
 import (
 	"context"
-	"os"
 	"os/exec"
 	"strings"
 	"time"
@@ -16,9 +15,10 @@ func (c *OpenFilesCollector) ID() string          { return "open_files" }
 func (c *OpenFilesCollector) Name() string        { return "Open Files (Network)" }
 func (c *OpenFilesCollector) Description() string { return "Collects open network files and connections via lsof" }
 func (c *OpenFilesCollector) RequiresRoot() bool  { return false }
+func (c *OpenFilesCollector) LiveOnly() bool      { return true }
 
 func (c *OpenFilesCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 	var artifacts []models.Artifact
 
 	out, err := exec.CommandContext(ctx, "lsof", "-i", "-n", "-P").CombinedOutput()
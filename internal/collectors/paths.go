@@ -0,0 +1,145 @@
+package collectors
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/plonxyz/triagectl/internal/plist"
+	"github.com/plonxyz/triagectl/internal/target"
+)
+
+// rootDir is the filesystem root file-based collectors read from.
+// Empty means the live system.
+var rootDir string
+
+// hostnameOverride replaces the collecting host's name on every artifact
+var hostnameOverride string
+
+// SetRoot points file-based collectors at a mounted disk image or extracted
+// filesystem. An empty root restores live collection.
+func SetRoot(root string) {
+	if root != "" {
+		root = filepath.Clean(root)
+	}
+	rootDir = root
+}
+
+// Root returns the configured offline root ("" when collecting live)
+func Root() string {
+	return rootDir
+}
+
+// SetHostname overrides the hostname recorded on artifacts (used for offline collection)
+func SetHostname(name string) {
+	hostnameOverride = name
+}
+
+// Offline reports whether collection targets an offline root instead of the live system
+func Offline() bool {
+	return rootDir != ""
+}
+
+// IsLiveOnly reports whether a collector cannot run against an offline root
+func IsLiveOnly(c Collector) bool {
+	return Offline() && c.LiveOnly()
+}
+
+// TargetHostname returns the hostname to record on artifacts
+func TargetHostname() string {
+	if hostnameOverride != "" {
+		return hostnameOverride
+	}
+	if rootDir != "" {
+		if name := offlineHostname(); name != "" {
+			return name
+		}
+		return filepath.Base(rootDir)
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// offlineHostname reads the target's configured name from SystemConfiguration
+func offlineHostname() string {
+	prefs, err := plist.DecodeDictFile(resolvePath("/Library/Preferences/SystemConfiguration/preferences.plist"))
+	if err != nil {
+		return ""
+	}
+	system, _ := prefs["System"].(map[string]interface{})
+	if sys, ok := system["System"].(map[string]interface{}); ok {
+		for _, key := range []string{"HostName", "ComputerName"} {
+			if name, ok := sys[key].(string); ok && name != "" {
+				return name
+			}
+		}
+	}
+	if network, ok := system["Network"].(map[string]interface{}); ok {
+		if names, ok := network["HostNames"].(map[string]interface{}); ok {
+			if name, ok := names["LocalHostName"].(string); ok && name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// resolvePath maps an absolute path on the target system to the path to read
+// on this host
+func resolvePath(p string) string {
+	return target.ResolvePath(rootDir, p)
+}
+
+// targetPath maps a host path back to the path as seen on the target system
+func targetPath(p string) string {
+	if rootDir == "" {
+		return p
+	}
+	rel, err := filepath.Rel(rootDir, p)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return p
+	}
+	return "/" + filepath.ToSlash(rel)
+}
+
+// userHome is a home directory to collect per-user artifacts from
+type userHome struct {
+	user string
+	path string // host path (already resolved under the root)
+}
+
+// userHomes returns the invoking user's home on a live system, or every home
+// under <root>/Users (plus root's home) when collecting offline.
+func userHomes() []userHome {
+	if rootDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		user := os.Getenv("USER")
+		if user == "" {
+			user = filepath.Base(homeDir)
+		}
+		return []userHome{{user: user, path: homeDir}}
+	}
+
+	var homes []userHome
+
+	usersDir := resolvePath("/Users")
+	entries, err := os.ReadDir(usersDir)
+	if err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") || entry.Name() == "Shared" {
+				continue
+			}
+			homes = append(homes, userHome{user: entry.Name(), path: filepath.Join(usersDir, entry.Name())})
+		}
+	}
+
+	rootHome := resolvePath("/var/root")
+	if info, err := os.Stat(rootHome); err == nil && info.IsDir() {
+		homes = append(homes, userHome{user: "root", path: rootHome})
+	}
+
+	return homes
+}
@@ -2,7 +2,6 @@ package collectors
 
 import (
 	"context"
-	"os"
 	"time"
 
 	"github.com/shirou/gopsutil/v3/process"
@@ -15,9 +14,10 @@ func (c *RunningProcessesCollector) ID() string          { return "running_proce
 func (c *RunningProcessesCollector) Name() string        { return "Running Processes" }
 func (c *RunningProcessesCollector) Description() string { return "Collects all running processes" }
 func (c *RunningProcessesCollector) RequiresRoot() bool  { return false }
+func (c *RunningProcessesCollector) LiveOnly() bool      { return true }
 
 func (c *RunningProcessesCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 
 	procs, err := process.Processes()
 	if err != nil {
@@ -69,6 +69,14 @@ func (c *RunningProcessesCollector) Collect(ctx context.Context) ([]models.Artif
 				CollectedAt:  time.Now().Format(time.RFC3339),
 			},
 		}
+		if createTime > 0 {
+			artifact.AddTimestamp(models.TimeStarted, "", time.UnixMilli(createTime))
+		}
+
+		if exe != "" {
+			artifact.Metadata.FileHash = addFileHashes(artifact.Data, "exe_", exe)
+			addCodeSignature(artifact.Data, "exe_", exe)
+		}
 
 		artifacts = append(artifacts, artifact)
 	}
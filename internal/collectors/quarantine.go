@@ -3,6 +3,7 @@ package collectors
 import (
 	"context"
 	"database/sql"
+	"net/url"
 	"os"
 	"path/filepath"
 	"time"
@@ -17,21 +18,28 @@ func (c *QuarantineEventsCollector) ID() string          { return "quarantine_ev
 func (c *QuarantineEventsCollector) Name() string        { return "Quarantine Events" }
 func (c *QuarantineEventsCollector) Description() string { return "Collects macOS quarantine events (downloaded files)" }
 func (c *QuarantineEventsCollector) RequiresRoot() bool  { return false }
+func (c *QuarantineEventsCollector) LiveOnly() bool      { return false }
 
 func (c *QuarantineEventsCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
+	hostname := TargetHostname()
+
+	var artifacts []models.Artifact
+
+	for _, home := range userHomes() {
+		artifacts = append(artifacts, c.collectQuarantineDB(home, hostname)...)
 	}
 
+	return artifacts, nil
+}
+
+func (c *QuarantineEventsCollector) collectQuarantineDB(home userHome, hostname string) []models.Artifact {
 	var artifacts []models.Artifact
 
 	// Quarantine events database
-	quarantineDB := filepath.Join(homeDir, "Library/Preferences/com.apple.LaunchServices.QuarantineEventsV2")
+	quarantineDB := filepath.Join(home.path, "Library/Preferences/com.apple.LaunchServices.QuarantineEventsV2")
 
 	if _, err := os.Stat(quarantineDB); os.IsNotExist(err) {
-		return artifacts, nil
+		return artifacts
 	}
 
 	// Copy database to avoid lock issues
@@ -40,16 +48,16 @@ func (c *QuarantineEventsCollector) Collect(ctx context.Context) ([]models.Artif
 
 	input, err := os.ReadFile(quarantineDB)
 	if err != nil {
-		return artifacts, nil
+		return artifacts
 	}
 
 	if err := os.WriteFile(tmpDB, input, 0600); err != nil {
-		return artifacts, nil
+		return artifacts
 	}
 
 	db, err := sql.Open("sqlite3", tmpDB)
 	if err != nil {
-		return artifacts, nil
+		return artifacts
 	}
 	defer db.Close()
 
@@ -65,7 +73,7 @@ func (c *QuarantineEventsCollector) Collect(ctx context.Context) ([]models.Artif
 		ORDER BY LSQuarantineTimeStamp DESC
 	`)
 	if err != nil {
-		return artifacts, nil
+		return artifacts
 	}
 	defer rows.Close()
 
@@ -93,6 +101,7 @@ func (c *QuarantineEventsCollector) Collect(ctx context.Context) ([]models.Artif
 				"agent_bundle":  agentBundle,
 				"data_url":      dataURL,
 				"origin_url":    originURL,
+				"user":          home.user,
 			},
 			Metadata: models.ArtifactMetadata{
 				Success:      true,
@@ -101,9 +110,15 @@ func (c *QuarantineEventsCollector) Collect(ctx context.Context) ([]models.Artif
 				CollectedAt:  time.Now().Format(time.RFC3339),
 			},
 		}
+		artifact.AddTimestamp(models.TimeDownloaded, "", eventTime)
+
+		// Hash the downloaded file if it is still on disk
+		if u, err := url.Parse(dataURL); err == nil && u.Scheme == "file" && u.Path != "" {
+			artifact.Metadata.FileHash = addFileHashes(artifact.Data, "file_", resolvePath(u.Path))
+		}
 
 		artifacts = append(artifacts, artifact)
 	}
 
-	return artifacts, nil
+	return artifacts
 }
@@ -16,31 +16,30 @@ func (c *RecentFilesCollector) ID() string          { return "recent_files" }
 func (c *RecentFilesCollector) Name() string        { return "Recent Files" }
 func (c *RecentFilesCollector) Description() string { return "Collects recently accessed files from user directories" }
 func (c *RecentFilesCollector) RequiresRoot() bool  { return false }
+func (c *RecentFilesCollector) LiveOnly() bool      { return false }
 
 func (c *RecentFilesCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
-	}
+	hostname := TargetHostname()
 
 	var artifacts []models.Artifact
 
-	dirs := []struct {
-		path     string
-		fileType string
-	}{
-		{filepath.Join(homeDir, "Downloads"), "download"},
-		{filepath.Join(homeDir, "Desktop"), "desktop"},
-		{filepath.Join(homeDir, "Documents"), "document"},
-	}
+	for _, home := range userHomes() {
+		dirs := []struct {
+			path     string
+			fileType string
+		}{
+			{filepath.Join(home.path, "Downloads"), "download"},
+			{filepath.Join(home.path, "Desktop"), "desktop"},
+			{filepath.Join(home.path, "Documents"), "document"},
+		}
 
-	for _, dir := range dirs {
-		if ctx.Err() != nil {
-			break
+		for _, dir := range dirs {
+			if ctx.Err() != nil {
+				break
+			}
+			dirArtifacts := c.collectRecentFiles(ctx, dir.path, dir.fileType, home.user, hostname, 500)
+			artifacts = append(artifacts, dirArtifacts...)
 		}
-		dirArtifacts := c.collectRecentFiles(ctx, dir.path, dir.fileType, hostname, 500)
-		artifacts = append(artifacts, dirArtifacts...)
 	}
 
 	return artifacts, nil
@@ -51,7 +50,7 @@ type fileEntry struct {
 	info    os.FileInfo
 }
 
-func (c *RecentFilesCollector) collectRecentFiles(ctx context.Context, dirPath, fileType, hostname string, limit int) []models.Artifact {
+func (c *RecentFilesCollector) collectRecentFiles(ctx context.Context, dirPath, fileType, user, hostname string, limit int) []models.Artifact {
 	var artifacts []models.Artifact
 	var files []fileEntry
 
@@ -90,11 +89,12 @@ func (c *RecentFilesCollector) collectRecentFiles(ctx context.Context, dirPath,
 			ArtifactType: "recent_file",
 			Hostname:     hostname,
 			Data: map[string]interface{}{
-				"path":      f.path,
+				"path":      targetPath(f.path),
 				"name":      f.info.Name(),
 				"size":      f.info.Size(),
 				"mod_time":  f.info.ModTime().Format(time.RFC3339),
 				"file_type": fileType,
+				"user":      user,
 			},
 			Metadata: models.ArtifactMetadata{
 				Success:      true,
@@ -103,6 +103,7 @@ func (c *RecentFilesCollector) collectRecentFiles(ctx context.Context, dirPath,
 				CollectedAt:  time.Now().Format(time.RFC3339),
 			},
 		}
+		addFileTimes(&artifact, "", f.info)
 
 		artifacts = append(artifacts, artifact)
 		count++
@@ -17,45 +17,46 @@ func (c *ScheduledTasksCollector) ID() string          { return "scheduled_tasks
 func (c *ScheduledTasksCollector) Name() string        { return "Scheduled Tasks (Cron)" }
 func (c *ScheduledTasksCollector) Description() string { return "Collects cron jobs and scheduled tasks" }
 func (c *ScheduledTasksCollector) RequiresRoot() bool  { return false }
+func (c *ScheduledTasksCollector) LiveOnly() bool      { return false }
 
 func (c *ScheduledTasksCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
-	}
+	hostname := TargetHostname()
 
 	var artifacts []models.Artifact
 
 	// User crontab
-	cmd := exec.Command("crontab", "-l")
-	output, err := cmd.Output()
-	if err == nil {
-		lines := strings.Split(string(output), "\n")
-		for i, line := range lines {
-			line = strings.TrimSpace(line)
-			if line == "" || strings.HasPrefix(line, "#") {
-				continue
-			}
+	if Offline() {
+		artifacts = append(artifacts, c.collectCrontabFiles(hostname)...)
+	} else {
+		cmd := exec.Command("crontab", "-l")
+		output, err := cmd.Output()
+		if err == nil {
+			lines := strings.Split(string(output), "\n")
+			for i, line := range lines {
+				line = strings.TrimSpace(line)
+				if line == "" || strings.HasPrefix(line, "#") {
+					continue
+				}
 
-			artifact := models.Artifact{
-				Timestamp:    time.Now(),
-				CollectorID:  c.ID(),
-				ArtifactType: "user_crontab",
-				Hostname:     hostname,
-				Data: map[string]interface{}{
-					"line_number": i + 1,
-					"entry":       line,
-					"user":        os.Getenv("USER"),
-				},
-				Metadata: models.ArtifactMetadata{
-					Success:      true,
-					RequiresRoot: false,
-					CollectedAt:  time.Now().Format(time.RFC3339),
-				},
-			}
+				artifact := models.Artifact{
+					Timestamp:    time.Now(),
+					CollectorID:  c.ID(),
+					ArtifactType: "user_crontab",
+					Hostname:     hostname,
+					Data: map[string]interface{}{
+						"line_number": i + 1,
+						"entry":       line,
+						"user":        os.Getenv("USER"),
+					},
+					Metadata: models.ArtifactMetadata{
+						Success:      true,
+						RequiresRoot: false,
+						CollectedAt:  time.Now().Format(time.RFC3339),
+					},
+				}
 
-			artifacts = append(artifacts, artifact)
+				artifacts = append(artifacts, artifact)
+			}
 		}
 	}
 
@@ -69,44 +70,48 @@ func (c *ScheduledTasksCollector) Collect(ctx context.Context) ([]models.Artifac
 	}
 
 	for _, cronPath := range systemCronPaths {
-		cronArtifacts := c.collectCronFiles(cronPath, hostname)
+		cronArtifacts := c.collectCronFiles(resolvePath(cronPath), hostname)
 		artifacts = append(artifacts, cronArtifacts...)
 	}
 
 	// User periodic tasks
-	userPeriodic := filepath.Join(homeDir, ".periodic")
-	if info, err := os.Stat(userPeriodic); err == nil && info.IsDir() {
-		periodicArtifacts := c.collectCronFiles(userPeriodic, hostname)
-		artifacts = append(artifacts, periodicArtifacts...)
+	for _, home := range userHomes() {
+		userPeriodic := filepath.Join(home.path, ".periodic")
+		if info, err := os.Stat(userPeriodic); err == nil && info.IsDir() {
+			periodicArtifacts := c.collectCronFiles(userPeriodic, hostname)
+			artifacts = append(artifacts, periodicArtifacts...)
+		}
 	}
 
-	// at jobs (atq command)
-	cmd = exec.Command("atq")
-	output, err = cmd.Output()
-	if err == nil {
-		lines := strings.Split(string(output), "\n")
-		for _, line := range lines {
-			line = strings.TrimSpace(line)
-			if line == "" {
-				continue
-			}
+	// at jobs (atq command, live only)
+	if !Offline() {
+		cmd := exec.Command("atq")
+		output, err := cmd.Output()
+		if err == nil {
+			lines := strings.Split(string(output), "\n")
+			for _, line := range lines {
+				line = strings.TrimSpace(line)
+				if line == "" {
+					continue
+				}
 
-			artifact := models.Artifact{
-				Timestamp:    time.Now(),
-				CollectorID:  c.ID(),
-				ArtifactType: "at_job",
-				Hostname:     hostname,
-				Data: map[string]interface{}{
-					"entry": line,
-				},
-				Metadata: models.ArtifactMetadata{
-					Success:      true,
-					RequiresRoot: false,
-					CollectedAt:  time.Now().Format(time.RFC3339),
-				},
-			}
+				artifact := models.Artifact{
+					Timestamp:    time.Now(),
+					CollectorID:  c.ID(),
+					ArtifactType: "at_job",
+					Hostname:     hostname,
+					Data: map[string]interface{}{
+						"entry": line,
+					},
+					Metadata: models.ArtifactMetadata{
+						Success:      true,
+						RequiresRoot: false,
+						CollectedAt:  time.Now().Format(time.RFC3339),
+					},
+				}
 
-			artifacts = append(artifacts, artifact)
+				artifacts = append(artifacts, artifact)
+			}
 		}
 	}
 
@@ -138,6 +143,10 @@ func (c *ScheduledTasksCollector) collectCronFiles(cronPath, hostname string) []
 			if err != nil {
 				continue
 			}
+			fileInfo, err := entry.Info()
+			if err != nil {
+				continue
+			}
 
 			lines := strings.Split(string(content), "\n")
 			for i, line := range lines {
@@ -153,7 +162,7 @@ func (c *ScheduledTasksCollector) collectCronFiles(cronPath, hostname string) []
 					Hostname:     hostname,
 					Data: map[string]interface{}{
 						"file":        entry.Name(),
-						"path":        fullPath,
+						"path":        targetPath(fullPath),
 						"line_number": i + 1,
 						"entry":       line,
 					},
@@ -164,6 +173,7 @@ func (c *ScheduledTasksCollector) collectCronFiles(cronPath, hostname string) []
 						CollectedAt:  time.Now().Format(time.RFC3339),
 					},
 				}
+				addFileTimes(&artifact, "", fileInfo)
 
 				artifacts = append(artifacts, artifact)
 			}
@@ -188,7 +198,7 @@ func (c *ScheduledTasksCollector) collectCronFiles(cronPath, hostname string) []
 				ArtifactType: "system_cron",
 				Hostname:     hostname,
 				Data: map[string]interface{}{
-					"path":        cronPath,
+					"path":        targetPath(cronPath),
 					"line_number": i + 1,
 					"entry":       line,
 				},
@@ -199,7 +209,67 @@ func (c *ScheduledTasksCollector) collectCronFiles(cronPath, hostname string) []
 					CollectedAt:  time.Now().Format(time.RFC3339),
 				},
 			}
+			addFileTimes(&artifact, "", info)
+
+			artifacts = append(artifacts, artifact)
+		}
+	}
+
+	return artifacts
+}
+
+// collectCrontabFiles reads per-user crontabs from the cron spool directory,
+// used offline where `crontab -l` cannot reach the target's tables
+func (c *ScheduledTasksCollector) collectCrontabFiles(hostname string) []models.Artifact {
+	var artifacts []models.Artifact
+
+	tabsDir := resolvePath("/var/at/tabs")
+	entries, err := os.ReadDir(tabsDir)
+	if err != nil {
+		return artifacts
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		fullPath := filepath.Join(tabsDir, entry.Name())
+		content, err := os.ReadFile(fullPath)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		lines := strings.Split(string(content), "\n")
+		for i, line := range lines {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
 
+			artifact := models.Artifact{
+				Timestamp:    time.Now(),
+				CollectorID:  c.ID(),
+				ArtifactType: "user_crontab",
+				Hostname:     hostname,
+				Data: map[string]interface{}{
+					"line_number": i + 1,
+					"entry":       line,
+					"user":        entry.Name(),
+					"path":        targetPath(fullPath),
+				},
+				Metadata: models.ArtifactMetadata{
+					Success:      true,
+					RequiresRoot: true,
+					SourcePath:   fullPath,
+					CollectedAt:  time.Now().Format(time.RFC3339),
+				},
+			}
+			addFileTimes(&artifact, "", info)
 			artifacts = append(artifacts, artifact)
 		}
 	}
@@ -16,30 +16,29 @@ func (c *ShellHistoryCollector) ID() string          { return "shell_history" }
 func (c *ShellHistoryCollector) Name() string        { return "Shell History" }
 func (c *ShellHistoryCollector) Description() string { return "Collects bash and zsh command history" }
 func (c *ShellHistoryCollector) RequiresRoot() bool  { return false }
+func (c *ShellHistoryCollector) LiveOnly() bool      { return false }
 
 func (c *ShellHistoryCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
-	}
+	hostname := TargetHostname()
 
 	var artifacts []models.Artifact
 
-	// Bash history
-	bashHistory := filepath.Join(homeDir, ".bash_history")
-	bashArtifacts := c.collectHistory(bashHistory, "bash_history", hostname)
-	artifacts = append(artifacts, bashArtifacts...)
+	for _, home := range userHomes() {
+		// Bash history
+		bashHistory := filepath.Join(home.path, ".bash_history")
+		bashArtifacts := c.collectHistory(bashHistory, "bash_history", home.user, hostname)
+		artifacts = append(artifacts, bashArtifacts...)
 
-	// Zsh history
-	zshHistory := filepath.Join(homeDir, ".zsh_history")
-	zshArtifacts := c.collectHistory(zshHistory, "zsh_history", hostname)
-	artifacts = append(artifacts, zshArtifacts...)
+		// Zsh history
+		zshHistory := filepath.Join(home.path, ".zsh_history")
+		zshArtifacts := c.collectHistory(zshHistory, "zsh_history", home.user, hostname)
+		artifacts = append(artifacts, zshArtifacts...)
+	}
 
 	return artifacts, nil
 }
 
-func (c *ShellHistoryCollector) collectHistory(historyPath, historyType, hostname string) []models.Artifact {
+func (c *ShellHistoryCollector) collectHistory(historyPath, historyType, user, hostname string) []models.Artifact {
 	var artifacts []models.Artifact
 
 	file, err := os.Open(historyPath)
@@ -67,6 +66,7 @@ func (c *ShellHistoryCollector) collectHistory(historyPath, historyType, hostnam
 			Data: map[string]interface{}{
 				"command":     line,
 				"line_number": lineNum,
+				"user":        user,
 			},
 			Metadata: models.ArtifactMetadata{
 				Success:      true,
@@ -16,20 +16,27 @@ func (c *SSHCollector) ID() string          { return "ssh_config" }
 func (c *SSHCollector) Name() string        { return "SSH Keys & Configuration" }
 func (c *SSHCollector) Description() string { return "Collects SSH keys, configs, and known hosts" }
 func (c *SSHCollector) RequiresRoot() bool  { return false }
+func (c *SSHCollector) LiveOnly() bool      { return false }
 
 func (c *SSHCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
+	hostname := TargetHostname()
+
+	var artifacts []models.Artifact
+
+	for _, home := range userHomes() {
+		artifacts = append(artifacts, c.collectSSHDir(home, hostname)...)
 	}
 
+	return artifacts, nil
+}
+
+func (c *SSHCollector) collectSSHDir(home userHome, hostname string) []models.Artifact {
 	var artifacts []models.Artifact
 
-	sshDir := filepath.Join(homeDir, ".ssh")
+	sshDir := filepath.Join(home.path, ".ssh")
 
 	if _, err := os.Stat(sshDir); os.IsNotExist(err) {
-		return artifacts, nil
+		return artifacts
 	}
 
 	// Collect SSH keys (private and public)
@@ -44,11 +51,12 @@ func (c *SSHCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
 				ArtifactType: "ssh_private_key",
 				Hostname:     hostname,
 				Data: map[string]interface{}{
-					"path":       privateKey,
+					"path":       targetPath(privateKey),
 					"key_type":   pattern,
 					"mod_time":   info.ModTime().Format(time.RFC3339),
 					"size":       info.Size(),
 					"mode":       info.Mode().String(),
+					"user":       home.user,
 				},
 				Metadata: models.ArtifactMetadata{
 					Success:      true,
@@ -57,6 +65,8 @@ func (c *SSHCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
 					CollectedAt:  time.Now().Format(time.RFC3339),
 				},
 			}
+			artifact.Metadata.FileHash = addFileHashes(artifact.Data, "file_", privateKey)
+			addFileTimes(&artifact, "", info)
 			artifacts = append(artifacts, artifact)
 		}
 
@@ -72,11 +82,12 @@ func (c *SSHCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
 				ArtifactType: "ssh_public_key",
 				Hostname:     hostname,
 				Data: map[string]interface{}{
-					"path":       publicKey,
+					"path":       targetPath(publicKey),
 					"key_type":   pattern,
 					"mod_time":   info.ModTime().Format(time.RFC3339),
 					"size":       info.Size(),
 					"content":    string(content),
+					"user":       home.user,
 				},
 				Metadata: models.ArtifactMetadata{
 					Success:      true,
@@ -85,6 +96,8 @@ func (c *SSHCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
 					CollectedAt:  time.Now().Format(time.RFC3339),
 				},
 			}
+			artifact.Metadata.FileHash = addFileHashes(artifact.Data, "file_", publicKey)
+			addFileTimes(&artifact, "", info)
 			artifacts = append(artifacts, artifact)
 		}
 	}
@@ -99,8 +112,9 @@ func (c *SSHCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
 			ArtifactType: "ssh_config",
 			Hostname:     hostname,
 			Data: map[string]interface{}{
-				"path":    sshConfig,
+				"path":    targetPath(sshConfig),
 				"content": string(content),
+				"user":    home.user,
 			},
 			Metadata: models.ArtifactMetadata{
 				Success:      true,
@@ -132,6 +146,7 @@ func (c *SSHCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
 				Data: map[string]interface{}{
 					"line_number": i + 1,
 					"entry":       line,
+					"user":        home.user,
 				},
 				Metadata: models.ArtifactMetadata{
 					Success:      true,
@@ -164,6 +179,7 @@ func (c *SSHCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
 				Data: map[string]interface{}{
 					"line_number": i + 1,
 					"key":         line,
+					"user":        home.user,
 				},
 				Metadata: models.ArtifactMetadata{
 					Success:      true,
@@ -176,5 +192,5 @@ func (c *SSHCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
 		}
 	}
 
-	return artifacts, nil
+	return artifacts
 }
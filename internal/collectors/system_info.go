@@ -2,7 +2,6 @@ package collectors
 
 import (
 	"context"
-	"os"
 	"os/exec"
 	"runtime"
 	"strings"
@@ -18,9 +17,10 @@ func (c *SystemInfoCollector) ID() string          { return "system_info" }
 func (c *SystemInfoCollector) Name() string        { return "System Information" }
 func (c *SystemInfoCollector) Description() string { return "Collects basic system information" }
 func (c *SystemInfoCollector) RequiresRoot() bool  { return false }
+func (c *SystemInfoCollector) LiveOnly() bool      { return true }
 
 func (c *SystemInfoCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 
 	info, err := host.Info()
 	if err != nil {
@@ -60,6 +60,7 @@ func (c *SystemInfoCollector) Collect(ctx context.Context) ([]models.Artifact, e
 			CollectedAt:  time.Now().Format(time.RFC3339),
 		},
 	}
+	artifact.AddTimestamp(models.TimeStarted, "boot", time.Unix(int64(info.BootTime), 0))
 
 	return []models.Artifact{artifact}, nil
 }
@@ -15,28 +15,27 @@ func (c *SystemLogsCollector) ID() string          { return "system_logs" }
 func (c *SystemLogsCollector) Name() string        { return "System Logs & Crash Reports" }
 func (c *SystemLogsCollector) Description() string { return "Collects system logs and crash reports" }
 func (c *SystemLogsCollector) RequiresRoot() bool  { return false }
+func (c *SystemLogsCollector) LiveOnly() bool      { return false }
 
 func (c *SystemLogsCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
-	}
+	hostname := TargetHostname()
 
 	var artifacts []models.Artifact
 
 	// User crash reports
-	userCrashPath := filepath.Join(homeDir, "Library/Logs/DiagnosticReports")
-	crashArtifacts := c.collectLogFiles(userCrashPath, "user_crash_report", hostname)
-	artifacts = append(artifacts, crashArtifacts...)
+	for _, home := range userHomes() {
+		userCrashPath := filepath.Join(home.path, "Library/Logs/DiagnosticReports")
+		crashArtifacts := c.collectLogFiles(userCrashPath, "user_crash_report", hostname)
+		artifacts = append(artifacts, crashArtifacts...)
+	}
 
 	// System crash reports (may require root)
-	systemCrashPath := "/Library/Logs/DiagnosticReports"
+	systemCrashPath := resolvePath("/Library/Logs/DiagnosticReports")
 	systemCrashArtifacts := c.collectLogFiles(systemCrashPath, "system_crash_report", hostname)
 	artifacts = append(artifacts, systemCrashArtifacts...)
 
 	// Install logs
-	installLogPath := "/var/log/install.log"
+	installLogPath := resolvePath("/var/log/install.log")
 	if info, err := os.Stat(installLogPath); err == nil {
 		artifact := models.Artifact{
 			Timestamp:    time.Now(),
@@ -44,7 +43,7 @@ func (c *SystemLogsCollector) Collect(ctx context.Context) ([]models.Artifact, e
 			ArtifactType: "install_log",
 			Hostname:     hostname,
 			Data: map[string]interface{}{
-				"path":     installLogPath,
+				"path":     targetPath(installLogPath),
 				"size":     info.Size(),
 				"mod_time": info.ModTime().Format(time.RFC3339),
 			},
@@ -55,6 +54,7 @@ func (c *SystemLogsCollector) Collect(ctx context.Context) ([]models.Artifact, e
 				CollectedAt:  time.Now().Format(time.RFC3339),
 			},
 		}
+		addFileTimes(&artifact, "", info)
 		artifacts = append(artifacts, artifact)
 	}
 
@@ -95,7 +95,7 @@ func (c *SystemLogsCollector) collectLogFiles(logDir, logType, hostname string)
 			Hostname:     hostname,
 			Data: map[string]interface{}{
 				"filename": entry.Name(),
-				"path":     fullPath,
+				"path":     targetPath(fullPath),
 				"size":     info.Size(),
 				"mod_time": info.ModTime().Format(time.RFC3339),
 			},
@@ -106,6 +106,7 @@ func (c *SystemLogsCollector) collectLogFiles(logDir, logType, hostname string)
 				CollectedAt:  time.Now().Format(time.RFC3339),
 			},
 		}
+		addFileTimes(&artifact, "", info)
 
 		artifacts = append(artifacts, artifact)
 	}
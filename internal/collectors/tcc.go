@@ -18,30 +18,29 @@ func (c *TCCCollector) ID() string          { return "tcc_permissions" }
 func (c *TCCCollector) Name() string        { return "TCC Privacy Permissions" }
 func (c *TCCCollector) Description() string { return "Collects Transparency, Consent, and Control database" }
 func (c *TCCCollector) RequiresRoot() bool  { return false }
+func (c *TCCCollector) LiveOnly() bool      { return false }
 
 func (c *TCCCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, err
-	}
+	hostname := TargetHostname()
 
 	var artifacts []models.Artifact
 
-	// User TCC database
-	userTCC := filepath.Join(homeDir, "Library/Application Support/com.apple.TCC/TCC.db")
-	userArtifacts := c.collectTCCDatabase(userTCC, "user", hostname)
-	artifacts = append(artifacts, userArtifacts...)
+	// User TCC databases
+	for _, home := range userHomes() {
+		userTCC := filepath.Join(home.path, "Library/Application Support/com.apple.TCC/TCC.db")
+		userArtifacts := c.collectTCCDatabase(userTCC, "user", home.user, hostname)
+		artifacts = append(artifacts, userArtifacts...)
+	}
 
 	// System TCC database (requires root)
-	systemTCC := "/Library/Application Support/com.apple.TCC/TCC.db"
-	systemArtifacts := c.collectTCCDatabase(systemTCC, "system", hostname)
+	systemTCC := resolvePath("/Library/Application Support/com.apple.TCC/TCC.db")
+	systemArtifacts := c.collectTCCDatabase(systemTCC, "system", "", hostname)
 	artifacts = append(artifacts, systemArtifacts...)
 
 	return artifacts, nil
 }
 
-func (c *TCCCollector) collectTCCDatabase(dbPath, dbType, hostname string) []models.Artifact {
+func (c *TCCCollector) collectTCCDatabase(dbPath, dbType, user, hostname string) []models.Artifact {
 	var artifacts []models.Artifact
 
 	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
@@ -104,7 +103,11 @@ func (c *TCCCollector) collectTCCDatabase(dbPath, dbType, hostname string) []mod
 		data := map[string]interface{}{
 			"database_type": dbType,
 		}
+		if user != "" {
+			data["user"] = user
+		}
 
+		var modified time.Time
 		for i, col := range columns {
 			val := values[i]
 			switch v := val.(type) {
@@ -113,7 +116,8 @@ func (c *TCCCollector) collectTCCDatabase(dbPath, dbType, hostname string) []mod
 			case int64:
 				if col == "last_modified" {
 					// TCC stores Unix timestamp
-					data[col] = time.Unix(v, 0).Format(time.RFC3339)
+					modified = time.Unix(v, 0)
+					data[col] = modified.Format(time.RFC3339)
 				} else {
 					data[col] = v
 				}
@@ -135,6 +139,7 @@ func (c *TCCCollector) collectTCCDatabase(dbPath, dbType, hostname string) []mod
 				CollectedAt:  time.Now().Format(time.RFC3339),
 			},
 		}
+		artifact.AddTimestamp(models.TimeModified, "", modified)
 
 		artifacts = append(artifacts, artifact)
 	}
@@ -3,7 +3,6 @@ package collectors
 import (
 	"context"
 	"encoding/json"
-	"os"
 	"os/exec"
 	"path/filepath"
 	"time"
@@ -17,6 +16,7 @@ func (c *UnifiedLogsCollector) ID() string          { return "unified_logs" }
 func (c *UnifiedLogsCollector) Name() string        { return "Unified Logs" }
 func (c *UnifiedLogsCollector) Description() string { return "Collects recent unified log entries" }
 func (c *UnifiedLogsCollector) RequiresRoot() bool  { return false }
+func (c *UnifiedLogsCollector) LiveOnly() bool      { return true }
 
 // logEntry represents a single entry from `log show --style json`
 type logEntry struct {
@@ -31,7 +31,7 @@ type logEntry struct {
 }
 
 func (c *UnifiedLogsCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 	var artifacts []models.Artifact
 
 	predicates := []struct {
@@ -16,9 +16,10 @@ func (c *UserAccountsCollector) ID() string          { return "user_accounts" }
 func (c *UserAccountsCollector) Name() string        { return "User Accounts" }
 func (c *UserAccountsCollector) Description() string { return "Collects local user accounts" }
 func (c *UserAccountsCollector) RequiresRoot() bool  { return false }
+func (c *UserAccountsCollector) LiveOnly() bool      { return false }
 
 func (c *UserAccountsCollector) Collect(ctx context.Context) ([]models.Artifact, error) {
-	hostname, _ := os.Hostname()
+	hostname := TargetHostname()
 
 	// Get list of users from /Users directory
 	users := c.getLocalUsers()
@@ -48,7 +49,7 @@ func (c *UserAccountsCollector) Collect(ctx context.Context) ([]models.Artifact,
 }
 
 func (c *UserAccountsCollector) getLocalUsers() []string {
-	entries, err := os.ReadDir("/Users")
+	entries, err := os.ReadDir(resolvePath("/Users"))
 	if err != nil {
 		return nil
 	}
@@ -68,34 +69,13 @@ func (c *UserAccountsCollector) getUserInfo(username string) map[string]interfac
 		"home_dir":  "/Users/" + username,
 	}
 
-	// Try to get user ID and full name via dscl
-	cmd := exec.Command("dscl", ".", "-read", "/Users/"+username)
-	output, err := cmd.Output()
-	if err == nil {
-		lines := strings.Split(string(output), "\n")
-		for _, line := range lines {
-			if strings.HasPrefix(line, "UniqueID:") {
-				parts := strings.Fields(line)
-				if len(parts) >= 2 {
-					info["uid"] = parts[1]
-				}
-			} else if strings.HasPrefix(line, "RealName:") {
-				realName := strings.TrimPrefix(line, "RealName:")
-				realName = strings.TrimSpace(realName)
-				if realName != "" {
-					info["real_name"] = realName
-				}
-			} else if strings.HasPrefix(line, "UserShell:") {
-				parts := strings.Fields(line)
-				if len(parts) >= 2 {
-					info["shell"] = parts[1]
-				}
-			}
-		}
+	// Try to get user ID and full name via dscl (live only)
+	if !Offline() {
+		c.readDirectoryService(username, info)
 	}
 
 	// Check if user home directory exists
-	if _, err := os.Stat("/Users/" + username); err == nil {
+	if _, err := os.Stat(resolvePath("/Users/" + username)); err == nil {
 		info["home_exists"] = true
 	} else {
 		info["home_exists"] = false
@@ -103,3 +83,32 @@ func (c *UserAccountsCollector) getUserInfo(username string) map[string]interfac
 
 	return info
 }
+
+func (c *UserAccountsCollector) readDirectoryService(username string, info map[string]interface{}) {
+	cmd := exec.Command("dscl", ".", "-read", "/Users/"+username)
+	output, err := cmd.Output()
+	if err != nil {
+		return
+	}
+
+	lines := strings.Split(string(output), "\n")
+	for _, line := range lines {
+		if strings.HasPrefix(line, "UniqueID:") {
+			parts := strings.Fields(line)
+			if len(parts) >= 2 {
+				info["uid"] = parts[1]
+			}
+		} else if strings.HasPrefix(line, "RealName:") {
+			realName := strings.TrimPrefix(line, "RealName:")
+			realName = strings.TrimSpace(realName)
+			if realName != "" {
+				info["real_name"] = realName
+			}
+		} else if strings.HasPrefix(line, "UserShell:") {
+			parts := strings.Fields(line)
+			if len(parts) >= 2 {
+				info["shell"] = parts[1]
+			}
+		}
+	}
+}
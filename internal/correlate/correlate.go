@@ -0,0 +1,288 @@
+// Package correlate joins running processes with the network connections,
+// open network files and persistence entries that refer to them. Enrich
+// copies process context onto connections before analysis so analyzers and
+// queries see it; Entities rolls everything up per process afterwards, when
+// risk scores are known.
+package correlate
+
+import (
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/plonxyz/triagectl/internal/analysis"
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// Connection artifact types, both keyed by pid
+const (
+	typeConnection = "network_connection"
+	typeOpenFile   = "open_network_file"
+)
+
+// processFields are the running_process fields copied onto connections,
+// under a process_ prefix
+var processFields = map[string]string{
+	"name":             "process_name",
+	"exe":              "process_exe",
+	"username":         "process_user",
+	"exe_signed":       "process_signed",
+	"exe_adhoc_signed": "process_adhoc_signed",
+	"exe_team_id":      "process_team_id",
+	"exe_signing_id":   "process_signing_id",
+	"exe_sha256":       "process_sha256",
+}
+
+// Enrich copies the owning process's name, executable, user, signature and
+// hash onto each connection, and the remote endpoints and listening
+// addresses of each process onto the process. It is idempotent.
+func Enrich(artifacts []models.Artifact) {
+	c := newCorrelation(artifacts)
+
+	for i, art := range artifacts {
+		if art.ArtifactType != typeConnection && art.ArtifactType != typeOpenFile {
+			continue
+		}
+		p := c.process(art)
+		if p == nil {
+			continue
+		}
+		proc := artifacts[p.Index].Data
+		for from, to := range processFields {
+			if v, ok := proc[from]; ok && v != "" {
+				artifacts[i].Data[to] = v
+			}
+		}
+	}
+
+	for _, p := range c.tree.Nodes {
+		e := c.entities[p]
+		data := artifacts[p.Index].Data
+		delete(data, "remote_endpoints")
+		delete(data, "listen_addrs")
+		if len(e.RemoteEndpoints) > 0 {
+			data["remote_endpoints"] = e.RemoteEndpoints
+		}
+		if len(e.ListenAddrs) > 0 {
+			data["listen_addrs"] = e.ListenAddrs
+		}
+	}
+}
+
+// Entities returns one entity per running process, in PID order
+func Entities(artifacts []models.Artifact) []models.Entity {
+	c := newCorrelation(artifacts)
+	entities := make([]models.Entity, 0, len(c.tree.Nodes))
+	for _, p := range c.tree.Nodes {
+		entities = append(entities, *c.entities[p])
+	}
+	sort.SliceStable(entities, func(i, j int) bool { return entities[i].PID < entities[j].PID })
+	return entities
+}
+
+type correlation struct {
+	tree     *analysis.ProcessTree
+	byPID    map[int]*analysis.ProcessNode
+	entities map[*analysis.ProcessNode]*models.Entity
+}
+
+func newCorrelation(artifacts []models.Artifact) *correlation {
+	c := &correlation{
+		tree:     analysis.BuildProcessTree(artifacts),
+		byPID:    make(map[int]*analysis.ProcessNode),
+		entities: make(map[*analysis.ProcessNode]*models.Entity),
+	}
+
+	// A reused PID maps to the newest process; connections were collected
+	// after the process list, so they belong to it
+	for _, p := range c.tree.Nodes {
+		if cur, ok := c.byPID[p.PID]; !ok || p.Created.After(cur.Created) {
+			c.byPID[p.PID] = p
+		}
+		c.entities[p] = newEntity(p, artifacts[p.Index])
+	}
+
+	for _, art := range artifacts {
+		switch art.ArtifactType {
+		case typeConnection, typeOpenFile:
+			if p := c.process(art); p != nil {
+				c.addConnection(c.entities[p], art)
+			}
+		case "user_launch_agent", "system_launch_agent", "system_launch_daemon",
+			"login_item_btm", "login_item_backgrounditems",
+			"user_crontab", "system_cron", "at_job":
+			c.addPersistence(art)
+		}
+	}
+
+	for _, e := range c.entities {
+		sort.Strings(e.RemoteEndpoints)
+		sort.Strings(e.ListenAddrs)
+		sort.Strings(e.OpenFiles)
+	}
+	return c
+}
+
+func newEntity(p *analysis.ProcessNode, art models.Artifact) *models.Entity {
+	e := &models.Entity{
+		PID:        p.PID,
+		PPID:       p.PPID,
+		Name:       p.Name,
+		Exe:        p.Exe,
+		User:       analysis.GetString(art.Data, "username"),
+		Cmdline:    p.Cmdline,
+		CreateTime: analysis.GetString(art.Data, "create_time"),
+		TeamID:     analysis.GetString(art.Data, "exe_team_id"),
+		SigningID:  analysis.GetString(art.Data, "exe_signing_id"),
+		RiskScore:  art.RiskScore,
+		Tags:       append([]string(nil), art.Tags...),
+	}
+	if p.Parent != nil {
+		e.ParentName = p.Parent.DisplayName()
+		e.ParentExe = p.Parent.Exe
+	}
+	if signed, ok := art.Data["exe_signed"].(bool); ok {
+		switch adhoc, _ := art.Data["exe_adhoc_signed"].(bool); {
+		case !signed:
+			e.Signature = "unsigned"
+		case adhoc:
+			e.Signature = "adhoc"
+		default:
+			e.Signature = "signed"
+		}
+	}
+	return e
+}
+
+// process returns the running process a connection artifact belongs to
+func (c *correlation) process(art models.Artifact) *analysis.ProcessNode {
+	pid, err := strconv.Atoi(analysis.GetString(art.Data, "pid"))
+	if err != nil || pid <= 0 {
+		return nil
+	}
+	return c.byPID[pid]
+}
+
+func (c *correlation) addConnection(e *models.Entity, art models.Artifact) {
+	e.Connections++
+	if art.RiskScore > e.RiskScore {
+		e.RiskScore = art.RiskScore
+	}
+	e.Tags = analysis.AppendUnique(e.Tags, art.Tags...)
+
+	var remote, listen string
+	if art.ArtifactType == typeConnection {
+		if addr := analysis.GetString(art.Data, "remote_addr"); !unspecified(addr) {
+			remote = joinHostPort(addr, analysis.GetString(art.Data, "remote_port"))
+		} else if analysis.GetString(art.Data, "status") == "LISTEN" {
+			listen = joinHostPort(analysis.GetString(art.Data, "local_addr"), analysis.GetString(art.Data, "local_port"))
+		}
+	} else {
+		remote = analysis.GetString(art.Data, "remote_addr")
+		listen = analysis.GetString(art.Data, "listen_addr")
+		desc := analysis.GetString(art.Data, "type") + " " + analysis.GetString(art.Data, "name")
+		if state := analysis.GetString(art.Data, "state"); state != "" {
+			desc += " (" + state + ")"
+		}
+		e.OpenFiles = analysis.AppendUnique(e.OpenFiles, desc)
+	}
+	if remote != "" {
+		e.RemoteEndpoints = analysis.AppendUnique(e.RemoteEndpoints, remote)
+	}
+	if listen != "" {
+		e.ListenAddrs = analysis.AppendUnique(e.ListenAddrs, listen)
+	}
+}
+
+// addPersistence records a persistence artifact on every process running
+// the executable it launches
+func (c *correlation) addPersistence(art models.Artifact) {
+	var matches func(exe string) bool
+	ref := models.EntityReference{
+		ArtifactType: art.ArtifactType,
+		Name:         firstString(art.Data, "label", "name", "identifier"),
+		Path:         analysis.GetString(art.Data, "path"),
+	}
+
+	switch art.ArtifactType {
+	case "user_launch_agent", "system_launch_agent", "system_launch_daemon":
+		target := analysis.GetString(art.Data, "target_path")
+		if target == "" {
+			return
+		}
+		matches = func(exe string) bool { return exe == target }
+	case "login_item_btm", "login_item_backgrounditems":
+		var paths []string
+		for _, key := range []string{"executable_path", "launchd_target", "path"} {
+			if p := analysis.GetString(art.Data, key); p != "" {
+				paths = append(paths, p)
+			}
+		}
+		app := analysis.GetString(art.Data, "app_path")
+		if len(paths) == 0 && app == "" {
+			return
+		}
+		matches = func(exe string) bool {
+			for _, p := range paths {
+				if exe == p {
+					return true
+				}
+			}
+			return app != "" && strings.HasPrefix(exe, strings.TrimSuffix(app, "/")+"/")
+		}
+	default:
+		fields := strings.Fields(analysis.GetString(art.Data, "entry"))
+		if ref.Name == "" {
+			ref.Name = analysis.GetString(art.Data, "entry")
+		}
+		matches = func(exe string) bool {
+			for _, f := range fields {
+				if f == exe {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	if ref.Name == "" {
+		ref.Name = filepath.Base(ref.Path)
+	}
+
+	for _, p := range c.tree.Nodes {
+		if p.Exe != "" && matches(p.Exe) {
+			e := c.entities[p]
+			e.Persistence = append(e.Persistence, ref)
+		}
+	}
+}
+
+// unspecified reports whether a remote address is empty or a wildcard,
+// as it is for listening and unconnected sockets
+func unspecified(addr string) bool {
+	if addr == "" || addr == "*" {
+		return true
+	}
+	ip := net.ParseIP(addr)
+	return ip != nil && ip.IsUnspecified()
+}
+
+func joinHostPort(host, port string) string {
+	if host == "" {
+		host = "*"
+	}
+	if port == "" || port == "0" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+func firstString(d map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if v := analysis.GetString(d, key); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,202 @@
+// Package diff compares two collections of the same host, e.g. before and
+// after remediation, matching artifacts by a per-type identity key.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/models"
+	"github.com/plonxyz/triagectl/internal/target"
+)
+
+// Change kinds
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Side describes one of the two compared collections
+type Side struct {
+	Path        string     `json:"path"`
+	Hostname    string     `json:"hostname,omitempty"`
+	CollectedAt *time.Time `json:"collected_at,omitempty"`
+	Artifacts   int        `json:"artifacts"`
+}
+
+// FieldChange is one data field that differs between the two collections
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// Change is one added, removed or changed artifact
+type Change struct {
+	Kind      string                 `json:"kind"`
+	Key       string                 `json:"key"`
+	Summary   string                 `json:"summary"`
+	RiskScore int                    `json:"risk_score,omitempty"`
+	Fields    []FieldChange          `json:"fields,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// TypeDiff groups the changes for one artifact type
+type TypeDiff struct {
+	ArtifactType string   `json:"artifact_type"`
+	Added        []Change `json:"added,omitempty"`
+	Removed      []Change `json:"removed,omitempty"`
+	Changed      []Change `json:"changed,omitempty"`
+}
+
+// Result is the full comparison, serialized as the JSON diff
+type Result struct {
+	Before  Side       `json:"before"`
+	After   Side       `json:"after"`
+	Added   int        `json:"added"`
+	Removed int        `json:"removed"`
+	Changed int        `json:"changed"`
+	Types   []TypeDiff `json:"types"`
+}
+
+// Compare matches artifacts in before and after by target.IdentityKey and reports
+// what was added, removed or changed per artifact type. summarize renders the
+// one-line description stored with each change.
+func Compare(before, after []models.Artifact, summarize func(models.Artifact) string) *Result {
+	beforeIdx := index(before)
+	afterIdx := index(after)
+
+	types := make(map[string]*TypeDiff)
+	typeDiff := func(t string) *TypeDiff {
+		if td, ok := types[t]; ok {
+			return td
+		}
+		td := &TypeDiff{ArtifactType: t}
+		types[t] = td
+		return td
+	}
+
+	res := &Result{
+		Before: Side{Artifacts: len(before)},
+		After:  Side{Artifacts: len(after)},
+	}
+
+	for k, a := range afterIdx {
+		b, ok := beforeIdx[k]
+		td := typeDiff(a.ArtifactType)
+		if !ok {
+			td.Added = append(td.Added, Change{
+				Kind: Added, Key: k.key, Summary: summarize(a), RiskScore: a.RiskScore, Data: a.Data,
+			})
+			res.Added++
+			continue
+		}
+		if fields := compareData(b, a); len(fields) > 0 {
+			td.Changed = append(td.Changed, Change{
+				Kind: Changed, Key: k.key, Summary: summarize(a), RiskScore: a.RiskScore, Fields: fields,
+			})
+			res.Changed++
+		}
+	}
+	for k, b := range beforeIdx {
+		if _, ok := afterIdx[k]; !ok {
+			td := typeDiff(b.ArtifactType)
+			td.Removed = append(td.Removed, Change{
+				Kind: Removed, Key: k.key, Summary: summarize(b), RiskScore: b.RiskScore, Data: b.Data,
+			})
+			res.Removed++
+		}
+	}
+
+	for _, td := range types {
+		if len(td.Added)+len(td.Removed)+len(td.Changed) == 0 {
+			continue
+		}
+		sortChanges(td.Added)
+		sortChanges(td.Removed)
+		sortChanges(td.Changed)
+		res.Types = append(res.Types, *td)
+	}
+	sort.Slice(res.Types, func(i, j int) bool { return res.Types[i].ArtifactType < res.Types[j].ArtifactType })
+
+	return res
+}
+
+type typedKey struct {
+	artifactType string
+	key          string
+}
+
+// index maps each artifact to its identity key. Artifacts sharing a key
+// (e.g. two identical processes) are numbered in collection order.
+func index(artifacts []models.Artifact) map[typedKey]models.Artifact {
+	idx := make(map[typedKey]models.Artifact, len(artifacts))
+	for _, a := range artifacts {
+		base := target.IdentityKey(a)
+		k := typedKey{a.ArtifactType, base}
+		for n := 2; ; n++ {
+			if _, dup := idx[k]; !dup {
+				break
+			}
+			k.key = fmt.Sprintf("%s #%d", base, n)
+		}
+		idx[k] = a
+	}
+	return idx
+}
+
+// compareData lists the non-volatile top-level data fields that differ
+func compareData(before, after models.Artifact) []FieldChange {
+	b, a := target.StableData(before), target.StableData(after)
+
+	keys := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		keys[k] = true
+	}
+	for k := range b {
+		keys[k] = true
+	}
+
+	var fields []FieldChange
+	for k := range keys {
+		if !equalValues(b[k], a[k]) {
+			fields = append(fields, FieldChange{Field: k, Before: b[k], After: a[k]})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields
+}
+
+// equalValues compares through JSON so values loaded from a database
+// (float64, []interface{}) match freshly collected ones (int, []string)
+func equalValues(x, y interface{}) bool {
+	if reflect.DeepEqual(x, y) {
+		return true
+	}
+	bx, errX := json.Marshal(x)
+	by, errY := json.Marshal(y)
+	return errX == nil && errY == nil && string(bx) == string(by)
+}
+
+func sortChanges(changes []Change) {
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].RiskScore != changes[j].RiskScore {
+			return changes[i].RiskScore > changes[j].RiskScore
+		}
+		return changes[i].Key < changes[j].Key
+	})
+}
+
+// WriteJSON writes the result as an indented JSON document
+func (r *Result) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
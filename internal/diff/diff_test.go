@@ -0,0 +1,146 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+func art(artifactType string, data map[string]interface{}) models.Artifact {
+	return models.Artifact{ArtifactType: artifactType, Data: data}
+}
+
+func proc(pid int, exe string, cpu float64) models.Artifact {
+	return art("running_process", map[string]interface{}{
+		"pid": pid, "exe": exe, "cmdline": exe, "username": "alice", "cpu_percent": cpu,
+	})
+}
+
+func yaraMatch(sourceID int64) models.Artifact {
+	return art("yara_match", map[string]interface{}{
+		"rule": "Evil", "file_path": "/tmp/evil", "source_key": "path=/tmp/evil.plist",
+		"source_artifact_type": "user_launch_agent", "source_artifact_id": sourceID,
+		"strings": []string{`$a@0x0: "evil"`},
+	})
+}
+
+// keys lists a type's changes as kind:key
+func keys(res *Result, artifactType string) []string {
+	var out []string
+	for _, td := range res.Types {
+		if td.ArtifactType != artifactType {
+			continue
+		}
+		for _, group := range [][]Change{td.Added, td.Removed, td.Changed} {
+			for _, c := range group {
+				out = append(out, c.Kind+":"+c.Key)
+			}
+		}
+	}
+	return out
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after []models.Artifact
+		artifactType  string
+		want          []string
+	}{
+		{
+			name:         "added and removed by identity",
+			before:       []models.Artifact{art("user_launch_agent", map[string]interface{}{"path": "/a.plist"})},
+			after:        []models.Artifact{art("user_launch_agent", map[string]interface{}{"path": "/b.plist"})},
+			artifactType: "user_launch_agent",
+			want:         []string{"added:path=/b.plist", "removed:path=/a.plist"},
+		},
+		{
+			name:         "changed in place",
+			before:       []models.Artifact{art("user_launch_agent", map[string]interface{}{"path": "/a.plist", "program": "/bin/old"})},
+			after:        []models.Artifact{art("user_launch_agent", map[string]interface{}{"path": "/a.plist", "program": "/bin/new"})},
+			artifactType: "user_launch_agent",
+			want:         []string{"changed:path=/a.plist"},
+		},
+		{
+			name:         "first identity alternative with all fields present",
+			before:       []models.Artifact{art("login_item_btm", map[string]interface{}{"uuid": "U1", "Name": "x"})},
+			after:        []models.Artifact{art("login_item_btm", map[string]interface{}{"uuid": "U1", "Name": "y"})},
+			artifactType: "login_item_btm",
+			want:         []string{"changed:uuid=U1"},
+		},
+		{
+			name:         "volatile process fields ignored",
+			before:       []models.Artifact{proc(100, "/bin/agent", 1.5)},
+			after:        []models.Artifact{proc(200, "/bin/agent", 80)},
+			artifactType: "running_process",
+			want:         nil,
+		},
+		{
+			name:         "duplicate keys numbered in order",
+			before:       []models.Artifact{proc(1, "/bin/sleep", 0), proc(2, "/bin/sleep", 0), proc(3, "/bin/sleep", 0)},
+			after:        []models.Artifact{proc(4, "/bin/sleep", 0)},
+			artifactType: "running_process",
+			want: []string{
+				"removed:exe=/bin/sleep cmdline=/bin/sleep username=alice #2",
+				"removed:exe=/bin/sleep cmdline=/bin/sleep username=alice #3",
+			},
+		},
+		{
+			name:         "singleton type",
+			before:       []models.Artifact{art("sip_status", map[string]interface{}{"enabled": true})},
+			after:        []models.Artifact{art("sip_status", map[string]interface{}{"enabled": false})},
+			artifactType: "sip_status",
+			want:         []string{"changed:sip_status"},
+		},
+		{
+			name:         "unlisted type identified by content",
+			before:       []models.Artifact{art("zsh_history", map[string]interface{}{"command": "ls"})},
+			after:        []models.Artifact{art("zsh_history", map[string]interface{}{"command": "ls"})},
+			artifactType: "zsh_history",
+			want:         nil,
+		},
+		{
+			name:         "numbers loaded from a database",
+			before:       []models.Artifact{art("user_account", map[string]interface{}{"username": "alice", "uid": float64(501)})},
+			after:        []models.Artifact{art("user_account", map[string]interface{}{"username": "alice", "uid": 501})},
+			artifactType: "user_account",
+			want:         nil,
+		},
+		{
+			// The source row ID depends on the order collectors finished in
+			name:         "yara match with another source row ID",
+			before:       []models.Artifact{yaraMatch(17)},
+			after:        []models.Artifact{yaraMatch(42)},
+			artifactType: "yara_match",
+			want:         nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Compare(tt.before, tt.after, func(a models.Artifact) string { return a.ArtifactType })
+			if got := keys(res, tt.artifactType); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changes = %q, want %q", got, tt.want)
+			}
+			if n := res.Added + res.Removed + res.Changed; n != len(tt.want) {
+				t.Errorf("totals = %d added, %d removed, %d changed, want %d in all", res.Added, res.Removed, res.Changed, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestCompareFields(t *testing.T) {
+	before := art("user_launch_agent", map[string]interface{}{"path": "/a.plist", "program": "/bin/old", "disabled": false})
+	after := art("user_launch_agent", map[string]interface{}{"path": "/a.plist", "program": "/bin/new", "run_at_load": true, "disabled": false})
+	res := Compare([]models.Artifact{before}, []models.Artifact{after}, func(models.Artifact) string { return "" })
+	if len(res.Types) != 1 || len(res.Types[0].Changed) != 1 {
+		t.Fatalf("result = %+v, want one change", res)
+	}
+	want := []FieldChange{
+		{Field: "program", Before: "/bin/old", After: "/bin/new"},
+		{Field: "run_at_load", After: true},
+	}
+	if got := res.Types[0].Changed[0].Fields; !reflect.DeepEqual(got, want) {
+		t.Errorf("fields = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package hashing
+
+import "os"
+
+// fileID is unavailable here; files are cached by path instead
+func fileID(info os.FileInfo) (dev, ino uint64, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,159 @@
+// Package hashing computes MD5, SHA-1 and SHA-256 digests of files for
+// collectors, with a size limit, a bound on concurrent reads and a cache so
+// a binary referenced by many artifacts is read once.
+package hashing
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// DefaultMaxSize is the default largest file hashed, in bytes
+const DefaultMaxSize = 100 << 20
+
+// DefaultWorkers is the default number of files hashed at once
+const DefaultWorkers = 4
+
+// ErrTooLarge is returned for files over the size limit
+var ErrTooLarge = errors.New("file exceeds hash size limit")
+
+// ErrDisabled is returned by a service with a zero size limit
+var ErrDisabled = errors.New("file hashing disabled")
+
+// Hashes are the digests of one file, lowercase hex
+type Hashes struct {
+	MD5    string
+	SHA1   string
+	SHA256 string
+	Size   int64
+}
+
+// Service hashes files. It is safe for concurrent use.
+type Service struct {
+	maxSize int64
+	sem     chan struct{}
+
+	mu       sync.Mutex
+	cache    map[cacheKey]Hashes
+	inflight map[cacheKey]*call
+}
+
+// cacheKey identifies a file version: the same inode with the same size and
+// modification time is assumed unchanged. path is set only where inodes are
+// not available.
+type cacheKey struct {
+	dev, ino uint64
+	path     string
+	size     int64
+	mtime    int64
+}
+
+type call struct {
+	done   chan struct{}
+	hashes Hashes
+	err    error
+}
+
+// New creates a service hashing files of at most maxSize bytes, reading at
+// most workers files at once. A maxSize of 0 disables hashing.
+func New(maxSize int64, workers int) *Service {
+	if workers < 1 {
+		workers = 1
+	}
+	return &Service{
+		maxSize:  maxSize,
+		sem:      make(chan struct{}, workers),
+		cache:    make(map[cacheKey]Hashes),
+		inflight: make(map[cacheKey]*call),
+	}
+}
+
+// Hash returns the digests of the regular file at path, following symlinks.
+// Concurrent requests for the same file share one read.
+func (s *Service) Hash(path string) (Hashes, error) {
+	if s.maxSize <= 0 {
+		return Hashes{}, ErrDisabled
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Hashes{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return Hashes{}, fmt.Errorf("%s is not a regular file", path)
+	}
+	if info.Size() > s.maxSize {
+		return Hashes{}, ErrTooLarge
+	}
+
+	key := cacheKey{size: info.Size(), mtime: info.ModTime().UnixNano()}
+	if dev, ino, ok := fileID(info); ok {
+		key.dev, key.ino = dev, ino
+	} else {
+		key.path = path
+	}
+
+	s.mu.Lock()
+	if h, ok := s.cache[key]; ok {
+		s.mu.Unlock()
+		return h, nil
+	}
+	if c, ok := s.inflight[key]; ok {
+		s.mu.Unlock()
+		<-c.done
+		return c.hashes, c.err
+	}
+	c := &call{done: make(chan struct{})}
+	s.inflight[key] = c
+	s.mu.Unlock()
+
+	s.sem <- struct{}{}
+	c.hashes, c.err = s.hashFile(path)
+	<-s.sem
+
+	s.mu.Lock()
+	delete(s.inflight, key)
+	if c.err == nil {
+		s.cache[key] = c.hashes
+	}
+	s.mu.Unlock()
+	close(c.done)
+	return c.hashes, c.err
+}
+
+func (s *Service) hashFile(path string) (Hashes, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Hashes{}, err
+	}
+	defer f.Close()
+
+	md5h, sha1h, sha256h := md5.New(), sha1.New(), sha256.New()
+	// The file may have grown since it was stat'ed
+	n, err := io.Copy(io.MultiWriter(md5h, sha1h, sha256h), io.LimitReader(f, s.maxSize+1))
+	if err != nil {
+		return Hashes{}, err
+	}
+	if n > s.maxSize {
+		return Hashes{}, ErrTooLarge
+	}
+	return Hashes{
+		MD5:    hex.EncodeToString(md5h.Sum(nil)),
+		SHA1:   hex.EncodeToString(sha1h.Sum(nil)),
+		SHA256: hex.EncodeToString(sha256h.Sum(nil)),
+		Size:   n,
+	}, nil
+}
+
+// Cached returns the number of cached file digests
+func (s *Service) Cached() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.cache)
+}
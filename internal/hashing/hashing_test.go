@@ -0,0 +1,179 @@
+package hashing
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Digests of "abc"
+const (
+	abcMD5    = "900150983cd24fb0d6963f7d28e17f72"
+	abcSHA1   = "a9993e364706816aba3e25717850c26c9cd0d89d"
+	abcSHA256 = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestHash(t *testing.T) {
+	s := New(DefaultMaxSize, DefaultWorkers)
+	h, err := s.Hash(writeFile(t, "abc", "abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Hashes{MD5: abcMD5, SHA1: abcSHA1, SHA256: abcSHA256, Size: 3}
+	if h != want {
+		t.Errorf("hashes = %+v, want %+v", h, want)
+	}
+}
+
+func TestHashErrors(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, "f", "0123456789")
+	tests := []struct {
+		name    string
+		maxSize int64
+		path    string
+		want    error
+	}{
+		{"disabled", 0, path, ErrDisabled},
+		{"negative limit disables", -1, path, ErrDisabled},
+		{"over the limit", 9, path, ErrTooLarge},
+		{"missing", 100, filepath.Join(dir, "missing"), os.ErrNotExist},
+		{"directory", 100, dir, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(tt.maxSize, 1)
+			_, err := s.Hash(tt.path)
+			if err == nil {
+				t.Fatal("no error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if s.Cached() != 0 {
+				t.Errorf("cached %d after an error", s.Cached())
+			}
+		})
+	}
+
+	// Exactly at the limit is fine
+	if h, err := New(10, 1).Hash(path); err != nil || h.Size != 10 {
+		t.Errorf("at the limit: %+v, %v", h, err)
+	}
+}
+
+func TestHashFileGrownAfterStat(t *testing.T) {
+	// Hash checked the size when it stat'ed the file; the read must stop
+	// at the limit if the file has grown since
+	s := New(10, 1)
+	if _, err := s.hashFile(writeFile(t, "f", "0123456789x")); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("err = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestHashCache(t *testing.T) {
+	s := New(DefaultMaxSize, 1)
+	path := writeFile(t, "f", "abc")
+	mtime := time.Date(2026, 2, 6, 9, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Hash(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// A link reaches the same inode
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(path, link); err != nil {
+		t.Fatal(err)
+	}
+	if h, err := s.Hash(link); err != nil || h.SHA256 != abcSHA256 || s.Cached() != 1 {
+		t.Errorf("through a symlink: %+v, %v, %d cached", h, err, s.Cached())
+	}
+
+	// Same inode, size and modification time: taken to be unchanged
+	if err := os.WriteFile(path, []byte("xyz"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := s.Hash(path); h.SHA256 != abcSHA256 {
+		t.Errorf("unchanged key re-read the file: %s", h.SHA256)
+	}
+
+	// A new modification time is a new version
+	later := mtime.Add(time.Minute)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if h, _ := s.Hash(path); h.SHA256 == abcSHA256 {
+		t.Error("modified file served from the cache")
+	}
+	if s.Cached() != 2 {
+		t.Errorf("cached = %d, want 2", s.Cached())
+	}
+}
+
+func TestHashSharesInflightReads(t *testing.T) {
+	s := New(DefaultMaxSize, 1)
+	// An unbuffered semaphore lets the test grant each read itself
+	s.sem = make(chan struct{})
+	path := writeFile(t, "f", "abc")
+
+	const callers = 8
+	var wg sync.WaitGroup
+	results := make([]Hashes, callers)
+	errs := make([]error, callers)
+	hash := func(i int) {
+		defer wg.Done()
+		results[i], errs[i] = s.Hash(path)
+	}
+	wg.Add(1)
+	go hash(0)
+	for {
+		s.mu.Lock()
+		n := len(s.inflight)
+		s.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	wg.Add(callers - 1)
+	for i := 1; i < callers; i++ {
+		go hash(i)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// Grant one read and take back its slot
+	<-s.sem
+	s.sem <- struct{}{}
+	wg.Wait()
+
+	select {
+	case <-s.sem:
+		t.Error("a second read was started")
+	case <-time.After(20 * time.Millisecond):
+	}
+	for i := range results {
+		if errs[i] != nil || results[i].SHA256 != abcSHA256 {
+			t.Errorf("caller %d: %+v, %v", i, results[i], errs[i])
+		}
+	}
+	if s.Cached() != 1 {
+		t.Errorf("cached = %d, want 1", s.Cached())
+	}
+}
@@ -0,0 +1,139 @@
+package ioc
+
+import "sort"
+
+// automaton is an Aho-Corasick automaton over bytes. The trie is built
+// breadth-first from the sorted patterns so each node's children are
+// contiguous node IDs, which keeps a node to a few integers instead of a map
+// and lets feeds with hundreds of thousands of literals load quickly.
+type automaton struct {
+	label      []byte  // byte on the edge into the node
+	childStart []int32 // first child's node ID
+	childCount []uint16
+	fail       []int32
+	// dict is the nearest node on the fail chain that ends a pattern, or -1
+	dict []int32
+	// pattern is the ID of the pattern ending at the node, or -1
+	pattern  []int32
+	patterns []string
+}
+
+// newAutomaton builds an automaton matching the given patterns. Pattern IDs
+// are their indexes in the slice; duplicates and empty patterns are ignored.
+func newAutomaton(patterns []string) *automaton {
+	order := make([]int, 0, len(patterns))
+	for i, p := range patterns {
+		if p != "" {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return patterns[order[i]] < patterns[order[j]] })
+
+	a := &automaton{patterns: patterns}
+	a.addNode(0)
+
+	type span struct {
+		node, lo, hi, depth int
+	}
+	queue := []span{{0, 0, len(order), 0}}
+	parent := []int32{0}
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+
+		lo := s.lo
+		// Sorted order puts the pattern equal to the prefix first
+		for lo < s.hi && len(patterns[order[lo]]) == s.depth {
+			if a.pattern[s.node] < 0 {
+				a.pattern[s.node] = int32(order[lo])
+			}
+			lo++
+		}
+
+		a.childStart[s.node] = int32(len(a.label))
+		for lo < s.hi {
+			b := patterns[order[lo]][s.depth]
+			hi := lo + 1
+			for hi < s.hi && patterns[order[hi]][s.depth] == b {
+				hi++
+			}
+			child := a.addNode(b)
+			parent = append(parent, int32(s.node))
+			a.childCount[s.node]++
+			queue = append(queue, span{child, lo, hi, s.depth + 1})
+			lo = hi
+		}
+	}
+
+	// Node IDs are in breadth-first order, so parents' fail links are set
+	// before their children's
+	for n := 1; n < len(a.label); n++ {
+		p := parent[n]
+		if p != 0 {
+			f := a.fail[p]
+			for {
+				if c := a.child(f, a.label[n]); c >= 0 {
+					a.fail[n] = c
+					break
+				}
+				if f == 0 {
+					break
+				}
+				f = a.fail[f]
+			}
+		}
+		if f := a.fail[n]; a.pattern[f] >= 0 {
+			a.dict[n] = f
+		} else {
+			a.dict[n] = a.dict[f]
+		}
+	}
+	return a
+}
+
+func (a *automaton) addNode(label byte) int {
+	a.label = append(a.label, label)
+	a.childStart = append(a.childStart, 0)
+	a.childCount = append(a.childCount, 0)
+	a.fail = append(a.fail, 0)
+	a.dict = append(a.dict, -1)
+	a.pattern = append(a.pattern, -1)
+	return len(a.label) - 1
+}
+
+// child returns the child of node n on byte b, or -1
+func (a *automaton) child(n int32, b byte) int32 {
+	start := int(a.childStart[n])
+	count := int(a.childCount[n])
+	i := sort.Search(count, func(i int) bool { return a.label[start+i] >= b })
+	if i < count && a.label[start+i] == b {
+		return int32(start + i)
+	}
+	return -1
+}
+
+// scan calls fn with the pattern ID and end offset (exclusive) of every
+// occurrence of a pattern in s, overlapping ones included
+func (a *automaton) scan(s []byte, fn func(pattern int, end int)) {
+	var state int32
+	for i, b := range s {
+		for {
+			if c := a.child(state, b); c >= 0 {
+				state = c
+				break
+			}
+			if state == 0 {
+				break
+			}
+			state = a.fail[state]
+		}
+		for n := state; n > 0; n = a.dict[n] {
+			if p := a.pattern[n]; p >= 0 {
+				fn(int(p), i+1)
+			}
+		}
+	}
+}
+
+// nodes returns the size of the automaton
+func (a *automaton) nodes() int { return len(a.label) }
@@ -0,0 +1,105 @@
+package ioc
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvColumns are the recognised header names of a typed CSV. value is
+// required; an empty type is guessed from the value.
+var csvColumns = map[string]bool{
+	"type": true, "value": true, "name": true, "description": true, "source": true,
+	"event": true, "event_id": true, "confidence": true, "valid_until": true, "tags": true,
+}
+
+// parseCSV reads a typed CSV with a header row, e.g.
+//
+//	type,value,name,source,event,confidence,valid_until,tags
+//	domain,evil.example,C2 domain,acme-intel,Campaign X,80,2026-12-31,apt;c2
+//
+// Tags are separated by semicolons.
+func parseCSV(data []byte, source string) ([]Indicator, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+	cols := make(map[string]int)
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if !csvColumns[h] {
+			return nil, fmt.Errorf("unknown CSV column %q", h)
+		}
+		cols[h] = i
+	}
+	if _, ok := cols["value"]; !ok {
+		return nil, fmt.Errorf("CSV header needs a value column")
+	}
+
+	var inds []Indicator
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := r.FieldPos(0)
+		field := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(rec) {
+				return strings.TrimSpace(rec[i])
+			}
+			return ""
+		}
+
+		ind := Indicator{
+			Type:        field("type"),
+			Value:       field("value"),
+			Name:        field("name"),
+			Description: field("description"),
+			Source:      field("source"),
+			Event:       field("event"),
+			EventID:     field("event_id"),
+			Confidence:  -1,
+		}
+		if ind.Value == "" {
+			continue
+		}
+		if ind.Type == "" {
+			ind.Type = GuessType(ind.Value)
+		}
+		if ind.Source == "" {
+			ind.Source = source
+		}
+		if c := field("confidence"); c != "" {
+			if ind.Confidence, err = strconv.Atoi(c); err != nil || ind.Confidence < 0 || ind.Confidence > 100 {
+				return nil, fmt.Errorf("line %d: confidence must be 0-100", line)
+			}
+		}
+		if v := field("valid_until"); v != "" {
+			if ind.ValidUntil, err = parseTime(v); err != nil {
+				return nil, fmt.Errorf("line %d: valid_until: %w", line, err)
+			}
+		}
+		for _, t := range strings.Split(field("tags"), ";") {
+			if t = strings.TrimSpace(t); t != "" {
+				ind.Tags = append(ind.Tags, t)
+			}
+		}
+
+		if ind, err = normalize(ind); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		inds = append(inds, ind)
+	}
+	return inds, nil
+}
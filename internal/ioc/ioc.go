@@ -0,0 +1,189 @@
+// Package ioc loads indicators of compromise from flat text lists, typed CSV,
+// STIX 2.1 bundles and MISP event exports, keeping the metadata of the feed
+// each indicator came from.
+package ioc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Indicator types
+const (
+	TypeIP       = "ip"
+	TypeDomain   = "domain"
+	TypeURL      = "url"
+	TypeHash     = "hash"
+	TypePath     = "path"
+	TypeFilename = "filename"
+	// TypeRegex is a Go regular expression matched against every string
+	TypeRegex = "regex"
+)
+
+// Indicator is one IOC with the metadata of the feed that supplied it
+type Indicator struct {
+	// Type is one of the Type constants. IP values may be CIDR ranges and
+	// path values may be globs.
+	Type  string
+	Value string
+	// Name is the indicator's own name or comment, if the feed has one
+	Name        string
+	Description string
+	// Source is the feed or producing organisation
+	Source string
+	// Event is the MISP event or STIX report the indicator belongs to
+	Event   string
+	EventID string
+	// Confidence is 0-100, or -1 when the feed does not say
+	Confidence int
+	ValidUntil time.Time
+	Tags       []string
+}
+
+// Expired reports whether the indicator's validity ended before now
+func (ind Indicator) Expired(now time.Time) bool {
+	return !ind.ValidUntil.IsZero() && now.After(ind.ValidUntil)
+}
+
+// Load reads indicators from path, detecting the format from the extension
+// and content: .csv is typed CSV, JSON with a "bundle" type is STIX 2.1,
+// JSON with an "Event" is MISP, anything else is a flat list. STIX
+// indicators that cannot be used are skipped and returned as errors.
+func Load(path string) ([]Indicator, []error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	source := filepath.Base(path)
+
+	var inds []Indicator
+	var skipped []error
+	switch {
+	case strings.EqualFold(filepath.Ext(path), ".csv"):
+		inds, err = parseCSV(data, source)
+	case looksLikeJSON(data):
+		var probe struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(data, &probe) == nil && probe.Type == "bundle" {
+			inds, skipped, err = parseSTIX(data, source)
+		} else {
+			inds, err = parseMISP(data, source)
+		}
+	default:
+		inds = parseText(data, source)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return inds, skipped, nil
+}
+
+func looksLikeJSON(data []byte) bool {
+	trimmed := bytes.TrimSpace(data)
+	return len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[')
+}
+
+var (
+	hashRegex   = regexp.MustCompile(`^[0-9a-fA-F]{32,128}$`)
+	domainRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?)+$`)
+)
+
+// parseText reads one indicator per line, guessing each type; lines
+// starting with # are comments
+func parseText(data []byte, source string) []Indicator {
+	var inds []Indicator
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		ind, err := normalize(Indicator{Type: GuessType(line), Value: line, Source: source, Confidence: -1})
+		if err == nil {
+			inds = append(inds, ind)
+		}
+	}
+	return inds
+}
+
+// GuessType infers the type of an untyped indicator
+func GuessType(v string) string {
+	switch {
+	case net.ParseIP(v) != nil:
+		return TypeIP
+	case strings.Contains(v, "/") && isCIDR(v):
+		return TypeIP
+	case hashRegex.MatchString(v):
+		return TypeHash
+	case strings.HasPrefix(v, "/"):
+		return TypePath
+	case strings.Contains(v, "://"):
+		return TypeURL
+	case domainRegex.MatchString(v):
+		return TypeDomain
+	}
+	// Try as domain anyway
+	return TypeDomain
+}
+
+func isCIDR(v string) bool {
+	_, err := netip.ParsePrefix(v)
+	return err == nil
+}
+
+// normalize brings values into the form the matcher compares: canonical
+// IPs and masked CIDR ranges, lowercase domains, hashes and URLs. Domains
+// lose a leading "*." since they match subdomains anyway. Invalid values
+// are rejected.
+func normalize(ind Indicator) (Indicator, error) {
+	ind.Type = strings.ToLower(strings.TrimSpace(ind.Type))
+	ind.Value = strings.TrimSpace(ind.Value)
+	if ind.Value == "" {
+		return ind, fmt.Errorf("empty %s indicator", ind.Type)
+	}
+
+	switch ind.Type {
+	case TypeIP:
+		if p, err := netip.ParsePrefix(ind.Value); err == nil {
+			ind.Value = p.Masked().String()
+		} else if addr, err := netip.ParseAddr(ind.Value); err == nil {
+			ind.Value = addr.Unmap().String()
+		} else {
+			return ind, fmt.Errorf("invalid IP address or CIDR range %q", ind.Value)
+		}
+	case TypeDomain:
+		ind.Value = strings.Trim(strings.TrimPrefix(strings.ToLower(ind.Value), "*."), ".")
+	case TypeHash:
+		ind.Value = strings.ToLower(ind.Value)
+		if !hashRegex.MatchString(ind.Value) {
+			return ind, fmt.Errorf("invalid hash %q", ind.Value)
+		}
+	case TypeURL:
+		ind.Value = strings.ToLower(ind.Value)
+	case TypePath:
+		if isGlob(ind.Value) {
+			if _, err := globRegexp(ind.Value); err != nil {
+				return ind, fmt.Errorf("invalid path glob %q: %w", ind.Value, err)
+			}
+		}
+	case TypeFilename:
+	case TypeRegex:
+		if _, err := regexp.Compile(ind.Value); err != nil {
+			return ind, fmt.Errorf("invalid regex %q: %w", ind.Value, err)
+		}
+	default:
+		return ind, fmt.Errorf("unknown indicator type %q", ind.Type)
+	}
+	if ind.Value == "" {
+		return ind, fmt.Errorf("empty %s indicator", ind.Type)
+	}
+	return ind, nil
+}
@@ -0,0 +1,195 @@
+package ioc
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadString writes src to a file named name and loads it
+func loadString(t *testing.T, name, src string) ([]Indicator, []error, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+// summary renders indicators as "type:value" for comparison
+func summary(inds []Indicator) string {
+	var parts []string
+	for _, ind := range inds {
+		parts = append(parts, ind.Type+":"+ind.Value)
+	}
+	return strings.Join(parts, " ")
+}
+
+const md5Hash = "d41d8cd98f00b204e9800998ecf8427e"
+
+func TestLoadText(t *testing.T) {
+	inds, _, err := loadString(t, "iocs.txt", `# comment
+1.2.3.4
+10.0.0.1/8
+`+strings.ToUpper(md5Hash)+`
+/tmp/payload
+https://Evil.example/x
+*.Evil.example
+bad/ip/1.2.3.4/99
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "ip:1.2.3.4 ip:10.0.0.0/8 hash:" + md5Hash + " path:/tmp/payload url:https://evil.example/x domain:evil.example domain:bad/ip/1.2.3.4/99"
+	if got := summary(inds); got != want {
+		t.Errorf("Load = %s\nwant   %s", got, want)
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    string
+		wantErr string
+	}{
+		{"typed", "type,value,confidence,valid_until,tags\nip,1.2.3.4,80,2026-12-31,apt; c2\n,evil.example,,,\n", "ip:1.2.3.4 domain:evil.example", ""},
+		{"comments and blanks", "value\n# skip\n\nevil.example\n", "domain:evil.example", ""},
+		{"header case", "Type , VALUE\ndomain,evil.example\n", "domain:evil.example", ""},
+		{"unknown column", "value,colour\nx,red\n", "", `unknown CSV column "colour"`},
+		{"no value column", "type,name\nip,x\n", "", "needs a value column"},
+		{"empty", "", "", "reading CSV header"},
+		{"bad confidence", "value,confidence\nevil.example,80\nevil.example,101\n", "", "line 3: confidence must be 0-100"},
+		// The reader's own line numbers hold across quoted multi-line fields
+		{"bad date", "value,name,valid_until\nevil.example,\"two\nlines\",2026-01-01\nevil.example,x,soon\n", "", "line 4: valid_until"},
+		{"bad value", "type,value\nip,1.2.3.4\nip,1.2.3.999\n", "", "line 3: invalid IP"},
+		{"bad type", "type,value\ncolour,red\n", "", `line 2: unknown indicator type "colour"`},
+		{"quote error", "value\n\"unterminated\n", "", "extraneous or missing"},
+	}
+	for _, tt := range tests {
+		inds, _, err := loadString(t, "iocs.csv", tt.src)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := summary(inds); got != tt.want {
+			t.Errorf("%s: Load = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	inds, _, err := loadString(t, "feed.CSV", "type,value,name,source,event,event_id,confidence,valid_until,tags\n"+
+		"domain,evil.example,C2,acme,Campaign X,ev-1,80,2026-12-31T12:00:00Z,apt; c2;\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Indicator{
+		Type: TypeDomain, Value: "evil.example", Name: "C2", Source: "acme", Event: "Campaign X", EventID: "ev-1",
+		Confidence: 80, ValidUntil: time.Date(2026, 12, 31, 12, 0, 0, 0, time.UTC), Tags: []string{"apt", "c2"},
+	}
+	if len(inds) != 1 || !reflect.DeepEqual(inds[0], want) {
+		t.Errorf("Load = %+v, want %+v", inds, want)
+	}
+}
+
+func TestLoadSTIX(t *testing.T) {
+	inds, skipped, err := loadString(t, "bundle.json", `{
+  "type": "bundle",
+  "objects": [
+    {"type": "identity", "id": "identity--1", "name": "Acme Intel"},
+    {"type": "report", "id": "report--1", "name": "Campaign X", "object_refs": ["indicator--or"]},
+    {"type": "indicator", "id": "indicator--or", "name": "C2", "created_by_ref": "identity--1",
+     "confidence": 70, "valid_until": "2027-01-01T00:00:00Z", "labels": ["c2"], "indicator_types": ["malicious-activity"],
+     "pattern": "[domain-name:value = 'Evil.example'] OR [ipv4-addr:value = '198.51.100.0/24'] OR [url:value = 'http://x/it\\'s']",
+     "pattern_type": "stix"},
+    {"type": "indicator", "id": "indicator--and",
+     "pattern": "[file:hashes.'SHA-256' = '`+strings.Repeat("ab", 32)+`' AND file:name = 'a.out' AND domain-name:value = 'and.example']"},
+    {"type": "indicator", "id": "indicator--seq",
+     "pattern": "[file:hashes.MD5 = '`+md5Hash+`'] FOLLOWEDBY [ipv4-addr:value = '192.0.2.1']"},
+    {"type": "indicator", "id": "indicator--quoted",
+     "pattern": "[file:name = '/tmp/AND FOLLOWEDBY'] OR [file:name = 'dropper']"},
+    {"type": "indicator", "id": "indicator--other",
+     "pattern": "[process:name = 'x'] OR [ipv4-addr:value = 'not-an-ip'] OR [ipv6-addr:value = '2001:db8::1']"},
+    {"type": "indicator", "id": "indicator--date", "valid_until": "next tuesday",
+     "pattern": "[domain-name:value = 'skipped.example']"},
+    {"type": "indicator", "id": "indicator--snort", "pattern_type": "snort", "pattern": "alert tcp any any"}
+  ]
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "domain:evil.example ip:198.51.100.0/24 url:http://x/it's hash:" + strings.Repeat("ab", 32) +
+		" hash:" + md5Hash + " path:/tmp/AND FOLLOWEDBY filename:dropper ip:2001:db8::1"
+	if got := summary(inds); got != want {
+		t.Errorf("Load = %s\nwant   %s", got, want)
+	}
+	if len(skipped) != 1 || !strings.Contains(skipped[0].Error(), "indicator--date: valid_until") {
+		t.Errorf("skipped = %v, want the malformed valid_until", skipped)
+	}
+
+	first := inds[0]
+	if first.Source != "Acme Intel" || first.Event != "Campaign X" || first.EventID != "report--1" ||
+		first.Confidence != 70 || !first.ValidUntil.Equal(time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)) ||
+		!reflect.DeepEqual(first.Tags, []string{"c2", "malicious-activity"}) {
+		t.Errorf("metadata = %+v", first)
+	}
+	if last := inds[len(inds)-1]; last.Source != "bundle.json" || last.Confidence != -1 {
+		t.Errorf("defaults = %+v", last)
+	}
+
+	if _, _, err := loadString(t, "bad.json", `{"type": "bundle", "objects": {}}`); err == nil || !strings.Contains(err.Error(), "decoding STIX bundle") {
+		t.Errorf("malformed bundle: err = %v", err)
+	}
+}
+
+func TestLoadMISP(t *testing.T) {
+	event := `{"id": "42", "info": "Phishing wave", "Orgc": {"name": "CERT"}, "Tag": [{"name": "tlp:amber"}],
+  "Attribute": [
+    {"type": "ip-dst", "value": "203.0.113.5", "to_ids": true, "comment": "C2", "Tag": [{"name": "c2"}]},
+    {"type": "domain", "value": "ignored.example", "to_ids": false},
+    {"type": "domain|ip", "value": "Evil.example|192.0.2.7", "to_ids": "1"},
+    {"type": "hostname|port", "value": "old.example|443", "to_ids": "0"},
+    {"type": "filename|md5", "value": "/tmp/x|` + md5Hash + `"},
+    {"type": "ip-src|port", "value": "198.51.100.1|8080", "to_ids": "yes please"},
+    {"type": "email-src", "value": "a@b.example", "to_ids": true},
+    {"type": "sha256", "value": "not-a-hash", "to_ids": true}
+  ],
+  "Object": [{"Attribute": [{"type": "filename", "value": "dropper", "to_ids": true}]}]}`
+	want := "ip:203.0.113.5 domain:evil.example ip:192.0.2.7 path:/tmp/x hash:" + md5Hash + " ip:198.51.100.1 filename:dropper"
+
+	for _, tt := range []struct{ name, src string }{
+		{"event", `{"Event": ` + event + `}`},
+		{"search", `{"response": [{"Event": ` + event + `}]}`},
+		{"list", `[{"Event": ` + event + `}]`},
+	} {
+		inds, _, err := loadString(t, "misp.json", tt.src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got := summary(inds); got != want {
+			t.Errorf("%s: Load = %s\nwant   %s", tt.name, got, want)
+			continue
+		}
+		first := inds[0]
+		if first.Source != "CERT" || first.Event != "Phishing wave" || first.EventID != "42" || first.Name != "C2" ||
+			!reflect.DeepEqual(first.Tags, []string{"tlp:amber", "c2"}) {
+			t.Errorf("%s: metadata = %+v", tt.name, first)
+		}
+		if !reflect.DeepEqual(inds[1].Tags, []string{"tlp:amber"}) {
+			t.Errorf("%s: attribute tags leak between attributes: %v", tt.name, inds[1].Tags)
+		}
+	}
+
+	if _, _, err := loadString(t, "misp.json", `{"Event": "nope"}`); err == nil || !strings.Contains(err.Error(), "not a STIX bundle or MISP") {
+		t.Errorf("malformed export: err = %v", err)
+	}
+}
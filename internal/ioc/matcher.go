@@ -0,0 +1,404 @@
+package ioc
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Matcher finds indicators in artifact data. URL, path and file name
+// literals are matched in one pass with an Aho-Corasick automaton; hashes,
+// IPs and domains are extracted from each string as tokens and looked up,
+// which also gives CIDR ranges and subdomain matching. Path globs and
+// regexes are evaluated one by one.
+//
+// Matches respect token boundaries: 1.2.3.4 does not match 11.2.3.45, a
+// hash does not match inside a longer hex string, and /tmp/x matches
+// /tmp/x/y but not /tmp/xy or /private/tmp/x.
+type Matcher struct {
+	indicators []Indicator
+
+	literals *automaton
+	// literalInds are the indicators of each automaton pattern
+	literalInds [][]int
+
+	hashes  map[string][]int
+	domains map[string][]int
+	ips     map[netip.Prefix][]int
+	// v4Bits and v6Bits are the prefix lengths present in ips
+	v4Bits, v6Bits []int
+
+	patterns []compiledPattern
+}
+
+type compiledPattern struct {
+	re  *regexp.Regexp
+	ind int
+}
+
+// Hit is an indicator found in a Data field. Field is the dotted path to
+// the value, with [i] for list elements.
+type Hit struct {
+	Indicator Indicator
+	Field     string
+}
+
+// NewMatcher builds a matcher for the indicators, normalizing them first
+func NewMatcher(inds []Indicator) (*Matcher, error) {
+	m := &Matcher{
+		hashes:  make(map[string][]int),
+		domains: make(map[string][]int),
+		ips:     make(map[netip.Prefix][]int),
+	}
+
+	var literals []string
+	literalIDs := make(map[string]int)
+	v4Bits := make(map[int]bool)
+	v6Bits := make(map[int]bool)
+
+	for _, ind := range inds {
+		ind, err := normalize(ind)
+		if err != nil {
+			return nil, err
+		}
+		i := len(m.indicators)
+		m.indicators = append(m.indicators, ind)
+
+		switch {
+		case ind.Type == TypeHash:
+			m.hashes[ind.Value] = append(m.hashes[ind.Value], i)
+		case ind.Type == TypeDomain:
+			m.domains[ind.Value] = append(m.domains[ind.Value], i)
+		case ind.Type == TypeIP:
+			p, err := netip.ParsePrefix(ind.Value)
+			if err != nil {
+				addr := netip.MustParseAddr(ind.Value)
+				p = netip.PrefixFrom(addr, addr.BitLen())
+			}
+			m.ips[p] = append(m.ips[p], i)
+			if p.Addr().Is4() {
+				v4Bits[p.Bits()] = true
+			} else {
+				v6Bits[p.Bits()] = true
+			}
+		case ind.Type == TypeRegex:
+			m.patterns = append(m.patterns, compiledPattern{regexp.MustCompile(ind.Value), i})
+		case ind.Type == TypePath && isGlob(ind.Value):
+			re, _ := globRegexp(ind.Value)
+			m.patterns = append(m.patterns, compiledPattern{re, i})
+		default:
+			key := string(asciiLower(ind.Value))
+			id, ok := literalIDs[key]
+			if !ok {
+				id = len(literals)
+				literalIDs[key] = id
+				literals = append(literals, key)
+				m.literalInds = append(m.literalInds, nil)
+			}
+			m.literalInds[id] = append(m.literalInds[id], i)
+		}
+	}
+
+	m.literals = newAutomaton(literals)
+	m.v4Bits = sortedBits(v4Bits)
+	m.v6Bits = sortedBits(v6Bits)
+	return m, nil
+}
+
+func sortedBits(set map[int]bool) []int {
+	bits := make([]int, 0, len(set))
+	for b := range set {
+		bits = append(bits, b)
+	}
+	sort.Ints(bits)
+	return bits
+}
+
+// Len returns the number of indicators
+func (m *Matcher) Len() int { return len(m.indicators) }
+
+// Stats describes the matcher's index sizes
+func (m *Matcher) Stats() string {
+	return fmt.Sprintf("%d literals (%d automaton nodes), %d hashes, %d domains, %d IPs/ranges, %d globs/regexes",
+		len(m.literalInds), m.literals.nodes(), len(m.hashes), len(m.domains), len(m.ips), len(m.patterns))
+}
+
+// FileHashField is the Hit field of a match on an artifact's file hash
+const FileHashField = "metadata.file_hash"
+
+// Match returns the indicators found in an artifact: hash indicators equal
+// to fileHash (the artifact's Metadata.FileHash, may be empty), then
+// indicators in the string values of data, including values nested in maps
+// and lists. Each indicator is reported once, for the first field (in key
+// order) it is found in, and hits are in indicator order.
+func (m *Matcher) Match(data map[string]interface{}, fileHash string) []Hit {
+	found := make(map[int]string)
+	if fileHash != "" {
+		for _, i := range m.hashes[strings.ToLower(fileHash)] {
+			found[i] = FileHashField
+		}
+	}
+	m.walk(data, "", found)
+	if len(found) == 0 {
+		return nil
+	}
+
+	idx := make([]int, 0, len(found))
+	for i := range found {
+		idx = append(idx, i)
+	}
+	sort.Ints(idx)
+	hits := make([]Hit, len(idx))
+	for n, i := range idx {
+		hits[n] = Hit{Indicator: m.indicators[i], Field: found[i]}
+	}
+	return hits
+}
+
+// walk matches every string under v; field is the path to v
+func (m *Matcher) walk(v interface{}, field string, found map[int]string) {
+	switch v := v.(type) {
+	case string:
+		m.matchString(v, field, found)
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			m.walk(v[k], joinField(field, k), found)
+		}
+	case map[string]string:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			m.matchString(v[k], joinField(field, k), found)
+		}
+	case []interface{}:
+		for i, e := range v {
+			m.walk(e, field+"["+strconv.Itoa(i)+"]", found)
+		}
+	case []string:
+		for i, e := range v {
+			m.matchString(e, field+"["+strconv.Itoa(i)+"]", found)
+		}
+	case []map[string]interface{}:
+		for i, e := range v {
+			m.walk(e, field+"["+strconv.Itoa(i)+"]", found)
+		}
+	}
+}
+
+func sortedKeys(d map[string]interface{}) []string {
+	keys := make([]string, 0, len(d))
+	for k := range d {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinField(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func (m *Matcher) matchString(s, field string, found map[int]string) {
+	if s == "" {
+		return
+	}
+	hit := func(i int) {
+		if _, ok := found[i]; !ok {
+			found[i] = field
+		}
+	}
+	lower := asciiLower(s)
+
+	if len(m.literalInds) > 0 {
+		m.literals.scan(lower, func(p, end int) {
+			start := end - len(m.literals.patterns[p])
+			if !leftBoundary(lower, start) || !rightBoundary(lower, end) {
+				return
+			}
+			for _, i := range m.literalInds[p] {
+				// Paths and file names are case-sensitive
+				ind := m.indicators[i]
+				if (ind.Type == TypePath || ind.Type == TypeFilename) && s[start:end] != ind.Value {
+					continue
+				}
+				hit(i)
+			}
+		})
+	}
+
+	if len(m.hashes) > 0 {
+		eachRun(lower, isAlnum, func(tok string) {
+			if len(tok) >= 32 {
+				for _, i := range m.hashes[tok] {
+					hit(i)
+				}
+			}
+		})
+	}
+
+	if len(m.domains) > 0 {
+		eachRun(lower, isHostByte, func(tok string) {
+			tok = strings.Trim(tok, ".-")
+			for {
+				for _, i := range m.domains[tok] {
+					hit(i)
+				}
+				dot := strings.IndexByte(tok, '.')
+				if dot < 0 {
+					break
+				}
+				tok = tok[dot+1:]
+			}
+		})
+	}
+
+	if len(m.ips) > 0 {
+		// IPv4 addresses are runs of digits and dots, so 11.2.3.45 is never
+		// read as 1.2.3.4; IPv6 addresses need at least two colons
+		eachRun(lower, isIPv4Byte, func(tok string) {
+			if addr, err := netip.ParseAddr(strings.TrimRight(tok, ".")); err == nil {
+				m.matchAddr(addr, hit)
+			}
+		})
+		eachRun(lower, isIPv6Byte, func(tok string) {
+			if strings.Count(tok, ":") < 2 {
+				return
+			}
+			if addr, err := netip.ParseAddr(tok); err == nil {
+				m.matchAddr(addr.Unmap(), hit)
+			}
+		})
+	}
+
+	for _, p := range m.patterns {
+		if _, ok := found[p.ind]; !ok && p.re.MatchString(s) {
+			hit(p.ind)
+		}
+	}
+}
+
+// matchAddr looks an address up in the IPs and every CIDR range length
+func (m *Matcher) matchAddr(addr netip.Addr, hit func(int)) {
+	bits := m.v6Bits
+	if addr.Is4() {
+		bits = m.v4Bits
+	}
+	for _, b := range bits {
+		p, err := addr.Prefix(b)
+		if err != nil {
+			continue
+		}
+		for _, i := range m.ips[p] {
+			hit(i)
+		}
+	}
+}
+
+// eachRun calls fn with every maximal run of bytes accepted by in
+func eachRun(s []byte, in func(byte) bool, fn func(string)) {
+	start := -1
+	for i, c := range s {
+		if in(c) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			fn(string(s[start:i]))
+			start = -1
+		}
+	}
+	if start >= 0 {
+		fn(string(s[start:]))
+	}
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func isHostByte(c byte) bool { return isAlnum(c) || c == '.' || c == '-' }
+
+func isIPv4Byte(c byte) bool { return c >= '0' && c <= '9' || c == '.' }
+
+func isIPv6Byte(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c == '.' || c == ':'
+}
+
+// isToken reports whether c continues a word, file name or host name
+func isToken(c byte) bool { return isAlnum(c) || c == '-' || c == '_' || c == '.' }
+
+// leftBoundary reports whether a literal starting at start is not the tail
+// of a longer token
+func leftBoundary(s []byte, start int) bool {
+	return start == 0 || !isToken(s[start-1])
+}
+
+// rightBoundary reports whether a literal ending at end is not the head of
+// a longer token. A literal ending in a separator (such as a path ending in
+// /) needs no boundary, and a trailing full stop does not count as a token.
+func rightBoundary(s []byte, end int) bool {
+	if end == len(s) || !isToken(s[end-1]) || !isToken(s[end]) {
+		return true
+	}
+	return s[end] == '.' && (end+1 == len(s) || !isToken(s[end+1]))
+}
+
+// asciiLower lowercases ASCII letters only, so byte offsets are unchanged
+func asciiLower(s string) []byte {
+	b := []byte(s)
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			b[i] = c + 'a' - 'A'
+		}
+	}
+	return b
+}
+
+func isGlob(v string) bool { return strings.ContainsAny(v, "*?[") }
+
+// globRegexp compiles a path glob: * matches within one path element, **
+// across elements, ? one character and [...] a character class. The glob
+// must start and end on token boundaries, like literal paths.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString(`(?:^|[^\w.\-])`)
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(`.*`)
+				i++
+			} else {
+				b.WriteString(`[^/]*`)
+			}
+		case '?':
+			b.WriteString(`[^/]`)
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [")
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString(`(?:$|[^\w\-])`)
+	return regexp.Compile(b.String())
+}
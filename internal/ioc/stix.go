@@ -0,0 +1,158 @@
+package ioc
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+type stixObject struct {
+	Type          string   `json:"type"`
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Description   string   `json:"description"`
+	Pattern       string   `json:"pattern"`
+	PatternType   string   `json:"pattern_type"`
+	Confidence    *int     `json:"confidence"`
+	ValidUntil    string   `json:"valid_until"`
+	Labels        []string `json:"labels"`
+	CreatedByRef  string   `json:"created_by_ref"`
+	ObjectRefs    []string `json:"object_refs"`
+	IndicatorType []string `json:"indicator_types"`
+}
+
+// stixComparison matches one equality comparison of a STIX pattern, e.g.
+// [file:hashes.'SHA-256' = '...'] or [domain-name:value = 'evil.example']
+var stixComparison = regexp.MustCompile(`(ipv4-addr|ipv6-addr|domain-name|url|file):([\w.'\-]+)\s*=\s*'((?:[^'\\]|\\.)*)'`)
+
+// parseSTIX reads the indicators of a STIX 2.1 bundle. Each equality
+// comparison on an IP, domain, URL, file hash or file name in a stix
+// pattern becomes an indicator; other comparisons are ignored. Indicators
+// are matched on their own, which only holds for patterns joined by OR: of
+// a pattern using AND or FOLLOWEDBY only the file hashes are kept, as they
+// identify a file by themselves. The source is the identity that created
+// the indicator and the event is the report that references it. Indicators
+// with an unreadable valid_until are skipped and returned as errors.
+func parseSTIX(data []byte, source string) ([]Indicator, []error, error) {
+	var bundle struct {
+		Objects []stixObject `json:"objects"`
+	}
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, nil, fmt.Errorf("decoding STIX bundle: %w", err)
+	}
+
+	identities := make(map[string]string)
+	reports := make(map[string]stixObject)
+	for _, o := range bundle.Objects {
+		switch o.Type {
+		case "identity":
+			identities[o.ID] = o.Name
+		case "report", "grouping":
+			for _, ref := range o.ObjectRefs {
+				if _, ok := reports[ref]; !ok {
+					reports[ref] = o
+				}
+			}
+		}
+	}
+
+	var inds []Indicator
+	var skipped []error
+	for _, o := range bundle.Objects {
+		if o.Type != "indicator" || (o.PatternType != "" && o.PatternType != "stix") {
+			continue
+		}
+		base := Indicator{
+			Name:        o.Name,
+			Description: o.Description,
+			Source:      source,
+			Confidence:  -1,
+			Tags:        append(append([]string(nil), o.Labels...), o.IndicatorType...),
+		}
+		if name := identities[o.CreatedByRef]; name != "" {
+			base.Source = name
+		}
+		if r, ok := reports[o.ID]; ok {
+			base.Event = r.Name
+			base.EventID = r.ID
+		}
+		if o.Confidence != nil {
+			base.Confidence = *o.Confidence
+		}
+		if o.ValidUntil != "" {
+			t, err := parseTime(o.ValidUntil)
+			if err != nil {
+				skipped = append(skipped, fmt.Errorf("indicator %s: valid_until: %w", o.ID, err))
+				continue
+			}
+			base.ValidUntil = t
+		}
+
+		conjunctive := stixConjunctive(o.Pattern)
+		for _, m := range stixComparison.FindAllStringSubmatch(o.Pattern, -1) {
+			ind := base
+			ind.Value = strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace(m[3])
+			switch obj, prop := m[1], m[2]; {
+			case obj == "ipv4-addr" || obj == "ipv6-addr":
+				ind.Type = TypeIP
+			case obj == "domain-name":
+				ind.Type = TypeDomain
+			case obj == "url":
+				ind.Type = TypeURL
+			case obj == "file" && strings.HasPrefix(prop, "hashes."):
+				ind.Type = TypeHash
+			case obj == "file" && prop == "name":
+				ind.Type = TypeFilename
+				if strings.HasPrefix(ind.Value, "/") {
+					ind.Type = TypePath
+				}
+			default:
+				continue
+			}
+			if conjunctive && ind.Type != TypeHash {
+				continue
+			}
+			ind, err := normalize(ind)
+			if err != nil {
+				continue
+			}
+			inds = append(inds, ind)
+		}
+	}
+	return inds, skipped, nil
+}
+
+// stixConjunctive reports whether a pattern joins comparisons or
+// observations with AND or FOLLOWEDBY, looking outside quoted strings
+func stixConjunctive(pattern string) bool {
+	var outside strings.Builder
+	quoted := false
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case quoted && c == '\\':
+			i++
+		case c == '\'':
+			quoted = !quoted
+			outside.WriteByte(' ')
+		case !quoted:
+			outside.WriteByte(c)
+		}
+	}
+	brackets := strings.NewReplacer("[", " ", "]", " ", "(", " ", ")", " ")
+	for _, f := range strings.Fields(brackets.Replace(outside.String())) {
+		if strings.EqualFold(f, "AND") || strings.EqualFold(f, "FOLLOWEDBY") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTime accepts RFC 3339 timestamps and plain dates
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
@@ -4,6 +4,10 @@ import "time"
 
 // Artifact represents a collected forensic artifact
 type Artifact struct {
+	// ID is the artifacts.db row ID of artifacts loaded back from a database,
+	// or the row ID collect numbers artifacts with before analysis so
+	// analyzer-created artifacts can refer to them; zero otherwise
+	ID           int64                  `json:"-"`
 	Timestamp    time.Time              `json:"timestamp"`
 	CollectorID  string                 `json:"collector_id"`
 	ArtifactType string                 `json:"artifact_type"`
@@ -14,8 +18,109 @@ type Artifact struct {
 	RiskScore    int                    `json:"risk_score,omitempty"`
 	Tags         []string               `json:"tags,omitempty"`
 	EventTime    *time.Time             `json:"event_time,omitempty"`
+	IOCMatches   []IOCMatch             `json:"ioc_matches,omitempty"`
+	Techniques   []string               `json:"techniques,omitempty"`
+	// Timestamps are the times the collector read from the source, most
+	// significant first
+	Timestamps []Timestamp `json:"timestamps,omitempty"`
+	// Contributions are the rules that make up RiskScore
+	Contributions []ScoreContribution `json:"score_contributions,omitempty"`
+	// Suppression is set when a suppression rule marked the artifact as
+	// expected
+	Suppression *Suppression `json:"suppression,omitempty"`
 }
 
+// TimestampKind says what a timestamp records
+type TimestampKind string
+
+// Timestamp kinds. Modified, accessed, changed (inode change) and created
+// (birth) are the MACB times of a file.
+const (
+	TimeModified   TimestampKind = "modified"
+	TimeAccessed   TimestampKind = "accessed"
+	TimeChanged    TimestampKind = "changed"
+	TimeCreated    TimestampKind = "created"
+	TimeFirstSeen  TimestampKind = "first_seen"
+	TimeLastSeen   TimestampKind = "last_seen"
+	TimeStarted    TimestampKind = "started"
+	TimeEnded      TimestampKind = "ended"
+	TimeDownloaded TimestampKind = "downloaded"
+)
+
+// Timestamp is one typed time of an artifact
+type Timestamp struct {
+	Kind TimestampKind `json:"kind"`
+	Time time.Time     `json:"time"`
+	// Source names what the time belongs to when an artifact describes
+	// more than one file, such as a launch agent's plist and target
+	Source string `json:"source,omitempty"`
+}
+
+// AddTimestamp records a time of kind, skipping zero times
+func (a *Artifact) AddTimestamp(kind TimestampKind, source string, t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	a.Timestamps = append(a.Timestamps, Timestamp{Kind: kind, Time: t, Source: source})
+}
+
+// When returns when the artifact's event happened: EventTime if the
+// collector set one, else its first timestamp, else the collection time
+func (a *Artifact) When() time.Time {
+	if a.EventTime != nil {
+		return *a.EventTime
+	}
+	if len(a.Timestamps) > 0 {
+		return a.Timestamps[0].Time
+	}
+	return a.Timestamp
+}
+
+// FindingScore is the lowest risk score reported as a finding
+const FindingScore = 40
+
+// IsFinding reports whether the artifact scored at least FindingScore and
+// was not suppressed
+func (a *Artifact) IsFinding() bool {
+	return a.RiskScore >= FindingScore && a.Suppression == nil
+}
+
+// Suppression records the rule that suppressed an artifact and why
+type Suppression struct {
+	ID            string     `json:"id"`
+	Justification string     `json:"justification"`
+	Expires       *time.Time `json:"expires,omitempty"`
+}
+
+// ScoreContribution is one analyzer rule's share of an artifact's risk
+// score, with the data field and value that triggered it
+type ScoreContribution struct {
+	Analyzer string `json:"analyzer"`
+	Rule     string `json:"rule"`
+	Points   int    `json:"points"`
+	Field    string `json:"field,omitempty"`
+	Value    string `json:"value,omitempty"`
+}
+
+// IOCMatch records which indicator matched an artifact, in which Data field,
+// and the feed and event the indicator came from
+type IOCMatch struct {
+	Type       string     `json:"type"`
+	Value      string     `json:"value"`
+	Field      string     `json:"field"`
+	Name       string     `json:"name,omitempty"`
+	Source     string     `json:"source,omitempty"`
+	Event      string     `json:"event,omitempty"`
+	EventID    string     `json:"event_id,omitempty"`
+	Confidence int        `json:"confidence"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+}
+
+// AnalysisCollectorID is the CollectorID of artifacts created by analyzers
+// rather than collectors, such as YARA matches. Re-running analysis replaces
+// them.
+const AnalysisCollectorID = "analysis"
+
 // ArtifactMetadata contains collection metadata
 type ArtifactMetadata struct {
 	Success      bool   `json:"success"`
@@ -33,18 +138,27 @@ type CollectionResult struct {
 	Error       error
 	Duration    time.Duration
 	StartedAt   time.Time
+	SkipReason  string
 }
 
-// SeverityInfo returns a human-readable severity level from risk score
+// Lowest risk score of each severity
+const (
+	CriticalScore = 80
+	HighScore     = 60
+	MediumScore   = FindingScore
+	LowScore      = 20
+)
+
+// SeverityFromScore returns a human-readable severity level from risk score
 func SeverityFromScore(score int) string {
 	switch {
-	case score >= 80:
+	case score >= CriticalScore:
 		return "critical"
-	case score >= 60:
+	case score >= HighScore:
 		return "high"
-	case score >= 40:
+	case score >= MediumScore:
 		return "medium"
-	case score >= 20:
+	case score >= LowScore:
 		return "low"
 	default:
 		return "info"
@@ -0,0 +1,37 @@
+package models
+
+// Entity is a running process joined with the artifacts that refer to it:
+// its parent, network connections, open network files and the persistence
+// entries that launch its executable
+type Entity struct {
+	PID        int    `json:"pid"`
+	PPID       int    `json:"ppid"`
+	Name       string `json:"name"`
+	Exe        string `json:"exe,omitempty"`
+	User       string `json:"user,omitempty"`
+	Cmdline    string `json:"cmdline,omitempty"`
+	CreateTime string `json:"create_time,omitempty"`
+	ParentName string `json:"parent_name,omitempty"`
+	ParentExe  string `json:"parent_exe,omitempty"`
+	// Signature is "signed", "adhoc" or "unsigned", empty when the
+	// executable's signature wasn't read
+	Signature string `json:"signature,omitempty"`
+	TeamID    string `json:"team_id,omitempty"`
+	SigningID string `json:"signing_id,omitempty"`
+	// RiskScore is the highest score of the process and its connections;
+	// Tags are theirs combined
+	RiskScore       int               `json:"risk_score"`
+	Tags            []string          `json:"tags,omitempty"`
+	Connections     int               `json:"connections"`
+	RemoteEndpoints []string          `json:"remote_endpoints,omitempty"`
+	ListenAddrs     []string          `json:"listen_addrs,omitempty"`
+	OpenFiles       []string          `json:"open_files,omitempty"`
+	Persistence     []EntityReference `json:"persistence,omitempty"`
+}
+
+// EntityReference is a persistence artifact that runs an entity's executable
+type EntityReference struct {
+	ArtifactType string `json:"artifact_type"`
+	Name         string `json:"name"`
+	Path         string `json:"path,omitempty"`
+}
@@ -0,0 +1,416 @@
+package normalize
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/analysis"
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// ecsVersion is the ECS release the mappings follow
+const ecsVersion = "8.11.0"
+
+// ecsMapping places one artifact type's data in ECS
+type ecsMapping struct {
+	category []string
+	typ      []string
+	fields   []field
+	// binaries are data key prefixes of hashes and code signatures
+	// (exe_, target_, ...) and the ECS object they describe
+	binaries []binary
+	extra    func(d Document, data map[string]interface{})
+}
+
+type binary struct {
+	prefix string
+	object string
+}
+
+var (
+	ecsLaunchd = ecsMapping{
+		category: []string{"configuration"},
+		typ:      []string{"info"},
+		fields: []field{
+			str("path", "file.path"), str("name", "file.name"), str("mod_time", "file.mtime"),
+			num("size", "file.size"), str("target_path", "process.executable"), str("user", "user.name"),
+		},
+		binaries: []binary{{"plist_", "file"}, {"target_", "process"}},
+		extra: func(d Document, data map[string]interface{}) {
+			d.Set("process.command_line", commandLine(data))
+		},
+	}
+	ecsLoginItem = ecsMapping{
+		category: []string{"configuration"},
+		typ:      []string{"info"},
+		fields: []field{
+			str("path", "file.path"), str("executable_path", "process.executable"),
+			str("team_id", "process.code_signature.team_id"), str("user", "user.name"),
+		},
+	}
+	ecsCron = ecsMapping{
+		category: []string{"configuration"},
+		typ:      []string{"info"},
+		fields:   []field{str("path", "file.path"), str("entry", "process.command_line"), str("user", "user.name")},
+	}
+	ecsSSH = ecsMapping{
+		category: []string{"configuration"},
+		typ:      []string{"info"},
+		fields: []field{
+			str("path", "file.path"), num("size", "file.size"), str("mod_time", "file.mtime"),
+			str("user", "user.name"),
+		},
+		binaries: []binary{{"file_", "file"}},
+	}
+	ecsWeb = ecsMapping{
+		category: []string{"web"},
+		typ:      []string{"access"},
+		fields:   []field{str("user", "user.name")},
+		extra: func(d Document, data map[string]interface{}) {
+			setECSURL(d, analysis.GetString(data, "url"))
+		},
+	}
+	ecsShell = ecsMapping{
+		category: []string{"process"},
+		typ:      []string{"start"},
+		fields:   []field{str("command", "process.command_line"), str("user", "user.name")},
+	}
+	ecsConnection = ecsMapping{
+		category: []string{"network"},
+		typ:      []string{"connection"},
+		fields: []field{
+			num("pid", "process.pid"), str("process_name", "process.name"), str("command", "process.name"),
+			str("process_exe", "process.executable"), str("process_user", "user.name"), str("user", "user.name"),
+		},
+		extra: func(d Document, data map[string]interface{}) {
+			localIP, localPort, remoteIP, remotePort := connection(data)
+			d.Set("source.ip", localIP)
+			d.Set("source.port", localPort)
+			d.Set("destination.ip", remoteIP)
+			d.Set("destination.port", remotePort)
+			proto, version := protocol(data)
+			d.Set("network.transport", proto)
+			if version != 0 {
+				d.Set("network.type", fmt.Sprintf("ipv%d", version))
+			}
+		},
+	}
+	ecsUnifiedLog = ecsMapping{
+		category: []string{"host"},
+		typ:      []string{"info"},
+		fields: []field{
+			num("pid", "process.pid"), str("process", "process.name"), str("process_path", "process.executable"),
+			str("message_type", "log.level"), str("subsystem", "log.logger"), str("event_message", "message"),
+		},
+	}
+	ecsCrashReport = ecsMapping{
+		category: []string{"process"},
+		typ:      []string{"end"},
+		fields: []field{
+			str("path", "file.path"), str("filename", "file.name"), str("mod_time", "file.mtime"),
+			num("size", "file.size"),
+		},
+	}
+	ecsExtension = ecsMapping{
+		category: []string{"driver"},
+		typ:      []string{"info"},
+		fields: []field{
+			str("path", "file.path"), str("name", "file.name"), str("identifier", "file.name"),
+			str("mod_time", "file.mtime"), str("version", "package.version"),
+		},
+		binaries: []binary{{"executable_", "file"}},
+	}
+	ecsApplication = ecsMapping{
+		category: []string{"package"},
+		typ:      []string{"info"},
+		fields:   []field{str("name", "package.name"), str("path", "package.path"), str("path", "file.path")},
+		binaries: []binary{{"executable_", "file"}},
+	}
+	ecsConfig = ecsMapping{
+		category: []string{"configuration"},
+		typ:      []string{"info"},
+		fields:   []field{str("user", "user.name")},
+	}
+)
+
+// ecsMappings maps each artifact type to ECS. Types not listed get a host
+// info event with only the common fields.
+var ecsMappings = map[string]ecsMapping{
+	"running_process": {
+		category: []string{"process"},
+		typ:      []string{"info"},
+		fields: []field{
+			num("pid", "process.pid"), num("ppid", "process.parent.pid"), str("name", "process.name"),
+			str("exe", "process.executable"), str("cmdline", "process.command_line"),
+			str("cwd", "process.working_directory"), str("create_time", "process.start"),
+			str("username", "user.name"),
+		},
+		binaries: []binary{{"exe_", "process"}},
+	},
+	"network_connection": ecsConnection,
+	"open_network_file":  ecsConnection,
+	"arp_entry": {
+		category: []string{"network"},
+		typ:      []string{"info"},
+		fields:   []field{str("ip", "destination.ip"), str("mac", "destination.mac")},
+	},
+	"network_interface": {
+		category: []string{"host"},
+		typ:      []string{"info"},
+		fields:   []field{str("mac", "host.mac")},
+	},
+	"routing_table_entry": {category: []string{"network"}, typ: []string{"info"}},
+	"dns_config":          {category: []string{"network"}, typ: []string{"info"}},
+	"dns_config_scutil":   {category: []string{"network"}, typ: []string{"info"}},
+
+	"safari_history": ecsWeb,
+	"chrome_history": ecsWeb,
+	"quarantine_event": {
+		category: []string{"web"},
+		typ:      []string{"access"},
+		fields: []field{
+			str("origin_url", "http.request.referrer"), str("agent_name", "process.name"),
+			str("event_id", "event.id"), str("user", "user.name"),
+		},
+		extra: func(d Document, data map[string]interface{}) {
+			setECSURL(d, analysis.GetString(data, "data_url"))
+		},
+	},
+	"bash_history": ecsShell,
+	"zsh_history":  ecsShell,
+	"recent_file": {
+		category: []string{"file"},
+		typ:      []string{"access"},
+		fields: []field{
+			str("path", "file.path"), str("name", "file.name"), num("size", "file.size"),
+			str("mod_time", "file.mtime"), str("user", "user.name"),
+		},
+	},
+	"fs_event": {
+		category: []string{"file"},
+		typ:      []string{"change"},
+		fields:   []field{str("raw_event", "event.original")},
+	},
+	"app_usage": {
+		category: []string{"process"},
+		typ:      []string{"info"},
+		fields: []field{
+			str("app_name", "process.name"), str("start_time", "event.start"), str("end_time", "event.end"),
+			str("user", "user.name"),
+		},
+		extra: func(d Document, data map[string]interface{}) {
+			if s, ok := asInt(data["duration_seconds"]).(int64); ok {
+				d.Set("event.duration", s*int64(time.Second))
+			}
+		},
+	},
+
+	"user_launch_agent":          ecsLaunchd,
+	"system_launch_agent":        ecsLaunchd,
+	"system_launch_daemon":       ecsLaunchd,
+	"login_item_btm":             ecsLoginItem,
+	"login_item_backgrounditems": ecsLoginItem,
+	"system_cron":                ecsCron,
+	"user_crontab":               ecsCron,
+	"at_job":                     ecsCron,
+
+	"user_account": {
+		category: []string{"iam"},
+		typ:      []string{"user", "info"},
+		fields: []field{
+			str("username", "user.name"), str("uid", "user.id"), str("real_name", "user.full_name"),
+		},
+	},
+	"ssh_private_key":    ecsSSH,
+	"ssh_public_key":     ecsSSH,
+	"ssh_authorized_key": ecsSSH,
+	"ssh_known_host":     ecsSSH,
+	"ssh_config":         ecsSSH,
+	"tcc_permission":     ecsConfig,
+
+	"kernel_extension":   ecsExtension,
+	"system_extension":   ecsExtension,
+	"library_extension":  ecsExtension,
+	"system_application": ecsApplication,
+	"user_application":   ecsApplication,
+
+	"env_variable":            ecsConfig,
+	"env_variable_suspicious": ecsConfig,
+	"gatekeeper_status":       ecsConfig,
+	"sip_status":              ecsConfig,
+	"firewall_status":         ecsConfig,
+	"filevault_status":        ecsConfig,
+	"apfs_encryption":         ecsConfig,
+	"xprotect_version":        ecsConfig,
+	"system_info": {
+		category: []string{"host"},
+		typ:      []string{"info"},
+		fields: []field{
+			str("macos_version", "host.os.version"), str("kernel_version", "host.os.kernel"),
+			str("architecture", "host.architecture"), num("uptime_seconds", "host.uptime"),
+		},
+	},
+
+	"unified_log_security": ecsUnifiedLog,
+	"unified_log_network": {
+		category: []string{"network"},
+		typ:      []string{"info"},
+		fields:   ecsUnifiedLog.fields,
+	},
+	"unified_log_process": {
+		category: []string{"process"},
+		typ:      []string{"info"},
+		fields:   ecsUnifiedLog.fields,
+	},
+	"unified_log_errors":  ecsUnifiedLog,
+	"user_crash_report":   ecsCrashReport,
+	"system_crash_report": ecsCrashReport,
+	"install_log": {
+		category: []string{"package"},
+		typ:      []string{"installation"},
+		fields:   []field{str("path", "file.path")},
+	},
+
+	"yara_match": {
+		category: []string{"malware"},
+		typ:      []string{"info"},
+		fields:   []field{str("rule", "rule.name"), str("file_path", "file.path")},
+	},
+}
+
+// ecsSeverity is event.severity for each risk level
+var ecsSeverity = map[string]int{
+	"info":     0,
+	"low":      21,
+	"medium":   47,
+	"high":     73,
+	"critical": 99,
+}
+
+func (m *Mapper) ecs(a models.Artifact, eventTime time.Time, msg string) Document {
+	d := Document{}
+	d.Set("@timestamp", eventTime.UTC().Format(time.RFC3339Nano))
+	d.Set("message", msg)
+	d.Set("ecs.version", ecsVersion)
+	d.Set("agent.type", "triagectl")
+	d.Set("agent.version", m.version)
+	d.Set("host.hostname", a.Hostname)
+	d.Set("host.name", a.Hostname)
+	d.Set("host.os.type", "macos")
+	d.Set("log.file.path", a.Metadata.SourcePath)
+
+	mapping, ok := ecsMappings[a.ArtifactType]
+	if !ok {
+		mapping = ecsMapping{category: []string{"host"}, typ: []string{"info"}}
+	}
+	kind := "event"
+	if a.IsFinding() {
+		kind = "alert"
+	}
+	d.Set("event.kind", kind)
+	d.Set("event.category", mapping.category)
+	d.Set("event.type", mapping.typ)
+	d.Set("event.action", a.ArtifactType)
+	d.Set("event.module", "triagectl")
+	d.Set("event.dataset", "triagectl."+a.CollectorID)
+	d.Set("event.created", a.Timestamp.UTC().Format(time.RFC3339Nano))
+	if a.RiskScore > 0 {
+		d.Set("event.risk_score", a.RiskScore)
+		d.Set("event.severity", ecsSeverity[a.Severity])
+	}
+
+	copyFields(d, a.Data, mapping.fields)
+	for _, b := range mapping.binaries {
+		setECSBinary(d, a.Data, b)
+	}
+	if mapping.extra != nil {
+		mapping.extra(d, a.Data)
+	}
+
+	d.Set("tags", a.Tags)
+	if len(a.Techniques) > 0 {
+		d.Set("threat.framework", "MITRE ATT&CK")
+		d.Set("threat.technique.id", a.Techniques)
+	}
+	if len(a.IOCMatches) > 0 {
+		var enrichments []interface{}
+		for _, im := range a.IOCMatches {
+			e := Document{}
+			e.Set("matched.atomic", im.Value)
+			e.Set("matched.field", im.Field)
+			e.Set("matched.type", "indicator_match_rule")
+			e.Set("indicator.type", ecsIndicatorType(im))
+			e.Set("indicator.provider", im.Source)
+			e.Set("indicator.description", im.Name)
+			enrichments = append(enrichments, e)
+		}
+		d.Set("threat.enrichments", enrichments)
+	}
+
+	// Everything triagectl knows, in its own namespace
+	d.Set("triagectl.artifact_type", a.ArtifactType)
+	d.Set("triagectl.collector_id", a.CollectorID)
+	d.Set("triagectl.severity", a.Severity)
+	if len(a.Timestamps) > 0 {
+		d.Set("triagectl.timestamps", a.Timestamps)
+	}
+	if len(a.Contributions) > 0 {
+		d.Set("triagectl.score_contributions", a.Contributions)
+	}
+	if a.Suppression != nil {
+		d.Set("triagectl.suppression", a.Suppression)
+	}
+	// Data keys differ in type between artifact types (pid is a number on a
+	// process, a string on an lsof open file), so each type gets its own
+	// namespace for Elasticsearch's dynamic mapping
+	d.Set("triagectl."+a.ArtifactType, a.Data)
+	return d
+}
+
+// setECSURL fills url.* from a URL string
+func setECSURL(d Document, s string) {
+	d.Set("url.original", s)
+	u := parseURL(s)
+	if u == nil {
+		return
+	}
+	d.Set("url.full", u.String())
+	d.Set("url.scheme", u.Scheme)
+	d.Set("url.domain", u.Hostname())
+	d.Set("url.path", u.Path)
+	d.Set("url.query", u.RawQuery)
+	d.Set("url.port", asInt(u.Port()))
+}
+
+// setECSBinary fills <object>.hash and <object>.code_signature from the
+// hashes and signature collectors attached under prefix
+func setECSBinary(d Document, data map[string]interface{}, b binary) {
+	for _, alg := range []string{"md5", "sha1", "sha256"} {
+		d.Set(b.object+".hash."+alg, analysis.GetString(data, b.prefix+alg))
+	}
+	if signed, ok := data[b.prefix+"signed"].(bool); ok {
+		d.Set(b.object+".code_signature.exists", signed)
+		d.Set(b.object+".code_signature.team_id", analysis.GetString(data, b.prefix+"team_id"))
+		d.Set(b.object+".code_signature.signing_id", analysis.GetString(data, b.prefix+"signing_id"))
+		d.Set(b.object+".code_signature.subject_name", signer(data, b.prefix))
+	}
+}
+
+// ecsIndicatorType maps an IOC type to threat.indicator.type
+func ecsIndicatorType(m models.IOCMatch) string {
+	switch m.Type {
+	case "ip":
+		if strings.Contains(m.Value, ":") {
+			return "ipv6-addr"
+		}
+		return "ipv4-addr"
+	case "domain":
+		return "domain-name"
+	case "url":
+		return "url"
+	case "hash", "path", "filename":
+		return "file"
+	}
+	return "unknown"
+}
@@ -0,0 +1,253 @@
+// Package normalize maps artifacts onto common schemas for SIEM ingestion:
+// the Elastic Common Schema (ECS) and the Open Cybersecurity Schema
+// Framework (OCSF). Collectors name their Data keys after their source
+// (remote_addr, exe, visit_time); per-type tables move them into the
+// schema's fields, and the original data is kept alongside.
+package normalize
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/analysis"
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// Supported schemas
+const (
+	SchemaECS  = "ecs"
+	SchemaOCSF = "ocsf"
+)
+
+// Document is a normalized artifact, with dotted schema field names
+// expanded into nested objects
+type Document map[string]interface{}
+
+// Set stores v at a dotted path, creating objects on the way. Empty
+// strings, nil values and empty lists are skipped.
+func (d Document) Set(path string, v interface{}) {
+	switch x := v.(type) {
+	case nil:
+		return
+	case string:
+		if x == "" {
+			return
+		}
+	case []interface{}:
+		if len(x) == 0 {
+			return
+		}
+	case []string:
+		if len(x) == 0 {
+			return
+		}
+	}
+	m := d
+	parts := strings.Split(path, ".")
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := m[p].(Document)
+		if !ok {
+			next = Document{}
+			m[p] = next
+		}
+		m = next
+	}
+	m[parts[len(parts)-1]] = v
+}
+
+// Mapper normalizes artifacts into one schema
+type Mapper struct {
+	schema    string
+	version   string
+	summarize func(models.Artifact) string
+}
+
+// NewMapper returns a mapper for schema. version is the triagectl version
+// recorded as the producing product; summarize, if set, provides each
+// document's message.
+func NewMapper(schema, version string, summarize func(models.Artifact) string) (*Mapper, error) {
+	switch schema {
+	case SchemaECS, SchemaOCSF:
+	default:
+		return nil, fmt.Errorf("unknown schema %q (want %s or %s)", schema, SchemaECS, SchemaOCSF)
+	}
+	return &Mapper{schema: schema, version: version, summarize: summarize}, nil
+}
+
+// Schema returns the schema the mapper produces
+func (m *Mapper) Schema() string { return m.schema }
+
+// Normalize maps an artifact that happened at eventTime to a document
+func (m *Mapper) Normalize(a models.Artifact, eventTime time.Time) Document {
+	var msg string
+	if m.summarize != nil {
+		msg = m.summarize(a)
+	}
+	if m.schema == SchemaOCSF {
+		return m.ocsf(a, eventTime, msg)
+	}
+	return m.ecs(a, eventTime, msg)
+}
+
+// field copies the Data key to a schema field, converted by conv
+type field struct {
+	key  string
+	path string
+	conv func(interface{}) interface{}
+}
+
+func str(key, path string) field { return field{key, path, nil} }
+func num(key, path string) field { return field{key, path, asInt} }
+
+func copyFields(d Document, data map[string]interface{}, fields []field) {
+	for _, f := range fields {
+		v, ok := data[f.key]
+		if !ok {
+			continue
+		}
+		if f.conv != nil {
+			v = f.conv(v)
+		}
+		d.Set(f.path, v)
+	}
+}
+
+// asInt converts the numbers and numeric strings collectors and SQLite
+// round trips produce to int64, or nil
+func asInt(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int:
+		return int64(n)
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case uint32:
+		return int64(n)
+	case uint64:
+		return int64(n)
+	case float64:
+		return int64(n)
+	case string:
+		if i, err := strconv.ParseInt(strings.TrimSpace(n), 10, 64); err == nil {
+			return i
+		}
+	}
+	return nil
+}
+
+// protocol returns the transport (tcp, udp) and IP version (4, 6, or 0
+// when unknown) of a connection: lsof's NODE and TYPE columns for open
+// network files, gopsutil's socket type and address family otherwise
+// (AF_INET6 is 30 on macOS and 10 on Linux)
+func protocol(data map[string]interface{}) (string, int) {
+	if node, ok := data["node"].(string); ok {
+		switch strings.ToLower(analysis.GetString(data, "type")) {
+		case "ipv4":
+			return strings.ToLower(node), 4
+		case "ipv6":
+			return strings.ToLower(node), 6
+		}
+		return strings.ToLower(node), 0
+	}
+
+	var transport string
+	switch asInt(data["type"]) {
+	case int64(1):
+		transport = "tcp"
+	case int64(2):
+		transport = "udp"
+	}
+	switch asInt(data["family"]) {
+	case int64(2):
+		return transport, 4
+	case int64(10), int64(30):
+		return transport, 6
+	}
+	return transport, 0
+}
+
+// splitAddr splits an lsof address (10.0.0.5:443, [::1]:53, *:22) into IP
+// and port; a wildcard host is returned empty
+func splitAddr(addr string) (string, interface{}) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", nil
+	}
+	if host == "*" {
+		host = ""
+	}
+	return host, asInt(port)
+}
+
+// connection returns the local and remote endpoints of a network_connection
+// or open_network_file artifact
+func connection(data map[string]interface{}) (localIP string, localPort interface{}, remoteIP string, remotePort interface{}) {
+	if _, ok := data["local_port"]; ok {
+		return analysis.GetString(data, "local_addr"), asInt(data["local_port"]),
+			analysis.GetString(data, "remote_addr"), asInt(data["remote_port"])
+	}
+	if listen := analysis.GetString(data, "listen_addr"); listen != "" {
+		localIP, localPort = splitAddr(listen)
+		return localIP, localPort, "", nil
+	}
+	localIP, localPort = splitAddr(analysis.GetString(data, "local_addr"))
+	remoteIP, remotePort = splitAddr(analysis.GetString(data, "remote_addr"))
+	return localIP, localPort, remoteIP, remotePort
+}
+
+// listening reports whether a connection artifact is a listening socket
+func listening(data map[string]interface{}) bool {
+	state := analysis.GetString(data, "status")
+	if state == "" {
+		state = analysis.GetString(data, "state")
+	}
+	return strings.EqualFold(state, "LISTEN") || analysis.GetString(data, "listen_addr") != ""
+}
+
+// parseURL returns the URL in s, or nil when s is not an absolute URL
+func parseURL(s string) *url.URL {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme == "" {
+		return nil
+	}
+	return u
+}
+
+// signer returns the leaf certificate's subject of a signature collectors
+// attached under prefix
+func signer(data map[string]interface{}, prefix string) string {
+	switch v := data[prefix+"signers"].(type) {
+	case []interface{}:
+		if len(v) > 0 {
+			s, _ := v[0].(string)
+			return s
+		}
+	case []string:
+		if len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+// commandLine joins launchd ProgramArguments, or returns Program
+func commandLine(data map[string]interface{}) string {
+	var args []string
+	switch v := data["program_arguments"].(type) {
+	case []interface{}:
+		for _, a := range v {
+			args = append(args, fmt.Sprint(a))
+		}
+	case []string:
+		args = v
+	}
+	if len(args) > 0 {
+		return strings.Join(args, " ")
+	}
+	return analysis.GetString(data, "program")
+}
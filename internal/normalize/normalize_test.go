@@ -0,0 +1,297 @@
+package normalize
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// get reads a dotted path from a document, or nil
+func get(d Document, path string) interface{} {
+	parts := strings.Split(path, ".")
+	for _, p := range parts[:len(parts)-1] {
+		next, ok := d[p].(Document)
+		if !ok {
+			return nil
+		}
+		d = next
+	}
+	return d[parts[len(parts)-1]]
+}
+
+func normalize(t *testing.T, schema string, a models.Artifact) Document {
+	t.Helper()
+	m, err := NewMapper(schema, "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m.Normalize(a, time.Date(2026, 2, 6, 19, 1, 1, 0, time.UTC))
+}
+
+func artifact(artifactType string, data map[string]interface{}) models.Artifact {
+	return models.Artifact{CollectorID: "test", ArtifactType: artifactType, Hostname: "host", Data: data}
+}
+
+// mappingCase is an artifact and the schema fields it must produce; a nil
+// value means the field must be absent
+type mappingCase struct {
+	name string
+	art  models.Artifact
+	want map[string]interface{}
+}
+
+func checkMapping(t *testing.T, schema string, tests []mappingCase) {
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := normalize(t, schema, tt.art)
+			for path, want := range tt.want {
+				if got := get(d, path); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %#v, want %#v", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+// Connections come from gopsutil (numeric socket type and family, split
+// address and port) or from lsof (NODE and TYPE columns, host:port
+// strings). SQLite round trips turn the numbers into float64.
+var (
+	gopsutilTCP4 = artifact("network_connection", map[string]interface{}{
+		"pid": float64(42), "process_name": "curl", "process_exe": "/usr/bin/curl", "process_user": "alice",
+		"local_addr": "10.0.0.5", "local_port": float64(50000), "remote_addr": "203.0.113.1", "remote_port": float64(443),
+		"type": float64(1), "family": float64(2), "status": "ESTABLISHED",
+	})
+	gopsutilUDP6 = artifact("network_connection", map[string]interface{}{
+		"pid": 7, "local_addr": "::", "local_port": 5353, "remote_addr": "", "remote_port": 0,
+		"type": 2, "family": 30,
+	})
+	gopsutilListen = artifact("network_connection", map[string]interface{}{
+		"pid": 9, "local_addr": "0.0.0.0", "local_port": 22, "type": 1, "family": 2, "status": "LISTEN",
+	})
+	lsofTCP6 = artifact("open_network_file", map[string]interface{}{
+		"pid": "123", "command": "Safari", "user": "alice", "type": "IPv6", "node": "TCP",
+		"local_addr": "[::1]:53000", "remote_addr": "[2001:db8::1]:443", "state": "ESTABLISHED",
+	})
+	lsofListen = artifact("open_network_file", map[string]interface{}{
+		"pid": "88", "command": "sshd", "type": "IPv4", "node": "TCP", "listen_addr": "*:22", "state": "LISTEN",
+	})
+	lsofUDP = artifact("open_network_file", map[string]interface{}{
+		"pid": "90", "command": "mDNSResponder", "type": "IPv4", "node": "UDP", "local_addr": "*:5353",
+	})
+)
+
+func TestECSMappings(t *testing.T) {
+	finding := artifact("running_process", map[string]interface{}{"pid": 1, "name": "x"})
+	finding.RiskScore, finding.Severity = 85, "critical"
+	finding.Techniques = []string{"T1059.004"}
+
+	checkMapping(t, SchemaECS, []mappingCase{
+		{"process", artifact("running_process", map[string]interface{}{
+			"pid": float64(42), "ppid": float64(1), "name": "sh", "exe": "/bin/sh", "cmdline": "sh -c id",
+			"cwd": "/tmp", "username": "root", "exe_sha256": "abcd", "exe_signed": true,
+			"exe_team_id": "TEAM", "exe_signing_id": "com.apple.sh", "exe_signers": []interface{}{"Apple"},
+		}), map[string]interface{}{
+			"process.pid": int64(42), "process.parent.pid": int64(1), "process.name": "sh",
+			"process.executable": "/bin/sh", "process.command_line": "sh -c id", "process.working_directory": "/tmp",
+			"user.name": "root", "process.hash.sha256": "abcd", "process.code_signature.exists": true,
+			"process.code_signature.team_id": "TEAM", "process.code_signature.signing_id": "com.apple.sh",
+			"process.code_signature.subject_name": "Apple", "event.kind": "event",
+			"event.category": []string{"process"}, "event.action": "running_process", "event.risk_score": nil,
+		}},
+		{"finding", finding, map[string]interface{}{
+			"event.kind": "alert", "event.risk_score": 85, "event.severity": 99,
+			"threat.technique.id": []string{"T1059.004"}, "threat.framework": "MITRE ATT&CK",
+		}},
+		{"gopsutil tcp4 from SQLite", gopsutilTCP4, map[string]interface{}{
+			"source.ip": "10.0.0.5", "source.port": int64(50000), "destination.ip": "203.0.113.1",
+			"destination.port": int64(443), "network.transport": "tcp", "network.type": "ipv4",
+			"process.pid": int64(42), "process.name": "curl", "process.executable": "/usr/bin/curl", "user.name": "alice",
+			"event.category": []string{"network"},
+		}},
+		{"gopsutil udp6", gopsutilUDP6, map[string]interface{}{
+			"source.ip": "::", "source.port": int64(5353), "destination.ip": nil, "destination.port": int64(0),
+			"network.transport": "udp", "network.type": "ipv6",
+		}},
+		{"lsof tcp6", lsofTCP6, map[string]interface{}{
+			"source.ip": "::1", "source.port": int64(53000), "destination.ip": "2001:db8::1",
+			"destination.port": int64(443), "network.transport": "tcp", "network.type": "ipv6",
+			"process.pid": int64(123), "process.name": "Safari", "user.name": "alice",
+		}},
+		{"lsof listen", lsofListen, map[string]interface{}{
+			"source.ip": nil, "source.port": int64(22), "destination.ip": nil, "destination.port": nil,
+			"network.transport": "tcp", "network.type": "ipv4",
+		}},
+		{"lsof udp without version", lsofUDP, map[string]interface{}{
+			"source.port": int64(5353), "network.transport": "udp", "network.type": "ipv4",
+		}},
+		{"browser visit", artifact("safari_history", map[string]interface{}{
+			"url": "https://example.com:8443/login?next=1", "user": "alice",
+		}), map[string]interface{}{
+			"url.original": "https://example.com:8443/login?next=1", "url.scheme": "https", "url.domain": "example.com",
+			"url.port": int64(8443), "url.path": "/login", "url.query": "next=1", "user.name": "alice",
+			"event.category": []string{"web"},
+		}},
+		{"browser visit without a host", artifact("chrome_history", map[string]interface{}{"url": "about:blank"}), map[string]interface{}{
+			"url.original": "about:blank", "url.scheme": "about", "url.domain": nil,
+		}},
+		{"quarantine event", artifact("quarantine_event", map[string]interface{}{
+			"data_url": "https://dl.example.com/x.dmg", "origin_url": "https://example.com/", "agent_name": "Safari",
+		}), map[string]interface{}{
+			"url.domain": "dl.example.com", "http.request.referrer": "https://example.com/", "process.name": "Safari",
+		}},
+		{"launch agent", artifact("user_launch_agent", map[string]interface{}{
+			"path": "/Users/a/Library/LaunchAgents/x.plist", "name": "x.plist", "size": float64(512),
+			"target_path": "/tmp/x", "program_arguments": []interface{}{"/tmp/x", "--daemon"},
+			"plist_sha256": "1111", "target_sha256": "2222", "target_signed": false,
+		}), map[string]interface{}{
+			"file.path": "/Users/a/Library/LaunchAgents/x.plist", "file.size": int64(512),
+			"process.executable": "/tmp/x", "process.command_line": "/tmp/x --daemon",
+			"file.hash.sha256": "1111", "process.hash.sha256": "2222", "process.code_signature.exists": false,
+			"event.category": []string{"configuration"},
+		}},
+		{"launch daemon with Program only", artifact("system_launch_daemon", map[string]interface{}{
+			"program": "/usr/local/bin/d",
+		}), map[string]interface{}{"process.command_line": "/usr/local/bin/d"}},
+		{"cron", artifact("user_crontab", map[string]interface{}{"entry": "* * * * * /tmp/x", "user": "bob"}), map[string]interface{}{
+			"process.command_line": "* * * * * /tmp/x", "user.name": "bob",
+		}},
+		{"app usage from SQLite", artifact("app_usage", map[string]interface{}{
+			"app_name": "Terminal", "duration_seconds": float64(90),
+		}), map[string]interface{}{"process.name": "Terminal", "event.duration": int64(90 * time.Second)}},
+		{"system info", artifact("system_info", map[string]interface{}{
+			"macos_version": "15.2", "uptime_seconds": float64(3600),
+		}), map[string]interface{}{"host.os.version": "15.2", "host.uptime": int64(3600)}},
+		{"yara match", artifact("yara_match", map[string]interface{}{"rule": "Evil", "file_path": "/tmp/x"}), map[string]interface{}{
+			"rule.name": "Evil", "file.path": "/tmp/x", "event.category": []string{"malware"},
+		}},
+		{"unmapped type", artifact("something_new", map[string]interface{}{"k": "v"}), map[string]interface{}{
+			"event.category": []string{"host"}, "event.type": []string{"info"},
+		}},
+	})
+}
+
+func TestOCSFMappings(t *testing.T) {
+	finding := artifact("yara_match", map[string]interface{}{"rule": "Evil", "file_path": "/tmp/x"})
+	finding.RiskScore, finding.Severity = 70, "high"
+	finding.Techniques = []string{"T1204"}
+
+	checkMapping(t, SchemaOCSF, []mappingCase{
+		{"process", artifact("running_process", map[string]interface{}{
+			"pid": float64(42), "ppid": float64(1), "name": "sh", "exe": "/bin/sh", "cmdline": "sh -c id",
+			"username": "root", "exe_sha256": "abcd", "exe_signed": true, "exe_team_id": "TEAM",
+		}), map[string]interface{}{
+			"class_uid": 1007, "activity_id": 1, "type_uid": 100701, "category_uid": 1,
+			"process.pid": int64(42), "process.parent_process.pid": int64(1), "process.name": "sh",
+			"process.file.path": "/bin/sh", "process.cmd_line": "sh -c id", "process.user.name": "root",
+			"process.file.signature.developer_uid": "TEAM", "severity_id": 1, "time": int64(1770404461000),
+			"process.file.hashes": []interface{}{Document{"algorithm_id": 3, "algorithm": "SHA-256", "value": "abcd"}},
+		}},
+		{"finding", finding, map[string]interface{}{
+			"class_uid": 2004, "severity_id": 4, "severity": "High",
+			"finding_info.title": "Evil", "evidences.file.path": "/tmp/x", "finding_info.types": []string{"YARA"},
+			"unmapped.risk_score": 70, "unmapped.finding": true,
+			"finding_info.attacks": []interface{}{Document{"technique": Document{"uid": "T1204"}, "version": "ATT&CK"}},
+		}},
+		{"gopsutil tcp4 from SQLite", gopsutilTCP4, map[string]interface{}{
+			"class_uid": 4001, "activity_id": 1, "type_uid": 400101,
+			"src_endpoint.ip": "10.0.0.5", "src_endpoint.port": int64(50000),
+			"dst_endpoint.ip": "203.0.113.1", "dst_endpoint.port": int64(443),
+			"connection_info.protocol_name": "tcp", "connection_info.protocol_ver_id": 4,
+			"actor.process.pid": int64(42), "actor.process.name": "curl",
+			"actor.process.file.path": "/usr/bin/curl", "actor.user.name": "alice",
+		}},
+		{"gopsutil udp6", gopsutilUDP6, map[string]interface{}{
+			"connection_info.protocol_name": "udp", "connection_info.protocol_ver_id": 6,
+		}},
+		{"gopsutil listen", gopsutilListen, map[string]interface{}{
+			"activity_id": 7, "activity_name": "Listen", "type_uid": 400107, "type_name": "Network Activity: Listen",
+		}},
+		{"lsof tcp6", lsofTCP6, map[string]interface{}{
+			"src_endpoint.ip": "::1", "src_endpoint.port": int64(53000), "dst_endpoint.ip": "2001:db8::1",
+			"dst_endpoint.port": int64(443), "connection_info.protocol_name": "tcp",
+			"connection_info.protocol_ver_id": 6, "actor.process.pid": int64(123), "activity_id": 1,
+		}},
+		{"lsof listen", lsofListen, map[string]interface{}{
+			"src_endpoint.ip": nil, "src_endpoint.port": int64(22), "dst_endpoint": nil,
+			"activity_id": 7, "type_uid": 400107, "connection_info.protocol_ver_id": 4,
+		}},
+		{"lsof udp without version", lsofUDP, map[string]interface{}{
+			"connection_info.protocol_name": "udp", "src_endpoint.port": int64(5353),
+		}},
+		{"browser visit", artifact("safari_history", map[string]interface{}{
+			"url": "https://example.com:8443/login?next=1",
+		}), map[string]interface{}{
+			"class_uid": 4002, "http_request.url.hostname": "example.com", "http_request.url.port": int64(8443),
+			"http_request.url.path": "/login", "http_request.url.query_string": "next=1",
+		}},
+		{"launch agent", artifact("user_launch_agent", map[string]interface{}{
+			"label": "com.x", "path": "/L/x.plist", "target_path": "/tmp/x",
+			"program_arguments": []interface{}{"/tmp/x", "--daemon"}, "plist_sha256": "1111",
+		}), map[string]interface{}{
+			"class_uid": 1006, "job.name": "com.x", "job.file.path": "/L/x.plist", "job.cmd_line": "/tmp/x --daemon",
+			"job.file.hashes": []interface{}{Document{"algorithm_id": 3, "algorithm": "SHA-256", "value": "1111"}},
+		}},
+		{"launch agent without arguments", artifact("user_launch_agent", map[string]interface{}{
+			"target_path": "/tmp/x",
+		}), map[string]interface{}{"job.cmd_line": "/tmp/x"}},
+		{"kext loads", artifact("kernel_extension", map[string]interface{}{"name": "com.k", "version": "1.0"}), map[string]interface{}{
+			"class_uid": 1002, "activity_id": 1, "driver.file.name": "com.k", "driver.file.version": "1.0",
+		}},
+		{"system extension", artifact("system_extension", map[string]interface{}{"identifier": "com.s"}), map[string]interface{}{
+			"class_uid": 1002, "activity_id": 99, "driver.file.name": "com.s",
+		}},
+		{"app usage from SQLite", artifact("app_usage", map[string]interface{}{
+			"app_name": "Terminal", "duration_seconds": float64(90),
+		}), map[string]interface{}{"process.name": "Terminal", "duration": int64(90000)}},
+		{"user account", artifact("user_account", map[string]interface{}{"username": "alice", "real_name": "Alice"}), map[string]interface{}{
+			"class_uid": 5003, "user.name": "alice", "user.full_name": "Alice",
+		}},
+		{"unmapped type", artifact("something_new", map[string]interface{}{"k": "v"}), map[string]interface{}{
+			"class_uid": 0, "activity_id": 99, "type_uid": 99, "unmapped.data": map[string]interface{}{"k": "v"},
+		}},
+	})
+}
+
+func TestAsInt(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want interface{}
+	}{
+		{42, int64(42)},
+		{int32(42), int64(42)},
+		{uint64(42), int64(42)},
+		{float64(42), int64(42)},
+		{" 42 ", int64(42)},
+		{"4x", nil},
+		{nil, nil},
+		{true, nil},
+	}
+	for _, tt := range tests {
+		if got := asInt(tt.in); got != tt.want {
+			t.Errorf("asInt(%#v) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDocumentSet(t *testing.T) {
+	d := Document{}
+	d.Set("a.b.c", 1)
+	d.Set("a.b.d", "x")
+	d.Set("a.empty", "")
+	d.Set("a.none", nil)
+	d.Set("a.list", []string{})
+	want := Document{"a": Document{"b": Document{"c": 1, "d": "x"}}}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("document = %#v, want %#v", d, want)
+	}
+}
+
+func TestNewMapperRejectsUnknownSchema(t *testing.T) {
+	if _, err := NewMapper("cef", "test", nil); err == nil {
+		t.Error("NewMapper(cef) succeeded")
+	}
+}
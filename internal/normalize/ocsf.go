@@ -0,0 +1,486 @@
+package normalize
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/analysis"
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// ocsfVersion is the OCSF schema release the mappings follow
+const ocsfVersion = "1.1.0"
+
+// ocsfClass is an OCSF event class and the category it belongs to
+type ocsfClass struct {
+	uid          int
+	name         string
+	categoryUID  int
+	categoryName string
+}
+
+var (
+	ocsfBase            = ocsfClass{0, "Base Event", 0, "Uncategorized"}
+	ocsfFileActivity    = ocsfClass{1001, "File System Activity", 1, "System Activity"}
+	ocsfKernelExtension = ocsfClass{1002, "Kernel Extension Activity", 1, "System Activity"}
+	ocsfScheduledJob    = ocsfClass{1006, "Scheduled Job Activity", 1, "System Activity"}
+	ocsfProcess         = ocsfClass{1007, "Process Activity", 1, "System Activity"}
+	ocsfDetection       = ocsfClass{2004, "Detection Finding", 2, "Findings"}
+	ocsfNetwork         = ocsfClass{4001, "Network Activity", 4, "Network Activity"}
+	ocsfHTTP            = ocsfClass{4002, "HTTP Activity", 4, "Network Activity"}
+	ocsfDeviceInventory = ocsfClass{5001, "Device Inventory Info", 5, "Discovery"}
+	ocsfDeviceConfig    = ocsfClass{5002, "Device Config State", 5, "Discovery"}
+	ocsfUserInventory   = ocsfClass{5003, "User Inventory Info", 5, "Discovery"}
+)
+
+// ocsfActivity is an activity_id and its name within a class
+type ocsfActivity struct {
+	id   int
+	name string
+}
+
+var (
+	activityOther     = ocsfActivity{99, "Other"}
+	activityCreate    = ocsfActivity{1, "Create"}
+	activityRead      = ocsfActivity{2, "Read"}
+	activityUpdate    = ocsfActivity{3, "Update"}
+	activityLaunch    = ocsfActivity{1, "Launch"}
+	activityTerminate = ocsfActivity{2, "Terminate"}
+	activityOpen      = ocsfActivity{1, "Open"}
+	activityListen    = ocsfActivity{7, "Listen"}
+	activityGet       = ocsfActivity{3, "Get"}
+	activityLoad      = ocsfActivity{1, "Load"}
+	activityCollect   = ocsfActivity{2, "Collect"}
+)
+
+// ocsfMapping places one artifact type's data in an OCSF class
+type ocsfMapping struct {
+	class    ocsfClass
+	activity ocsfActivity
+	fields   []field
+	// binaries are data key prefixes of hashes and code signatures and
+	// the OCSF file object they describe
+	binaries []binary
+	extra    func(d Document, data map[string]interface{})
+}
+
+var (
+	ocsfLaunchd = ocsfMapping{
+		class:    ocsfScheduledJob,
+		activity: activityCreate,
+		fields: []field{
+			str("label", "job.name"), str("path", "job.file.path"), str("name", "job.file.name"),
+			str("mod_time", "job.file.modified_time_dt"), num("size", "job.file.size"),
+			str("target_path", "job.cmd_line"), str("user", "actor.user.name"),
+		},
+		binaries: []binary{{"plist_", "job.file"}},
+		extra: func(d Document, data map[string]interface{}) {
+			d.Set("job.cmd_line", commandLine(data))
+		},
+	}
+	ocsfLoginItem = ocsfMapping{
+		class:    ocsfScheduledJob,
+		activity: activityCreate,
+		fields: []field{
+			str("name", "job.name"), str("path", "job.file.path"), str("executable_path", "job.cmd_line"),
+			str("team_id", "job.file.signature.developer_uid"), str("user", "actor.user.name"),
+		},
+	}
+	ocsfCron = ocsfMapping{
+		class:    ocsfScheduledJob,
+		activity: activityCreate,
+		fields: []field{
+			str("entry", "job.cmd_line"), str("path", "job.name"), str("file", "job.name"), str("path", "job.file.path"),
+			str("user", "actor.user.name"),
+		},
+	}
+	ocsfSSH = ocsfMapping{
+		class:    ocsfFileActivity,
+		activity: activityRead,
+		fields: []field{
+			str("path", "file.path"), num("size", "file.size"), str("mod_time", "file.modified_time_dt"),
+			str("user", "actor.user.name"),
+		},
+		binaries: []binary{{"file_", "file"}},
+	}
+	ocsfWeb = ocsfMapping{
+		class:    ocsfHTTP,
+		activity: activityGet,
+		fields:   []field{str("user", "actor.user.name")},
+		extra: func(d Document, data map[string]interface{}) {
+			setOCSFURL(d, analysis.GetString(data, "url"))
+		},
+	}
+	ocsfShell = ocsfMapping{
+		class:    ocsfProcess,
+		activity: activityLaunch,
+		fields:   []field{str("command", "process.cmd_line"), str("user", "actor.user.name")},
+	}
+	ocsfConnection = ocsfMapping{
+		class:    ocsfNetwork,
+		activity: activityOpen,
+		fields: []field{
+			num("pid", "actor.process.pid"), str("process_name", "actor.process.name"), str("command", "actor.process.name"),
+			str("process_exe", "actor.process.file.path"), str("process_user", "actor.user.name"), str("user", "actor.user.name"),
+		},
+		extra: func(d Document, data map[string]interface{}) {
+			localIP, localPort, remoteIP, remotePort := connection(data)
+			d.Set("src_endpoint.ip", localIP)
+			d.Set("src_endpoint.port", localPort)
+			d.Set("dst_endpoint.ip", remoteIP)
+			d.Set("dst_endpoint.port", remotePort)
+			proto, version := protocol(data)
+			d.Set("connection_info.protocol_name", proto)
+			if version != 0 {
+				d.Set("connection_info.protocol_ver_id", version)
+			}
+			if listening(data) {
+				d.Set("activity_id", activityListen.id)
+				d.Set("activity_name", activityListen.name)
+			}
+		},
+	}
+	ocsfUnifiedLog = []field{
+		num("pid", "actor.process.pid"), str("process", "actor.process.name"),
+		str("process_path", "actor.process.file.path"), str("event_message", "message"),
+	}
+	ocsfCrashReport = ocsfMapping{
+		class:    ocsfProcess,
+		activity: activityTerminate,
+		fields: []field{
+			str("filename", "unmapped.report.name"), str("path", "unmapped.report.path"),
+			str("mod_time", "unmapped.report.modified_time_dt"),
+		},
+	}
+	ocsfExtension = ocsfMapping{
+		class:    ocsfKernelExtension,
+		activity: activityOther,
+		fields: []field{
+			str("path", "driver.file.path"), str("name", "driver.file.name"), str("identifier", "driver.file.name"),
+			str("mod_time", "driver.file.modified_time_dt"), str("version", "driver.file.version"),
+		},
+		binaries: []binary{{"executable_", "driver.file"}},
+	}
+	ocsfApplication = ocsfMapping{
+		class:    ocsfDeviceInventory,
+		activity: activityCollect,
+		fields: []field{
+			str("name", "unmapped.application.name"), str("path", "unmapped.application.path"),
+			str("version", "unmapped.application.version"),
+		},
+	}
+	ocsfConfig = ocsfMapping{
+		class:    ocsfDeviceConfig,
+		activity: activityCollect,
+		fields:   []field{str("user", "actor.user.name")},
+	}
+	ocsfInventory = ocsfMapping{class: ocsfDeviceInventory, activity: activityCollect}
+)
+
+// ocsfMappings maps each artifact type to an OCSF class. Types not listed
+// become base events.
+var ocsfMappings = map[string]ocsfMapping{
+	"running_process": {
+		class:    ocsfProcess,
+		activity: activityLaunch,
+		fields: []field{
+			num("pid", "process.pid"), num("ppid", "process.parent_process.pid"), str("name", "process.name"),
+			str("exe", "process.file.path"), str("cmdline", "process.cmd_line"),
+			str("create_time", "process.created_time_dt"), str("username", "process.user.name"),
+		},
+		binaries: []binary{{"exe_", "process.file"}},
+	},
+	"network_connection": ocsfConnection,
+	"open_network_file":  ocsfConnection,
+	"arp_entry": {
+		class:    ocsfDeviceInventory,
+		activity: activityCollect,
+		fields:   []field{str("ip", "unmapped.neighbor.ip"), str("mac", "unmapped.neighbor.mac")},
+	},
+	"network_interface": {
+		class:    ocsfDeviceInventory,
+		activity: activityCollect,
+		fields:   []field{str("mac", "device.mac")},
+	},
+	"routing_table_entry": ocsfInventory,
+	"dns_config":          ocsfInventory,
+	"dns_config_scutil":   ocsfInventory,
+
+	"safari_history": ocsfWeb,
+	"chrome_history": ocsfWeb,
+	"quarantine_event": {
+		class:    ocsfHTTP,
+		activity: activityGet,
+		fields: []field{
+			str("origin_url", "http_request.referrer"), str("agent_name", "http_request.user_agent"),
+			str("event_id", "metadata.uid"), str("user", "actor.user.name"),
+		},
+		extra: func(d Document, data map[string]interface{}) {
+			setOCSFURL(d, analysis.GetString(data, "data_url"))
+		},
+	},
+	"bash_history": ocsfShell,
+	"zsh_history":  ocsfShell,
+	"recent_file": {
+		class:    ocsfFileActivity,
+		activity: activityRead,
+		fields: []field{
+			str("path", "file.path"), str("name", "file.name"), num("size", "file.size"),
+			str("mod_time", "file.modified_time_dt"), str("user", "actor.user.name"),
+		},
+	},
+	"fs_event": {
+		class:    ocsfFileActivity,
+		activity: activityUpdate,
+		fields:   []field{str("raw_event", "raw_data")},
+	},
+	"app_usage": {
+		class:    ocsfProcess,
+		activity: activityLaunch,
+		fields: []field{
+			str("app_name", "process.name"), str("start_time", "start_time_dt"), str("end_time", "end_time_dt"),
+			str("user", "actor.user.name"),
+		},
+		extra: func(d Document, data map[string]interface{}) {
+			if s, ok := asInt(data["duration_seconds"]).(int64); ok {
+				d.Set("duration", s*1000)
+			}
+		},
+	},
+
+	"user_launch_agent":          ocsfLaunchd,
+	"system_launch_agent":        ocsfLaunchd,
+	"system_launch_daemon":       ocsfLaunchd,
+	"login_item_btm":             ocsfLoginItem,
+	"login_item_backgrounditems": ocsfLoginItem,
+	"system_cron":                ocsfCron,
+	"user_crontab":               ocsfCron,
+	"at_job":                     ocsfCron,
+
+	"user_account": {
+		class:    ocsfUserInventory,
+		activity: activityCollect,
+		fields: []field{
+			str("username", "user.name"), str("uid", "user.uid"), str("real_name", "user.full_name"),
+		},
+	},
+	"ssh_private_key":    ocsfSSH,
+	"ssh_public_key":     ocsfSSH,
+	"ssh_authorized_key": ocsfSSH,
+	"ssh_known_host":     ocsfSSH,
+	"ssh_config":         ocsfSSH,
+	"tcc_permission":     ocsfConfig,
+
+	"kernel_extension": {
+		class:    ocsfExtension.class,
+		activity: activityLoad,
+		fields:   ocsfExtension.fields,
+		binaries: ocsfExtension.binaries,
+	},
+	"system_extension":   ocsfExtension,
+	"library_extension":  ocsfExtension,
+	"system_application": ocsfApplication,
+	"user_application":   ocsfApplication,
+
+	"env_variable":            ocsfConfig,
+	"env_variable_suspicious": ocsfConfig,
+	"gatekeeper_status":       ocsfConfig,
+	"sip_status":              ocsfConfig,
+	"firewall_status":         ocsfConfig,
+	"filevault_status":        ocsfConfig,
+	"apfs_encryption":         ocsfConfig,
+	"xprotect_version":        ocsfConfig,
+	"system_info": {
+		class:    ocsfDeviceInventory,
+		activity: activityCollect,
+		fields: []field{
+			str("macos_version", "device.os.version"), str("kernel_version", "device.os.build"),
+			str("architecture", "device.hw_info.cpu_architecture"),
+		},
+	},
+
+	"unified_log_security": {class: ocsfBase, activity: activityOther, fields: ocsfUnifiedLog},
+	"unified_log_network":  {class: ocsfNetwork, activity: activityOther, fields: ocsfUnifiedLog},
+	"unified_log_process":  {class: ocsfProcess, activity: activityOther, fields: ocsfUnifiedLog},
+	"unified_log_errors":   {class: ocsfBase, activity: activityOther, fields: ocsfUnifiedLog},
+	"user_crash_report":    ocsfCrashReport,
+	"system_crash_report":  ocsfCrashReport,
+
+	"yara_match": {
+		class:    ocsfDetection,
+		activity: activityCreate,
+		fields:   []field{str("rule", "finding_info.title"), str("file_path", "evidences.file.path")},
+		extra: func(d Document, data map[string]interface{}) {
+			d.Set("finding_info.types", []string{"YARA"})
+		},
+	},
+}
+
+// ocsfSeverity is severity_id for each risk level; unscored artifacts are
+// informational
+var ocsfSeverity = map[string]struct {
+	id   int
+	name string
+}{
+	"info":     {1, "Informational"},
+	"low":      {2, "Low"},
+	"medium":   {3, "Medium"},
+	"high":     {4, "High"},
+	"critical": {5, "Critical"},
+}
+
+func (m *Mapper) ocsf(a models.Artifact, eventTime time.Time, msg string) Document {
+	mapping, ok := ocsfMappings[a.ArtifactType]
+	if !ok {
+		mapping = ocsfMapping{class: ocsfBase, activity: activityOther}
+	}
+
+	d := Document{}
+	d.Set("class_uid", mapping.class.uid)
+	d.Set("class_name", mapping.class.name)
+	d.Set("category_uid", mapping.class.categoryUID)
+	d.Set("category_name", mapping.class.categoryName)
+	d.Set("activity_id", mapping.activity.id)
+	d.Set("activity_name", mapping.activity.name)
+	d.Set("time", eventTime.UnixMilli())
+	d.Set("message", msg)
+
+	severity, ok := ocsfSeverity[a.Severity]
+	if !ok {
+		severity = ocsfSeverity["info"]
+	}
+	d.Set("severity_id", severity.id)
+	d.Set("severity", severity.name)
+
+	d.Set("metadata.version", ocsfVersion)
+	d.Set("metadata.product.name", "triagectl")
+	d.Set("metadata.product.vendor_name", "triagectl")
+	d.Set("metadata.product.version", m.version)
+	d.Set("metadata.log_name", a.CollectorID)
+	d.Set("metadata.logged_time", a.Timestamp.UnixMilli())
+	d.Set("metadata.labels", a.Tags)
+
+	d.Set("device.hostname", a.Hostname)
+	d.Set("device.name", a.Hostname)
+	d.Set("device.type_id", 0)
+	d.Set("device.os.name", "macOS")
+	d.Set("device.os.type_id", 300)
+
+	copyFields(d, a.Data, mapping.fields)
+	for _, b := range mapping.binaries {
+		setOCSFBinary(d, a.Data, b)
+	}
+	if mapping.extra != nil {
+		mapping.extra(d, a.Data)
+	}
+	// type_uid follows the activity, which extra may have refined
+	activity, _ := d["activity_id"].(int)
+	d.Set("type_uid", mapping.class.uid*100+activity)
+	d.Set("type_name", fmt.Sprintf("%s: %v", mapping.class.name, d["activity_name"]))
+
+	if len(a.Techniques) > 0 {
+		var attacks []interface{}
+		for _, id := range a.Techniques {
+			attack := Document{}
+			attack.Set("technique.uid", id)
+			attack.Set("version", "ATT&CK")
+			attacks = append(attacks, attack)
+		}
+		if mapping.class == ocsfDetection {
+			d.Set("finding_info.attacks", attacks)
+		} else {
+			d.Set("unmapped.attacks", attacks)
+		}
+	}
+	if len(a.IOCMatches) > 0 {
+		var observables []interface{}
+		for _, im := range a.IOCMatches {
+			o := Document{}
+			o.Set("name", im.Field)
+			o.Set("value", im.Value)
+			o.Set("type_id", ocsfObservableType(im))
+			o.Set("reputation.provider", im.Source)
+			o.Set("reputation.score_id", 10)
+			observables = append(observables, o)
+		}
+		d.Set("observables", observables)
+	}
+
+	// Everything triagectl knows, in its own namespace
+	d.Set("unmapped.artifact_type", a.ArtifactType)
+	if len(a.Timestamps) > 0 {
+		d.Set("unmapped.timestamps", a.Timestamps)
+	}
+	if a.RiskScore > 0 {
+		d.Set("unmapped.risk_score", a.RiskScore)
+		d.Set("unmapped.finding", a.IsFinding())
+	}
+	if len(a.Contributions) > 0 {
+		d.Set("unmapped.score_contributions", a.Contributions)
+	}
+	if a.Suppression != nil {
+		d.Set("unmapped.suppression", a.Suppression)
+	}
+	d.Set("unmapped.data", a.Data)
+	d.Set("unmapped.source_path", a.Metadata.SourcePath)
+	return d
+}
+
+// setOCSFURL fills http_request.url from a URL string
+func setOCSFURL(d Document, s string) {
+	d.Set("http_request.url.url_string", s)
+	u := parseURL(s)
+	if u == nil {
+		return
+	}
+	d.Set("http_request.url.scheme", u.Scheme)
+	d.Set("http_request.url.hostname", u.Hostname())
+	d.Set("http_request.url.path", u.Path)
+	d.Set("http_request.url.query_string", u.RawQuery)
+	d.Set("http_request.url.port", asInt(u.Port()))
+}
+
+// ocsfHashAlgorithms are fingerprint algorithm_id values
+var ocsfHashAlgorithms = []struct {
+	key  string
+	id   int
+	name string
+}{
+	{"md5", 1, "MD5"},
+	{"sha1", 2, "SHA-1"},
+	{"sha256", 3, "SHA-256"},
+}
+
+// setOCSFBinary fills a file object's hashes and signature from the data
+// collectors attached under prefix
+func setOCSFBinary(d Document, data map[string]interface{}, b binary) {
+	var hashes []interface{}
+	for _, alg := range ocsfHashAlgorithms {
+		if v := analysis.GetString(data, b.prefix+alg.key); v != "" {
+			hashes = append(hashes, Document{"algorithm_id": alg.id, "algorithm": alg.name, "value": v})
+		}
+	}
+	d.Set(b.object+".hashes", hashes)
+	if signed, ok := data[b.prefix+"signed"].(bool); ok && signed {
+		d.Set(b.object+".signature.algorithm_id", 0)
+		d.Set(b.object+".signature.developer_uid", analysis.GetString(data, b.prefix+"team_id"))
+		d.Set(b.object+".signature.certificate.subject", signer(data, b.prefix))
+	}
+}
+
+// ocsfObservableType maps an IOC type to an observable type_id
+func ocsfObservableType(m models.IOCMatch) int {
+	switch m.Type {
+	case "ip":
+		return 2
+	case "domain":
+		return 1
+	case "url":
+		return 6
+	case "hash":
+		return 8
+	case "filename", "path":
+		return 7
+	}
+	return 0
+}
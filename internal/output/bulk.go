@@ -0,0 +1,103 @@
+package output
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/models"
+	"github.com/plonxyz/triagectl/internal/normalize"
+)
+
+// BulkWriter writes artifacts normalized to ECS or OCSF as NDJSON for the
+// Elasticsearch and OpenSearch _bulk API: an index action line, then the
+// document. Document IDs are derived from the artifact, so loading the same
+// case twice overwrites documents instead of duplicating them.
+type BulkWriter struct {
+	out    *JSONLWriter
+	index  string
+	mapper *normalize.Mapper
+}
+
+// compile-time interface check
+var _ Writer = (*BulkWriter)(nil)
+
+type bulkAction struct {
+	Index bulkTarget `json:"index"`
+}
+
+type bulkTarget struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+// NewBulkWriter creates a bulk NDJSON file of documents for index,
+// gzip-compressed when the path ends in .gz
+func NewBulkWriter(outputPath, index string, mapper *normalize.Mapper) (*BulkWriter, error) {
+	out, err := NewJSONLWriter(outputPath)
+	if err != nil {
+		return nil, err
+	}
+	return &BulkWriter{out: out, index: index, mapper: mapper}, nil
+}
+
+// NewBulkStream writes bulk NDJSON to w, optionally gzip-compressed. Close
+// flushes the stream but does not close w.
+func NewBulkStream(w io.Writer, compress bool, index string, mapper *normalize.Mapper) *BulkWriter {
+	return &BulkWriter{out: NewJSONLStream(w, compress), index: index, mapper: mapper}
+}
+
+// Write encodes the action and document lines for one artifact
+func (bw *BulkWriter) Write(artifact models.Artifact) error {
+	entry, err := bulkEntry(artifact, bw.index, bw.mapper)
+	if err != nil {
+		return err
+	}
+	_, err = bw.out.buf.Write(entry)
+	return err
+}
+
+// WriteMany encodes the artifacts and flushes them to the underlying writer
+func (bw *BulkWriter) WriteMany(artifacts []models.Artifact) error {
+	for _, artifact := range artifacts {
+		if err := bw.Write(artifact); err != nil {
+			return err
+		}
+	}
+	return bw.out.flush()
+}
+
+// Close flushes buffered lines and closes the file
+func (bw *BulkWriter) Close() error {
+	return bw.out.Close()
+}
+
+// bulkEntry returns the action and document lines for one artifact
+func bulkEntry(a models.Artifact, index string, mapper *normalize.Mapper) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(bulkAction{bulkTarget{Index: index, ID: documentID(a)}}); err != nil {
+		return nil, err
+	}
+	if err := enc.Encode(mapper.Normalize(a, a.When())); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// documentID identifies an artifact by its host, source, collection time
+// and data
+func documentID(a models.Artifact) string {
+	h := sha1.New()
+	data, _ := json.Marshal(a.Data)
+	for _, part := range []string{a.Hostname, a.CollectorID, a.ArtifactType, a.Timestamp.UTC().Format(time.RFC3339Nano)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,151 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/plonxyz/triagectl/internal/models"
+	"github.com/plonxyz/triagectl/internal/normalize"
+)
+
+// ElasticWriter ships artifacts normalized to ECS or OCSF to an
+// Elasticsearch or OpenSearch _bulk endpoint. Documents the cluster rejects
+// or that cannot be delivered are spooled as bulk NDJSON.
+type ElasticWriter struct {
+	*shipper
+	cfg    ElasticConfig
+	mapper *normalize.Mapper
+}
+
+// compile-time interface check
+var _ Writer = (*ElasticWriter)(nil)
+
+// ElasticConfig adds Elasticsearch authentication to HTTPConfig. APIKey is
+// the base64 encoded id:key pair; Username and Password are used for basic
+// authentication when no key is set.
+type ElasticConfig struct {
+	HTTPConfig
+	APIKey   string
+	Username string
+	Password string
+}
+
+// NewElasticWriter ships to the cluster at cfg.URL
+func NewElasticWriter(cfg ElasticConfig, mapper *normalize.Mapper) (*ElasticWriter, error) {
+	s, err := newShipper(cfg.HTTPConfig)
+	if err != nil {
+		return nil, fmt.Errorf("elasticsearch: %w", err)
+	}
+	if cfg.Index == "" {
+		cfg.Index = "triagectl-" + mapper.Schema()
+	}
+	ew := &ElasticWriter{shipper: s, cfg: cfg, mapper: mapper}
+	s.send = ew.send
+	return ew, nil
+}
+
+// Write queues an artifact, sending a batch when it is full
+func (ew *ElasticWriter) Write(artifact models.Artifact) error {
+	entry, err := bulkEntry(artifact, ew.cfg.Index, ew.mapper)
+	if err != nil {
+		return err
+	}
+	return ew.add(entry)
+}
+
+// WriteMany queues the artifacts and sends them
+func (ew *ElasticWriter) WriteMany(artifacts []models.Artifact) error {
+	for _, artifact := range artifacts {
+		if err := ew.Write(artifact); err != nil {
+			return err
+		}
+	}
+	return ew.flush()
+}
+
+// Close sends any queued artifacts
+func (ew *ElasticWriter) Close() error { return ew.close() }
+
+// Stats reports how many artifacts were indexed and spooled
+func (ew *ElasticWriter) Stats() ShipStats { return ew.stats() }
+
+// bulkResponse is the part of a _bulk response that reports failed items
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+func (ew *ElasticWriter) send(batch [][]byte) (retry, rejected [][]byte, err error) {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(ew.cfg.URL, "/")+"/_bulk", bytes.NewReader(bytes.Join(batch, nil)))
+	if err != nil {
+		return nil, batch, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if ew.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+ew.cfg.APIKey)
+	} else if ew.cfg.Username != "" {
+		req.SetBasicAuth(ew.cfg.Username, ew.cfg.Password)
+	}
+
+	resp, err := ew.client.Do(req)
+	if err != nil {
+		return batch, nil, requestError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		io.Copy(io.Discard, resp.Body)
+		return batch, nil, statusError(resp)
+	}
+
+	var br bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&br); err != nil {
+		// Not a bulk response, e.g. a proxy's page: nothing says the
+		// batch was indexed
+		return batch, nil, retryable{fmt.Errorf("reading bulk response: %w", err)}
+	}
+	if !br.Errors {
+		return nil, nil, nil
+	}
+	// Items are reported in request order; retry those the cluster was
+	// too busy for and spool the others
+	var retryErr, rejectErr error
+	for i, item := range br.Items {
+		if i >= len(batch) {
+			break
+		}
+		for _, result := range item {
+			switch {
+			case result.Status/100 == 2:
+			case result.Status == http.StatusTooManyRequests || result.Status >= 500:
+				retry = append(retry, batch[i])
+				if retryErr == nil {
+					retryErr = retryable{fmt.Errorf("bulk item failed: %s: %s", result.Error.Type, result.Error.Reason)}
+				}
+			default:
+				rejected = append(rejected, batch[i])
+				if rejectErr == nil {
+					rejectErr = fmt.Errorf("bulk item rejected: %s: %s", result.Error.Type, result.Error.Reason)
+				}
+			}
+		}
+	}
+	switch {
+	case rejectErr != nil && retryErr != nil:
+		err = fmt.Errorf("%w; %w", rejectErr, retryErr)
+	case rejectErr != nil:
+		err = rejectErr
+	default:
+		err = retryErr
+	}
+	return retry, rejected, err
+}
@@ -0,0 +1,178 @@
+package output
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestElasticPartialFailure(t *testing.T) {
+	var log requestLog
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statuses := created(len(bulkItems(t, log.add(r))))
+		// First request: the second item is throttled, the third is rejected
+		if log.count() == 1 {
+			statuses[1] = http.StatusTooManyRequests
+			statuses[2] = http.StatusBadRequest
+		}
+		bulkResult(w, statuses)
+	}))
+	defer srv.Close()
+
+	w := newTestElasticWriter(t, ElasticConfig{HTTPConfig: testHTTPConfig(t, srv.URL)})
+	artifacts := testArtifacts(4)
+	if err := w.WriteMany(artifacts); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := log.count(); got != 2 {
+		t.Fatalf("requests = %d, want 2", got)
+	}
+	retried := bulkItems(t, log.bodies[1])
+	if len(retried) != 1 || bulkID(retried[0]) != bulkID(bulkItems(t, log.bodies[0])[1]) {
+		t.Errorf("retry request = %v, want only the throttled item", retried)
+	}
+
+	stats := w.Stats()
+	if stats.Sent != 3 || stats.Spooled != 0 || stats.Rejected != 1 {
+		t.Errorf("stats = %+v, want 3 sent, 1 rejected", stats)
+	}
+	// The rejected item must not end up in the spool, which is for replay
+	if _, err := os.Stat(w.cfg.SpoolPath); !os.IsNotExist(err) {
+		t.Errorf("spool file exists (err %v), want none", err)
+	}
+	rejected, err := os.ReadFile(w.cfg.SpoolPath + ".rejected")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := bulkEntry(artifacts[2], w.cfg.Index, w.mapper)
+	if len(bulkItems(t, rejected)) != 1 || !bytes.Equal(rejected, want) {
+		t.Errorf("rejected file = %q, want the rejected item %q", rejected, want)
+	}
+}
+
+func TestElasticNotRetryable(t *testing.T) {
+	var log requestLog
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	w := newTestElasticWriter(t, ElasticConfig{HTTPConfig: testHTTPConfig(t, srv.URL)})
+	if err := w.WriteMany(testArtifacts(3)); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	if got := log.count(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+	stats := w.Stats()
+	if stats.Spooled != 3 || stats.LastErr == nil {
+		t.Errorf("stats = %+v, want 3 spooled with an error", stats)
+	}
+}
+
+func TestElasticUnreadableResponse(t *testing.T) {
+	var log requestLog
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		// A proxy answering in place of the cluster
+		w.Write([]byte("<html>maintenance</html>"))
+	}))
+	defer srv.Close()
+
+	cfg := testHTTPConfig(t, srv.URL)
+	cfg.MaxRetries = 2
+	w := newTestElasticWriter(t, ElasticConfig{HTTPConfig: cfg})
+	if err := w.WriteMany(testArtifacts(3)); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	if got := log.count(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+	stats := w.Stats()
+	if stats.Sent != 0 || stats.Spooled != 3 || stats.LastErr == nil {
+		t.Errorf("stats = %+v, want 3 spooled with an error", stats)
+	}
+}
+
+func TestElasticAuthentication(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ElasticConfig
+		want string
+	}{
+		{"api key", ElasticConfig{APIKey: "aWQ6a2V5"}, "ApiKey aWQ6a2V5"},
+		{"basic", ElasticConfig{Username: "elastic", Password: "secret"}, "Basic ZWxhc3RpYzpzZWNyZXQ="},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var log requestLog
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				bulkResult(w, created(len(bulkItems(t, log.add(r)))))
+			}))
+			defer srv.Close()
+
+			tt.cfg.HTTPConfig = testHTTPConfig(t, srv.URL)
+			w := newTestElasticWriter(t, tt.cfg)
+			if err := w.WriteMany(testArtifacts(1)); err != nil {
+				t.Fatal(err)
+			}
+			w.Close()
+			if log.count() == 0 {
+				t.Fatal("no request")
+			}
+			r := log.requests[0]
+			if got := r.Header.Get("Authorization"); got != tt.want {
+				t.Errorf("Authorization = %q, want %q", got, tt.want)
+			}
+			if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+				t.Errorf("request = %s %s, want /_bulk with NDJSON", r.URL.Path, r.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+// TestElasticSpoolReplay posts a spool written while the cluster was down
+// to a working cluster, as responders do with curl
+func TestElasticSpoolReplay(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	w := newTestElasticWriter(t, ElasticConfig{HTTPConfig: testHTTPConfig(t, down.URL)})
+	if err := w.WriteMany(testArtifacts(5)); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	var log requestLog
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bulkResult(w, created(len(bulkItems(t, log.add(r)))))
+	}))
+	defer up.Close()
+
+	spool, err := os.Open(w.cfg.SpoolPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spool.Close()
+	resp, err := http.Post(up.URL+"/_bulk", "application/x-ndjson", spool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if items := bulkItems(t, log.bodies[0]); len(items) != 5 {
+		t.Errorf("replayed %d items, want 5", len(items))
+	}
+}
@@ -0,0 +1,100 @@
+package output
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// JSONLWriter writes artifacts as JSON Lines: one complete artifact object
+// per line, with its data, metadata, analysis results and severity. Each
+// artifact is encoded as it is written, so the writer can be used as a
+// streaming sink; output is flushed after every WriteMany and on Close.
+type JSONLWriter struct {
+	closer io.Closer
+	gz     *gzip.Writer
+	buf    *bufio.Writer
+	enc    *json.Encoder
+}
+
+// compile-time interface check
+var _ Writer = (*JSONLWriter)(nil)
+
+// jsonlRecord adds the severity, which the artifact's own JSON form leaves
+// out, to each line
+type jsonlRecord struct {
+	models.Artifact
+	Severity string `json:"severity,omitempty"`
+}
+
+// NewJSONLWriter creates a JSON Lines file, gzip-compressed when the path
+// ends in .gz
+func NewJSONLWriter(outputPath string) (*JSONLWriter, error) {
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return nil, err
+	}
+	w := NewJSONLStream(file, strings.HasSuffix(outputPath, ".gz"))
+	w.closer = file
+	return w, nil
+}
+
+// NewJSONLStream writes JSON Lines to w, optionally gzip-compressed. Close
+// flushes the stream but does not close w.
+func NewJSONLStream(w io.Writer, compress bool) *JSONLWriter {
+	jw := &JSONLWriter{}
+	if compress {
+		jw.gz = gzip.NewWriter(w)
+		w = jw.gz
+	}
+	jw.buf = bufio.NewWriterSize(w, 64<<10)
+	jw.enc = json.NewEncoder(jw.buf)
+	jw.enc.SetEscapeHTML(false)
+	return jw
+}
+
+// Write encodes one artifact as a line
+func (jw *JSONLWriter) Write(artifact models.Artifact) error {
+	return jw.enc.Encode(jsonlRecord{Artifact: artifact, Severity: artifact.Severity})
+}
+
+// WriteMany encodes the artifacts and flushes them to the underlying writer
+func (jw *JSONLWriter) WriteMany(artifacts []models.Artifact) error {
+	for _, artifact := range artifacts {
+		if err := jw.Write(artifact); err != nil {
+			return err
+		}
+	}
+	return jw.flush()
+}
+
+func (jw *JSONLWriter) flush() error {
+	if err := jw.buf.Flush(); err != nil {
+		return err
+	}
+	if jw.gz != nil {
+		return jw.gz.Flush()
+	}
+	return nil
+}
+
+// Close flushes buffered lines, ends the gzip stream and closes the file
+func (jw *JSONLWriter) Close() error {
+	err := jw.buf.Flush()
+	if jw.gz != nil {
+		if cerr := jw.gz.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if jw.closer != nil {
+		if cerr := jw.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
@@ -0,0 +1,265 @@
+package output
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// HTTPConfig configures the writers that ship artifacts to a SIEM over HTTP
+type HTTPConfig struct {
+	URL   string
+	Index string
+
+	// BatchSize is the number of artifacts sent per request (default 500)
+	BatchSize int
+	// MaxRetries is how often a failed batch is retried, waiting Backoff
+	// and then twice as long each time, up to 30s (defaults 5 and 1s)
+	MaxRetries int
+	Backoff    time.Duration
+	// Timeout limits each request (default 30s)
+	Timeout time.Duration
+
+	// CACert is a PEM file of CAs to trust instead of the system roots;
+	// ClientCert and ClientKey authenticate with a client certificate
+	CACert     string
+	ClientCert string
+	ClientKey  string
+	Insecure   bool
+
+	// SpoolPath receives, as a file that can be posted to the endpoint
+	// later, every artifact that could not be delivered. Artifacts the
+	// endpoint rejected go to SpoolPath+".rejected" instead, since posting
+	// them again would only have them rejected again.
+	SpoolPath string
+}
+
+// retryable marks a delivery failure that may succeed later: a network
+// error, 429 or a 5xx response
+type retryable struct{ err error }
+
+func (e retryable) Error() string { return e.err.Error() }
+func (e retryable) Unwrap() error { return e.err }
+
+// shipper batches encoded artifacts and posts them with send, retrying
+// with backoff. What cannot be delivered is appended to the spool file,
+// what the endpoint rejected to the rejected file next to it.
+type shipper struct {
+	cfg    HTTPConfig
+	client *http.Client
+	// send posts a batch and returns the entries to retry, the entries
+	// the endpoint rejected, and the error behind either
+	send func(batch [][]byte) (retry, rejected [][]byte, err error)
+
+	batch    [][]byte
+	spool    *os.File
+	rejects  *os.File
+	sent     int
+	spooled  int
+	rejected int
+	lastErr  error
+	closed   bool
+	// down is why the endpoint was given up on; later batches go
+	// straight to the spool instead of waiting out the same retries
+	down error
+}
+
+func newShipper(cfg HTTPConfig) (*shipper, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("no URL")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	tlsConfig, err := cfg.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &shipper{
+		cfg:    cfg,
+		client: &http.Client{Transport: transport, Timeout: cfg.Timeout},
+	}, nil
+}
+
+func (cfg HTTPConfig) tlsConfig() (*tls.Config, error) {
+	tc := &tls.Config{InsecureSkipVerify: cfg.Insecure}
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no PEM certificates", cfg.CACert)
+		}
+		tc.RootCAs = pool
+	}
+	if cfg.ClientCert != "" || cfg.ClientKey != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("loading client certificate: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return tc, nil
+}
+
+// statusError classifies an HTTP response status
+func statusError(resp *http.Response) error {
+	err := fmt.Errorf("%s returned %s", resp.Request.URL.Host, resp.Status)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return retryable{err}
+	}
+	return err
+}
+
+// requestError classifies a request that got no response: certificate
+// problems will not go away on retry, other transport errors may
+func requestError(err error) error {
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return err
+	}
+	return retryable{err}
+}
+
+// add queues an encoded artifact, sending the batch once it is full
+func (s *shipper) add(entry []byte) error {
+	s.batch = append(s.batch, entry)
+	if len(s.batch) >= s.cfg.BatchSize {
+		return s.flush()
+	}
+	return nil
+}
+
+// flush sends the queued batch, retrying what failed temporarily and
+// spooling what could not be delivered. Once a batch could not be
+// delivered at all, the endpoint is taken to be down and later batches
+// are spooled without being sent.
+func (s *shipper) flush() error {
+	batch := s.batch
+	s.batch = nil
+	if s.down != nil {
+		return s.spoolEntries(batch, s.down)
+	}
+	delay := s.cfg.Backoff
+	for attempt := 0; len(batch) > 0; attempt++ {
+		retry, rejected, err := s.send(batch)
+		s.sent += len(batch) - len(retry) - len(rejected)
+		if err := s.rejectEntries(rejected, err); err != nil {
+			return err
+		}
+		if len(retry) > 0 && (!errors.As(err, new(retryable)) || attempt == s.cfg.MaxRetries) {
+			// Out of retries, or not worth retrying, e.g. bad credentials
+			s.down = err
+			return s.spoolEntries(retry, err)
+		}
+		batch = retry
+		if len(batch) > 0 {
+			time.Sleep(delay)
+			if delay *= 2; delay > 30*time.Second {
+				delay = 30 * time.Second
+			}
+		}
+	}
+	return nil
+}
+
+// spoolEntries saves entries that were not delivered because of reason
+func (s *shipper) spoolEntries(entries [][]byte, reason error) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	s.lastErr = reason
+	if s.cfg.SpoolPath == "" {
+		return fmt.Errorf("%d artifacts not delivered: %w", len(entries), reason)
+	}
+	if err := appendEntries(&s.spool, s.cfg.SpoolPath, entries); err != nil {
+		return fmt.Errorf("writing spool: %w", err)
+	}
+	s.spooled += len(entries)
+	return nil
+}
+
+// rejectEntries saves entries the endpoint refused because of reason,
+// apart from the spool so that replaying it does not send them again
+func (s *shipper) rejectEntries(entries [][]byte, reason error) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	s.lastErr = reason
+	if s.cfg.SpoolPath == "" {
+		return fmt.Errorf("%d artifacts rejected: %w", len(entries), reason)
+	}
+	if err := appendEntries(&s.rejects, s.cfg.SpoolPath+".rejected", entries); err != nil {
+		return fmt.Errorf("writing rejected entries: %w", err)
+	}
+	s.rejected += len(entries)
+	return nil
+}
+
+// appendEntries writes entries to *f, opening path for appending first
+// if it is not open yet
+func appendEntries(f **os.File, path string, entries [][]byte) error {
+	if *f == nil {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		*f = file
+	}
+	for _, e := range entries {
+		if _, err := (*f).Write(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close sends the last batch and closes the spool and rejected files
+func (s *shipper) close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	err := s.flush()
+	for _, f := range []*os.File{s.spool, s.rejects} {
+		if f == nil {
+			continue
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// ShipStats reports what a network writer delivered
+type ShipStats struct {
+	Sent    int
+	Spooled int
+	// Rejected counts artifacts the endpoint refused, saved to the
+	// rejected file rather than the spool
+	Rejected int
+	// LastErr is why artifacts were last spooled or rejected, if any were
+	LastErr error
+}
+
+func (s *shipper) stats() ShipStats {
+	return ShipStats{Sent: s.sent, Spooled: s.spooled, Rejected: s.rejected, LastErr: s.lastErr}
+}
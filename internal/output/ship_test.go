@@ -0,0 +1,226 @@
+package output
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	stdlog "log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/models"
+	"github.com/plonxyz/triagectl/internal/normalize"
+)
+
+func testArtifacts(n int) []models.Artifact {
+	artifacts := make([]models.Artifact, n)
+	for i := range artifacts {
+		artifacts[i] = models.Artifact{
+			Timestamp:    time.Date(2026, 2, 6, 19, 1, 1, 0, time.UTC),
+			CollectorID:  "processes",
+			ArtifactType: "running_process",
+			Hostname:     "host",
+			Data:         map[string]interface{}{"pid": i, "name": fmt.Sprintf("proc%d", i)},
+		}
+	}
+	return artifacts
+}
+
+func testHTTPConfig(t *testing.T, url string) HTTPConfig {
+	return HTTPConfig{
+		URL:        url,
+		BatchSize:  10,
+		MaxRetries: 3,
+		Backoff:    time.Millisecond,
+		Timeout:    5 * time.Second,
+		SpoolPath:  filepath.Join(t.TempDir(), "spool"),
+	}
+}
+
+// requestLog records the requests a stand-in received
+type requestLog struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (l *requestLog) add(r *http.Request) []byte {
+	body, _ := io.ReadAll(r.Body)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.requests = append(l.requests, r)
+	l.bodies = append(l.bodies, body)
+	return body
+}
+
+func (l *requestLog) count() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.requests)
+}
+
+// bulkItems splits a _bulk body into action/document pairs, failing the
+// test on anything Elasticsearch would not accept
+func bulkItems(t *testing.T, body []byte) [][2]map[string]interface{} {
+	t.Helper()
+	var items [][2]map[string]interface{}
+	sc := bufio.NewScanner(bytes.NewReader(body))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		var action, doc map[string]interface{}
+		if err := json.Unmarshal(sc.Bytes(), &action); err != nil {
+			t.Fatalf("bad action line %q: %v", sc.Text(), err)
+		}
+		if _, ok := action["index"]; !ok {
+			t.Fatalf("action line without index: %q", sc.Text())
+		}
+		if !sc.Scan() {
+			t.Fatalf("action without document")
+		}
+		if err := json.Unmarshal(sc.Bytes(), &doc); err != nil {
+			t.Fatalf("bad document line %q: %v", sc.Text(), err)
+		}
+		items = append(items, [2]map[string]interface{}{action, doc})
+	}
+	return items
+}
+
+// bulkID returns the document ID of a bulk item
+func bulkID(item [2]map[string]interface{}) interface{} {
+	action, _ := item[0]["index"].(map[string]interface{})
+	return action["_id"]
+}
+
+// created returns n successful bulk item statuses
+func created(n int) []int {
+	statuses := make([]int, n)
+	for i := range statuses {
+		statuses[i] = http.StatusCreated
+	}
+	return statuses
+}
+
+// bulkResult answers a _bulk request with one status per item
+func bulkResult(w http.ResponseWriter, statuses []int) {
+	var resp struct {
+		Errors bool                     `json:"errors"`
+		Items  []map[string]interface{} `json:"items"`
+	}
+	for _, status := range statuses {
+		item := map[string]interface{}{"status": status}
+		if status/100 != 2 {
+			resp.Errors = true
+			item["error"] = map[string]string{"type": "test_exception", "reason": fmt.Sprintf("status %d", status)}
+		}
+		resp.Items = append(resp.Items, map[string]interface{}{"index": item})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func newTestElasticWriter(t *testing.T, cfg ElasticConfig) *ElasticWriter {
+	t.Helper()
+	mapper, err := normalize.NewMapper(normalize.SchemaECS, "test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewElasticWriter(cfg, mapper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestShipperGivesUpOnDownEndpoint(t *testing.T) {
+	var log requestLog
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cfg := ElasticConfig{HTTPConfig: testHTTPConfig(t, srv.URL)}
+	cfg.BatchSize = 2
+	cfg.MaxRetries = 2
+	w := newTestElasticWriter(t, cfg)
+	if err := w.WriteMany(testArtifacts(6)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first batch is tried MaxRetries+1 times, the others not at all
+	if got := log.count(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+	stats := w.Stats()
+	if stats.Sent != 0 || stats.Spooled != 6 {
+		t.Errorf("stats = %+v, want 0 sent, 6 spooled", stats)
+	}
+}
+
+func TestShipTLSWithCACert(t *testing.T) {
+	var log requestLog
+	var conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bulkResult(w, created(len(bulkItems(t, log.add(r)))))
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Config.ErrorLog = stdlog.New(io.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	caPath := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caPath, caPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("trusted", func(t *testing.T) {
+		cfg := ElasticConfig{HTTPConfig: testHTTPConfig(t, srv.URL)}
+		cfg.CACert = caPath
+		w := newTestElasticWriter(t, cfg)
+		if err := w.WriteMany(testArtifacts(3)); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		if stats := w.Stats(); stats.Sent != 3 || stats.Spooled != 0 {
+			t.Errorf("stats = %+v, want 3 sent", stats)
+		}
+	})
+
+	t.Run("untrusted", func(t *testing.T) {
+		before := log.count()
+		conns.Store(0)
+		w := newTestElasticWriter(t, ElasticConfig{HTTPConfig: testHTTPConfig(t, srv.URL)})
+		if err := w.WriteMany(testArtifacts(3)); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		if stats := w.Stats(); stats.Sent != 0 || stats.Spooled != 3 {
+			t.Errorf("stats = %+v, want 3 spooled", stats)
+		}
+		if log.count() != before {
+			t.Errorf("request reached the server without a trusted certificate")
+		}
+		// Certificate errors are not retried
+		if got := conns.Load(); got != 1 {
+			t.Errorf("connections = %d, want 1", got)
+		}
+	})
+}
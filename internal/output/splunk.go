@@ -0,0 +1,133 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// SplunkWriter ships artifacts to a Splunk HTTP Event Collector. Each
+// artifact is one event with the same fields as a JSON Lines record, timed
+// at its event time. Batches that cannot be delivered are spooled as HEC
+// event JSON.
+type SplunkWriter struct {
+	*shipper
+	cfg SplunkConfig
+}
+
+// compile-time interface check
+var _ Writer = (*SplunkWriter)(nil)
+
+// SplunkConfig adds the HEC token and event fields to HTTPConfig. Index is
+// the Splunk index, left to the token's default when empty.
+type SplunkConfig struct {
+	HTTPConfig
+	Token      string
+	Source     string
+	Sourcetype string
+}
+
+type hecEvent struct {
+	Time       float64     `json:"time"`
+	Host       string      `json:"host,omitempty"`
+	Source     string      `json:"source,omitempty"`
+	Sourcetype string      `json:"sourcetype,omitempty"`
+	Index      string      `json:"index,omitempty"`
+	Event      jsonlRecord `json:"event"`
+}
+
+// NewSplunkWriter ships to the collector at cfg.URL
+func NewSplunkWriter(cfg SplunkConfig) (*SplunkWriter, error) {
+	s, err := newShipper(cfg.HTTPConfig)
+	if err != nil {
+		return nil, fmt.Errorf("splunk: %w", err)
+	}
+	if cfg.Source == "" {
+		cfg.Source = "triagectl"
+	}
+	if cfg.Sourcetype == "" {
+		cfg.Sourcetype = "triagectl:artifact"
+	}
+	sw := &SplunkWriter{shipper: s, cfg: cfg}
+	s.send = sw.send
+	return sw, nil
+}
+
+// Write queues an artifact, sending a batch when it is full
+func (sw *SplunkWriter) Write(artifact models.Artifact) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(hecEvent{
+		Time:       float64(artifact.When().UnixMilli()) / 1000,
+		Host:       artifact.Hostname,
+		Source:     sw.cfg.Source,
+		Sourcetype: sw.cfg.Sourcetype,
+		Index:      sw.cfg.Index,
+		Event:      jsonlRecord{Artifact: artifact, Severity: artifact.Severity},
+	})
+	if err != nil {
+		return err
+	}
+	return sw.add(buf.Bytes())
+}
+
+// WriteMany queues the artifacts and sends them
+func (sw *SplunkWriter) WriteMany(artifacts []models.Artifact) error {
+	for _, artifact := range artifacts {
+		if err := sw.Write(artifact); err != nil {
+			return err
+		}
+	}
+	return sw.flush()
+}
+
+// Close sends any queued artifacts
+func (sw *SplunkWriter) Close() error { return sw.close() }
+
+// Stats reports how many artifacts were accepted and spooled
+func (sw *SplunkWriter) Stats() ShipStats { return sw.stats() }
+
+// hecEndpoint accepts the collector's base URL or the full event endpoint
+func (sw *SplunkWriter) hecEndpoint() string {
+	u := strings.TrimRight(sw.cfg.URL, "/")
+	if strings.Contains(u, "/services/collector") {
+		return u
+	}
+	return u + "/services/collector/event"
+}
+
+// send posts a batch. HEC accepts or refuses a request as a whole, so there
+// are no partial failures to sort out.
+func (sw *SplunkWriter) send(batch [][]byte) (retry, rejected [][]byte, err error) {
+	req, err := http.NewRequest(http.MethodPost, sw.hecEndpoint(), bytes.NewReader(bytes.Join(batch, nil)))
+	if err != nil {
+		return nil, batch, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Splunk "+sw.cfg.Token)
+
+	resp, err := sw.client.Do(req)
+	if err != nil {
+		return batch, nil, requestError(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		var hec struct {
+			Text string `json:"text"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		err := statusError(resp)
+		if json.Unmarshal(body, &hec) == nil && hec.Text != "" {
+			err = fmt.Errorf("%w: %s", err, hec.Text)
+		}
+		return batch, nil, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil, nil, nil
+}
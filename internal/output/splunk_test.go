@@ -0,0 +1,113 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// hecEvents decodes a HEC request body: event objects back to back
+func hecEvents(t *testing.T, body io.Reader) []hecEvent {
+	t.Helper()
+	var events []hecEvent
+	dec := json.NewDecoder(body)
+	for dec.More() {
+		var e struct {
+			hecEvent
+			Event map[string]interface{} `json:"event"`
+		}
+		if err := dec.Decode(&e); err != nil {
+			t.Fatalf("bad HEC event: %v", err)
+		}
+		if e.Event == nil || e.Time == 0 {
+			t.Fatalf("HEC event without event or time")
+		}
+		events = append(events, e.hecEvent)
+	}
+	return events
+}
+
+func newTestSplunkWriter(t *testing.T, url string) *SplunkWriter {
+	t.Helper()
+	w, err := NewSplunkWriter(SplunkConfig{HTTPConfig: testHTTPConfig(t, url), Token: "0000-1111"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestSplunkRetriesUnavailable(t *testing.T) {
+	var log requestLog
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		if log.count() == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"text":"Server is busy","code":9}`))
+			return
+		}
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer srv.Close()
+
+	w := newTestSplunkWriter(t, srv.URL)
+	if err := w.WriteMany(testArtifacts(3)); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	if got := log.count(); got != 2 {
+		t.Fatalf("requests = %d, want 2", got)
+	}
+	r := log.requests[1]
+	if got := r.Header.Get("Authorization"); got != "Splunk 0000-1111" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if r.URL.Path != "/services/collector/event" {
+		t.Errorf("path = %q", r.URL.Path)
+	}
+	if events := hecEvents(t, bytes.NewReader(log.bodies[1])); len(events) != 3 {
+		t.Errorf("delivered %d events, want 3", len(events))
+	}
+	if stats := w.Stats(); stats.Sent != 3 || stats.Spooled != 0 {
+		t.Errorf("stats = %+v, want 3 sent", stats)
+	}
+}
+
+func TestSplunkNotRetryable(t *testing.T) {
+	var log requestLog
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.add(r)
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"text":"Invalid token","code":4}`))
+	}))
+	defer srv.Close()
+
+	w := newTestSplunkWriter(t, srv.URL)
+	if err := w.WriteMany(testArtifacts(3)); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	if got := log.count(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+	stats := w.Stats()
+	if stats.Spooled != 3 || stats.LastErr == nil {
+		t.Fatalf("stats = %+v, want 3 spooled with an error", stats)
+	}
+
+	// The spool is HEC event JSON that can be posted to the collector as is
+	spool, err := os.Open(w.cfg.SpoolPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer spool.Close()
+	events := hecEvents(t, spool)
+	if len(events) != 3 || events[0].Host != "host" || events[0].Sourcetype != "triagectl:artifact" {
+		t.Errorf("spooled events = %+v", events)
+	}
+}
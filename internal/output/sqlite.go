@@ -4,14 +4,20 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/plonxyz/triagectl/internal/models"
 )
 
+// sqliteTimeFormat is the layout used for timestamp and event_time columns
+const sqliteTimeFormat = "2006-01-02T15:04:05.000Z"
+
 // SQLiteWriter writes artifacts to a SQLite database
 type SQLiteWriter struct {
 	db *sql.DB
+	// fts is set when the full-text index can be updated
+	fts bool
 }
 
 // compile-time interface check
@@ -52,7 +58,8 @@ func (w *SQLiteWriter) createSchema() error {
 		collected_at TEXT NOT NULL,
 		risk_score INTEGER DEFAULT 0,
 		tags TEXT DEFAULT '[]',
-		event_time TEXT
+		event_time TEXT,
+		techniques TEXT DEFAULT '[]'
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_collector_id ON artifacts(collector_id);
@@ -60,12 +67,121 @@ func (w *SQLiteWriter) createSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_hostname ON artifacts(hostname);
 	CREATE INDEX IF NOT EXISTS idx_timestamp ON artifacts(timestamp);
 	CREATE INDEX IF NOT EXISTS idx_event_time ON artifacts(event_time);
+	CREATE INDEX IF NOT EXISTS idx_risk_score ON artifacts(risk_score);
+
+	CREATE TABLE IF NOT EXISTS collector_results (
+		collector_id TEXT PRIMARY KEY,
+		started_at TEXT NOT NULL,
+		duration_ms INTEGER NOT NULL,
+		artifact_count INTEGER NOT NULL,
+		error_message TEXT,
+		skip_reason TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS ioc_matches (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		artifact_id INTEGER NOT NULL,
+		indicator_type TEXT NOT NULL,
+		indicator_value TEXT NOT NULL,
+		field TEXT NOT NULL,
+		name TEXT,
+		source TEXT,
+		event TEXT,
+		event_id TEXT,
+		confidence INTEGER,
+		valid_until TEXT
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_ioc_matches_artifact ON ioc_matches(artifact_id);
+
+	CREATE TABLE IF NOT EXISTS score_contributions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		artifact_id INTEGER NOT NULL,
+		analyzer TEXT NOT NULL,
+		rule TEXT NOT NULL,
+		points INTEGER NOT NULL,
+		field TEXT,
+		value TEXT
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_score_contributions_artifact ON score_contributions(artifact_id);
+	CREATE INDEX IF NOT EXISTS idx_score_contributions_rule ON score_contributions(rule);
+
+	CREATE TABLE IF NOT EXISTS suppressions (
+		artifact_id INTEGER PRIMARY KEY,
+		rule_id TEXT NOT NULL,
+		justification TEXT NOT NULL,
+		expires TEXT
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_suppressions_rule ON suppressions(rule_id);
+
+	CREATE TABLE IF NOT EXISTS timestamps (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		artifact_id INTEGER NOT NULL,
+		kind TEXT NOT NULL,
+		source TEXT,
+		time TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_timestamps_artifact ON timestamps(artifact_id);
+	CREATE INDEX IF NOT EXISTS idx_timestamps_time ON timestamps(time);
+
+	CREATE TABLE IF NOT EXISTS entities (
+		pid INTEGER NOT NULL,
+		ppid INTEGER,
+		name TEXT NOT NULL,
+		exe TEXT,
+		username TEXT,
+		cmdline TEXT,
+		create_time TEXT,
+		parent_name TEXT,
+		parent_exe TEXT,
+		signature TEXT,
+		team_id TEXT,
+		signing_id TEXT,
+		risk_score INTEGER DEFAULT 0,
+		tags TEXT DEFAULT '[]',
+		connection_count INTEGER DEFAULT 0,
+		remote_endpoints TEXT DEFAULT '[]',
+		listen_addrs TEXT DEFAULT '[]',
+		open_files TEXT DEFAULT '[]',
+		persistence TEXT DEFAULT '[]'
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_entities_pid ON entities(pid);
+	CREATE INDEX IF NOT EXISTS idx_entities_exe ON entities(exe);
 	`
 
-	_, err := w.db.Exec(schema)
+	if _, err := w.db.Exec(schema); err != nil {
+		return err
+	}
+	if err := w.addColumn("artifacts", "techniques", `TEXT DEFAULT '[]'`); err != nil {
+		return err
+	}
+	if err := w.createViews(); err != nil {
+		return err
+	}
+	return w.createTextIndex()
+}
+
+// addColumn adds a column to a table created by an older version
+func (w *SQLiteWriter) addColumn(table, column, decl string) error {
+	ok, err := w.hasColumn(table, column)
+	if err != nil || ok {
+		return err
+	}
+	_, err = w.db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, column, decl))
 	return err
 }
 
+// hasColumn reports whether a table has the named column
+func (w *SQLiteWriter) hasColumn(table, column string) (bool, error) {
+	var n int
+	err := w.db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column).Scan(&n)
+	return n > 0, err
+}
+
 // Write writes an artifact to the SQLite database
 func (w *SQLiteWriter) Write(artifact models.Artifact) error {
 	dataJSON, err := json.Marshal(artifact.Data)
@@ -83,22 +199,28 @@ func (w *SQLiteWriter) Write(artifact models.Artifact) error {
 		return err
 	}
 
+	techniquesJSON, err := json.Marshal(stringList(artifact.Techniques))
+	if err != nil {
+		return err
+	}
+
 	var eventTimeStr string
 	if artifact.EventTime != nil {
-		eventTimeStr = artifact.EventTime.Format("2006-01-02T15:04:05.000Z")
+		eventTimeStr = artifact.EventTime.Format(sqliteTimeFormat)
 	}
 
 	query := `
 		INSERT INTO artifacts (
-			timestamp, collector_id, artifact_type, hostname, data, metadata,
+			id, timestamp, collector_id, artifact_type, hostname, data, metadata,
 			success, error_message, requires_root, source_path, collected_at,
-			risk_score, tags, event_time
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			risk_score, tags, event_time, techniques
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err = w.db.Exec(
+	res, err := w.db.Exec(
 		query,
-		artifact.Timestamp.Format("2006-01-02T15:04:05.000Z"),
+		rowID(artifact),
+		artifact.Timestamp.Format(sqliteTimeFormat),
 		artifact.CollectorID,
 		artifact.ArtifactType,
 		artifact.Hostname,
@@ -112,9 +234,29 @@ func (w *SQLiteWriter) Write(artifact models.Artifact) error {
 		artifact.RiskScore,
 		string(tagsJSON),
 		eventTimeStr,
+		string(techniquesJSON),
 	)
+	if err != nil {
+		return err
+	}
 
-	return err
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if err := w.indexText(w.db, id, dataJSON); err != nil {
+		return err
+	}
+	if err := insertTimestamps(w.db, id, artifact.Timestamps); err != nil {
+		return err
+	}
+	if err := insertIOCMatches(w.db, id, artifact.IOCMatches); err != nil {
+		return err
+	}
+	if err := insertContributions(w.db, id, artifact.Contributions); err != nil {
+		return err
+	}
+	return insertSuppression(w.db, id, artifact.Suppression)
 }
 
 // WriteMany writes multiple artifacts using transaction
@@ -123,16 +265,24 @@ func (w *SQLiteWriter) WriteMany(artifacts []models.Artifact) error {
 	if err != nil {
 		return err
 	}
+	if err := w.insertArtifacts(tx, artifacts); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
 
+// insertArtifacts inserts artifacts with their timestamps, full-text rows,
+// IOC matches, contributions and suppressions; the caller owns tx
+func (w *SQLiteWriter) insertArtifacts(tx *sql.Tx, artifacts []models.Artifact) error {
 	stmt, err := tx.Prepare(`
 		INSERT INTO artifacts (
-			timestamp, collector_id, artifact_type, hostname, data, metadata,
+			id, timestamp, collector_id, artifact_type, hostname, data, metadata,
 			success, error_message, requires_root, source_path, collected_at,
-			risk_score, tags, event_time
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			risk_score, tags, event_time, techniques
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`)
 	if err != nil {
-		tx.Rollback()
 		return err
 	}
 	defer stmt.Close()
@@ -140,29 +290,32 @@ func (w *SQLiteWriter) WriteMany(artifacts []models.Artifact) error {
 	for _, artifact := range artifacts {
 		dataJSON, err := json.Marshal(artifact.Data)
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
 
 		metadataJSON, err := json.Marshal(artifact.Metadata)
 		if err != nil {
-			tx.Rollback()
 			return err
 		}
 
 		tagsJSON, err := json.Marshal(artifact.Tags)
 		if err != nil {
-			tx.Rollback()
+			return err
+		}
+
+		techniquesJSON, err := json.Marshal(stringList(artifact.Techniques))
+		if err != nil {
 			return err
 		}
 
 		var eventTimeStr string
 		if artifact.EventTime != nil {
-			eventTimeStr = artifact.EventTime.Format("2006-01-02T15:04:05.000Z")
+			eventTimeStr = artifact.EventTime.Format(sqliteTimeFormat)
 		}
 
-		_, err = stmt.Exec(
-			artifact.Timestamp.Format("2006-01-02T15:04:05.000Z"),
+		res, err := stmt.Exec(
+			rowID(artifact),
+			artifact.Timestamp.Format(sqliteTimeFormat),
 			artifact.CollectorID,
 			artifact.ArtifactType,
 			artifact.Hostname,
@@ -176,16 +329,177 @@ func (w *SQLiteWriter) WriteMany(artifacts []models.Artifact) error {
 			artifact.RiskScore,
 			string(tagsJSON),
 			eventTimeStr,
+			string(techniquesJSON),
 		)
+		if err != nil {
+			return err
+		}
+
+		id, err := res.LastInsertId()
+		if err == nil {
+			err = w.indexText(tx, id, dataJSON)
+		}
+		if err == nil {
+			err = insertTimestamps(tx, id, artifact.Timestamps)
+		}
+		if err == nil {
+			err = insertIOCMatches(tx, id, artifact.IOCMatches)
+		}
+		if err == nil {
+			err = insertContributions(tx, id, artifact.Contributions)
+		}
+		if err == nil {
+			err = insertSuppression(tx, id, artifact.Suppression)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rowID is the row ID to insert an artifact under: the ID it was numbered
+// with before analysis, or NULL to let SQLite assign the next one
+func rowID(a models.Artifact) interface{} {
+	if a.ID == 0 {
+		return nil
+	}
+	return a.ID
+}
+
+// execer is the Exec method shared by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertTimestamps records the typed times of an artifact
+func insertTimestamps(db execer, artifactID int64, timestamps []models.Timestamp) error {
+	for _, t := range timestamps {
+		if _, err := db.Exec(`
+			INSERT INTO timestamps (artifact_id, kind, source, time)
+			VALUES (?, ?, ?, ?)
+		`, artifactID, string(t.Kind), t.Source, t.Time.UTC().Format(sqliteTimeFormat)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertIOCMatches records the indicators that matched an artifact
+func insertIOCMatches(db execer, artifactID int64, matches []models.IOCMatch) error {
+	for _, m := range matches {
+		var validUntil string
+		if m.ValidUntil != nil {
+			validUntil = m.ValidUntil.Format(time.RFC3339)
+		}
+		if _, err := db.Exec(`
+			INSERT INTO ioc_matches (
+				artifact_id, indicator_type, indicator_value, field, name,
+				source, event, event_id, confidence, valid_until
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, artifactID, m.Type, m.Value, m.Field, m.Name,
+			m.Source, m.Event, m.EventID, m.Confidence, validUntil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertContributions records the rules that make up an artifact's score
+func insertContributions(db execer, artifactID int64, contributions []models.ScoreContribution) error {
+	for _, c := range contributions {
+		if _, err := db.Exec(`
+			INSERT INTO score_contributions (
+				artifact_id, analyzer, rule, points, field, value
+			) VALUES (?, ?, ?, ?, ?, ?)
+		`, artifactID, c.Analyzer, c.Rule, c.Points, c.Field, c.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// insertSuppression records the rule that suppressed an artifact, if any
+func insertSuppression(db execer, artifactID int64, s *models.Suppression) error {
+	if s == nil {
+		return nil
+	}
+	var expires string
+	if s.Expires != nil {
+		expires = s.Expires.Format(time.RFC3339)
+	}
+	_, err := db.Exec(`
+		INSERT INTO suppressions (artifact_id, rule_id, justification, expires)
+		VALUES (?, ?, ?, ?)
+	`, artifactID, s.ID, s.Justification, expires)
+	return err
+}
+
+// WriteEntities replaces the entities table with the given process entities
+func (w *SQLiteWriter) WriteEntities(entities []models.Entity) error {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM entities`); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO entities (
+			pid, ppid, name, exe, username, cmdline, create_time,
+			parent_name, parent_exe, signature, team_id, signing_id,
+			risk_score, tags, connection_count, remote_endpoints,
+			listen_addrs, open_files, persistence
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, e := range entities {
+		persistence, err := json.Marshal(e.Persistence)
 		if err != nil {
 			tx.Rollback()
 			return err
 		}
+		if e.Persistence == nil {
+			persistence = []byte("[]")
+		}
+		lists := make([]string, 4)
+		for i, v := range [][]string{e.Tags, e.RemoteEndpoints, e.ListenAddrs, e.OpenFiles} {
+			b, err := json.Marshal(stringList(v))
+			if err != nil {
+				tx.Rollback()
+				return err
+			}
+			lists[i] = string(b)
+		}
+		if _, err := stmt.Exec(
+			e.PID, e.PPID, e.Name, e.Exe, e.User, e.Cmdline, e.CreateTime,
+			e.ParentName, e.ParentExe, e.Signature, e.TeamID, e.SigningID,
+			e.RiskScore, lists[0], e.Connections, lists[1],
+			lists[2], lists[3], string(persistence),
+		); err != nil {
+			tx.Rollback()
+			return err
+		}
 	}
 
 	return tx.Commit()
 }
 
+// stringList returns v, or an empty list for nil so it is stored as []
+func stringList(v []string) []string {
+	if v == nil {
+		return []string{}
+	}
+	return v
+}
+
 // UpdateArtifact updates risk_score and tags for an artifact by ID
 func (w *SQLiteWriter) UpdateArtifact(id int64, riskScore int, tags []string) error {
 	tagsJSON, err := json.Marshal(tags)
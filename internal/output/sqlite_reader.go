@@ -0,0 +1,555 @@
+package output
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// OpenSQLite opens an existing artifacts database for re-analysis or
+// reporting. Unlike NewSQLiteWriter it refuses to create a new file.
+func OpenSQLite(path string) (*SQLiteWriter, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	return NewSQLiteWriter(path)
+}
+
+// OpenSQLiteReadOnly opens an existing artifacts database that cannot be
+// modified through the returned handle, for ad-hoc queries
+func OpenSQLiteReadOnly(path string) (*SQLiteWriter, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	// A URI filename, escaped so ? and # in the path are not taken as its
+	// query or fragment
+	uri := url.URL{Scheme: "file", Path: filepath.ToSlash(abs), RawQuery: "mode=ro"}
+	db, err := sql.Open("sqlite3", uri.String())
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteWriter{db: db}, nil
+}
+
+// LoadArtifacts reads every artifact back from the database. Collected data
+// and metadata are restored as written; risk scores and tags are left empty
+// unless withAnalysis is set, so analyzers can be re-run from a clean slate.
+// Without analysis, artifacts created by analyzers are left out too.
+func (w *SQLiteWriter) LoadArtifacts(withAnalysis bool) ([]models.Artifact, error) {
+	// Read-only handles can't migrate databases written before techniques
+	// were recorded
+	techniques := "techniques"
+	if ok, err := w.hasColumn("artifacts", "techniques"); err != nil {
+		return nil, err
+	} else if !ok {
+		techniques = "NULL"
+	}
+	rows, err := w.db.Query(`
+		SELECT id, timestamp, collector_id, artifact_type, hostname, data, metadata,
+			risk_score, tags, event_time, ` + techniques + `
+		FROM artifacts
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var artifacts []models.Artifact
+	for rows.Next() {
+		var (
+			a                          models.Artifact
+			ts, dataJSON, metadataJSON string
+			tagsJSON, eventTime        *string
+			techniquesJSON             *string
+		)
+		if err := rows.Scan(&a.ID, &ts, &a.CollectorID, &a.ArtifactType, &a.Hostname,
+			&dataJSON, &metadataJSON, &a.RiskScore, &tagsJSON, &eventTime, &techniquesJSON); err != nil {
+			return nil, err
+		}
+
+		a.Timestamp, _ = time.Parse(sqliteTimeFormat, ts)
+		if err := json.Unmarshal([]byte(dataJSON), &a.Data); err != nil {
+			return nil, fmt.Errorf("artifact %d: decoding data: %w", a.ID, err)
+		}
+		if err := json.Unmarshal([]byte(metadataJSON), &a.Metadata); err != nil {
+			return nil, fmt.Errorf("artifact %d: decoding metadata: %w", a.ID, err)
+		}
+		if eventTime != nil && *eventTime != "" {
+			if t, err := time.Parse(sqliteTimeFormat, *eventTime); err == nil {
+				a.EventTime = &t
+			}
+		}
+
+		if withAnalysis {
+			if tagsJSON != nil {
+				_ = json.Unmarshal([]byte(*tagsJSON), &a.Tags)
+			}
+			if techniquesJSON != nil {
+				_ = json.Unmarshal([]byte(*techniquesJSON), &a.Techniques)
+			}
+			if a.RiskScore > 0 {
+				a.Severity = models.SeverityFromScore(a.RiskScore)
+			}
+		} else {
+			if a.CollectorID == models.AnalysisCollectorID {
+				continue
+			}
+			a.RiskScore = 0
+		}
+
+		artifacts = append(artifacts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := w.loadTimestamps(artifacts); err != nil {
+		return nil, err
+	}
+	if withAnalysis {
+		if err := w.loadIOCMatches(artifacts); err != nil {
+			return nil, err
+		}
+		if err := w.loadContributions(artifacts); err != nil {
+			return nil, err
+		}
+		if err := w.loadSuppressions(artifacts); err != nil {
+			return nil, err
+		}
+	}
+	return artifacts, nil
+}
+
+// loadTimestamps attaches the recorded typed times to their artifacts.
+// Databases written before typed times were recorded have none.
+func (w *SQLiteWriter) loadTimestamps(artifacts []models.Artifact) error {
+	var exists int
+	if err := w.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'timestamps'`).Scan(&exists); err != nil || exists == 0 {
+		return err
+	}
+
+	byID := make(map[int64]int, len(artifacts))
+	for i, a := range artifacts {
+		byID[a.ID] = i
+	}
+
+	rows, err := w.db.Query(`SELECT artifact_id, kind, source, time FROM timestamps ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			artifactID int64
+			t          models.Timestamp
+			source     *string
+			ts         string
+		)
+		if err := rows.Scan(&artifactID, &t.Kind, &source, &ts); err != nil {
+			return err
+		}
+		i, ok := byID[artifactID]
+		if !ok {
+			continue
+		}
+		t.Source = deref(source)
+		t.Time, _ = time.Parse(sqliteTimeFormat, ts)
+		artifacts[i].Timestamps = append(artifacts[i].Timestamps, t)
+	}
+	return rows.Err()
+}
+
+// loadIOCMatches attaches the recorded IOC matches to their artifacts.
+// Databases written before matches were recorded have none.
+func (w *SQLiteWriter) loadIOCMatches(artifacts []models.Artifact) error {
+	var exists int
+	if err := w.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'ioc_matches'`).Scan(&exists); err != nil || exists == 0 {
+		return err
+	}
+
+	byID := make(map[int64]int, len(artifacts))
+	for i, a := range artifacts {
+		byID[a.ID] = i
+	}
+
+	rows, err := w.db.Query(`
+		SELECT artifact_id, indicator_type, indicator_value, field, name,
+			source, event, event_id, confidence, valid_until
+		FROM ioc_matches
+		ORDER BY id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			artifactID                               int64
+			m                                        models.IOCMatch
+			name, source, event, eventID, validUntil *string
+			confidence                               *int
+		)
+		if err := rows.Scan(&artifactID, &m.Type, &m.Value, &m.Field, &name,
+			&source, &event, &eventID, &confidence, &validUntil); err != nil {
+			return err
+		}
+		i, ok := byID[artifactID]
+		if !ok {
+			continue
+		}
+		m.Name, m.Source, m.Event, m.EventID = deref(name), deref(source), deref(event), deref(eventID)
+		m.Confidence = -1
+		if confidence != nil {
+			m.Confidence = *confidence
+		}
+		if validUntil != nil && *validUntil != "" {
+			if t, err := time.Parse(time.RFC3339, *validUntil); err == nil {
+				m.ValidUntil = &t
+			}
+		}
+		artifacts[i].IOCMatches = append(artifacts[i].IOCMatches, m)
+	}
+	return rows.Err()
+}
+
+// loadContributions attaches the recorded score contributions to their
+// artifacts. Databases written before contributions were recorded have none.
+func (w *SQLiteWriter) loadContributions(artifacts []models.Artifact) error {
+	var exists int
+	if err := w.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'score_contributions'`).Scan(&exists); err != nil || exists == 0 {
+		return err
+	}
+
+	byID := make(map[int64]int, len(artifacts))
+	for i, a := range artifacts {
+		byID[a.ID] = i
+	}
+
+	rows, err := w.db.Query(`
+		SELECT artifact_id, analyzer, rule, points, field, value
+		FROM score_contributions
+		ORDER BY id
+	`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			artifactID   int64
+			c            models.ScoreContribution
+			field, value *string
+		)
+		if err := rows.Scan(&artifactID, &c.Analyzer, &c.Rule, &c.Points, &field, &value); err != nil {
+			return err
+		}
+		if i, ok := byID[artifactID]; ok {
+			c.Field, c.Value = deref(field), deref(value)
+			artifacts[i].Contributions = append(artifacts[i].Contributions, c)
+		}
+	}
+	return rows.Err()
+}
+
+// loadSuppressions marks artifacts with the suppression rule recorded for
+// them. Databases written before suppressions were recorded have none.
+func (w *SQLiteWriter) loadSuppressions(artifacts []models.Artifact) error {
+	var exists int
+	if err := w.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'suppressions'`).Scan(&exists); err != nil || exists == 0 {
+		return err
+	}
+
+	byID := make(map[int64]int, len(artifacts))
+	for i, a := range artifacts {
+		byID[a.ID] = i
+	}
+
+	rows, err := w.db.Query(`SELECT artifact_id, rule_id, justification, expires FROM suppressions`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			artifactID int64
+			s          models.Suppression
+			expires    *string
+		)
+		if err := rows.Scan(&artifactID, &s.ID, &s.Justification, &expires); err != nil {
+			return err
+		}
+		i, ok := byID[artifactID]
+		if !ok {
+			continue
+		}
+		if expires != nil && *expires != "" {
+			if t, err := time.Parse(time.RFC3339, *expires); err == nil {
+				s.Expires = &t
+			}
+		}
+		artifacts[i].Suppression = &s
+	}
+	return rows.Err()
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// SaveAnalysis writes risk scores, score contributions, suppressions, tags
+// and techniques back for artifacts loaded with LoadArtifacts, along with
+// their data where analysis changed it (the process context correlation
+// adds), and inserts any artifacts the analyzers created, replacing those
+// of previous analyses
+func (w *SQLiteWriter) SaveAnalysis(artifacts []models.Artifact) error {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	// Analyzer-created rows not handed back are from a previous analysis
+	kept := make(map[int64]bool)
+	for _, a := range artifacts {
+		if a.ID != 0 && a.CollectorID == models.AnalysisCollectorID {
+			kept[a.ID] = true
+		}
+	}
+	rows, err := tx.Query(`SELECT id FROM artifacts WHERE collector_id = ?`, models.AnalysisCollectorID)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	var stale []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return err
+		}
+		if !kept[id] {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+	for _, id := range stale {
+		if _, err := tx.Exec(`DELETE FROM artifacts WHERE id = ?`, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM timestamps WHERE artifact_id = ?`, id); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if w.fts {
+			if _, err := tx.Exec(`DELETE FROM artifacts_fts WHERE rowid = ?`, id); err != nil {
+				tx.Rollback()
+				return err
+			}
+		}
+	}
+
+	// IOC matches, contributions and suppressions are rewritten along with
+	// the scores they explain
+	for _, table := range []string{"ioc_matches", "score_contributions", "suppressions"} {
+		if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	stmt, err := tx.Prepare(`UPDATE artifacts SET risk_score = ?, tags = ?, techniques = ? WHERE id = ?`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	var created []models.Artifact
+	for _, a := range artifacts {
+		if a.ID == 0 {
+			created = append(created, a)
+			continue
+		}
+		tagsJSON, err := json.Marshal(a.Tags)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		techniquesJSON, err := json.Marshal(stringList(a.Techniques))
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if _, err := stmt.Exec(a.RiskScore, string(tagsJSON), string(techniquesJSON), a.ID); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := w.updateData(tx, a); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := insertIOCMatches(tx, a.ID, a.IOCMatches); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := insertContributions(tx, a.ID, a.Contributions); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := insertSuppression(tx, a.ID, a.Suppression); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := w.insertArtifacts(tx, created); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// updateData rewrites an artifact's data, and its full-text index entry,
+// if it differs from what is stored
+func (w *SQLiteWriter) updateData(tx *sql.Tx, a models.Artifact) error {
+	dataJSON, err := json.Marshal(a.Data)
+	if err != nil {
+		return err
+	}
+	var stored string
+	err = tx.QueryRow(`SELECT data FROM artifacts WHERE id = ?`, a.ID).Scan(&stored)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return err
+	case stored == string(dataJSON):
+		return nil
+	}
+	if _, err := tx.Exec(`UPDATE artifacts SET data = ? WHERE id = ?`, string(dataJSON), a.ID); err != nil {
+		return err
+	}
+	if w.fts {
+		if _, err := tx.Exec(`DELETE FROM artifacts_fts WHERE rowid = ?`, a.ID); err != nil {
+			return err
+		}
+	}
+	return w.indexText(tx, a.ID, dataJSON)
+}
+
+// WriteResults records per-collector outcomes so reports can be regenerated
+// from the database alone
+func (w *SQLiteWriter) WriteResults(results []models.CollectionResult) error {
+	tx, err := w.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT OR REPLACE INTO collector_results (
+			collector_id, started_at, duration_ms, artifact_count, error_message, skip_reason
+		) VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, r := range results {
+		var errMsg string
+		if r.Error != nil {
+			errMsg = r.Error.Error()
+		}
+		if _, err := stmt.Exec(r.CollectorID, r.StartedAt.Format(sqliteTimeFormat),
+			r.Duration.Milliseconds(), len(r.Artifacts), errMsg, r.SkipReason); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LoadResults rebuilds the collection results recorded by WriteResults,
+// attaching each collector's artifacts. Databases written before results were
+// recorded get one synthesized result per collector seen in artifacts.
+func (w *SQLiteWriter) LoadResults(artifacts []models.Artifact) ([]models.CollectionResult, error) {
+	byCollector := make(map[string][]models.Artifact)
+	var order []string
+	for _, a := range artifacts {
+		if _, ok := byCollector[a.CollectorID]; !ok {
+			order = append(order, a.CollectorID)
+		}
+		byCollector[a.CollectorID] = append(byCollector[a.CollectorID], a)
+	}
+
+	rows, err := w.db.Query(`
+		SELECT collector_id, started_at, duration_ms, error_message, skip_reason
+		FROM collector_results
+		ORDER BY collector_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.CollectionResult
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var (
+			r                  models.CollectionResult
+			startedAt          string
+			durationMs         int64
+			errMsg, skipReason *string
+		)
+		if err := rows.Scan(&r.CollectorID, &startedAt, &durationMs, &errMsg, &skipReason); err != nil {
+			return nil, err
+		}
+		r.StartedAt, _ = time.Parse(sqliteTimeFormat, startedAt)
+		r.Duration = time.Duration(durationMs) * time.Millisecond
+		if errMsg != nil && *errMsg != "" {
+			r.Error = errors.New(*errMsg)
+		}
+		if skipReason != nil {
+			r.SkipReason = *skipReason
+		}
+		r.Artifacts = byCollector[r.CollectorID]
+		seen[r.CollectorID] = true
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range order {
+		if !seen[id] {
+			results = append(results, models.CollectionResult{CollectorID: id, Artifacts: byCollector[id]})
+		}
+	}
+	return results, nil
+}
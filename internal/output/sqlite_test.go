@@ -0,0 +1,243 @@
+package output
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+func newTestSQLite(t *testing.T) *SQLiteWriter {
+	t.Helper()
+	w, err := NewSQLiteWriter(filepath.Join(t.TempDir(), "artifacts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w
+}
+
+func testArtifact(artifactType string, data map[string]interface{}) models.Artifact {
+	return models.Artifact{
+		Timestamp:    time.Date(2026, 2, 6, 19, 1, 1, 0, time.UTC),
+		CollectorID:  "test",
+		ArtifactType: artifactType,
+		Hostname:     "host",
+		Data:         data,
+		Metadata:     models.ArtifactMetadata{Success: true},
+	}
+}
+
+func queryString(t *testing.T, w *SQLiteWriter, query string, args ...interface{}) string {
+	t.Helper()
+	var s string
+	if err := w.db.QueryRow(query, args...).Scan(&s); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return s
+}
+
+func TestSaveAnalysisUpdatesData(t *testing.T) {
+	w := newTestSQLite(t)
+	if err := w.WriteMany([]models.Artifact{
+		testArtifact("running_process", map[string]interface{}{"pid": 42, "name": "curl"}),
+		testArtifact("network_connection", map[string]interface{}{"pid": 42, "remote_addr": "10.1.2.3"}),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	artifacts, err := w.LoadArtifacts(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// What correlate.Enrich adds to a connection
+	artifacts[1].Data["process_name"] = "curl"
+	if err := w.SaveAnalysis(artifacts); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := queryString(t, w, `SELECT process_name FROM connections`); got != "curl" {
+		t.Errorf("connections.process_name = %q, want curl", got)
+	}
+	reloaded, err := w.LoadArtifacts(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded[1].Data["process_name"]; got != "curl" {
+		t.Errorf("reloaded process_name = %v, want curl", got)
+	}
+	if got := queryString(t, w, `SELECT COUNT(*) FROM artifacts_fts WHERE artifacts_fts MATCH 'process_name'`); got != "1" {
+		t.Errorf("full-text matches for the new field = %s, want 1", got)
+	}
+	// The unchanged process keeps its single index entry
+	if got := queryString(t, w, `SELECT COUNT(*) FROM artifacts_fts`); got != "2" {
+		t.Errorf("full-text rows = %s, want 2", got)
+	}
+}
+
+func TestViews(t *testing.T) {
+	w := newTestSQLite(t)
+	scored := func(score int, name string) models.Artifact {
+		a := testArtifact("persistence_item", map[string]interface{}{"name": name})
+		a.RiskScore = score
+		return a
+	}
+	suppressed := scored(90, "allowed")
+	suppressed.Suppression = &models.Suppression{ID: "allow-1", Justification: "known tool"}
+	if err := w.WriteMany([]models.Artifact{
+		testArtifact("running_process", map[string]interface{}{
+			"pid": 42, "name": "curl", "exe": "/usr/bin/curl", "cmdline": "curl -s evil.example",
+		}),
+		scored(models.CriticalScore, "critical"),
+		scored(models.HighScore, "high"),
+		scored(models.FindingScore, "medium"),
+		scored(models.FindingScore-1, "low"),
+		suppressed,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := queryString(t, w, `SELECT name || ' ' || exe || ' ' || cmdline FROM processes WHERE pid = 42`); got != "curl /usr/bin/curl curl -s evil.example" {
+		t.Errorf("processes row = %q", got)
+	}
+
+	rows, err := w.db.Query(`SELECT json_extract(data, '$.name'), severity FROM findings ORDER BY risk_score DESC`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var name, severity string
+		if err := rows.Scan(&name, &severity); err != nil {
+			t.Fatal(err)
+		}
+		if want := models.SeverityFromScore(map[string]int{
+			"critical": models.CriticalScore, "high": models.HighScore, "medium": models.FindingScore,
+		}[name]); severity != want {
+			t.Errorf("%s finding severity = %s, want %s", name, severity, want)
+		}
+		got = append(got, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	// The suppressed artifact and the one below FindingScore are left out
+	if want := []string{"critical", "high", "medium"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("findings = %v, want %v", got, want)
+	}
+
+	if got := queryString(t, w, `
+		SELECT a.artifact_type FROM artifacts_fts f JOIN artifacts a ON a.id = f.rowid
+		WHERE artifacts_fts MATCH 'evil'
+	`); got != "running_process" {
+		t.Errorf("full-text match = %q, want running_process", got)
+	}
+}
+
+func TestAnalysisRoundTrip(t *testing.T) {
+	w := newTestSQLite(t)
+	if err := w.WriteMany([]models.Artifact{
+		testArtifact("running_process", map[string]interface{}{"pid": 42, "name": "curl"}),
+		testArtifact("launch_agent", map[string]interface{}{"label": "com.example.agent"}),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	yaraMatch := func(rule string) models.Artifact {
+		a := testArtifact("yara_match", map[string]interface{}{"rule": rule})
+		a.CollectorID = models.AnalysisCollectorID
+		a.RiskScore = 50
+		a.AddTimestamp(models.TimeModified, "", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
+		return a
+	}
+	count := func(query string) string {
+		t.Helper()
+		return queryString(t, w, `SELECT COUNT(*) FROM `+query)
+	}
+
+	// First analysis: a scored process with an IOC match, a suppressed
+	// launch agent and a created YARA match
+	artifacts, err := w.LoadArtifacts(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	artifacts[0].RiskScore = 70
+	artifacts[0].Tags = []string{"ioc_match"}
+	artifacts[0].IOCMatches = []models.IOCMatch{{Type: "process", Value: "curl", Field: "name", Confidence: -1}}
+	artifacts[0].Contributions = []models.ScoreContribution{
+		{Analyzer: "ioc", Rule: "ioc_match", Points: 50},
+		{Analyzer: "process", Rule: "network_tool", Points: 20},
+	}
+	artifacts[1].RiskScore = 45
+	artifacts[1].Suppression = &models.Suppression{ID: "allow-1", Justification: "known agent"}
+	artifacts = append(artifacts, yaraMatch("first_rule"))
+	if err := w.SaveAnalysis(artifacts); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := w.LoadArtifacts(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 3 {
+		t.Fatalf("loaded %d artifacts, want 3", len(loaded))
+	}
+	if loaded[0].RiskScore != 70 || len(loaded[0].IOCMatches) != 1 || len(loaded[0].Contributions) != 2 {
+		t.Errorf("process = score %d, %d IOC matches, %d contributions; want 70, 1, 2",
+			loaded[0].RiskScore, len(loaded[0].IOCMatches), len(loaded[0].Contributions))
+	}
+	if s := loaded[1].Suppression; s == nil || s.ID != "allow-1" {
+		t.Errorf("launch agent suppression = %v, want allow-1", s)
+	}
+	if got := loaded[2]; got.Data["rule"] != "first_rule" || len(got.Timestamps) != 1 {
+		t.Errorf("created artifact = rule %v, %d timestamps; want first_rule, 1", got.Data["rule"], len(got.Timestamps))
+	}
+
+	// Loading for re-analysis drops scores and the created artifact
+	artifacts, err = w.LoadArtifacts(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(artifacts) != 2 {
+		t.Fatalf("loaded %d artifacts without analysis, want 2", len(artifacts))
+	}
+	for _, a := range artifacts {
+		if a.RiskScore != 0 || a.Tags != nil || a.IOCMatches != nil || a.Contributions != nil || a.Suppression != nil {
+			t.Errorf("%s loaded without analysis = %+v, want no analysis", a.ArtifactType, a)
+		}
+	}
+
+	// Second analysis: one contribution left, nothing suppressed and a
+	// different YARA match
+	artifacts[0].RiskScore = 20
+	artifacts[0].Contributions = []models.ScoreContribution{{Analyzer: "process", Rule: "network_tool", Points: 20}}
+	artifacts = append(artifacts, yaraMatch("second_rule"))
+	if err := w.SaveAnalysis(artifacts); err != nil {
+		t.Fatal(err)
+	}
+
+	for query, want := range map[string]string{
+		`artifacts`:           "3",
+		`ioc_matches`:         "0",
+		`score_contributions`: "1",
+		`suppressions`:        "0",
+		// The first YARA match's timestamp went with it
+		`timestamps`: "1",
+		`artifacts_fts WHERE artifacts_fts MATCH 'first_rule'`:  "0",
+		`artifacts_fts WHERE artifacts_fts MATCH 'second_rule'`: "1",
+	} {
+		if got := count(query); got != want {
+			t.Errorf("count of %s = %s, want %s", query, got, want)
+		}
+	}
+	if got := queryString(t, w, `
+		SELECT json_extract(a.data, '$.rule') FROM timestamps t JOIN artifacts a ON a.id = t.artifact_id
+	`); got != "second_rule" {
+		t.Errorf("timestamp belongs to %q, want second_rule", got)
+	}
+	if got := queryString(t, w, `SELECT risk_score FROM artifacts WHERE id = ?`, artifacts[0].ID); got != "20" {
+		t.Errorf("process risk_score = %s, want 20", got)
+	}
+}
@@ -0,0 +1,225 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// sqlView is a view over the artifacts of some types with their Data keys
+// as columns, so they can be queried without json_extract
+type sqlView struct {
+	name    string
+	types   []string
+	columns []viewColumn
+}
+
+// viewColumn is a view column read from the first of keys that is set.
+// Indexed columns get an expression index on artifacts limited to the
+// view's types, which SQLite uses for lookups through the view.
+type viewColumn struct {
+	name    string
+	keys    []string
+	indexed bool
+}
+
+// column reads name from the Data keys given, or from the key name itself
+func column(name string, keys ...string) viewColumn {
+	if len(keys) == 0 {
+		keys = []string{name}
+	}
+	return viewColumn{name: name, keys: keys}
+}
+
+// indexed is a column with an index
+func indexed(name string, keys ...string) viewColumn {
+	c := column(name, keys...)
+	c.indexed = true
+	return c
+}
+
+// sqlViews are the per-type views created in every database
+var sqlViews = []sqlView{
+	{"processes", []string{"running_process"}, []viewColumn{
+		indexed("pid"), column("ppid"), indexed("name"), indexed("exe"), column("cmdline"),
+		column("username"), column("cwd"), column("create_time"), column("cpu_percent"),
+		column("memory_percent"), column("memory_rss_bytes"), column("num_connections"),
+		indexed("exe_sha256"), column("exe_signed"), column("exe_adhoc_signed"),
+		column("exe_team_id"), column("exe_signing_id"),
+	}},
+	{"connections", []string{"network_connection", "open_network_file"}, []viewColumn{
+		indexed("pid"), column("process_name", "process_name", "command"), column("process_exe"),
+		column("process_user", "process_user", "user"), column("process_signed"),
+		column("family"), column("type"), column("local_addr"), column("local_port"),
+		indexed("remote_addr"), column("remote_port"), column("status", "status", "state"),
+	}},
+	{"launch_items", []string{"user_launch_agent", "system_launch_agent", "system_launch_daemon"}, []viewColumn{
+		column("name"), column("path"), indexed("label"), column("program"),
+		column("program_arguments"), column("run_at_load"), column("keep_alive"),
+		column("disabled"), indexed("target_path"), column("target_exists"),
+		indexed("target_sha256"), column("target_signed"), column("target_team_id"),
+		column("plist_sha256"), column("mod_time"), column("user"),
+	}},
+	{"login_items", []string{"login_item_btm", "login_item_backgrounditems"}, []viewColumn{
+		column("name"), indexed("identifier"), column("developer_name"), indexed("team_id"),
+		column("bundle_id"), column("path"), column("executable_path"), column("app_path"),
+		column("url"), column("enabled"), column("allowed"), column("launchd_label"),
+		column("launchd_target"),
+	}},
+	{"scheduled_tasks", []string{"system_cron", "user_crontab", "at_job"}, []viewColumn{
+		column("path"), column("line_number"), column("entry"), column("user"),
+	}},
+	{"browser_visits", []string{"safari_history", "chrome_history"}, []viewColumn{
+		indexed("url"), column("title"), column("visit_time", "visit_time", "last_visit_time"),
+		column("visit_count"), column("user"),
+	}},
+	{"quarantine_events", []string{"quarantine_event"}, []viewColumn{
+		column("event_id"), column("timestamp"), column("agent_name"), indexed("agent_bundle"),
+		indexed("data_url"), column("origin_url"), indexed("file_sha256"), column("user"),
+	}},
+	{"tcc_grants", []string{"tcc_permission"}, []viewColumn{
+		indexed("service"), indexed("client"), column("client_type"),
+		column("auth_value", "auth_value", "allowed"), column("auth_reason"),
+		column("indirect_object_identifier"), column("last_modified"),
+		column("database_type"), column("user"),
+	}},
+	{"shell_commands", []string{"bash_history", "zsh_history"}, []viewColumn{
+		column("user"), column("line_number"), column("command"),
+	}},
+	{"applications", []string{"system_application", "user_application"}, []viewColumn{
+		column("name"), column("path"), indexed("bundle_id"), column("version"),
+		column("executable"), indexed("executable_sha256"), column("executable_signed"),
+		column("executable_team_id"), column("mod_time"),
+	}},
+	{"extensions", []string{"kernel_extension", "system_extension", "library_extension"}, []viewColumn{
+		column("name"), indexed("identifier"), column("version"), column("state"),
+		column("path"), column("executable_sha256"), column("executable_team_id"),
+		column("mod_time"),
+	}},
+	{"ssh_keys", []string{"ssh_private_key", "ssh_public_key", "ssh_authorized_key", "ssh_known_host"}, []viewColumn{
+		column("path"), column("key_type"), column("user"), column("mod_time"),
+		indexed("file_sha256"),
+	}},
+	{"user_accounts", []string{"user_account"}, []viewColumn{
+		indexed("username"), column("uid"), column("real_name"), column("shell"),
+		column("home_dir"), column("home_exists"),
+	}},
+	{"app_usage", []string{"app_usage"}, []viewColumn{
+		indexed("app_name"), column("start_time"), column("end_time"),
+		column("duration_seconds"), column("user"),
+	}},
+	{"log_entries", []string{"unified_log_security", "unified_log_network", "unified_log_process", "unified_log_errors"}, []viewColumn{
+		column("timestamp"), column("category"), indexed("process"), column("process_path"),
+		column("pid"), column("subsystem"), column("message_type"), column("event_message"),
+	}},
+}
+
+// expr is the SQL expression of a column
+func (c viewColumn) expr() string {
+	parts := make([]string, len(c.keys))
+	for i, k := range c.keys {
+		parts[i] = fmt.Sprintf("json_extract(data, '$.%s')", k)
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "COALESCE(" + strings.Join(parts, ", ") + ")"
+}
+
+// filter is the WHERE clause selecting a view's artifacts
+func (v sqlView) filter() string {
+	return "artifact_type IN ('" + strings.Join(v.types, "', '") + "')"
+}
+
+// statements returns the SQL that (re)creates the view and its indexes
+func (v sqlView) statements() []string {
+	cols := []string{"id", "artifact_type", "hostname"}
+	var stmts []string
+	for _, c := range v.columns {
+		cols = append(cols, c.expr()+" AS "+c.name)
+		if c.indexed {
+			stmts = append(stmts, fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_%s ON artifacts(%s) WHERE %s",
+				v.name, c.name, c.expr(), v.filter()))
+		}
+	}
+	cols = append(cols, "risk_score", "tags", "source_path")
+	return append([]string{
+		"DROP VIEW IF EXISTS " + v.name,
+		fmt.Sprintf("CREATE VIEW %s AS SELECT %s FROM artifacts WHERE %s", v.name, strings.Join(cols, ", "), v.filter()),
+	}, stmts...)
+}
+
+// findingsView lists the artifacts reported as findings: scored at least
+// models.FindingScore and not suppressed. Severity follows
+// models.SeverityFromScore.
+var findingsView = fmt.Sprintf(`
+	DROP VIEW IF EXISTS findings;
+	CREATE VIEW findings AS
+	SELECT a.id, a.artifact_type, a.collector_id, a.hostname, a.risk_score,
+		CASE WHEN a.risk_score >= %d THEN 'critical'
+			WHEN a.risk_score >= %d THEN 'high'
+			ELSE 'medium' END AS severity,
+		a.tags, a.techniques, a.source_path, a.data
+	FROM artifacts a LEFT JOIN suppressions s ON s.artifact_id = a.id
+	WHERE a.risk_score >= %d AND s.artifact_id IS NULL;
+`, models.CriticalScore, models.HighScore, models.FindingScore)
+
+// createViews (re)creates the per-type views, their indexes and the
+// findings view, so databases written by older versions get the current
+// definitions
+func (w *SQLiteWriter) createViews() error {
+	for _, v := range sqlViews {
+		for _, stmt := range v.statements() {
+			if _, err := w.db.Exec(stmt); err != nil {
+				return fmt.Errorf("creating view %s: %w", v.name, err)
+			}
+		}
+	}
+	if _, err := w.db.Exec(findingsView); err != nil {
+		return fmt.Errorf("creating view findings: %w", err)
+	}
+	return nil
+}
+
+// createTextIndex creates the full-text index over Data, filling it from
+// the artifacts already stored. FTS5 needs the sqlite_fts5 build tag;
+// builds without it fall back to FTS4, which answers the same MATCH
+// queries. An FTS5 index opened by a build without FTS5 is left alone, and
+// caught up with the artifacts written meanwhile when a build with FTS5
+// opens the database again.
+func (w *SQLiteWriter) createTextIndex() error {
+	var exists int
+	if err := w.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'artifacts_fts'`).Scan(&exists); err != nil {
+		return err
+	}
+	if exists > 0 {
+		_, err := w.db.Exec(`
+			INSERT INTO artifacts_fts (rowid, data)
+			SELECT id, data FROM artifacts
+			WHERE id > (SELECT COALESCE(MAX(rowid), 0) FROM artifacts_fts)
+		`)
+		w.fts = err == nil
+		return nil
+	}
+
+	if _, err := w.db.Exec(`CREATE VIRTUAL TABLE artifacts_fts USING fts5(data)`); err != nil {
+		if _, err := w.db.Exec(`CREATE VIRTUAL TABLE artifacts_fts USING fts4(data)`); err != nil {
+			return fmt.Errorf("creating full-text index: %w", err)
+		}
+	}
+	if _, err := w.db.Exec(`INSERT INTO artifacts_fts (rowid, data) SELECT id, data FROM artifacts`); err != nil {
+		return fmt.Errorf("filling full-text index: %w", err)
+	}
+	w.fts = true
+	return nil
+}
+
+// indexText adds an artifact's data to the full-text index
+func (w *SQLiteWriter) indexText(db execer, id int64, data []byte) error {
+	if !w.fts {
+		return nil
+	}
+	_, err := db.Exec(`INSERT INTO artifacts_fts (rowid, data) VALUES (?, ?)`, id, string(data))
+	return err
+}
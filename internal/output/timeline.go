@@ -27,31 +27,33 @@ type TimelineEntry struct {
 }
 
 // GenerateTimeline creates a timeline.csv from collected artifacts in Timesketch CSV format.
+// An artifact gets a row for each time it records.
 func GenerateTimeline(artifacts []models.Artifact, outputPath string, summarize func(models.Artifact) string) error {
 	var entries []TimelineEntry
 
 	for _, a := range artifacts {
-		et := resolveEventTime(a)
 		msg := ""
 		if summarize != nil {
 			msg = summarize(a)
 		}
 
-		entries = append(entries, TimelineEntry{
-			Message:       msg,
-			Datetime:      et.UTC().Format(time.RFC3339),
-			Timestamp:     et.UnixMicro(),
-			TimestampDesc: timestampDesc(a.ArtifactType),
-			CollectorID:   a.CollectorID,
-			ArtifactType:  a.ArtifactType,
-			Hostname:      a.Hostname,
-			RiskScore:     a.RiskScore,
-			Data:          a.Data,
-		})
+		for _, ev := range timelineEvents(a) {
+			entries = append(entries, TimelineEntry{
+				Message:       msg,
+				Datetime:      ev.time.UTC().Format(time.RFC3339),
+				Timestamp:     ev.time.UnixMicro(),
+				TimestampDesc: ev.desc,
+				CollectorID:   a.CollectorID,
+				ArtifactType:  a.ArtifactType,
+				Hostname:      a.Hostname,
+				RiskScore:     a.RiskScore,
+				Data:          a.Data,
+			})
+		}
 	}
 
 	// Sort chronologically by timestamp (microsecond epoch)
-	sort.Slice(entries, func(i, j int) bool {
+	sort.SliceStable(entries, func(i, j int) bool {
 		return entries[i].Timestamp < entries[j].Timestamp
 	})
 
@@ -122,32 +124,46 @@ func timestampDesc(artifactType string) string {
 	}
 }
 
-// resolveEventTime determines the best event time for an artifact
-func resolveEventTime(a models.Artifact) time.Time {
-	// Priority 1: explicit EventTime
+// timestampKindDesc are the Timesketch timestamp descriptions of each kind
+// of time, named as Plaso names them
+var timestampKindDesc = map[models.TimestampKind]string{
+	models.TimeModified:   "Content Modification Time",
+	models.TimeAccessed:   "Last Access Time",
+	models.TimeChanged:    "Metadata Modification Time",
+	models.TimeCreated:    "Creation Time",
+	models.TimeFirstSeen:  "First Seen Time",
+	models.TimeLastSeen:   "Last Seen Time",
+	models.TimeStarted:    "Start Time",
+	models.TimeEnded:      "End Time",
+	models.TimeDownloaded: "File Downloaded",
+}
+
+// timelineEvent is one time of an artifact
+type timelineEvent struct {
+	time time.Time
+	desc string
+}
+
+// timelineEvents returns one event per time an artifact records, its event
+// time first. An artifact that records no time of its own has a single
+// event at its collection time, described by its type.
+func timelineEvents(a models.Artifact) []timelineEvent {
+	var events []timelineEvent
 	if a.EventTime != nil {
-		return *a.EventTime
+		events = append(events, timelineEvent{*a.EventTime, timestampDesc(a.ArtifactType)})
 	}
-
-	// Priority 2: known time fields in Data
-	timeFields := []string{"visit_time", "last_visit_time", "mod_time", "timestamp", "event_time", "last_modified", "modified", "created"}
-	for _, field := range timeFields {
-		if v, ok := a.Data[field]; ok {
-			if s, ok := v.(string); ok {
-				for _, layout := range []string{
-					time.RFC3339,
-					"2006-01-02T15:04:05.000Z",
-					"2006-01-02 15:04:05",
-					time.RFC3339Nano,
-				} {
-					if t, err := time.Parse(layout, s); err == nil {
-						return t
-					}
-				}
-			}
+	for _, ts := range a.Timestamps {
+		desc, ok := timestampKindDesc[ts.Kind]
+		if !ok {
+			desc = string(ts.Kind)
+		}
+		if ts.Source != "" {
+			desc += " (" + ts.Source + ")"
 		}
+		events = append(events, timelineEvent{ts.Time, desc})
 	}
-
-	// Priority 3: collection timestamp
-	return a.Timestamp
+	if len(events) == 0 {
+		events = append(events, timelineEvent{a.Timestamp, timestampDesc(a.ArtifactType)})
+	}
+	return events
 }
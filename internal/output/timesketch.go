@@ -0,0 +1,148 @@
+package output
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// timelineReserved are the attributes the JSONL timeline sets itself; Data
+// keys with these names are prefixed with data_
+var timelineReserved = map[string]bool{
+	"message": true, "datetime": true, "timestamp": true, "timestamp_desc": true,
+	"data_type": true, "display_name": true, "collector_id": true, "artifact_type": true,
+	"hostname": true, "risk_score": true, "severity": true, "techniques": true,
+	"suppression": true, "tag": true,
+}
+
+// GenerateTimelineJSONL creates a Timesketch JSONL timeline. Unlike the CSV
+// timeline, Data keys become attributes of their own (nested keys joined
+// with _), so they can be filtered on, and tags, severity and suppression
+// go in the tag attribute Timesketch shows as labels. Like the CSV
+// timeline, an artifact produces one event per time it records.
+func GenerateTimelineJSONL(artifacts []models.Artifact, outputPath string, summarize func(models.Artifact) string) error {
+	var entries []map[string]interface{}
+	for _, a := range artifacts {
+		msg := ""
+		if summarize != nil {
+			msg = summarize(a)
+		}
+		attrs := timelineAttributes(a)
+		for _, ev := range timelineEvents(a) {
+			entry := make(map[string]interface{}, len(attrs)+4)
+			for k, v := range attrs {
+				entry[k] = v
+			}
+			entry["message"] = msg
+			entry["datetime"] = ev.time.UTC().Format(time.RFC3339Nano)
+			entry["timestamp"] = ev.time.UnixMicro()
+			entry["timestamp_desc"] = ev.desc
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i]["timestamp"].(int64) < entries[j]["timestamp"].(int64)
+	})
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	buf := bufio.NewWriter(file)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// timelineAttributes returns the attributes shared by all events of an
+// artifact
+func timelineAttributes(a models.Artifact) map[string]interface{} {
+	attrs := map[string]interface{}{
+		"data_type":     "triagectl:" + a.ArtifactType,
+		"collector_id":  a.CollectorID,
+		"artifact_type": a.ArtifactType,
+		"hostname":      a.Hostname,
+	}
+	if a.Metadata.SourcePath != "" {
+		attrs["display_name"] = a.Metadata.SourcePath
+	}
+	for _, k := range sortedKeys(a.Data) {
+		flattenAttribute(attrs, k, a.Data[k])
+	}
+
+	tags := append([]string{}, a.Tags...)
+	if a.RiskScore > 0 {
+		attrs["risk_score"] = a.RiskScore
+		attrs["severity"] = a.Severity
+		tags = append(tags, "severity:"+a.Severity)
+	}
+	if len(a.Techniques) > 0 {
+		attrs["techniques"] = a.Techniques
+	}
+	if a.Suppression != nil {
+		attrs["suppression"] = a.Suppression.ID
+		tags = append(tags, "suppressed")
+	}
+	if len(tags) > 0 {
+		attrs["tag"] = tags
+	}
+	return attrs
+}
+
+// flattenAttribute sets a Data value as an attribute, prefixing names the
+// timeline reserves and joining nested keys with _. Lists of objects are
+// kept as JSON text, since Timesketch cannot filter on them anyway. Keys
+// are visited in sorted order so that which of two colliding names gets
+// the prefix is the same on every run.
+func flattenAttribute(attrs map[string]interface{}, key string, v interface{}) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for _, k := range sortedKeys(x) {
+			flattenAttribute(attrs, key+"_"+k, x[k])
+		}
+		return
+	case []interface{}:
+		if hasObjects(x) {
+			b, _ := json.Marshal(x)
+			v = string(b)
+		}
+	}
+	for {
+		if _, taken := attrs[key]; !taken && !timelineReserved[key] {
+			break
+		}
+		key = "data_" + key
+	}
+	attrs[key] = v
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func hasObjects(list []interface{}) bool {
+	for _, item := range list {
+		switch item.(type) {
+		case map[string]interface{}, []interface{}:
+			return true
+		}
+	}
+	return false
+}
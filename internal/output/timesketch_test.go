@@ -0,0 +1,62 @@
+package output
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+func TestTimelineAttributes(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			name: "nested keys joined",
+			data: map[string]interface{}{"proc": map[string]interface{}{"pid": 1, "name": "sh"}},
+			want: map[string]interface{}{"proc_pid": 1, "proc_name": "sh"},
+		},
+		{
+			name: "reserved name prefixed",
+			data: map[string]interface{}{"message": "x", "hostname": "y"},
+			want: map[string]interface{}{"data_message": "x", "data_hostname": "y"},
+		},
+		{
+			name: "flattened name collides with a key",
+			data: map[string]interface{}{"a_b": 1, "a": map[string]interface{}{"b": 2}},
+			want: map[string]interface{}{"a_b": 2, "data_a_b": 1},
+		},
+		{
+			name: "nil value still takes its name",
+			data: map[string]interface{}{"a_b": nil, "a": map[string]interface{}{"b": 2}},
+			want: map[string]interface{}{"a_b": 2, "data_a_b": nil},
+		},
+		{
+			name: "list of objects kept as JSON",
+			data: map[string]interface{}{"args": []interface{}{map[string]interface{}{"k": "v"}}},
+			want: map[string]interface{}{"args": `[{"k":"v"}]`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := models.Artifact{CollectorID: "c", ArtifactType: "t", Hostname: "h", Data: tt.data}
+			want := map[string]interface{}{
+				"data_type":     "triagectl:t",
+				"collector_id":  "c",
+				"artifact_type": "t",
+				"hostname":      "h",
+			}
+			for k, v := range tt.want {
+				want[k] = v
+			}
+			// Map order differs between runs; the result must not
+			for i := 0; i < 50; i++ {
+				if got := timelineAttributes(a); !reflect.DeepEqual(got, want) {
+					t.Fatalf("run %d: attributes = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
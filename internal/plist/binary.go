@@ -0,0 +1,326 @@
+package plist
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+	"unicode/utf16"
+)
+
+// bplistTrailer is the fixed 32-byte trailer at the end of a bplist00 file
+type bplistTrailer struct {
+	offsetIntSize     int
+	objectRefSize     int
+	numObjects        uint64
+	topObject         uint64
+	offsetTableOffset uint64
+}
+
+type binaryDecoder struct {
+	data    []byte
+	trailer bplistTrailer
+	offsets []uint64
+	// active tracks objects on the current decode path to reject reference cycles
+	active map[uint64]bool
+	// budget is the number of objects left to decode. A collection referenced
+	// from several places is decoded once per reference, so a small file whose
+	// arrays all point at the same child would otherwise expand exponentially.
+	budget uint64
+}
+
+func decodeBinary(data []byte) (interface{}, error) {
+	if len(data) < 8+32 {
+		return nil, fmt.Errorf("plist: binary plist too short (%d bytes)", len(data))
+	}
+
+	t := data[len(data)-32:]
+	d := &binaryDecoder{
+		data: data,
+		trailer: bplistTrailer{
+			offsetIntSize:     int(t[6]),
+			objectRefSize:     int(t[7]),
+			numObjects:        binary.BigEndian.Uint64(t[8:16]),
+			topObject:         binary.BigEndian.Uint64(t[16:24]),
+			offsetTableOffset: binary.BigEndian.Uint64(t[24:32]),
+		},
+		active: make(map[uint64]bool),
+	}
+
+	if err := d.readOffsets(); err != nil {
+		return nil, err
+	}
+	// Without shared collections every reference in the object table is
+	// followed at most once; allow some sharing on top of that
+	tr := d.trailer
+	d.budget = 4 * (tr.numObjects + tr.offsetTableOffset/uint64(tr.objectRefSize))
+
+	return d.object(d.trailer.topObject)
+}
+
+func (d *binaryDecoder) readOffsets() error {
+	tr := d.trailer
+	if tr.offsetIntSize < 1 || tr.offsetIntSize > 8 || tr.objectRefSize < 1 || tr.objectRefSize > 8 {
+		return fmt.Errorf("plist: invalid binary trailer (offset size %d, ref size %d)", tr.offsetIntSize, tr.objectRefSize)
+	}
+
+	tableEnd := uint64(len(d.data) - 32)
+	if tr.offsetTableOffset < 8 || tr.offsetTableOffset > tableEnd ||
+		tr.numObjects > (tableEnd-tr.offsetTableOffset)/uint64(tr.offsetIntSize) {
+		return fmt.Errorf("plist: offset table out of range")
+	}
+	if tr.topObject >= tr.numObjects {
+		return fmt.Errorf("plist: top object %d out of range", tr.topObject)
+	}
+
+	d.offsets = make([]uint64, tr.numObjects)
+	pos := tr.offsetTableOffset
+	for i := range d.offsets {
+		off := readUint(d.data[pos : pos+uint64(tr.offsetIntSize)])
+		if off < 8 || off >= tr.offsetTableOffset {
+			return fmt.Errorf("plist: object %d offset %d out of range", i, off)
+		}
+		d.offsets[i] = off
+		pos += uint64(tr.offsetIntSize)
+	}
+	return nil
+}
+
+func (d *binaryDecoder) object(ref uint64) (interface{}, error) {
+	if ref >= uint64(len(d.offsets)) {
+		return nil, fmt.Errorf("plist: object reference %d out of range", ref)
+	}
+	if d.active[ref] {
+		return nil, fmt.Errorf("plist: reference cycle at object %d", ref)
+	}
+	if d.budget == 0 {
+		return nil, fmt.Errorf("plist: too many shared object references")
+	}
+	if len(d.active) >= maxDepth {
+		return nil, fmt.Errorf("plist: containers nested deeper than %d", maxDepth)
+	}
+	d.budget--
+	d.active[ref] = true
+	defer delete(d.active, ref)
+
+	off := d.offsets[ref]
+	limit := d.trailer.offsetTableOffset
+	marker := d.data[off]
+	kind, info := marker>>4, marker&0x0F
+	pos := off + 1
+
+	switch kind {
+	case 0x0:
+		switch info {
+		case 0x8:
+			return false, nil
+		case 0x9:
+			return true, nil
+		default:
+			return nil, nil
+		}
+
+	case 0x1: // integer, 2^info bytes big-endian
+		n := uint64(1) << info
+		b, err := d.slice(pos, n, limit)
+		if err != nil {
+			return nil, err
+		}
+		return decodeInt(b), nil
+
+	case 0x2: // real
+		n := uint64(1) << info
+		b, err := d.slice(pos, n, limit)
+		if err != nil {
+			return nil, err
+		}
+		switch n {
+		case 4:
+			return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+		case 8:
+			return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+		}
+		return nil, fmt.Errorf("plist: unsupported real size %d", n)
+
+	case 0x3: // date, float64 seconds since 2001-01-01
+		b, err := d.slice(pos, 8, limit)
+		if err != nil {
+			return nil, err
+		}
+		return macTime(math.Float64frombits(binary.BigEndian.Uint64(b))), nil
+
+	case 0x4: // data
+		count, start, err := d.count(info, pos, limit)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.slice(start, count, limit)
+		if err != nil {
+			return nil, err
+		}
+		out := make([]byte, len(b))
+		copy(out, b)
+		return out, nil
+
+	case 0x5: // ASCII string
+		count, start, err := d.count(info, pos, limit)
+		if err != nil {
+			return nil, err
+		}
+		b, err := d.slice(start, count, limit)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+
+	case 0x6: // UTF-16BE string, count is in code units
+		count, start, err := d.count(info, pos, limit)
+		if err != nil {
+			return nil, err
+		}
+		if count > math.MaxUint64/2 {
+			return nil, fmt.Errorf("plist: string length overflow")
+		}
+		b, err := d.slice(start, count*2, limit)
+		if err != nil {
+			return nil, err
+		}
+		units := make([]uint16, count)
+		for i := range units {
+			units[i] = binary.BigEndian.Uint16(b[i*2:])
+		}
+		return string(utf16.Decode(units)), nil
+
+	case 0x8: // UID, info+1 bytes
+		b, err := d.slice(pos, uint64(info)+1, limit)
+		if err != nil {
+			return nil, err
+		}
+		return UID(readUint(b)), nil
+
+	case 0xA, 0xC: // array, set
+		count, start, err := d.count(info, pos, limit)
+		if err != nil {
+			return nil, err
+		}
+		refs, err := d.refs(start, count, limit)
+		if err != nil {
+			return nil, err
+		}
+		arr := make([]interface{}, 0, len(refs))
+		for _, r := range refs {
+			v, err := d.object(r)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+
+	case 0xD: // dict: count key refs followed by count value refs
+		count, start, err := d.count(info, pos, limit)
+		if err != nil {
+			return nil, err
+		}
+		if count > math.MaxUint64/2 {
+			return nil, fmt.Errorf("plist: dict size overflow")
+		}
+		refs, err := d.refs(start, count*2, limit)
+		if err != nil {
+			return nil, err
+		}
+		dict := make(map[string]interface{}, count)
+		for i := uint64(0); i < count; i++ {
+			k, err := d.object(refs[i])
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				key = fmt.Sprintf("%v", k)
+			}
+			v, err := d.object(refs[count+i])
+			if err != nil {
+				return nil, err
+			}
+			dict[key] = v
+		}
+		return dict, nil
+	}
+
+	return nil, fmt.Errorf("plist: unknown object marker 0x%02x at offset %d", marker, off)
+}
+
+// count returns the element count encoded in a marker's low nibble, reading
+// the trailing integer object when the nibble is 0xF
+func (d *binaryDecoder) count(info byte, pos, limit uint64) (uint64, uint64, error) {
+	if info != 0x0F {
+		return uint64(info), pos, nil
+	}
+	if pos >= limit {
+		return 0, 0, fmt.Errorf("plist: truncated length at offset %d", pos)
+	}
+	marker := d.data[pos]
+	if marker>>4 != 0x1 {
+		return 0, 0, fmt.Errorf("plist: expected integer length at offset %d", pos)
+	}
+	n := uint64(1) << (marker & 0x0F)
+	b, err := d.slice(pos+1, n, limit)
+	if err != nil {
+		return 0, 0, err
+	}
+	return readUint(b), pos + 1 + n, nil
+}
+
+func (d *binaryDecoder) refs(pos, count, limit uint64) ([]uint64, error) {
+	size := uint64(d.trailer.objectRefSize)
+	if count > math.MaxUint64/size {
+		return nil, fmt.Errorf("plist: reference list overflow")
+	}
+	b, err := d.slice(pos, count*size, limit)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]uint64, count)
+	for i := range refs {
+		refs[i] = readUint(b[uint64(i)*size : uint64(i+1)*size])
+	}
+	return refs, nil
+}
+
+func (d *binaryDecoder) slice(pos, n, limit uint64) ([]byte, error) {
+	if pos > limit || n > limit-pos {
+		return nil, fmt.Errorf("plist: object at offset %d overruns object table", pos)
+	}
+	return d.data[pos : pos+n], nil
+}
+
+// decodeInt interprets 1, 2 and 4 byte integers as unsigned, 8 byte integers
+// as signed and 16 byte integers by their low 8 bytes, matching CoreFoundation
+func decodeInt(b []byte) interface{} {
+	if len(b) == 16 {
+		hi := binary.BigEndian.Uint64(b[:8])
+		lo := binary.BigEndian.Uint64(b[8:])
+		if hi == 0 && lo > math.MaxInt64 {
+			return lo
+		}
+		return int64(lo)
+	}
+	if len(b) == 8 {
+		return int64(binary.BigEndian.Uint64(b))
+	}
+	return int64(readUint(b))
+}
+
+func readUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func macTime(secs float64) time.Time {
+	whole, frac := math.Modf(secs)
+	return macEpoch.Add(time.Duration(whole) * time.Second).Add(time.Duration(frac * float64(time.Second)))
+}
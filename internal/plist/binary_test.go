@@ -0,0 +1,108 @@
+package plist
+
+import (
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildBinary encodes pre-marked objects as a bplist00 file with one byte
+// object references, the first object being the top object
+func buildBinary(objects [][]byte) []byte {
+	data := []byte("bplist00")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = len(data)
+		data = append(data, obj...)
+	}
+	table := len(data)
+	for _, off := range offsets {
+		data = binary.BigEndian.AppendUint16(data, uint16(off))
+	}
+	trailer := make([]byte, 32)
+	trailer[6] = 2
+	trailer[7] = 1
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(objects)))
+	binary.BigEndian.PutUint64(trailer[24:], uint64(table))
+	return append(data, trailer...)
+}
+
+func TestDecodeBinarySharedKeys(t *testing.T) {
+	// Two dicts sharing their key and value objects, as CoreFoundation writes them
+	data := buildBinary([][]byte{
+		{0xA2, 1, 2},
+		{0xD1, 3, 4},
+		{0xD1, 3, 4},
+		append([]byte{0x55}, "Label"...),
+		append([]byte{0x53}, "com"...),
+	})
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dict := map[string]interface{}{"Label": "com"}
+	if want := []interface{}{dict, dict}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeBinaryCycle(t *testing.T) {
+	data := buildBinary([][]byte{{0xA1, 1}, {0xA1, 0}})
+	if _, err := Decode(data); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("Decode = %v, want a reference cycle error", err)
+	}
+}
+
+func TestDecodeBinarySharedDAG(t *testing.T) {
+	// Each array references the next one twice: 2^60 paths in a few hundred bytes
+	const depth = 60
+	objects := make([][]byte, depth+1)
+	for i := 0; i < depth; i++ {
+		objects[i] = []byte{0xA2, byte(i + 1), byte(i + 1)}
+	}
+	objects[depth] = []byte{0x10, 1}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := Decode(buildBinary(objects))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "shared object references") {
+			t.Errorf("Decode = %v, want a shared reference error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Decode did not finish")
+	}
+}
+
+func TestDecodeBinaryDeepNesting(t *testing.T) {
+	// A chain of arrays, each holding the next one; refs need two bytes here
+	const depth = maxDepth + 10
+	data := []byte("bplist00")
+	offsets := make([]int, depth+1)
+	for i := 0; i < depth; i++ {
+		offsets[i] = len(data)
+		data = append(data, 0xA1)
+		data = binary.BigEndian.AppendUint16(data, uint16(i+1))
+	}
+	offsets[depth] = len(data)
+	data = append(data, 0x10, 1)
+	table := len(data)
+	for _, off := range offsets {
+		data = binary.BigEndian.AppendUint32(data, uint32(off))
+	}
+	trailer := make([]byte, 32)
+	trailer[6] = 4
+	trailer[7] = 2
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(offsets)))
+	binary.BigEndian.PutUint64(trailer[24:], uint64(table))
+	data = append(data, trailer...)
+
+	if _, err := Decode(data); err == nil || !strings.Contains(err.Error(), "nested deeper") {
+		t.Errorf("Decode = %v, want a nesting error", err)
+	}
+}
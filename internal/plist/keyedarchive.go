@@ -0,0 +1,231 @@
+package plist
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ClassKey holds the Objective-C class name on objects returned by Unarchive
+// that are not one of the Foundation collection or value types
+const ClassKey = "$class"
+
+// IsKeyedArchive reports whether v is an NSKeyedArchiver document
+func IsKeyedArchive(v interface{}) bool {
+	d, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	archiver, _ := d["$archiver"].(string)
+	_, hasObjects := d["$objects"].([]interface{})
+	return hasObjects && (archiver == "NSKeyedArchiver" || archiver == "")
+}
+
+// Unarchive resolves the object graph of an NSKeyedArchiver document into
+// plain values: Foundation collections become maps and slices, NSString,
+// NSData, NSDate, NSUUID and NSURL become their Go equivalents, and any
+// other object becomes a map of its encoded fields plus ClassKey.
+func Unarchive(v interface{}) (interface{}, error) {
+	if !IsKeyedArchive(v) {
+		return nil, fmt.Errorf("plist: not an NSKeyedArchiver document")
+	}
+	doc := v.(map[string]interface{})
+	objects := doc["$objects"].([]interface{})
+	top, _ := doc["$top"].(map[string]interface{})
+
+	u := &unarchiver{objects: objects, done: make(map[UID]interface{}), active: make(map[UID]bool)}
+
+	if rootRef, ok := top["root"]; ok {
+		return u.resolve(rootRef), nil
+	}
+	// Some archives use other top-level keys; return them all
+	out := make(map[string]interface{}, len(top))
+	for k, ref := range top {
+		out[k] = u.resolve(ref)
+	}
+	return out, nil
+}
+
+// UnarchiveFile decodes a property list file and unarchives it
+func UnarchiveFile(path string) (interface{}, error) {
+	v, err := DecodeFile(path)
+	if err != nil {
+		return nil, err
+	}
+	out, err := Unarchive(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return out, nil
+}
+
+type unarchiver struct {
+	objects []interface{}
+	done    map[UID]interface{}
+	active  map[UID]bool
+}
+
+// asUID accepts both binary UIDs and the {CF$UID = n} dicts XML archives use
+func asUID(v interface{}) (UID, bool) {
+	switch t := v.(type) {
+	case UID:
+		return t, true
+	case map[string]interface{}:
+		if len(t) == 1 {
+			if n, ok := t["CF$UID"].(int64); ok && n >= 0 {
+				return UID(n), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func (u *unarchiver) resolve(v interface{}) interface{} {
+	uid, ok := asUID(v)
+	if !ok {
+		if arr, isArr := v.([]interface{}); isArr {
+			out := make([]interface{}, len(arr))
+			for i, e := range arr {
+				out[i] = u.resolve(e)
+			}
+			return out
+		}
+		return v
+	}
+	if r, ok := u.done[uid]; ok {
+		return r
+	}
+	if u.active[uid] || uint64(uid) >= uint64(len(u.objects)) {
+		// Back-references are rare in practice; break the cycle rather than recurse forever
+		return nil
+	}
+	if len(u.active) >= maxDepth {
+		return nil
+	}
+	u.active[uid] = true
+	defer delete(u.active, uid)
+
+	r := u.object(u.objects[uid])
+	u.done[uid] = r
+	return r
+}
+
+func (u *unarchiver) object(obj interface{}) interface{} {
+	if s, ok := obj.(string); ok && s == "$null" {
+		return nil
+	}
+	d, ok := obj.(map[string]interface{})
+	if !ok {
+		return obj
+	}
+
+	classRef, hasClass := d["$class"]
+	if !hasClass {
+		out := make(map[string]interface{}, len(d))
+		for k, v := range d {
+			out[k] = u.resolve(v)
+		}
+		return out
+	}
+	class := u.className(classRef)
+
+	switch class {
+	case "NSDictionary", "NSMutableDictionary":
+		keys, _ := d["NS.keys"].([]interface{})
+		vals, _ := d["NS.objects"].([]interface{})
+		out := make(map[string]interface{}, len(keys))
+		for i, k := range keys {
+			if i >= len(vals) {
+				break
+			}
+			key := u.resolve(k)
+			ks, ok := key.(string)
+			if !ok {
+				ks = fmt.Sprintf("%v", key)
+			}
+			out[ks] = u.resolve(vals[i])
+		}
+		return out
+
+	case "NSArray", "NSMutableArray", "NSSet", "NSMutableSet", "NSOrderedSet", "NSMutableOrderedSet":
+		vals, _ := d["NS.objects"].([]interface{})
+		out := make([]interface{}, 0, len(vals))
+		for _, v := range vals {
+			out = append(out, u.resolve(v))
+		}
+		return out
+
+	case "NSString", "NSMutableString":
+		if s, ok := u.resolve(d["NS.string"]).(string); ok {
+			return s
+		}
+		if b, ok := d["NS.bytes"].([]byte); ok {
+			return string(b)
+		}
+		return ""
+
+	case "NSData", "NSMutableData":
+		if b, ok := u.resolve(d["NS.data"]).([]byte); ok {
+			return b
+		}
+		if b, ok := d["NS.bytes"].([]byte); ok {
+			return b
+		}
+		return []byte(nil)
+
+	case "NSDate":
+		if t, ok := toFloat(d["NS.time"]); ok {
+			return macTime(t)
+		}
+		return time.Time{}
+
+	case "NSUUID":
+		if b, ok := d["NS.uuidbytes"].([]byte); ok && len(b) == 16 {
+			return fmt.Sprintf("%X-%X-%X-%X-%X", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+		}
+		return ""
+
+	case "NSURL":
+		base, _ := u.resolve(d["NS.base"]).(string)
+		rel, _ := u.resolve(d["NS.relative"]).(string)
+		if base != "" && !strings.Contains(rel, "://") {
+			return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(rel, "/")
+		}
+		return rel
+	}
+
+	out := make(map[string]interface{}, len(d))
+	for k, v := range d {
+		if k == "$class" {
+			continue
+		}
+		out[k] = u.resolve(v)
+	}
+	out[ClassKey] = class
+	return out
+}
+
+func (u *unarchiver) className(ref interface{}) string {
+	uid, ok := asUID(ref)
+	if !ok || uint64(uid) >= uint64(len(u.objects)) {
+		return ""
+	}
+	cls, ok := u.objects[uid].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	name, _ := cls["$classname"].(string)
+	return name
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
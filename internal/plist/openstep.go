@@ -0,0 +1,274 @@
+package plist
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// openStepParser decodes the old-style ASCII format, e.g.
+//
+//	{ Label = "com.example.agent"; ProgramArguments = ( "/bin/sh", "-c" ); Data = <0fbd77>; }
+//
+// Scalars are always strings, as in CoreFoundation.
+type openStepParser struct {
+	src   []byte
+	pos   int
+	depth int
+}
+
+func decodeOpenStep(data []byte) (interface{}, error) {
+	p := &openStepParser{src: data}
+	p.skipSpace()
+
+	// A top-level "strings file" is a dict without the surrounding braces
+	if !p.atEnd() && p.src[p.pos] != '{' && p.src[p.pos] != '(' && p.src[p.pos] != '<' {
+		save := p.pos
+		if _, err := p.string(); err == nil {
+			p.skipSpace()
+			if !p.atEnd() && p.src[p.pos] == '=' {
+				p.pos = save
+				return p.dictBody(0)
+			}
+		}
+		p.pos = save
+	}
+
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if !p.atEnd() {
+		return nil, p.errorf("unexpected trailing content")
+	}
+	return v, nil
+}
+
+func (p *openStepParser) atEnd() bool { return p.pos >= len(p.src) }
+
+func (p *openStepParser) errorf(format string, args ...interface{}) error {
+	line := 1 + strings.Count(string(p.src[:p.pos]), "\n")
+	return fmt.Errorf("plist: openstep line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+func (p *openStepParser) skipSpace() {
+	for !p.atEnd() {
+		c := p.src[p.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			p.pos++
+		case c == '/' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '/':
+			for !p.atEnd() && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		case c == '/' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '*':
+			end := strings.Index(string(p.src[p.pos+2:]), "*/")
+			if end < 0 {
+				p.pos = len(p.src)
+				return
+			}
+			p.pos += end + 4
+		default:
+			return
+		}
+	}
+}
+
+func (p *openStepParser) value() (interface{}, error) {
+	p.skipSpace()
+	if p.atEnd() {
+		return nil, p.errorf("unexpected end of input")
+	}
+	switch p.src[p.pos] {
+	case '{', '(':
+		if p.depth >= maxDepth {
+			return nil, p.errorf("containers nested deeper than %d", maxDepth)
+		}
+		p.depth++
+		defer func() { p.depth-- }()
+		p.pos++
+		if p.src[p.pos-1] == '{' {
+			return p.dictBody('}')
+		}
+		return p.array()
+	case '<':
+		p.pos++
+		return p.data()
+	default:
+		return p.string()
+	}
+}
+
+// dictBody parses "key = value;" pairs until the closing byte (0 means end of input)
+func (p *openStepParser) dictBody(closing byte) (interface{}, error) {
+	dict := make(map[string]interface{})
+	for {
+		p.skipSpace()
+		if p.atEnd() {
+			if closing == 0 {
+				return dict, nil
+			}
+			return nil, p.errorf("unterminated dict")
+		}
+		if closing != 0 && p.src[p.pos] == closing {
+			p.pos++
+			return dict, nil
+		}
+
+		key, err := p.string()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.atEnd() || p.src[p.pos] != '=' {
+			return nil, p.errorf("expected '=' after key %q", key)
+		}
+		p.pos++
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = v
+		p.skipSpace()
+		if p.atEnd() || p.src[p.pos] != ';' {
+			return nil, p.errorf("expected ';' after value for key %q", key)
+		}
+		p.pos++
+	}
+}
+
+func (p *openStepParser) array() (interface{}, error) {
+	arr := []interface{}{}
+	for {
+		p.skipSpace()
+		if p.atEnd() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.src[p.pos] == ')' {
+			p.pos++
+			return arr, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+		p.skipSpace()
+		if !p.atEnd() && p.src[p.pos] == ',' {
+			p.pos++
+		} else if p.atEnd() || p.src[p.pos] != ')' {
+			return nil, p.errorf("expected ',' or ')' in array")
+		}
+	}
+}
+
+func (p *openStepParser) data() (interface{}, error) {
+	var hexDigits []byte
+	for !p.atEnd() && p.src[p.pos] != '>' {
+		c := p.src[p.pos]
+		if c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			hexDigits = append(hexDigits, c)
+		}
+		p.pos++
+	}
+	if p.atEnd() {
+		return nil, p.errorf("unterminated data")
+	}
+	p.pos++
+	b, err := hex.DecodeString(string(hexDigits))
+	if err != nil {
+		return nil, p.errorf("invalid hex data: %v", err)
+	}
+	return b, nil
+}
+
+func isUnquotedChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '$' || c == '+' || c == '/' || c == ':' || c == '.' || c == '-'
+}
+
+func (p *openStepParser) string() (string, error) {
+	p.skipSpace()
+	if p.atEnd() {
+		return "", p.errorf("expected string")
+	}
+
+	quote := p.src[p.pos]
+	if quote != '"' && quote != '\'' {
+		start := p.pos
+		for !p.atEnd() && isUnquotedChar(p.src[p.pos]) {
+			p.pos++
+		}
+		if p.pos == start {
+			return "", p.errorf("unexpected character %q", p.src[p.pos])
+		}
+		return string(p.src[start:p.pos]), nil
+	}
+
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.atEnd() {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.src[p.pos]
+		if c == quote {
+			p.pos++
+			return sb.String(), nil
+		}
+		if c != '\\' {
+			r, size := utf8.DecodeRune(p.src[p.pos:])
+			sb.WriteRune(r)
+			p.pos += size
+			continue
+		}
+
+		p.pos++
+		if p.atEnd() {
+			return "", p.errorf("unterminated escape")
+		}
+		esc := p.src[p.pos]
+		p.pos++
+		switch esc {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'a':
+			sb.WriteByte('\a')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'v':
+			sb.WriteByte('\v')
+		case 'U', 'u':
+			end := p.pos
+			for end < len(p.src) && end-p.pos < 4 && isHex(p.src[end]) {
+				end++
+			}
+			n, _ := strconv.ParseUint(string(p.src[p.pos:end]), 16, 32)
+			sb.WriteRune(rune(n))
+			p.pos = end
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			end := p.pos
+			for end < len(p.src) && end-p.pos < 2 && p.src[end] >= '0' && p.src[end] <= '7' {
+				end++
+			}
+			n, _ := strconv.ParseUint(string(p.src[p.pos-1:end]), 8, 32)
+			sb.WriteRune(rune(n))
+			p.pos = end
+		default:
+			sb.WriteByte(esc)
+		}
+	}
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
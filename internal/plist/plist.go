@@ -0,0 +1,125 @@
+// Package plist decodes Apple property lists (binary, XML and OpenStep) into
+// plain Go values without shelling out to plutil.
+//
+// Decoded values map to Go types as follows:
+//
+//	dict    map[string]interface{}
+//	array   []interface{}  (sets decode as arrays)
+//	string  string
+//	integer int64 (uint64 for values above math.MaxInt64)
+//	real    float64
+//	bool    bool
+//	date    time.Time (UTC)
+//	data    []byte
+//	UID     UID
+package plist
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// UID is an object reference used by NSKeyedArchiver in binary plists
+type UID uint64
+
+// Format identifies the on-disk encoding of a property list
+type Format int
+
+const (
+	FormatInvalid Format = iota
+	FormatBinary
+	FormatXML
+	FormatOpenStep
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatBinary:
+		return "binary"
+	case FormatXML:
+		return "xml"
+	case FormatOpenStep:
+		return "openstep"
+	default:
+		return "invalid"
+	}
+}
+
+// macEpoch is the reference date for plist dates (2001-01-01 UTC)
+var macEpoch = time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// maxDepth bounds container nesting. Real property lists are a few levels
+// deep; a crafted one nested millions deep would otherwise overflow the stack.
+const maxDepth = 512
+
+// ErrInvalid is returned when the input is not a recognizable property list
+var ErrInvalid = errors.New("plist: invalid property list")
+
+// DetectFormat guesses the encoding of a property list from its leading bytes
+func DetectFormat(data []byte) Format {
+	if IsBinary(data) {
+		return FormatBinary
+	}
+	trimmed := bytes.TrimLeft(skipBOM(data), " \t\r\n")
+	if len(trimmed) == 0 {
+		return FormatInvalid
+	}
+	if bytes.HasPrefix(trimmed, []byte("<?xml")) || bytes.HasPrefix(trimmed, []byte("<!DOCTYPE")) ||
+		bytes.HasPrefix(trimmed, []byte("<plist")) {
+		return FormatXML
+	}
+	return FormatOpenStep
+}
+
+// IsBinary reports whether data starts with the bplist00 magic, which is
+// useful for spotting binary plists nested inside data values
+func IsBinary(data []byte) bool {
+	return len(data) >= 8 && string(data[:6]) == "bplist" && data[6] == '0'
+}
+
+// Decode parses a property list in any supported format
+func Decode(data []byte) (interface{}, error) {
+	switch DetectFormat(data) {
+	case FormatBinary:
+		return decodeBinary(data)
+	case FormatXML:
+		return decodeXML(data)
+	case FormatOpenStep:
+		return decodeOpenStep(skipBOM(data))
+	default:
+		return nil, ErrInvalid
+	}
+}
+
+// DecodeFile reads and parses the property list at path
+func DecodeFile(path string) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	v, err := Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return v, nil
+}
+
+// DecodeDictFile parses the property list at path and requires a dict at the top level
+func DecodeDictFile(path string) (map[string]interface{}, error) {
+	v, err := DecodeFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: top-level object is %T, not a dict", path, v)
+	}
+	return d, nil
+}
+
+func skipBOM(data []byte) []byte {
+	return bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})
+}
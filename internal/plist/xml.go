@@ -0,0 +1,217 @@
+package plist
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type xmlDecoder struct {
+	dec   *xml.Decoder
+	depth int
+}
+
+func decodeXML(data []byte) (interface{}, error) {
+	d := &xmlDecoder{dec: xml.NewDecoder(bytes.NewReader(data))}
+	d.dec.Strict = false
+	// Property lists declare UTF-8; accept other labels as-is rather than failing
+	d.dec.CharsetReader = func(_ string, r io.Reader) (io.Reader, error) { return r, nil }
+
+	for {
+		start, err := d.nextStart()
+		if err != nil {
+			return nil, err
+		}
+		if start.Name.Local == "plist" {
+			continue
+		}
+		return d.value(start)
+	}
+}
+
+// nextStart advances to the next start element, returning io.ErrUnexpectedEOF
+// if the document ends first
+func (d *xmlDecoder) nextStart() (xml.StartElement, error) {
+	for {
+		tok, err := d.dec.Token()
+		if err == io.EOF {
+			return xml.StartElement{}, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return xml.StartElement{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			return t, nil
+		case xml.EndElement:
+			return xml.StartElement{}, errEnd{t.Name.Local}
+		}
+	}
+}
+
+// errEnd signals that a container closed while looking for its next child
+type errEnd struct{ name string }
+
+func (e errEnd) Error() string { return "plist: unexpected </" + e.name + ">" }
+
+func (d *xmlDecoder) value(start xml.StartElement) (interface{}, error) {
+	switch start.Name.Local {
+	case "dict", "array":
+		if d.depth >= maxDepth {
+			return nil, fmt.Errorf("plist: containers nested deeper than %d", maxDepth)
+		}
+		d.depth++
+		defer func() { d.depth-- }()
+		if start.Name.Local == "dict" {
+			return d.dict()
+		}
+		return d.array()
+	case "true":
+		return true, d.dec.Skip()
+	case "false":
+		return false, d.dec.Skip()
+	}
+
+	text, err := d.text()
+	if err != nil {
+		return nil, err
+	}
+
+	switch start.Name.Local {
+	case "string", "key":
+		return text, nil
+	case "integer":
+		return parseInteger(strings.TrimSpace(text))
+	case "real":
+		return parseReal(strings.TrimSpace(text))
+	case "date":
+		t, err := time.Parse(time.RFC3339, strings.TrimSpace(text))
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid date %q", text)
+		}
+		return t.UTC(), nil
+	case "data":
+		clean := strings.Map(func(r rune) rune {
+			if r == ' ' || r == '\t' || r == '\r' || r == '\n' {
+				return -1
+			}
+			return r
+		}, text)
+		b, err := base64.StdEncoding.DecodeString(clean)
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid data element: %w", err)
+		}
+		return b, nil
+	}
+
+	return nil, fmt.Errorf("plist: unknown element <%s>", start.Name.Local)
+}
+
+func (d *xmlDecoder) dict() (interface{}, error) {
+	dict := make(map[string]interface{})
+	for {
+		start, err := d.nextStart()
+		if _, ok := err.(errEnd); ok {
+			return dict, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start.Name.Local != "key" {
+			return nil, fmt.Errorf("plist: expected <key> in dict, got <%s>", start.Name.Local)
+		}
+		key, err := d.text()
+		if err != nil {
+			return nil, err
+		}
+
+		start, err = d.nextStart()
+		if err != nil {
+			return nil, fmt.Errorf("plist: missing value for key %q", key)
+		}
+		v, err := d.value(start)
+		if err != nil {
+			return nil, err
+		}
+		dict[key] = v
+	}
+}
+
+func (d *xmlDecoder) array() (interface{}, error) {
+	arr := []interface{}{}
+	for {
+		start, err := d.nextStart()
+		if _, ok := err.(errEnd); ok {
+			return arr, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		v, err := d.value(start)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+	}
+}
+
+// text collects character data up to the closing tag of the current element
+func (d *xmlDecoder) text() (string, error) {
+	var sb strings.Builder
+	for {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			sb.Write(t)
+		case xml.EndElement:
+			return sb.String(), nil
+		case xml.StartElement:
+			return "", fmt.Errorf("plist: unexpected <%s> inside scalar", t.Name.Local)
+		}
+	}
+}
+
+func parseInteger(s string) (interface{}, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		u, err := strconv.ParseUint(s[2:], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("plist: invalid integer %q", s)
+		}
+		if u > math.MaxInt64 {
+			return u, nil
+		}
+		return int64(u), nil
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i, nil
+	}
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return u, nil
+	}
+	return nil, fmt.Errorf("plist: invalid integer %q", s)
+}
+
+func parseReal(s string) (float64, error) {
+	switch strings.ToLower(s) {
+	case "nan":
+		return math.NaN(), nil
+	case "inf", "+inf", "infinity":
+		return math.Inf(1), nil
+	case "-inf", "-infinity":
+		return math.Inf(-1), nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("plist: invalid real %q", s)
+	}
+	return f, nil
+}
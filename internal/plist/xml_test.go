@@ -0,0 +1,51 @@
+package plist
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeXML(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0"><dict>
+	<key>Label</key><string>com.example.agent</string>
+	<key>ProgramArguments</key><array><string>/bin/sh</string><string>-c</string></array>
+	<key>RunAtLoad</key><true/>
+	<key>Interval</key><integer>300</integer>
+</dict></plist>`)
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"Label":            "com.example.agent",
+		"ProgramArguments": []interface{}{"/bin/sh", "-c"},
+		"RunAtLoad":        true,
+		"Interval":         int64(300),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Decode = %v, want %v", got, want)
+	}
+}
+
+func TestDecodeDeepNesting(t *testing.T) {
+	const n = 2000000
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"xml", `<plist>` + strings.Repeat("<array>", n) + strings.Repeat("</array>", n) + `</plist>`},
+		{"openstep", strings.Repeat("(", n) + strings.Repeat(")", n)},
+	}
+	for _, tt := range tests {
+		if _, err := Decode([]byte(tt.data)); err == nil || !strings.Contains(err.Error(), "nested deeper") {
+			t.Errorf("%s: Decode = %v, want a nesting error", tt.name, err)
+		}
+	}
+
+	ok := `<plist>` + strings.Repeat("<array>", maxDepth) + strings.Repeat("</array>", maxDepth) + `</plist>`
+	if _, err := Decode([]byte(ok)); err != nil {
+		t.Errorf("Decode at depth %d: %v", maxDepth, err)
+	}
+}
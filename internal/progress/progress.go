@@ -73,6 +73,18 @@ func (t *Tracker) Fail(collectorID string, err error) {
 	}
 }
 
+// Skip marks a collector as not run (e.g. live-only collectors in offline mode)
+func (t *Tracker) Skip(collectorID, reason string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done++
+	if t.isTTY {
+		t.render()
+	} else {
+		fmt.Printf("  [-] %s: skipped (%s)\n", collectorID, reason)
+	}
+}
+
 // Finish clears the progress line (TTY mode)
 func (t *Tracker) Finish() {
 	t.mu.Lock()
@@ -10,6 +10,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/plonxyz/triagectl/internal/analysis"
+	"github.com/plonxyz/triagectl/internal/attack"
+	"github.com/plonxyz/triagectl/internal/correlate"
+	"github.com/plonxyz/triagectl/internal/diff"
 	"github.com/plonxyz/triagectl/internal/models"
 )
 
@@ -18,10 +22,11 @@ var templateFS embed.FS
 
 // CollectorStat holds per-collector statistics
 type CollectorStat struct {
-	ID       string
-	Count    int
-	Duration string
-	Success  bool
+	ID         string
+	Count      int
+	Duration   string
+	Success    bool
+	SkipReason string
 }
 
 // SecurityPostureItem represents a security setting status
@@ -37,7 +42,44 @@ type FindingRow struct {
 	Summary      string
 	RiskScore    int
 	Tags         []string
+	Techniques   []string
 	DataJSON     string
+	// Breakdown lists the rules behind RiskScore; RawScore is their sum
+	// before capping
+	Breakdown []models.ScoreContribution
+	RawScore  int
+	// Suppression is the rule that suppressed the finding, if any
+	Suppression *models.Suppression
+}
+
+// AttackTactic is one column of the ATT&CK heatmap
+type AttackTactic struct {
+	Name       string
+	Techniques []AttackCell
+}
+
+// AttackCell is a technique in the ATT&CK heatmap, with the number of
+// artifacts placed under it and the highest risk score among them
+type AttackCell struct {
+	ID       string
+	Name     string
+	Count    int
+	MaxScore int
+	Tags     []string
+}
+
+// IOCMatchRow is one indicator that matched an artifact
+type IOCMatchRow struct {
+	ArtifactType  string
+	Summary       string
+	IndicatorType string
+	Indicator     string
+	Name          string
+	Field         string
+	Source        string
+	Event         string
+	EventID       string
+	Confidence    int
 }
 
 // TimelineRow is a simplified timeline entry for the template
@@ -76,6 +118,46 @@ type ProcessRow struct {
 	RiskScore int
 }
 
+// ProcessTreeRow is one process in the depth-first rendering of the process tree
+type ProcessTreeRow struct {
+	Depth     int
+	PID       string
+	Name      string
+	User      string
+	Cmdline   string
+	Lineage   string
+	RiskScore int
+	Tags      []string
+	// Flagged processes have a risk score; OnFlaggedBranch marks them and
+	// their ancestors so the tree can be cut down to flagged branches
+	Flagged         bool
+	OnFlaggedBranch bool
+	ParentReused    bool
+}
+
+// Indent is the row's left padding in pixels
+func (r ProcessTreeRow) Indent() int { return 8 + r.Depth*18 }
+
+// EntityRow is a process with the connections, open files and persistence
+// entries correlated to it
+type EntityRow struct {
+	PID             int
+	Name            string
+	Exe             string
+	Cmdline         string
+	User            string
+	Parent          string
+	Signature       string
+	TeamID          string
+	Connections     int
+	RemoteEndpoints []string
+	ListenAddrs     []string
+	OpenFiles       []string
+	Persistence     []string
+	RiskScore       int
+	Tags            []string
+}
+
 // UserActivityRow represents user activity
 type UserActivityRow struct {
 	ArtifactType string
@@ -131,6 +213,25 @@ type EnvironmentRow struct {
 	RiskScore int
 }
 
+// DiffRow is one added, removed or changed artifact relative to a previous collection
+type DiffRow struct {
+	Kind         string
+	ArtifactType string
+	Key          string
+	Summary      string
+	Detail       string
+	RiskScore    int
+}
+
+// DiffSummary describes the comparison shown in the Changes section
+type DiffSummary struct {
+	BeforePath string
+	BeforeTime string
+	Added      int
+	Removed    int
+	Changed    int
+}
+
 // ReportData is the full data model passed to the HTML template
 type ReportData struct {
 	// System profile
@@ -156,9 +257,15 @@ type ReportData struct {
 	TCCPermissions  []TCCRow
 	Environment     []EnvironmentRow
 	Findings        []FindingRow
+	Suppressed      []FindingRow
+	AttackMatrix    []AttackTactic
+	AttackCount     int
+	IOCMatches      []IOCMatchRow
 	UserAccounts    []UserAccountRow
 	SSHArtifacts    []SSHRow
 	Processes       []ProcessRow
+	ProcessTree     []ProcessTreeRow
+	Entities        []EntityRow
 	InstalledApps   []InstalledAppRow
 	Persistence     []PersistenceRow
 	BrowserHistory  []UserActivityRow
@@ -171,6 +278,10 @@ type ReportData struct {
 	Timeline        []TimelineRow
 	RemainingTimeline []TimelineRow
 	CollectorStats  []CollectorStat
+
+	// Changes since a previous collection, when the report is rendered with a diff
+	Diff     *DiffSummary
+	DiffRows []DiffRow
 }
 
 // GenerateHTMLReport creates an HTML report from artifacts and results
@@ -179,6 +290,18 @@ func GenerateHTMLReport(
 	artifacts []models.Artifact,
 	results []models.CollectionResult,
 	duration time.Duration,
+) error {
+	return GenerateHTMLReportWithDiff(outputPath, artifacts, results, duration, nil)
+}
+
+// GenerateHTMLReportWithDiff is GenerateHTMLReport plus a Changes section
+// comparing this collection with an earlier one
+func GenerateHTMLReportWithDiff(
+	outputPath string,
+	artifacts []models.Artifact,
+	results []models.CollectionResult,
+	duration time.Duration,
+	d *diff.Result,
 ) error {
 	tmplData, err := templateFS.ReadFile("report_template.html")
 	if err != nil {
@@ -191,6 +314,9 @@ func GenerateHTMLReport(
 	}
 
 	data := buildReportData(artifacts, results, duration)
+	if d != nil {
+		data.Diff, data.DiffRows = buildDiff(d)
+	}
 
 	f, err := os.Create(outputPath)
 	if err != nil {
@@ -256,9 +382,9 @@ func buildReportData(artifacts []models.Artifact, results []models.CollectionRes
 		Duration:       duration.Round(time.Millisecond).String(),
 	}
 
-	// Count findings (risk score >= 40)
+	// Count findings (risk score >= 40, not suppressed)
 	for _, a := range artifacts {
-		if a.RiskScore >= 40 {
+		if a.IsFinding() {
 			data.FindingsCount++
 		}
 	}
@@ -267,10 +393,14 @@ func buildReportData(artifacts []models.Artifact, results []models.CollectionRes
 	data.SecurityPosture = buildSecurityPosture(artifacts)
 	data.TCCPermissions = buildTCC(artifacts)
 	data.Environment = buildEnvironment(artifacts)
-	data.Findings = buildFindings(artifacts)
+	data.Findings, data.Suppressed = buildFindings(artifacts)
+	data.AttackMatrix, data.AttackCount = buildAttackMatrix(artifacts)
+	data.IOCMatches = buildIOCMatches(artifacts)
 	data.UserAccounts = buildUserAccounts(artifacts)
 	data.SSHArtifacts = buildSSH(artifacts)
 	data.Processes = buildProcesses(artifacts)
+	data.ProcessTree = buildProcessTree(artifacts)
+	data.Entities = buildEntities(artifacts)
 	data.InstalledApps = buildInstalledApps(artifacts)
 	data.Persistence = buildPersistence(artifacts)
 	data.BrowserHistory = buildActivityByTypes(artifacts, map[string]bool{
@@ -325,28 +455,122 @@ func buildSecurityPosture(artifacts []models.Artifact) []SecurityPostureItem {
 	return items
 }
 
-func buildFindings(artifacts []models.Artifact) []FindingRow {
-	var findings []FindingRow
-
+// buildFindings returns the findings and, separately, the artifacts that
+// would have been findings but were suppressed
+func buildFindings(artifacts []models.Artifact) (findings, suppressed []FindingRow) {
 	for _, a := range artifacts {
-		if a.RiskScore >= 40 { // medium and above
+		if a.RiskScore >= models.FindingScore { // medium and above
 			dataJSON, _ := json.MarshalIndent(a.Data, "", "  ")
-			findings = append(findings, FindingRow{
+			row := FindingRow{
 				ArtifactType: a.ArtifactType,
 				CollectorID:  a.CollectorID,
 				Summary:      Summarize(a),
 				RiskScore:    a.RiskScore,
 				Tags:         a.Tags,
+				Techniques:   a.Techniques,
 				DataJSON:     string(dataJSON),
-			})
+				Breakdown:    breakdown(a.Contributions),
+				RawScore:     rawScore(a.Contributions),
+				Suppression:  a.Suppression,
+			}
+			if a.Suppression != nil {
+				suppressed = append(suppressed, row)
+			} else {
+				findings = append(findings, row)
+			}
 		}
 	}
 
-	sort.Slice(findings, func(i, j int) bool {
-		return findings[i].RiskScore > findings[j].RiskScore
-	})
+	for _, rows := range [][]FindingRow{findings, suppressed} {
+		sort.Slice(rows, func(i, j int) bool {
+			return rows[i].RiskScore > rows[j].RiskScore
+		})
+	}
+
+	return findings, suppressed
+}
+
+// breakdown orders contributions by points, largest first
+func breakdown(contributions []models.ScoreContribution) []models.ScoreContribution {
+	rows := append([]models.ScoreContribution(nil), contributions...)
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Points > rows[j].Points })
+	return rows
+}
+
+func rawScore(contributions []models.ScoreContribution) int {
+	total := 0
+	for _, c := range contributions {
+		total += c.Points
+	}
+	return total
+}
+
+// buildAttackMatrix lays out the techniques found on artifacts under their
+// tactics, in matrix order. A technique appears under each of its tactics;
+// techniques without one go in a final Other column.
+func buildAttackMatrix(artifacts []models.Artifact) ([]AttackTactic, int) {
+	hits := attack.Summarize(artifacts, analysis.AttackMapping())
+	byTactic := make(map[string][]AttackCell)
+	for _, h := range hits {
+		cell := AttackCell{ID: h.ID, Name: h.Name, Count: h.Count, MaxScore: h.MaxScore, Tags: h.Tags}
+		tactics := h.Tactics
+		if len(tactics) == 0 {
+			tactics = []string{""}
+		}
+		for _, t := range tactics {
+			byTactic[t] = append(byTactic[t], cell)
+		}
+	}
 
-	return findings
+	var columns []AttackTactic
+	for _, t := range attack.Tactics {
+		if cells := byTactic[t.ID]; len(cells) > 0 {
+			columns = append(columns, AttackTactic{Name: t.Name, Techniques: cells})
+			delete(byTactic, t.ID)
+		}
+	}
+	// Tactics unknown to the matrix, such as from a custom mapping
+	var other []AttackCell
+	seen := make(map[string]bool)
+	for _, cells := range byTactic {
+		for _, c := range cells {
+			if !seen[c.ID] {
+				seen[c.ID] = true
+				other = append(other, c)
+			}
+		}
+	}
+	if len(other) > 0 {
+		sort.Slice(other, func(i, j int) bool { return other[i].ID < other[j].ID })
+		columns = append(columns, AttackTactic{Name: "Other", Techniques: other})
+	}
+	for _, c := range columns {
+		sort.SliceStable(c.Techniques, func(i, j int) bool {
+			return c.Techniques[i].MaxScore > c.Techniques[j].MaxScore
+		})
+	}
+	return columns, len(hits)
+}
+
+func buildIOCMatches(artifacts []models.Artifact) []IOCMatchRow {
+	var rows []IOCMatchRow
+	for _, a := range artifacts {
+		for _, m := range a.IOCMatches {
+			rows = append(rows, IOCMatchRow{
+				ArtifactType:  a.ArtifactType,
+				Summary:       Summarize(a),
+				IndicatorType: m.Type,
+				Indicator:     m.Value,
+				Name:          m.Name,
+				Field:         m.Field,
+				Source:        m.Source,
+				Event:         m.Event,
+				EventID:       m.EventID,
+				Confidence:    m.Confidence,
+			})
+		}
+	}
+	return rows
 }
 
 func buildTimeline(artifacts []models.Artifact) []TimelineRow {
@@ -357,7 +581,7 @@ func buildTimeline(artifacts []models.Artifact) []TimelineRow {
 	var entries []entry
 
 	for _, a := range artifacts {
-		et := resolveEventTime(a)
+		et := a.When()
 		entries = append(entries, entry{
 			t: et,
 			row: TimelineRow{
@@ -380,23 +604,43 @@ func buildTimeline(artifacts []models.Artifact) []TimelineRow {
 	return rows
 }
 
-func resolveEventTime(a models.Artifact) time.Time {
-	if a.EventTime != nil {
-		return *a.EventTime
+func buildDiff(d *diff.Result) (*DiffSummary, []DiffRow) {
+	summary := &DiffSummary{
+		BeforePath: d.Before.Path,
+		Added:      d.Added,
+		Removed:    d.Removed,
+		Changed:    d.Changed,
 	}
-	timeFields := []string{"visit_time", "last_visit_time", "mod_time", "timestamp", "event_time", "last_modified"}
-	for _, field := range timeFields {
-		if v, ok := a.Data[field]; ok {
-			if s, ok := v.(string); ok {
-				for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05.000Z", "2006-01-02 15:04:05"} {
-					if t, err := time.Parse(layout, s); err == nil {
-						return t
-					}
+	if d.Before.CollectedAt != nil {
+		summary.BeforeTime = d.Before.CollectedAt.Format("2006-01-02 15:04:05 MST")
+	}
+
+	var rows []DiffRow
+	for _, td := range d.Types {
+		for _, group := range [][]diff.Change{td.Added, td.Removed, td.Changed} {
+			for _, c := range group {
+				row := DiffRow{
+					Kind:         c.Kind,
+					ArtifactType: td.ArtifactType,
+					Key:          c.Key,
+					Summary:      c.Summary,
+					RiskScore:    c.RiskScore,
 				}
+				var fields []string
+				for _, f := range c.Fields {
+					fields = append(fields, fmt.Sprintf("%s: %v -> %v", f.Field, f.Before, f.After))
+				}
+				row.Detail = strings.Join(fields, "; ")
+				rows = append(rows, row)
 			}
 		}
 	}
-	return a.Timestamp
+
+	// Cap for report readability; the JSON diff has everything
+	if len(rows) > 5000 {
+		rows = rows[:5000]
+	}
+	return summary, rows
 }
 
 func buildPersistence(artifacts []models.Artifact) []PersistenceRow {
@@ -413,14 +657,23 @@ func buildPersistence(artifacts []models.Artifact) []PersistenceRow {
 		if !persistTypes[a.ArtifactType] {
 			continue
 		}
-		name := getStr(a.Data, "name")
+		name := getStr(a.Data, "label")
+		if name == "" {
+			name = getStr(a.Data, "name")
+		}
 		if name == "" {
 			name = getStr(a.Data, "identifier")
 		}
 		path := getStr(a.Data, "path")
-		program := getStr(a.Data, "program")
+		program := joinArgs(a.Data["program_arguments"])
+		if program == "" {
+			program = getStr(a.Data, "program")
+		}
+		if program == "" {
+			program = getStr(a.Data, "executable_path")
+		}
 		if program == "" {
-			program = getStr(a.Data, "program_arguments")
+			program = getStr(a.Data, "launchd_target")
 		}
 		if program == "" {
 			program = getStr(a.Data, "content")
@@ -492,6 +745,84 @@ func buildProcesses(artifacts []models.Artifact) []ProcessRow {
 	return rows
 }
 
+// buildProcessTree flattens the process tree depth-first, children in PID
+// order under their parent
+func buildProcessTree(artifacts []models.Artifact) []ProcessTreeRow {
+	tree := analysis.BuildProcessTree(artifacts)
+	var rows []ProcessTreeRow
+
+	var walk func(n *analysis.ProcessNode, depth int) bool
+	walk = func(n *analysis.ProcessNode, depth int) bool {
+		a := artifacts[n.Index]
+		idx := len(rows)
+		rows = append(rows, ProcessTreeRow{
+			Depth:        depth,
+			PID:          getStr(a.Data, "pid"),
+			Name:         n.DisplayName(),
+			User:         getStr(a.Data, "username"),
+			Cmdline:      n.Cmdline,
+			Lineage:      n.Lineage(),
+			RiskScore:    a.RiskScore,
+			Tags:         a.Tags,
+			Flagged:      a.RiskScore > 0,
+			ParentReused: n.ParentReused,
+		})
+		flagged := a.RiskScore > 0
+		for _, c := range n.Children {
+			if walk(c, depth+1) {
+				flagged = true
+			}
+		}
+		rows[idx].OnFlaggedBranch = flagged
+		return flagged
+	}
+	for _, root := range tree.Roots {
+		walk(root, 0)
+	}
+	return rows
+}
+
+// buildEntities lists the processes that have connections, open network
+// files or persistence entries, riskiest first
+func buildEntities(artifacts []models.Artifact) []EntityRow {
+	var rows []EntityRow
+	for _, e := range correlate.Entities(artifacts) {
+		if e.Connections == 0 && len(e.Persistence) == 0 {
+			continue
+		}
+		row := EntityRow{
+			PID:             e.PID,
+			Name:            e.Name,
+			Exe:             e.Exe,
+			Cmdline:         e.Cmdline,
+			User:            e.User,
+			Signature:       e.Signature,
+			TeamID:          e.TeamID,
+			Connections:     e.Connections,
+			RemoteEndpoints: e.RemoteEndpoints,
+			ListenAddrs:     e.ListenAddrs,
+			OpenFiles:       e.OpenFiles,
+			RiskScore:       e.RiskScore,
+			Tags:            e.Tags,
+		}
+		if e.ParentName != "" {
+			row.Parent = fmt.Sprintf("%s (%d)", e.ParentName, e.PPID)
+		}
+		for _, ref := range e.Persistence {
+			row.Persistence = append(row.Persistence, ref.ArtifactType+": "+ref.Name)
+		}
+		rows = append(rows, row)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].RiskScore != rows[j].RiskScore {
+			return rows[i].RiskScore > rows[j].RiskScore
+		}
+		return rows[i].Connections > rows[j].Connections
+	})
+	return rows
+}
+
 func buildActivityByTypes(artifacts []models.Artifact, types map[string]bool, limit int) []UserActivityRow {
 	// Group by type first so each type gets fair representation
 	byType := make(map[string][]UserActivityRow)
@@ -499,7 +830,7 @@ func buildActivityByTypes(artifacts []models.Artifact, types map[string]bool, li
 		if !types[a.ArtifactType] {
 			continue
 		}
-		et := resolveEventTime(a)
+		et := a.When()
 		byType[a.ArtifactType] = append(byType[a.ArtifactType], UserActivityRow{
 			ArtifactType: a.ArtifactType,
 			Summary:      Summarize(a),
@@ -621,7 +952,7 @@ func buildLogs(artifacts []models.Artifact) []LogRow {
 		if !logTypes[a.ArtifactType] {
 			continue
 		}
-		et := resolveEventTime(a)
+		et := a.When()
 		rows = append(rows, LogRow{
 			ArtifactType: a.ArtifactType,
 			Summary:      Summarize(a),
@@ -659,16 +990,32 @@ func buildCollectorStats(results []models.CollectionResult) []CollectorStat {
 
 	for _, r := range results {
 		stats = append(stats, CollectorStat{
-			ID:       r.CollectorID,
-			Count:    len(r.Artifacts),
-			Duration: r.Duration.Round(time.Millisecond).String(),
-			Success:  r.Error == nil,
+			ID:         r.CollectorID,
+			Count:      len(r.Artifacts),
+			Duration:   r.Duration.Round(time.Millisecond).String(),
+			Success:    r.Error == nil,
+			SkipReason: r.SkipReason,
 		})
 	}
 
 	return stats
 }
 
+// joinArgs renders an argument vector (as collected or as decoded from JSON) as a command line
+func joinArgs(v interface{}) string {
+	switch args := v.(type) {
+	case []string:
+		return strings.Join(args, " ")
+	case []interface{}:
+		parts := make([]string, 0, len(args))
+		for _, arg := range args {
+			parts = append(parts, fmt.Sprintf("%v", arg))
+		}
+		return strings.Join(parts, " ")
+	}
+	return ""
+}
+
 func getStr(d map[string]interface{}, key string) string {
 	if v, ok := d[key]; ok {
 		return fmt.Sprintf("%v", v)
@@ -14,11 +14,25 @@ func Summarize(a models.Artifact) string {
 	switch a.ArtifactType {
 	// Persistence
 	case "user_launch_agent", "system_launch_agent", "system_launch_daemon":
+		if target := getString(d, "target_path"); target != "" {
+			label := getString(d, "label")
+			if label == "" {
+				label = getString(d, "name")
+			}
+			return fmt.Sprintf("Launch agent: %s -> %s", label, target)
+		}
 		return fmt.Sprintf("Launch agent: %s at %s", getString(d, "name"), getString(d, "path"))
 	case "login_item_btm":
-		return fmt.Sprintf("Login item (BTM): %s", getString(d, "Name"))
+		name := getString(d, "name")
+		if name == "" {
+			name = getString(d, "Name")
+		}
+		if path := getString(d, "path"); path != "" {
+			return fmt.Sprintf("Login item (BTM): %s -> %s", name, path)
+		}
+		return fmt.Sprintf("Login item (BTM): %s", name)
 	case "login_item_backgrounditems":
-		return fmt.Sprintf("Background login item: %s", getString(d, "path"))
+		return fmt.Sprintf("Background login item: %s -> %s", getString(d, "name"), getString(d, "path"))
 	case "user_crontab", "system_cron":
 		return fmt.Sprintf("Cron job: %s", getString(d, "entry"))
 	case "at_job":
@@ -133,6 +147,11 @@ func Summarize(a models.Artifact) string {
 		}
 		return fmt.Sprintf("[%s] %s", getString(d, "category"), truncate(msg, 60))
 
+	// Analysis
+	case "yara_match":
+		return fmt.Sprintf("YARA %s: %s (from %s)", getString(d, "rule"), getString(d, "file_path"),
+			strings.ReplaceAll(getString(d, "source_artifact_type"), "_", " "))
+
 	// System info
 	case "system_info":
 		return fmt.Sprintf("System: %s %s", getString(d, "platform"), getString(d, "platform_version"))
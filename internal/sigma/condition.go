@@ -0,0 +1,187 @@
+package sigma
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// node is a parsed condition expression
+type node interface {
+	eval(e *event) bool
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ n node }
+type refNode struct{ d *detection }
+
+// ofNode is "1 of <pattern>" or "all of <pattern>"
+type ofNode struct {
+	all        bool
+	detections []*detection
+}
+
+func (n andNode) eval(e *event) bool { return n.left.eval(e) && n.right.eval(e) }
+func (n orNode) eval(e *event) bool  { return n.left.eval(e) || n.right.eval(e) }
+func (n notNode) eval(e *event) bool { return !n.n.eval(e) }
+func (n refNode) eval(e *event) bool { return n.d.eval(e) }
+
+func (n ofNode) eval(e *event) bool {
+	for _, d := range n.detections {
+		if d.eval(e) != n.all {
+			return !n.all
+		}
+	}
+	return n.all
+}
+
+// parseCondition parses a condition with the precedence not > and > or:
+//
+//	expr   = term { "or" term }
+//	term   = factor { "and" factor }
+//	factor = "not" factor | "(" expr ")" | ("1"|"any"|"all") "of" pattern | name
+func parseCondition(s string, detections map[string]*detection) (node, error) {
+	if strings.Contains(s, "|") {
+		return nil, fmt.Errorf("aggregations are not supported")
+	}
+	p := &condParser{tokens: tokenize(s), detections: detections}
+	n, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return n, nil
+}
+
+func tokenize(s string) []string {
+	s = strings.NewReplacer("(", " ( ", ")", " ) ").Replace(s)
+	return strings.Fields(s)
+}
+
+type condParser struct {
+	tokens     []string
+	pos        int
+	detections map[string]*detection
+}
+
+func (p *condParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return strings.ToLower(p.tokens[p.pos])
+	}
+	return ""
+}
+
+func (p *condParser) next() string {
+	t := p.tokens[p.pos]
+	p.pos++
+	return t
+}
+
+func (p *condParser) expr() (node, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" {
+		p.next()
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *condParser) term() (node, error) {
+	left, err := p.factor()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" {
+		p.next()
+		right, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *condParser) factor() (node, error) {
+	switch tok := p.peek(); tok {
+	case "":
+		return nil, fmt.Errorf("unexpected end of condition")
+	case "not":
+		p.next()
+		n, err := p.factor()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{n}, nil
+	case "(":
+		p.next()
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.next()
+		return n, nil
+	case "1", "any", "all":
+		if p.pos+1 < len(p.tokens) && strings.ToLower(p.tokens[p.pos+1]) == "of" {
+			p.next()
+			p.next()
+			if p.peek() == "" {
+				return nil, fmt.Errorf("%s of: missing pattern", tok)
+			}
+			ds, err := p.resolve(p.next())
+			if err != nil {
+				return nil, err
+			}
+			return ofNode{all: tok == "all", detections: ds}, nil
+		}
+	case ")", "and", "or":
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+
+	name := p.next()
+	d, ok := p.detections[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown detection %q", name)
+	}
+	return refNode{d}, nil
+}
+
+// resolve expands an "of" pattern; "them" is every detection not starting
+// with an underscore
+func (p *condParser) resolve(pattern string) ([]*detection, error) {
+	var names []string
+	for name := range p.detections {
+		var ok bool
+		if pattern == "them" {
+			ok = !strings.HasPrefix(name, "_")
+		} else {
+			ok, _ = path.Match(pattern, name)
+		}
+		if ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no detection matches %q", pattern)
+	}
+	sort.Strings(names)
+	ds := make([]*detection, len(names))
+	for i, name := range names {
+		ds[i] = p.detections[name]
+	}
+	return ds, nil
+}
@@ -0,0 +1,374 @@
+package sigma
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// detection is one named search identifier of a rule. A map compiles to a
+// single alternative (fields ANDed), a list of maps to several alternatives
+// (ORed), and a list of plain values to keywords searched in every field.
+type detection struct {
+	alternatives [][]*fieldMatcher
+	keywords     *fieldMatcher
+}
+
+func compileDetection(v interface{}) (*detection, error) {
+	d := &detection{}
+	switch v := v.(type) {
+	case map[string]interface{}:
+		alt, err := compileMap(v)
+		if err != nil {
+			return nil, err
+		}
+		d.alternatives = append(d.alternatives, alt)
+	case []interface{}:
+		var keywords []interface{}
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				alt, err := compileMap(m)
+				if err != nil {
+					return nil, err
+				}
+				d.alternatives = append(d.alternatives, alt)
+			} else {
+				keywords = append(keywords, item)
+			}
+		}
+		if len(keywords) > 0 && len(d.alternatives) > 0 {
+			return nil, fmt.Errorf("cannot mix field maps and keywords")
+		}
+		if len(keywords) > 0 {
+			fm, err := compileField("|contains", keywords)
+			if err != nil {
+				return nil, err
+			}
+			d.keywords = fm
+		}
+	case nil:
+		return nil, fmt.Errorf("empty detection")
+	default:
+		fm, err := compileField("|contains", v)
+		if err != nil {
+			return nil, err
+		}
+		d.keywords = fm
+	}
+	return d, nil
+}
+
+func compileMap(m map[string]interface{}) ([]*fieldMatcher, error) {
+	var fields []*fieldMatcher
+	for key, v := range m {
+		fm, err := compileField(key, v)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", key, err)
+		}
+		fields = append(fields, fm)
+	}
+	return fields, nil
+}
+
+func (d *detection) eval(e *event) bool {
+	if d.keywords != nil {
+		return d.keywords.eval(e)
+	}
+	for _, alt := range d.alternatives {
+		matched := true
+		for _, fm := range alt {
+			if !fm.eval(e) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldMatcher is one "Field|modifier...: values" entry. An empty field
+// searches every Data value.
+type fieldMatcher struct {
+	field    string
+	all      bool
+	null     bool
+	exists   *bool
+	matchers []matcher
+}
+
+type matcher interface {
+	match(s string) bool
+}
+
+type regexpMatcher struct{ re *regexp.Regexp }
+
+func (m regexpMatcher) match(s string) bool { return m.re.MatchString(s) }
+
+type cidrMatcher struct{ net *net.IPNet }
+
+func (m cidrMatcher) match(s string) bool {
+	ip := net.ParseIP(s)
+	return ip != nil && m.net.Contains(ip)
+}
+
+func compileField(key string, v interface{}) (*fieldMatcher, error) {
+	parts := strings.Split(key, "|")
+	fm := &fieldMatcher{field: parts[0]}
+
+	var position string
+	var cased, b64, b64offset, re, cidr, exists, windash bool
+	var reFlags string
+	for _, mod := range parts[1:] {
+		switch mod {
+		case "contains", "startswith", "endswith":
+			if position != "" {
+				return nil, fmt.Errorf("modifiers %s and %s conflict", position, mod)
+			}
+			position = mod
+		case "all":
+			fm.all = true
+		case "cased":
+			cased = true
+		case "base64":
+			b64 = true
+		case "base64offset":
+			b64offset = true
+		case "re":
+			re = true
+		case "i", "m", "s":
+			if !re {
+				return nil, fmt.Errorf("modifier %s requires re", mod)
+			}
+			reFlags += mod
+		case "cidr":
+			cidr = true
+		case "exists":
+			exists = true
+		case "windash":
+			windash = true
+		default:
+			return nil, fmt.Errorf("unsupported modifier %q", mod)
+		}
+	}
+	if b64offset && position != "contains" {
+		return nil, fmt.Errorf("base64offset must be combined with contains")
+	}
+
+	values, ok := v.([]interface{})
+	if !ok {
+		values = []interface{}{v}
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no values")
+	}
+
+	for _, value := range values {
+		if exists {
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("exists needs true or false")
+			}
+			fm.exists = &b
+			continue
+		}
+		if value == nil {
+			fm.null = true
+			continue
+		}
+		s := fmt.Sprint(value)
+
+		switch {
+		case re:
+			prefix := ""
+			if reFlags != "" {
+				prefix = "(?" + reFlags + ")"
+			}
+			compiled, err := regexp.Compile(prefix + s)
+			if err != nil {
+				return nil, err
+			}
+			fm.matchers = append(fm.matchers, regexpMatcher{compiled})
+		case cidr:
+			_, n, err := net.ParseCIDR(s)
+			if err != nil {
+				return nil, err
+			}
+			fm.matchers = append(fm.matchers, cidrMatcher{n})
+		default:
+			patterns := []string{s}
+			caseSensitive := cased
+			if b64 {
+				patterns = []string{base64.StdEncoding.EncodeToString([]byte(s))}
+				caseSensitive = true
+			} else if b64offset {
+				patterns = base64Offsets(s)
+				caseSensitive = true
+			}
+			for _, p := range patterns {
+				compiled, err := wildcardRegexp(p, position, caseSensitive, windash && !b64 && !b64offset)
+				if err != nil {
+					return nil, err
+				}
+				fm.matchers = append(fm.matchers, regexpMatcher{compiled})
+			}
+		}
+	}
+	return fm, nil
+}
+
+// base64Offsets returns the three encodings of s as it would appear at each
+// byte offset inside a longer base64 string, trimmed of the characters that
+// depend on the surrounding data
+func base64Offsets(s string) []string {
+	start := [3]int{0, 2, 3}
+	trim := [3]int{0, 3, 2}
+	out := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		enc := base64.StdEncoding.EncodeToString([]byte(strings.Repeat(" ", i) + s))
+		end := len(enc) - trim[(len(s)+i)%3]
+		if start[i] < end {
+			out = append(out, enc[start[i]:end])
+		}
+	}
+	return out
+}
+
+// windashes are the characters the windash modifier accepts for a leading
+// - or / of a command-line flag
+const windashes = "[-/\u2013\u2014\u2015]"
+
+// wildcardRegexp translates a Sigma value, where * and ? are wildcards and
+// a backslash escapes them, into an anchored regular expression. With
+// windash, a - or / that starts a flag (not preceded by a word character,
+// followed by one) matches any of windashes.
+func wildcardRegexp(value, position string, caseSensitive, windash bool) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("(?s)")
+	if !caseSensitive {
+		b.WriteString("(?i)")
+	}
+	if position != "contains" && position != "endswith" {
+		b.WriteString("^")
+	}
+	runes := []rune(value)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '\\':
+			if i+1 < len(runes) && (runes[i+1] == '*' || runes[i+1] == '?' || runes[i+1] == '\\') {
+				i++
+				b.WriteString(regexp.QuoteMeta(string(runes[i])))
+			} else {
+				b.WriteString(`\\`)
+			}
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '-', '/':
+			if windash && (i == 0 || !isWordRune(runes[i-1])) && i+1 < len(runes) && isWordRune(runes[i+1]) {
+				b.WriteString(windashes)
+			} else {
+				b.WriteString(regexp.QuoteMeta(string(c)))
+			}
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if position != "contains" && position != "startswith" {
+		b.WriteString("$")
+	}
+	return regexp.Compile(b.String())
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+func (fm *fieldMatcher) eval(e *event) bool {
+	values, present := e.values(fm.field)
+
+	if fm.exists != nil {
+		return present == *fm.exists
+	}
+	if fm.null {
+		empty := !present
+		for _, v := range values {
+			if v == "" {
+				empty = true
+			}
+		}
+		if empty {
+			return true
+		}
+	}
+	if len(fm.matchers) == 0 {
+		return false
+	}
+
+	matchAny := func(m matcher) bool {
+		for _, v := range values {
+			if m.match(v) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, m := range fm.matchers {
+		if matchAny(m) {
+			if !fm.all {
+				return true
+			}
+		} else if fm.all {
+			return false
+		}
+	}
+	return fm.all
+}
+
+// event is an artifact being evaluated, with Sigma field names resolved to
+// its Data keys
+type event struct {
+	artifact models.Artifact
+}
+
+// values returns the string forms of a field's value; list values yield one
+// string per element. present is false when the field is missing or null.
+func (e *event) values(field string) (values []string, present bool) {
+	if field == "" {
+		for _, v := range e.artifact.Data {
+			values = appendValue(values, v)
+		}
+		return values, len(values) > 0
+	}
+	v, ok := e.artifact.Data[dataKey(e.artifact.ArtifactType, field)]
+	if !ok || v == nil {
+		return nil, false
+	}
+	return appendValue(nil, v), true
+}
+
+func appendValue(values []string, v interface{}) []string {
+	switch v := v.(type) {
+	case nil:
+	case string:
+		values = append(values, v)
+	case []string:
+		values = append(values, v...)
+	case []interface{}:
+		for _, item := range v {
+			values = appendValue(values, item)
+		}
+	case map[string]interface{}:
+		// Nested structures are not addressable by Sigma field names
+	default:
+		values = append(values, fmt.Sprint(v))
+	}
+	return values
+}
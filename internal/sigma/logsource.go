@@ -0,0 +1,172 @@
+package sigma
+
+import "strings"
+
+// persistenceTypes are the artifact types of the persistence collectors
+var persistenceTypes = []string{
+	"user_launch_agent", "system_launch_agent", "system_launch_daemon",
+	"login_item_btm", "login_item_backgrounditems",
+	"user_crontab", "system_cron", "at_job",
+}
+
+// categoryTypes maps a logsource category (or service) to artifact types.
+// The Sigma taxonomy categories are mapped to their closest macOS artifacts;
+// the rest are triagectl-specific. A trailing * matches a type prefix.
+var categoryTypes = map[string][]string{
+	// Sigma taxonomy
+	"process_creation":   {"running_process"},
+	"network_connection": {"network_connection", "open_network_file"},
+	"file_event":         {"fs_event", "recent_file"},
+	"dns":                {"dns_config", "dns_config_scutil"},
+
+	// triagectl
+	"persistence":      persistenceTypes,
+	"launchd":          {"user_launch_agent", "system_launch_agent", "system_launch_daemon"},
+	"login_item":       {"login_item_btm", "login_item_backgrounditems"},
+	"cron":             {"user_crontab", "system_cron", "at_job"},
+	"shell_history":    {"bash_history", "zsh_history"},
+	"unifiedlog":       {"unified_log_*"},
+	"unified_log":      {"unified_log_*"},
+	"tcc":              {"tcc_permission"},
+	"quarantine":       {"quarantine_event"},
+	"application":      {"system_application", "user_application"},
+	"extension":        {"kernel_extension", "system_extension", "library_extension"},
+	"browser_history":  {"safari_history", "chrome_history"},
+	"ssh":              {"ssh_private_key", "ssh_public_key", "ssh_config", "ssh_authorized_key", "ssh_known_host"},
+	"user_account":     {"user_account"},
+	"environment":      {"env_variable", "env_variable_suspicious"},
+	"security_posture": {"gatekeeper_status", "sip_status", "firewall_status", "filevault_status", "xprotect_version", "apfs_encryption"},
+}
+
+// artifactTypes resolves a logsource. Only macOS (or product-less and
+// triagectl) rules apply. A category or service that is not in
+// categoryTypes is taken to be an artifact type itself; when both are given
+// the rule applies to the types both resolve to.
+func artifactTypes(ls Logsource) []string {
+	switch strings.ToLower(ls.Product) {
+	case "", "macos", "triagectl":
+	default:
+		return nil
+	}
+
+	resolve := func(name string) []string {
+		name = strings.ToLower(name)
+		if types, ok := categoryTypes[name]; ok {
+			return types
+		}
+		return []string{name}
+	}
+
+	category, service := ls.Category, ls.Service
+	switch {
+	case category != "" && service != "":
+		var types []string
+		svc := resolve(service)
+		for _, t := range resolve(category) {
+			for _, s := range svc {
+				if t == s {
+					types = append(types, t)
+				}
+			}
+		}
+		return types
+	case category != "":
+		return resolve(category)
+	case service != "":
+		return resolve(service)
+	}
+	// A product-only rule applies to everything collected from the host
+	return []string{"*"}
+}
+
+// fieldMap maps Sigma field names to Data keys per artifact type. Field
+// names not listed are looked up as Data keys unchanged, so rules may also
+// be written against triagectl's own field names.
+var fieldMap = map[string]map[string]string{
+	"running_process": {
+		"Image":            "exe",
+		"CommandLine":      "cmdline",
+		"User":             "username",
+		"ProcessId":        "pid",
+		"ParentProcessId":  "ppid",
+		"CurrentDirectory": "cwd",
+		"ProcessName":      "name",
+	},
+	"network_connection": {
+		"DestinationIp":   "remote_addr",
+		"DestinationPort": "remote_port",
+		"SourceIp":        "local_addr",
+		"SourcePort":      "local_port",
+		"ProcessId":       "pid",
+		"Status":          "status",
+	},
+	"open_network_file": {
+		"ProcessName": "command",
+		"ProcessId":   "pid",
+		"User":        "user",
+	},
+	"fs_event": {
+		"TargetFilename": "raw_event",
+	},
+	"recent_file": {
+		"TargetFilename": "path",
+		"User":           "user",
+	},
+	"bash_history": {
+		"CommandLine": "command",
+		"User":        "user",
+	},
+	"zsh_history": {
+		"CommandLine": "command",
+		"User":        "user",
+	},
+	"unified_log_*": {
+		"Image":       "process_path",
+		"ProcessName": "process",
+		"ProcessId":   "pid",
+		"Message":     "event_message",
+		"Subsystem":   "subsystem",
+	},
+	"launchd": {
+		"Image":          "target_path",
+		"CommandLine":    "program_arguments",
+		"TargetFilename": "path",
+	},
+	"login_item_btm": {
+		"Image":          "executable_path",
+		"TargetFilename": "path",
+	},
+	"login_item_backgrounditems": {
+		"TargetFilename": "path",
+	},
+	"cron": {
+		"CommandLine": "entry",
+		"User":        "user",
+	},
+	"quarantine_event": {
+		"Image": "agent_name",
+		"Url":   "data_url",
+		"User":  "user",
+	},
+}
+
+// fieldMapKey groups artifact types that share a field mapping
+func fieldMapKey(artifactType string) string {
+	switch {
+	case strings.HasPrefix(artifactType, "unified_log_"):
+		return "unified_log_*"
+	case artifactType == "user_launch_agent" || artifactType == "system_launch_agent" || artifactType == "system_launch_daemon":
+		return "launchd"
+	case artifactType == "user_crontab" || artifactType == "system_cron" || artifactType == "at_job":
+		return "cron"
+	}
+	return artifactType
+}
+
+// dataKey returns the Data key a Sigma field name refers to
+func dataKey(artifactType, field string) string {
+	if key, ok := fieldMap[fieldMapKey(artifactType)][field]; ok {
+		return key
+	}
+	return field
+}
@@ -0,0 +1,240 @@
+// Package sigma loads Sigma detection rules and evaluates them against
+// triagectl artifacts. Rule logsources are mapped to artifact types and Sigma
+// field names to artifact Data keys (see logsource.go); aggregations and
+// correlation rules are not supported.
+package sigma
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// Logsource is the rule's logsource section
+type Logsource struct {
+	Product  string `yaml:"product"`
+	Category string `yaml:"category"`
+	Service  string `yaml:"service"`
+}
+
+// Rule is a compiled Sigma rule
+type Rule struct {
+	ID          string
+	Title       string
+	Description string
+	Status      string
+	Level       string
+	Tags        []string
+	Logsource   Logsource
+	// Path is the file the rule was loaded from
+	Path string
+
+	types      []string
+	detections map[string]*detection
+	condition  node
+}
+
+// rawRule is the YAML document as written
+type rawRule struct {
+	Title       string                 `yaml:"title"`
+	ID          string                 `yaml:"id"`
+	Status      string                 `yaml:"status"`
+	Description string                 `yaml:"description"`
+	Level       string                 `yaml:"level"`
+	Tags        []string               `yaml:"tags"`
+	Logsource   Logsource              `yaml:"logsource"`
+	Detection   map[string]interface{} `yaml:"detection"`
+}
+
+// Load reads rules from a .yml/.yaml file or, recursively, from every such
+// file in a directory. Files may hold several YAML documents. Rules that fail
+// to compile, and files that cannot be read or are not valid YAML, are
+// skipped and returned as errors naming the file; Load fails only if no rule
+// loads at all.
+func Load(path string) ([]*Rule, []error, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = ruleFiles(path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var rules []*Rule
+	var skipped []error
+	for _, f := range files {
+		r, errs, err := LoadFile(f)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		rules = append(rules, r...)
+		skipped = append(skipped, errs...)
+	}
+	if len(rules) == 0 {
+		if len(skipped) > 0 {
+			return nil, skipped, fmt.Errorf("no Sigma rules loaded from %s: %d failed, first: %w", path, len(skipped), skipped[0])
+		}
+		return nil, nil, fmt.Errorf("no Sigma rules found in %s", path)
+	}
+	return rules, skipped, nil
+}
+
+// ruleFiles lists the .yml/.yaml files under dir in order
+func ruleFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(p))
+		if !d.IsDir() && (ext == ".yml" || ext == ".yaml") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// LoadFile reads the rules in one YAML file, returning the rules that
+// compiled and an error for each one that did not. A read or YAML error
+// ends the file with the rules decoded before it.
+func LoadFile(path string) ([]*Rule, []error, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rules []*Rule
+	var skipped []error
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for n := 1; ; n++ {
+		var raw rawRule
+		if err := dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return rules, skipped, fmt.Errorf("%s: %w", path, err)
+		}
+		// Sigma collections use documents without a detection as shared
+		// headers; they carry nothing to evaluate
+		if raw.Detection == nil {
+			continue
+		}
+		r, err := compile(raw)
+		if err != nil {
+			name := raw.ID
+			if name == "" {
+				name = fmt.Sprintf("document %d", n)
+			}
+			skipped = append(skipped, fmt.Errorf("%s: rule %s: %w", path, name, err))
+			continue
+		}
+		r.Path = path
+		rules = append(rules, r)
+	}
+	return rules, skipped, nil
+}
+
+func compile(raw rawRule) (*Rule, error) {
+	r := &Rule{
+		ID:          raw.ID,
+		Title:       raw.Title,
+		Description: raw.Description,
+		Status:      raw.Status,
+		Level:       strings.ToLower(raw.Level),
+		Tags:        raw.Tags,
+		Logsource:   raw.Logsource,
+		detections:  make(map[string]*detection),
+	}
+	if r.ID == "" {
+		r.ID = r.Title
+	}
+	r.types = artifactTypes(raw.Logsource)
+
+	var conditions []string
+	for name, v := range raw.Detection {
+		switch name {
+		case "condition":
+			switch c := v.(type) {
+			case string:
+				conditions = append(conditions, c)
+			case []interface{}:
+				for _, s := range c {
+					conditions = append(conditions, fmt.Sprint(s))
+				}
+			default:
+				return nil, fmt.Errorf("condition must be a string or list")
+			}
+		case "timeframe":
+			// Only meaningful for aggregations, which are rejected below
+		default:
+			d, err := compileDetection(v)
+			if err != nil {
+				return nil, fmt.Errorf("detection %s: %w", name, err)
+			}
+			r.detections[name] = d
+		}
+	}
+	if len(conditions) == 0 {
+		return nil, fmt.Errorf("missing condition")
+	}
+
+	// Several conditions are alternatives
+	for _, c := range conditions {
+		n, err := parseCondition(c, r.detections)
+		if err != nil {
+			return nil, fmt.Errorf("condition %q: %w", c, err)
+		}
+		if r.condition == nil {
+			r.condition = n
+		} else {
+			r.condition = orNode{r.condition, n}
+		}
+	}
+	return r, nil
+}
+
+// ArtifactTypes returns the artifact types the rule's logsource maps to.
+// An empty result means the rule targets a logsource triagectl does not
+// collect (e.g. product: windows).
+func (r *Rule) ArtifactTypes() []string { return r.types }
+
+// AppliesTo reports whether the rule should be evaluated for artifactType
+func (r *Rule) AppliesTo(artifactType string) bool {
+	for _, t := range r.types {
+		if t == artifactType || (strings.HasSuffix(t, "*") && strings.HasPrefix(artifactType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match evaluates the rule against an artifact, regardless of its type
+func (r *Rule) Match(a models.Artifact) bool {
+	return r.condition.eval(&event{artifact: a})
+}
+
+// AttackTags returns the rule's ATT&CK tags (attack.*)
+func (r *Rule) AttackTags() []string {
+	var tags []string
+	for _, t := range r.Tags {
+		if strings.HasPrefix(strings.ToLower(t), "attack.") {
+			tags = append(tags, strings.ToLower(t))
+		}
+	}
+	return tags
+}
@@ -0,0 +1,233 @@
+package sigma
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// compileYAML compiles one rule document
+func compileYAML(t *testing.T, src string) (*Rule, error) {
+	t.Helper()
+	var raw rawRule
+	if err := yaml.Unmarshal([]byte(src), &raw); err != nil {
+		t.Fatalf("yaml: %v", err)
+	}
+	return compile(raw)
+}
+
+func process(cmdline string) models.Artifact {
+	return models.Artifact{
+		ArtifactType: "running_process",
+		Data: map[string]interface{}{
+			"exe":      "/usr/bin/curl",
+			"cmdline":  cmdline,
+			"username": "alice",
+			"pid":      int32(42),
+		},
+	}
+}
+
+func TestCondition(t *testing.T) {
+	detection := `
+detection:
+  curl:
+    Image|endswith: /curl
+  pipe:
+    CommandLine|contains: '| sh'
+  _helper:
+    User: root
+  other:
+    User: bob
+`
+	tests := []struct {
+		condition string
+		cmdline   string
+		want      bool
+		wantErr   string
+	}{
+		{"curl and pipe", "curl -s x | sh", true, ""},
+		{"curl and pipe", "curl -s x", false, ""},
+		{"curl and not pipe", "curl -s x", true, ""},
+		{"not curl or pipe", "curl x | sh", true, ""},
+		{"pipe or curl and other", "curl x", false, ""},
+		{"(pipe or curl) and not other", "curl x", true, ""},
+		{"1 of them", "curl x", true, ""},
+		{"all of them", "curl x | sh", false, ""},
+		{"all of c*", "curl x", true, ""},
+		{"1 of _*", "curl x", false, ""},
+		{"all of them", "", false, ""},
+		{"curl and", "", false, "unexpected end"},
+		{"(curl", "", false, "missing )"},
+		{"curl pipe", "", false, `unexpected "pipe"`},
+		{"missing", "", false, "unknown detection"},
+		{"1 of nothing*", "", false, "no detection matches"},
+		{"curl | count() > 1", "", false, "aggregations"},
+	}
+	for _, tt := range tests {
+		r, err := compileYAML(t, detection+"  condition: '"+tt.condition+"'\n")
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%q: err = %v, want %q", tt.condition, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.condition, err)
+			continue
+		}
+		if got := r.Match(process(tt.cmdline)); got != tt.want {
+			t.Errorf("%q on %q = %v, want %v", tt.condition, tt.cmdline, got, tt.want)
+		}
+	}
+}
+
+func TestModifiers(t *testing.T) {
+	tests := []struct {
+		field   string
+		values  string
+		cmdline string
+		want    bool
+	}{
+		{"CommandLine", "'curl*sh'", "CURL x | sh", true},
+		{"CommandLine|cased", "'curl*sh'", "CURL x | sh", false},
+		{"CommandLine", `'a\*b'`, "a*b", true},
+		{"CommandLine", `'a\*b'`, "axb", false},
+		{"CommandLine|startswith", "curl", "curl -s", true},
+		{"CommandLine|startswith", "-s", "curl -s", false},
+		{"CommandLine|contains|all", "[-s, -k]", "curl -s -k", true},
+		{"CommandLine|contains|all", "[-s, -k]", "curl -s", false},
+		{"CommandLine|contains", "[-s, -k]", "curl -k", true},
+		{"CommandLine|re", "'^curl\\s+-[sk]$'", "curl -k", true},
+		{"CommandLine|re", "'^CURL'", "curl -k", false},
+		{"CommandLine|re|i", "'^CURL'", "curl -k", true},
+		{"CommandLine|base64", "secret", "c2VjcmV0", true},
+		{"CommandLine|base64", "secret", "echo c2VjcmV0", false},
+		{"CommandLine|base64|contains", "secret", "echo c2VjcmV0", true},
+		{"CommandLine|base64|contains", "secret", "echo C2VJCMV0", false},
+		// "secret" at each offset inside a longer base64 string
+		{"CommandLine|base64offset|contains", "secret", "echo c2VjcmV0IHg=", true},
+		{"CommandLine|base64offset|contains", "secret", "echo eHNlY3JldA==", true},
+		{"CommandLine|base64offset|contains", "secret", "echo eHhzZWNyZXQ=", true},
+		{"CommandLine|base64offset|contains", "secret", "echo c2VjcmV", false},
+		{"CommandLine|windash|contains", "' -enc '", "powershell /enc x", true},
+		{"CommandLine|windash|contains", "' -enc '", "powershell –enc x", true},
+		{"CommandLine|windash|contains", "' -enc '", "powershell +enc x", false},
+		{"CommandLine|windash|contains", "'a-b'", "a/b", false},
+		{"CommandLine|contains", "' -enc '", "powershell /enc x", false},
+		{"CommandLine", "null", "", true},
+		{"Missing", "null", "x", true},
+		{"CommandLine|exists", "true", "x", true},
+		{"Missing|exists", "false", "x", true},
+		{"ProcessId", "42", "x", true},
+		{"pid", "42", "x", true},
+	}
+	for _, tt := range tests {
+		src := "detection:\n  sel:\n    " + tt.field + ": " + tt.values + "\n  condition: sel\n"
+		r, err := compileYAML(t, src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.field, err)
+			continue
+		}
+		if got := r.Match(process(tt.cmdline)); got != tt.want {
+			t.Errorf("%s: %s on %q = %v, want %v", tt.field, tt.values, tt.cmdline, got, tt.want)
+		}
+	}
+}
+
+func TestCIDR(t *testing.T) {
+	r, err := compileYAML(t, `
+logsource: {category: network_connection}
+detection:
+  sel:
+    DestinationIp|cidr: [10.0.0.0/8, 'fd00::/8']
+  condition: sel
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for addr, want := range map[string]bool{
+		"10.1.2.3": true, "11.0.0.1": false, "fd12::1": true, "fe80::1": false, "not-an-ip": false,
+	} {
+		conn := models.Artifact{ArtifactType: "network_connection", Data: map[string]interface{}{"remote_addr": addr}}
+		if got := r.Match(conn); got != want {
+			t.Errorf("cidr %s = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		detection string
+		wantErr   string
+	}{
+		{"sel: {CommandLine|lowercase: x}", "unsupported modifier"},
+		{"sel: {CommandLine|startswith|endswith: x}", "conflict"},
+		{"sel: {CommandLine|base64offset: x}", "must be combined with contains"},
+		{"sel: {CommandLine|i: x}", "requires re"},
+		{"sel: {CommandLine|re: '('}", "missing closing"},
+		{"sel: {DestinationIp|cidr: 10.0.0.0}", "invalid CIDR"},
+		{"sel: {CommandLine|exists: yes please}", "exists needs"},
+		{"sel: {CommandLine: []}", "no values"},
+		{"sel: [{CommandLine: x}, keyword]", "cannot mix"},
+		{"sel:", "empty detection"},
+	}
+	for _, tt := range tests {
+		src := "detection:\n  " + tt.detection + "\n  condition: sel\n"
+		if _, err := compileYAML(t, src); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want %q", tt.detection, err, tt.wantErr)
+		}
+	}
+	if _, err := compileYAML(t, "detection:\n  sel: {User: x}\n"); err == nil || !strings.Contains(err.Error(), "missing condition") {
+		t.Errorf("no condition: err = %v", err)
+	}
+}
+
+func TestLogsource(t *testing.T) {
+	tests := []struct {
+		ls   Logsource
+		want string
+	}{
+		{Logsource{Product: "macos", Category: "process_creation"}, "running_process"},
+		{Logsource{Product: "windows", Category: "process_creation"}, ""},
+		{Logsource{Product: "linux"}, ""},
+		{Logsource{Product: "macos"}, "*"},
+		{Logsource{}, "*"},
+		{Logsource{Category: "LAUNCHD"}, "user_launch_agent,system_launch_agent,system_launch_daemon"},
+		{Logsource{Category: "persistence", Service: "cron"}, "user_crontab,system_cron,at_job"},
+		{Logsource{Category: "persistence", Service: "tcc"}, ""},
+		{Logsource{Service: "unifiedlog"}, "unified_log_*"},
+		{Logsource{Product: "triagectl", Category: "ssh_config"}, "ssh_config"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(artifactTypes(tt.ls), ","); got != tt.want {
+			t.Errorf("artifactTypes(%+v) = %q, want %q", tt.ls, got, tt.want)
+		}
+	}
+
+	r := &Rule{types: artifactTypes(Logsource{Category: "unified_log"})}
+	if !r.AppliesTo("unified_log_security") || r.AppliesTo("running_process") {
+		t.Errorf("AppliesTo does not honour the unified_log_* prefix")
+	}
+}
+
+func TestFieldMapping(t *testing.T) {
+	tests := []struct {
+		artifactType, field, want string
+	}{
+		{"running_process", "Image", "exe"},
+		{"system_launch_daemon", "Image", "target_path"},
+		{"at_job", "CommandLine", "entry"},
+		{"unified_log_security", "Message", "event_message"},
+		{"running_process", "exe", "exe"},
+		{"tcc_permission", "Image", "Image"},
+	}
+	for _, tt := range tests {
+		if got := dataKey(tt.artifactType, tt.field); got != tt.want {
+			t.Errorf("dataKey(%s, %s) = %s, want %s", tt.artifactType, tt.field, got, tt.want)
+		}
+	}
+}
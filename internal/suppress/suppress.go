@@ -0,0 +1,282 @@
+// Package suppress marks findings that are expected on a host, such as an
+// MDM agent's launch daemon or developers' interpreters, from a YAML file of
+// suppression rules. Suppressed artifacts keep their score and tags but are
+// not counted as findings.
+package suppress
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// Rule suppresses the scored artifacts that match every criterion it sets
+type Rule struct {
+	ID            string     `yaml:"id"`
+	Justification string     `yaml:"justification"`
+	Expires       *time.Time `yaml:"expires"`
+
+	ArtifactType *Pattern           `yaml:"artifact_type"`
+	Fields       map[string]Pattern `yaml:"fields"`
+	// TeamID matches any code signing team ID on the artifact
+	// (exe_team_id, target_team_id, ...)
+	TeamID string `yaml:"team_id"`
+	// Hash matches the artifact's file hash or any MD5, SHA-1 or SHA-256
+	// in its data
+	Hash string   `yaml:"hash"`
+	Tag  *Pattern `yaml:"tag"`
+}
+
+// Expired reports whether the rule stopped applying before now
+func (r *Rule) Expired(now time.Time) bool {
+	return r.Expires != nil && !now.Before(*r.Expires)
+}
+
+// Pattern matches a string exactly, by glob or by regular expression. In
+// YAML it is a plain value (exact) or a map with one of exact, glob or
+// regex.
+type Pattern struct {
+	Exact string `yaml:"exact"`
+	// Glob is anchored: * matches within one path element, ** across
+	// elements and ? one character
+	Glob  string `yaml:"glob"`
+	Regex string `yaml:"regex"`
+
+	re *regexp.Regexp
+}
+
+// UnmarshalYAML accepts a plain value as an exact pattern
+func (p *Pattern) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		p.Exact = node.Value
+		return nil
+	}
+	type pattern Pattern
+	return node.Decode((*pattern)(p))
+}
+
+func (p *Pattern) compile() error {
+	set := 0
+	for _, v := range []string{p.Exact, p.Glob, p.Regex} {
+		if v != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("pattern needs exactly one of exact, glob or regex")
+	}
+	var err error
+	switch {
+	case p.Glob != "":
+		p.re, err = globRegexp(p.Glob)
+	case p.Regex != "":
+		p.re, err = regexp.Compile(p.Regex)
+	}
+	return err
+}
+
+// Match reports whether s matches the pattern
+func (p *Pattern) Match(s string) bool {
+	if p.re != nil {
+		return p.re.MatchString(s)
+	}
+	return s == p.Exact
+}
+
+// Rules are the suppression rules loaded from a file
+type Rules struct {
+	Suppressions []*Rule `yaml:"suppressions"`
+}
+
+// Load reads and validates a suppression file
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules := &Rules{}
+	if err := yaml.Unmarshal(data, rules); err != nil {
+		return nil, fmt.Errorf("parsing suppressions %s: %w", path, err)
+	}
+
+	seen := make(map[string]bool)
+	for i, r := range rules.Suppressions {
+		if r.ID == "" {
+			return nil, fmt.Errorf("%s: suppression %d has no id", path, i+1)
+		}
+		if seen[r.ID] {
+			return nil, fmt.Errorf("%s: duplicate suppression id %q", path, r.ID)
+		}
+		seen[r.ID] = true
+		if err := r.compile(); err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", path, r.ID, err)
+		}
+	}
+	return rules, nil
+}
+
+func (r *Rule) compile() error {
+	if strings.TrimSpace(r.Justification) == "" {
+		return fmt.Errorf("justification is required")
+	}
+	if r.ArtifactType == nil && len(r.Fields) == 0 && r.TeamID == "" && r.Hash == "" && r.Tag == nil {
+		return fmt.Errorf("no match criteria")
+	}
+	for _, p := range []*Pattern{r.ArtifactType, r.Tag} {
+		if p != nil {
+			if err := p.compile(); err != nil {
+				return err
+			}
+		}
+	}
+	for field, p := range r.Fields {
+		if err := p.compile(); err != nil {
+			return fmt.Errorf("field %s: %w", field, err)
+		}
+		r.Fields[field] = p
+	}
+	return nil
+}
+
+// Count returns the number of active and expired rules at now
+func (rs *Rules) Count(now time.Time) (active, expired int) {
+	for _, r := range rs.Suppressions {
+		if r.Expired(now) {
+			expired++
+		} else {
+			active++
+		}
+	}
+	return active, expired
+}
+
+// Apply marks every scored artifact matched by an active rule with the
+// first such rule, and returns how many findings it suppressed
+func (rs *Rules) Apply(artifacts []models.Artifact, now time.Time) int {
+	suppressed := 0
+	for i := range artifacts {
+		a := &artifacts[i]
+		a.Suppression = nil
+		if a.RiskScore == 0 {
+			continue
+		}
+		for _, r := range rs.Suppressions {
+			if r.Expired(now) || !r.Match(a) {
+				continue
+			}
+			if a.IsFinding() {
+				suppressed++
+			}
+			a.Suppression = &models.Suppression{
+				ID:            r.ID,
+				Justification: r.Justification,
+				Expires:       r.Expires,
+			}
+			break
+		}
+	}
+	return suppressed
+}
+
+// Match reports whether the artifact meets every criterion of the rule
+func (r *Rule) Match(a *models.Artifact) bool {
+	if r.ArtifactType != nil && !r.ArtifactType.Match(a.ArtifactType) {
+		return false
+	}
+	for field, p := range r.Fields {
+		if !matchValue(&p, a.Data[field]) {
+			return false
+		}
+	}
+	if r.TeamID != "" && !anyField(a.Data, func(key, v string) bool {
+		return (key == "team_id" || strings.HasSuffix(key, "_team_id")) && strings.EqualFold(v, r.TeamID)
+	}) {
+		return false
+	}
+	if r.Hash != "" && !strings.EqualFold(a.Metadata.FileHash, r.Hash) && !anyField(a.Data, func(key, v string) bool {
+		return isHashField(key) && strings.EqualFold(v, r.Hash)
+	}) {
+		return false
+	}
+	if r.Tag != nil && !anyTag(a.Tags, r.Tag) {
+		return false
+	}
+	return true
+}
+
+// matchValue matches a data value; lists match when any element does
+func matchValue(p *Pattern, v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case string:
+		return p.Match(v)
+	case []interface{}:
+		for _, item := range v {
+			if matchValue(p, item) {
+				return true
+			}
+		}
+		return false
+	default:
+		return p.Match(fmt.Sprint(v))
+	}
+}
+
+func anyField(data map[string]interface{}, match func(key, value string) bool) bool {
+	for key, v := range data {
+		if s, ok := v.(string); ok && s != "" && match(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHashField(key string) bool {
+	for _, suffix := range []string{"md5", "sha1", "sha256"} {
+		if key == suffix || strings.HasSuffix(key, "_"+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyTag(tags []string, p *Pattern) bool {
+	for _, t := range tags {
+		if p.Match(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// globRegexp compiles an anchored glob: * matches within one path element,
+// ** across elements and ? one character
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	runes := []rune(glob)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				b.WriteString(`.*`)
+				i++
+			} else {
+				b.WriteString(`[^/]*`)
+			}
+		case '?':
+			b.WriteString(`[^/]`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
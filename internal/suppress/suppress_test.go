@@ -0,0 +1,149 @@
+package suppress
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+func load(t *testing.T, src string) (*Rules, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "suppressions.yaml")
+	if err := os.WriteFile(path, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return Load(path)
+}
+
+func TestPatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"/Library/LaunchDaemons/com.jamf.plist", "/Library/LaunchDaemons/com.jamf.plist", true},
+		{"/Library/LaunchDaemons/com.jamf.plist", "/library/launchdaemons/com.jamf.plist", false},
+		{"{exact: 'a*b'}", "a*b", true},
+		{"{exact: 'a*b'}", "axb", false},
+		{"{glob: '/Library/LaunchDaemons/com.jamf.*'}", "/Library/LaunchDaemons/com.jamf.agent.plist", true},
+		{"{glob: '/Library/LaunchDaemons/com.jamf.*'}", "/Library/LaunchDaemons/com.jamf.d/x.plist", false},
+		{"{glob: '/Users/**/node'}", "/Users/alice/.nvm/versions/node", true},
+		{"{glob: '/Users/*/node'}", "/Users/alice/.nvm/node", false},
+		{"{glob: '/tmp/?.sh'}", "/tmp/a.sh", true},
+		{"{glob: '/tmp/?.sh'}", "/tmp/ab.sh", false},
+		{"{glob: '/tmp/?.sh'}", "/tmp//.sh", false},
+		{"{glob: '/Users/josé/?/x'}", "/Users/josé/é/x", true},
+		{"{glob: 'a.b'}", "axb", false},
+		{"{glob: 'bin'}", "/usr/bin", false},
+		{"{regex: 'bin/(python|ruby)[0-9.]*$'}", "/usr/local/bin/python3.12", true},
+		{"{regex: '^/usr/bin/'}", "/opt/usr/bin/x", false},
+	}
+	for _, tt := range tests {
+		rules, err := load(t, "suppressions:\n- id: p\n  justification: test\n  fields:\n    path: "+tt.pattern+"\n")
+		if err != nil {
+			t.Errorf("%s: %v", tt.pattern, err)
+			continue
+		}
+		a := &models.Artifact{Data: map[string]interface{}{"path": tt.value}}
+		if got := rules.Suppressions[0].Match(a); got != tt.want {
+			t.Errorf("%s on %q = %v, want %v", tt.pattern, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		src     string
+		wantErr string
+	}{
+		{"suppressions:\n- justification: x\n  team_id: ABC", "has no id"},
+		{"suppressions:\n- id: a\n  justification: x\n  team_id: A\n- id: a\n  justification: y\n  team_id: B", "duplicate suppression id"},
+		{"suppressions:\n- id: a\n  team_id: ABC", "justification is required"},
+		{"suppressions:\n- id: a\n  justification: '  '\n  team_id: ABC", "justification is required"},
+		{"suppressions:\n- id: a\n  justification: x", "no match criteria"},
+		{"suppressions:\n- id: a\n  justification: x\n  tag: {glob: 'a', regex: 'b'}", "exactly one of"},
+		{"suppressions:\n- id: a\n  justification: x\n  fields: {path: {}}", "field path: pattern needs"},
+		{"suppressions:\n- id: a\n  justification: x\n  fields: {path: {regex: '('}}", "missing closing"},
+		{"suppressions:\n- id: a\n  justification: x\n  expires: next week\n  team_id: ABC", "parsing suppressions"},
+		{"suppressions: [", "parsing suppressions"},
+	}
+	for _, tt := range tests {
+		if _, err := load(t, tt.src); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%q: err = %v, want %q", tt.src, err, tt.wantErr)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	rules, err := load(t, `
+suppressions:
+- id: expired
+  justification: old exception
+  expires: 2026-01-01T00:00:00Z
+  artifact_type: system_launch_daemon
+- id: jamf
+  justification: MDM agent
+  artifact_type: {glob: 'system_launch_*'}
+  team_id: 483dwkw443
+- id: daemons
+  justification: everything else under LaunchDaemons
+  artifact_type: system_launch_daemon
+  tag: {regex: '^unsigned'}
+- id: known-hash
+  justification: approved build
+  hash: ABCDEF
+- id: args
+  justification: list values match by element
+  fields:
+    program_arguments: --managed
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	if active, expired := rules.Count(now); active != 4 || expired != 1 {
+		t.Errorf("Count = %d active, %d expired; want 4, 1", active, expired)
+	}
+
+	daemon := func(score int, data map[string]interface{}, tags ...string) models.Artifact {
+		return models.Artifact{ArtifactType: "system_launch_daemon", RiskScore: score, Data: data, Tags: tags}
+	}
+	artifacts := []models.Artifact{
+		// Matches jamf and daemons: the first rule in file order wins
+		daemon(60, map[string]interface{}{"target_team_id": "483DWKW443"}, "unsigned_binary"),
+		daemon(60, map[string]interface{}{"team_id": "OTHER"}, "unsigned_binary"),
+		daemon(60, map[string]interface{}{"team_id": "OTHER"}),
+		// Scored below the finding threshold: marked but not counted
+		daemon(10, map[string]interface{}{"exe_sha256": "abcdef"}),
+		// Unscored artifacts are never marked
+		daemon(0, map[string]interface{}{"exe_sha256": "abcdef"}),
+		{ArtifactType: "running_process", RiskScore: 50, Metadata: models.ArtifactMetadata{FileHash: "abcdef"}},
+		daemon(50, map[string]interface{}{"program_arguments": []interface{}{"/usr/local/bin/agent", "--managed"}}),
+		// A previous run's suppression is cleared when no rule matches
+		{ArtifactType: "running_process", RiskScore: 50, Suppression: &models.Suppression{ID: "stale"}},
+	}
+	want := []string{"jamf", "daemons", "", "known-hash", "", "known-hash", "args", ""}
+
+	if got := rules.Apply(artifacts, now); got != 4 {
+		t.Errorf("Apply suppressed %d findings, want 4", got)
+	}
+	for i, a := range artifacts {
+		got := ""
+		if a.Suppression != nil {
+			got = a.Suppression.ID
+		}
+		if got != want[i] {
+			t.Errorf("artifact %d suppressed by %q, want %q", i, got, want[i])
+		}
+	}
+
+	// Before the expiry the first rule still applies
+	rules.Apply(artifacts, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC))
+	if s := artifacts[2].Suppression; s == nil || s.ID != "expired" || s.Expires == nil {
+		t.Errorf("before expiry: suppression = %+v, want expired", s)
+	}
+}
@@ -0,0 +1,146 @@
+package target
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/plonxyz/triagectl/internal/models"
+)
+
+// identityFields lists, per artifact type, the data fields that identify the
+// same entity across two collections. Alternatives are tried in order and the
+// first whose fields are all present wins. An empty list marks a singleton
+// type (one per host). Types not listed are identified by their content, so
+// any modification shows up as a removal plus an addition.
+var identityFields = map[string][][]string{
+	// Persistence
+	"user_launch_agent":          {{"path"}},
+	"system_launch_agent":        {{"path"}},
+	"system_launch_daemon":       {{"path"}},
+	"login_item_btm":             {{"user_uuid", "identifier"}, {"uuid"}, {"Name"}},
+	"login_item_backgrounditems": {{"btm_path", "name", "path"}},
+	"user_crontab":               {{"path", "entry"}, {"user", "entry"}},
+	"system_cron":                {{"path", "entry"}},
+	"at_job":                     {{"entry"}},
+	"system_extension":           {{"identifier"}},
+	"kernel_extension":           {{"name"}},
+	"library_extension":          {{"path"}},
+
+	// Accounts, auth and privacy
+	"user_account":       {{"username"}},
+	"tcc_permission":     {{"database_type", "user", "service", "client"}, {"database_type", "service", "client"}},
+	"ssh_private_key":    {{"path"}},
+	"ssh_public_key":     {{"path"}},
+	"ssh_config":         {{"path"}},
+	"ssh_authorized_key": {{"user", "key"}},
+	"ssh_known_host":     {{"user", "entry"}},
+
+	// Software
+	"system_application": {{"path"}},
+	"user_application":   {{"path"}},
+
+	// System state
+	"running_process":         {{"exe", "cmdline", "username"}},
+	"network_connection":      {{"type", "local_addr", "local_port", "remote_addr", "remote_port"}},
+	"network_interface":       {{"name"}},
+	"arp_entry":               {{"interface", "ip"}},
+	"routing_table_entry":     {{"destination", "gateway", "interface"}},
+	"env_variable":            {{"key"}},
+	"env_variable_suspicious": {{"key"}},
+
+	// Security posture
+	"system_info":       {},
+	"gatekeeper_status": {},
+	"sip_status":        {},
+	"firewall_status":   {},
+	"filevault_status":  {},
+	"xprotect_version":  {},
+	"apfs_encryption":   {},
+
+	// Analysis
+	"yara_match": {{"rule", "file_path", "source_key"}},
+}
+
+// volatileFields change between any two collections without meaning
+// anything and are ignored when deciding whether an artifact changed. A
+// process restarted with the same executable, command line and user is the
+// same process for a diff. They also include what correlate.Enrich adds: a
+// process's current connections, and the owning process's context on each
+// connection.
+var volatileFields = map[string]map[string]bool{
+	"running_process": {
+		"pid": true, "ppid": true, "create_time": true,
+		"cpu_percent": true, "memory_percent": true, "memory_rss_bytes": true, "num_connections": true,
+		"remote_endpoints": true, "listen_addrs": true,
+	},
+	"system_info":        {"uptime_seconds": true, "procs": true},
+	"network_connection": withProcessContext("fd", "status"),
+	"open_network_file":  withProcessContext(),
+	// the row ID of the scanned artifact depends on the order collectors
+	// finished in
+	"yara_match": {"source_artifact_id": true},
+}
+
+// processContext are the process_* fields correlate.Enrich copies onto
+// connections from their process
+var processContext = []string{
+	"process_name", "process_exe", "process_user", "process_signed",
+	"process_adhoc_signed", "process_team_id", "process_signing_id", "process_sha256",
+}
+
+func withProcessContext(fields ...string) map[string]bool {
+	set := make(map[string]bool, len(fields)+len(processContext))
+	for _, f := range append(fields, processContext...) {
+		set[f] = true
+	}
+	return set
+}
+
+// IdentityKey returns the stable key used to match an artifact between two
+// collections of the same host
+func IdentityKey(a models.Artifact) string {
+	alternatives, ok := identityFields[a.ArtifactType]
+	if ok && len(alternatives) == 0 {
+		return a.ArtifactType
+	}
+	for _, fields := range alternatives {
+		parts := make([]string, 0, len(fields))
+		for _, f := range fields {
+			v, present := a.Data[f]
+			if !present || v == nil || fmt.Sprint(v) == "" {
+				parts = nil
+				break
+			}
+			parts = append(parts, f+"="+fmt.Sprint(v))
+		}
+		if len(parts) > 0 {
+			return strings.Join(parts, " ")
+		}
+	}
+	return "content=" + contentHash(a)
+}
+
+// contentHash hashes the artifact's stable data
+func contentHash(a models.Artifact) string {
+	b, _ := json.Marshal(StableData(a))
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+// StableData returns the artifact's data without its volatile fields
+func StableData(a models.Artifact) map[string]interface{} {
+	skip := volatileFields[a.ArtifactType]
+	if len(skip) == 0 {
+		return a.Data
+	}
+	out := make(map[string]interface{}, len(a.Data))
+	for k, v := range a.Data {
+		if !skip[k] {
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,26 @@
+// Package target describes the system a collection was taken from: where
+// its files are on this host and how its artifacts are identified across
+// collections. Collectors, analyzers and diff share it.
+package target
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// ResolvePath maps an absolute path on a target system collected from root
+// to the path to read on this host. An empty root is the live system.
+// Offline, /etc, /tmp and /var are redirected to /private since their
+// top-level symlinks may be absolute and escape the root.
+func ResolvePath(root, p string) string {
+	if root == "" {
+		return p
+	}
+	for _, dir := range []string{"/etc", "/tmp", "/var"} {
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			p = "/private" + p
+			break
+		}
+	}
+	return filepath.Join(root, p)
+}
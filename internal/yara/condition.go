@@ -0,0 +1,444 @@
+package yara
+
+import (
+	"encoding/binary"
+	"regexp"
+	"strings"
+)
+
+// value is the result of evaluating an expression: int64, float64, string,
+// bool, or nil for undefined (e.g. @a[3] when $a matched twice)
+type value interface{}
+
+type expr interface {
+	eval(sc *scanContext) value
+}
+
+// scanContext holds the data being scanned and what has been computed
+// about it so far
+type scanContext struct {
+	data    []byte
+	lower   []byte
+	matches map[*stringDef][]stringMatch
+	results map[string]bool
+	cache   map[string]value
+	rule    *rule
+}
+
+func newScanContext(data []byte) *scanContext {
+	return &scanContext{
+		data:    data,
+		matches: make(map[*stringDef][]stringMatch),
+		results: make(map[string]bool),
+		cache:   make(map[string]value),
+	}
+}
+
+// lowered returns the data with A-Z lowercased, for nocase strings
+func (sc *scanContext) lowered() []byte {
+	if sc.lower == nil {
+		sc.lower = asciiLower(sc.data)
+	}
+	return sc.lower
+}
+
+// stringMatches finds a string's matches on first use
+func (sc *scanContext) stringMatches(s *stringDef) []stringMatch {
+	m, ok := sc.matches[s]
+	if !ok {
+		m = s.find(sc)
+		sc.matches[s] = m
+	}
+	return m
+}
+
+func (sc *scanContext) lookup(id string) *stringDef { return sc.rule.byID[id] }
+
+func toBool(v value) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case float64:
+		return v != 0
+	case string:
+		return v != ""
+	}
+	return false
+}
+
+func toFloat(v value) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+type litNode struct{ v value }
+
+func (n litNode) eval(sc *scanContext) value { return n.v }
+
+type filesizeNode struct{}
+
+func (filesizeNode) eval(sc *scanContext) value { return int64(len(sc.data)) }
+
+type andNode struct{ l, r expr }
+type orNode struct{ l, r expr }
+type notNode struct{ x expr }
+
+func (n andNode) eval(sc *scanContext) value { return toBool(n.l.eval(sc)) && toBool(n.r.eval(sc)) }
+func (n orNode) eval(sc *scanContext) value  { return toBool(n.l.eval(sc)) || toBool(n.r.eval(sc)) }
+func (n notNode) eval(sc *scanContext) value { return !toBool(n.x.eval(sc)) }
+
+// cmpNode is a relational or string comparison operator
+type cmpNode struct {
+	op   string
+	l, r expr
+}
+
+func (n cmpNode) eval(sc *scanContext) value {
+	l, r := n.l.eval(sc), n.r.eval(sc)
+	if ls, ok := l.(string); ok {
+		rs, ok := r.(string)
+		if !ok {
+			return false
+		}
+		switch n.op {
+		case "==":
+			return ls == rs
+		case "!=":
+			return ls != rs
+		case "<":
+			return ls < rs
+		case "<=":
+			return ls <= rs
+		case ">":
+			return ls > rs
+		case ">=":
+			return ls >= rs
+		case "contains":
+			return strings.Contains(ls, rs)
+		case "icontains":
+			return strings.Contains(strings.ToLower(ls), strings.ToLower(rs))
+		case "startswith":
+			return strings.HasPrefix(ls, rs)
+		case "istartswith":
+			return strings.HasPrefix(strings.ToLower(ls), strings.ToLower(rs))
+		case "endswith":
+			return strings.HasSuffix(ls, rs)
+		case "iendswith":
+			return strings.HasSuffix(strings.ToLower(ls), strings.ToLower(rs))
+		case "iequals":
+			return strings.EqualFold(ls, rs)
+		}
+		return false
+	}
+
+	li, lInt := l.(int64)
+	ri, rInt := r.(int64)
+	if lInt && rInt {
+		switch n.op {
+		case "==":
+			return li == ri
+		case "!=":
+			return li != ri
+		case "<":
+			return li < ri
+		case "<=":
+			return li <= ri
+		case ">":
+			return li > ri
+		case ">=":
+			return li >= ri
+		}
+		return false
+	}
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	if !lok || !rok {
+		return false
+	}
+	switch n.op {
+	case "==":
+		return lf == rf
+	case "!=":
+		return lf != rf
+	case "<":
+		return lf < rf
+	case "<=":
+		return lf <= rf
+	case ">":
+		return lf > rf
+	case ">=":
+		return lf >= rf
+	}
+	return false
+}
+
+type matchesNode struct {
+	x  expr
+	re *regexp.Regexp
+}
+
+func (n matchesNode) eval(sc *scanContext) value {
+	s, ok := n.x.eval(sc).(string)
+	return ok && n.re.MatchString(s)
+}
+
+// arithNode is an arithmetic or bitwise operator. Division (\) and modulo
+// by zero are undefined.
+type arithNode struct {
+	op   string
+	l, r expr
+}
+
+func (n arithNode) eval(sc *scanContext) value {
+	l, r := n.l.eval(sc), n.r.eval(sc)
+	li, lInt := l.(int64)
+	ri, rInt := r.(int64)
+	if lInt && rInt {
+		switch n.op {
+		case "+":
+			return li + ri
+		case "-":
+			return li - ri
+		case "*":
+			return li * ri
+		case "\\":
+			if ri == 0 {
+				return nil
+			}
+			return li / ri
+		case "%":
+			if ri == 0 {
+				return nil
+			}
+			return li % ri
+		case "&":
+			return li & ri
+		case "|":
+			return li | ri
+		case "^":
+			return li ^ ri
+		case "<<":
+			return li << uint64(ri)
+		case ">>":
+			return li >> uint64(ri)
+		}
+		return nil
+	}
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	if !lok || !rok {
+		return nil
+	}
+	switch n.op {
+	case "+":
+		return lf + rf
+	case "-":
+		return lf - rf
+	case "*":
+		return lf * rf
+	case "\\":
+		if rf == 0 {
+			return nil
+		}
+		return lf / rf
+	}
+	return nil
+}
+
+type negNode struct {
+	op string
+	x  expr
+}
+
+func (n negNode) eval(sc *scanContext) value {
+	switch v := n.x.eval(sc).(type) {
+	case int64:
+		if n.op == "~" {
+			return ^v
+		}
+		return -v
+	case float64:
+		if n.op == "-" {
+			return -v
+		}
+	}
+	return nil
+}
+
+// strNode is $a, optionally "at <offset>" or "in (<lo>..<hi>)"
+type strNode struct {
+	s      *stringDef
+	at     expr
+	lo, hi expr
+}
+
+func (n strNode) eval(sc *scanContext) value {
+	return matchedWhere(sc, n.s, n.at, n.lo, n.hi)
+}
+
+func matchedWhere(sc *scanContext, s *stringDef, at, lo, hi expr) bool {
+	matches := sc.stringMatches(s)
+	switch {
+	case at != nil:
+		off, ok := at.eval(sc).(int64)
+		if !ok {
+			return false
+		}
+		for _, m := range matches {
+			if int64(m.offset) == off {
+				return true
+			}
+		}
+		return false
+	case lo != nil:
+		return countIn(sc, matches, lo, hi) > 0
+	}
+	return len(matches) > 0
+}
+
+func countIn(sc *scanContext, matches []stringMatch, lo, hi expr) int64 {
+	l, lok := lo.eval(sc).(int64)
+	h, hok := hi.eval(sc).(int64)
+	if !lok || !hok {
+		return 0
+	}
+	var n int64
+	for _, m := range matches {
+		if int64(m.offset) >= l && int64(m.offset) <= h {
+			n++
+		}
+	}
+	return n
+}
+
+// countNode is #a, optionally "in (<lo>..<hi>)"
+type countNode struct {
+	s      *stringDef
+	lo, hi expr
+}
+
+func (n countNode) eval(sc *scanContext) value {
+	matches := sc.stringMatches(n.s)
+	if n.lo != nil {
+		return countIn(sc, matches, n.lo, n.hi)
+	}
+	return int64(len(matches))
+}
+
+// offsetNode is @a[i] (offset) or !a[i] (length), i starting at 1
+type offsetNode struct {
+	s      *stringDef
+	index  expr
+	length bool
+}
+
+func (n offsetNode) eval(sc *scanContext) value {
+	i := int64(1)
+	if n.index != nil {
+		v, ok := n.index.eval(sc).(int64)
+		if !ok {
+			return nil
+		}
+		i = v
+	}
+	matches := sc.stringMatches(n.s)
+	if i < 1 || i > int64(len(matches)) {
+		return nil
+	}
+	if n.length {
+		return int64(matches[i-1].length)
+	}
+	return int64(matches[i-1].offset)
+}
+
+// intReadNode is uint8(off), int32be(off), ...
+type intReadNode struct {
+	size      int
+	signed    bool
+	bigEndian bool
+	off       expr
+}
+
+func (n intReadNode) eval(sc *scanContext) value {
+	off, ok := n.off.eval(sc).(int64)
+	if !ok || off < 0 || off > int64(len(sc.data))-int64(n.size) {
+		return nil
+	}
+	b := sc.data[off : off+int64(n.size)]
+	var order binary.ByteOrder = binary.LittleEndian
+	if n.bigEndian {
+		order = binary.BigEndian
+	}
+	switch n.size {
+	case 1:
+		if n.signed {
+			return int64(int8(b[0]))
+		}
+		return int64(b[0])
+	case 2:
+		v := order.Uint16(b)
+		if n.signed {
+			return int64(int16(v))
+		}
+		return int64(v)
+	default:
+		v := order.Uint32(b)
+		if n.signed {
+			return int64(int32(v))
+		}
+		return int64(v)
+	}
+}
+
+type ruleRefNode struct{ name string }
+
+func (n ruleRefNode) eval(sc *scanContext) value { return sc.results[n.name] }
+
+// ofNode is "<quantifier> of <string set>", optionally with "at" or "in".
+// count is nil for all, and -1 for none.
+type ofNode struct {
+	count   expr
+	all     bool
+	none    bool
+	strings []*stringDef
+	at      expr
+	lo, hi  expr
+}
+
+func (n ofNode) eval(sc *scanContext) value {
+	var matched int64
+	for _, s := range n.strings {
+		if matchedWhere(sc, s, n.at, n.lo, n.hi) {
+			matched++
+		}
+	}
+	switch {
+	case n.all:
+		return matched == int64(len(n.strings))
+	case n.none:
+		return matched == 0
+	}
+	want, ok := n.count.eval(sc).(int64)
+	return ok && matched >= want
+}
+
+// callNode is a module function call such as hash.md5(0, filesize)
+type callNode struct {
+	fn   string
+	args []expr
+}
+
+func (n callNode) eval(sc *scanContext) value {
+	args := make([]value, len(n.args))
+	for i, a := range n.args {
+		args[i] = a.eval(sc)
+	}
+	return callModule(sc, n.fn, args)
+}
@@ -0,0 +1,295 @@
+package yara
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var jumpSpace = regexp.MustCompile(`\[[^\]]*\]`)
+
+// hexElem is one element of a compiled hex string: a (masked) byte, a jump,
+// or a group of alternatives
+type hexElem struct {
+	kind  int
+	value byte
+	mask  byte
+	not   bool
+	min   int
+	max   int // -1 for an unbounded jump
+	alts  [][]hexElem
+	// cont is what follows an alternative, linked once compiled
+	cont *hexCont
+}
+
+const (
+	hexByte = iota
+	hexJump
+	hexAlt
+)
+
+// compileHex parses the body of a hex string such as
+// "4D 5A ?? [2-4] (90 | EB ?0) ~00"
+func compileHex(body string) ([]hexElem, error) {
+	// Separate brackets and bars from the bytes around them, keeping each
+	// jump as one token
+	body = jumpSpace.ReplaceAllStringFunc(body, func(j string) string {
+		return " " + strings.Join(strings.Fields(j), "") + " "
+	})
+	r := strings.NewReplacer("(", " ( ", ")", " ) ", "|", " | ")
+	fields := strings.Fields(r.Replace(body))
+
+	// Byte pairs may be written without spaces ("4D5A")
+	var tokens []string
+	for _, f := range fields {
+		if strings.HasPrefix(f, "[") || len(f) <= 2 || strings.ContainsAny(f, "()|") {
+			tokens = append(tokens, f)
+			continue
+		}
+		for f != "" {
+			n := 2
+			if f[0] == '~' {
+				n = 3
+			}
+			if len(f) < n {
+				return nil, fmt.Errorf("bad hex byte %q", f)
+			}
+			tokens = append(tokens, f[:n])
+			f = f[n:]
+		}
+	}
+
+	pos := 0
+	elems, err := parseHexSeq(tokens, &pos, 0)
+	if err != nil {
+		return nil, err
+	}
+	if pos != len(tokens) {
+		return nil, fmt.Errorf("unexpected %q in hex string", tokens[pos])
+	}
+	if len(elems) == 0 {
+		return nil, fmt.Errorf("empty hex string")
+	}
+	if elems[0].kind == hexJump || elems[len(elems)-1].kind == hexJump {
+		return nil, fmt.Errorf("hex string cannot start or end with a jump")
+	}
+	linkHex(elems, nil)
+	return elems, nil
+}
+
+// linkHex points each alternative at the elements that follow it, so
+// matching needs no copies of them
+func linkHex(elems []hexElem, rest *hexCont) {
+	for i := range elems {
+		if elems[i].kind != hexAlt {
+			continue
+		}
+		elems[i].cont = &hexCont{elems: elems[i+1:], next: rest}
+		for _, alt := range elems[i].alts {
+			linkHex(alt, elems[i].cont)
+		}
+	}
+}
+
+func parseHexSeq(tokens []string, pos *int, depth int) ([]hexElem, error) {
+	var elems []hexElem
+	for *pos < len(tokens) {
+		t := tokens[*pos]
+		switch {
+		case t == ")" || t == "|":
+			if depth == 0 {
+				return nil, fmt.Errorf("unexpected %q in hex string", t)
+			}
+			return elems, nil
+		case t == "(":
+			*pos++
+			alt := hexElem{kind: hexAlt}
+			for {
+				seq, err := parseHexSeq(tokens, pos, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				if len(seq) == 0 {
+					return nil, fmt.Errorf("empty alternative in hex string")
+				}
+				alt.alts = append(alt.alts, seq)
+				if *pos >= len(tokens) {
+					return nil, fmt.Errorf("missing ) in hex string")
+				}
+				if tokens[*pos] == ")" {
+					*pos++
+					break
+				}
+				*pos++ // |
+			}
+			elems = append(elems, alt)
+			continue
+		case strings.HasPrefix(t, "["):
+			j, err := parseJump(t)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, j)
+		default:
+			b, err := parseHexByte(t)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, b)
+		}
+		*pos++
+	}
+	return elems, nil
+}
+
+func parseJump(t string) (hexElem, error) {
+	inner := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(t, "["), "]"))
+	j := hexElem{kind: hexJump, max: -1}
+	lo, hi, isRange := strings.Cut(inner, "-")
+	var err error
+	if lo = strings.TrimSpace(lo); lo != "" {
+		if j.min, err = strconv.Atoi(lo); err != nil {
+			return j, fmt.Errorf("bad jump %s", t)
+		}
+	}
+	if !isRange {
+		j.max = j.min
+		return j, nil
+	}
+	if hi = strings.TrimSpace(hi); hi != "" {
+		if j.max, err = strconv.Atoi(hi); err != nil || j.max < j.min {
+			return j, fmt.Errorf("bad jump %s", t)
+		}
+	}
+	return j, nil
+}
+
+func parseHexByte(t string) (hexElem, error) {
+	e := hexElem{kind: hexByte}
+	if strings.HasPrefix(t, "~") {
+		e.not = true
+		t = t[1:]
+	}
+	if len(t) != 2 {
+		return e, fmt.Errorf("bad hex byte %q", t)
+	}
+	for i := 0; i < 2; i++ {
+		shift := uint(4 * (1 - i))
+		if t[i] == '?' {
+			continue
+		}
+		v, err := strconv.ParseUint(t[i:i+1], 16, 8)
+		if err != nil {
+			return e, fmt.Errorf("bad hex byte %q", t)
+		}
+		e.value |= byte(v) << shift
+		e.mask |= 0xF << shift
+	}
+	return e, nil
+}
+
+// hexCont is the rest of a hex string to match after an alternative
+type hexCont struct {
+	elems []hexElem
+	next  *hexCont
+}
+
+// hexScan matches a hex string at successive offsets of data. For each jump
+// it remembers which offsets the elements after the jump were tried at, so
+// a later start does not try them again and long jumps stay linear in the
+// data size.
+type hexScan struct {
+	data  []byte
+	jumps map[*hexElem]*jumpMemo
+}
+
+// jumpMemo records that the elements after a jump match nowhere in
+// [from, to) but at next (-1 if nowhere), with the match ending at end
+type jumpMemo struct {
+	from, to  int
+	next, end int
+}
+
+func newHexScan(data []byte) *hexScan {
+	return &hexScan{data: data, jumps: make(map[*hexElem]*jumpMemo)}
+}
+
+// match returns the end of the first (shortest-jump) match of elems, and
+// then rest, at data[pos:]
+func (s *hexScan) match(pos int, elems []hexElem, rest *hexCont) (int, bool) {
+	data := s.data
+	for i := range elems {
+		e := &elems[i]
+		switch e.kind {
+		case hexByte:
+			if pos >= len(data) {
+				return 0, false
+			}
+			if (data[pos]&e.mask == e.value) == e.not {
+				return 0, false
+			}
+			pos++
+		case hexJump:
+			last := len(data)
+			if e.max >= 0 && pos+e.max < last {
+				last = pos + e.max
+			}
+			return s.follow(e, pos+e.min, last, elems[i+1:], rest)
+		case hexAlt:
+			for _, alt := range e.alts {
+				if end, ok := s.match(pos, alt, e.cont); ok {
+					return end, true
+				}
+			}
+			return 0, false
+		}
+	}
+	if rest != nil {
+		return s.match(pos, rest.elems, rest.next)
+	}
+	return pos, true
+}
+
+// follow returns the end of a match of the elements after jump at the first
+// offset in [first, last] they match at
+func (s *hexScan) follow(jump *hexElem, first, last int, elems []hexElem, rest *hexCont) (int, bool) {
+	m := s.jumps[jump]
+	if m == nil || first < m.from || first > m.to || (m.next >= 0 && m.next < first) {
+		m = &jumpMemo{from: first, to: first, next: -1}
+		s.jumps[jump] = m
+	}
+
+	anchor, anchored := byte(0), false
+	if len(elems) > 0 {
+		anchor, anchored = hexAnchor(elems)
+	}
+	for m.next < 0 && m.to <= last {
+		at := m.to
+		if anchored {
+			// Skip straight to the next offset the following byte matches at
+			j := bytes.IndexByte(s.data[at:min(last+1, len(s.data))], anchor)
+			if j < 0 {
+				m.to = last + 1
+				break
+			}
+			at += j
+		}
+		if end, ok := s.match(at, elems, rest); ok {
+			m.next, m.end = at, end
+		}
+		m.to = at + 1
+	}
+	if m.next >= 0 && m.next <= last {
+		return m.end, true
+	}
+	return 0, false
+}
+
+// hexAnchor returns the first byte of a hex string if it is fully specified,
+// so candidate offsets can be found with IndexByte
+func hexAnchor(elems []hexElem) (byte, bool) {
+	e := elems[0]
+	return e.value, e.kind == hexByte && e.mask == 0xFF && !e.not
+}
@@ -0,0 +1,288 @@
+package yara
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tEOF      tokenKind = iota
+	tIdent              // rule, and, hash.md5, ...
+	tStringID           // $a, $a*, $
+	tCount              // #a
+	tOffset             // @a
+	tLength             // !a
+	tInt
+	tFloat
+	tText  // "..."
+	tPunct // operators and delimiters
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	ival int64
+	fval float64
+	line int
+}
+
+// lexer tokenizes rule source. String values in the strings section (hex
+// strings and regular expressions) do not fit the token grammar and are read
+// with the raw* methods when the parser expects them.
+type lexer struct {
+	src  string
+	pos  int
+	line int
+}
+
+func newLexer(src string) *lexer { return &lexer{src: src, line: 1} }
+
+func (l *lexer) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", l.line, fmt.Sprintf(format, args...))
+}
+
+func (l *lexer) skipSpace() error {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '\n':
+			l.line++
+			l.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			l.pos++
+		case strings.HasPrefix(l.src[l.pos:], "//"):
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.src[l.pos:], "/*"):
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return l.errorf("unterminated comment")
+			}
+			l.line += strings.Count(l.src[l.pos:l.pos+2+end], "\n")
+			l.pos += end + 4
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+var punctuation = []string{"..", "<<", ">>", "<=", ">=", "==", "!=", "{", "}", "(", ")", "[", "]", ":", "=", ",", "<", ">", "+", "-", "*", "\\", "%", "&", "|", "^", "~", "."}
+
+func (l *lexer) next() (token, error) {
+	if err := l.skipSpace(); err != nil {
+		return token{}, err
+	}
+	tok := token{line: l.line}
+	if l.pos >= len(l.src) {
+		return tok, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case c == '$' || c == '#' || c == '@' || (c == '!' && l.pos+1 < len(l.src) && isIdentByte(l.src[l.pos+1])):
+		start := l.pos
+		l.pos++
+		for l.pos < len(l.src) && isIdentByte(l.src[l.pos]) {
+			l.pos++
+		}
+		if c == '$' && l.pos < len(l.src) && l.src[l.pos] == '*' {
+			l.pos++
+		}
+		tok.text = l.src[start:l.pos]
+		tok.kind = map[byte]tokenKind{'$': tStringID, '#': tCount, '@': tOffset, '!': tLength}[c]
+		return tok, nil
+
+	case c >= '0' && c <= '9':
+		return l.number(tok)
+
+	case c == '"':
+		s, err := l.quoted()
+		if err != nil {
+			return tok, err
+		}
+		tok.kind, tok.text = tText, s
+		return tok, nil
+
+	case isIdentByte(c):
+		start := l.pos
+		// Module members are lexed as one dotted identifier
+		for l.pos < len(l.src) && (isIdentByte(l.src[l.pos]) || l.src[l.pos] == '.' && l.pos+1 < len(l.src) && isIdentByte(l.src[l.pos+1])) {
+			l.pos++
+		}
+		tok.kind, tok.text = tIdent, l.src[start:l.pos]
+		return tok, nil
+	}
+
+	for _, p := range punctuation {
+		if strings.HasPrefix(l.src[l.pos:], p) {
+			l.pos += len(p)
+			tok.kind, tok.text = tPunct, p
+			return tok, nil
+		}
+	}
+	return tok, l.errorf("unexpected character %q", c)
+}
+
+func (l *lexer) number(tok token) (token, error) {
+	start := l.pos
+	if strings.HasPrefix(l.src[l.pos:], "0x") || strings.HasPrefix(l.src[l.pos:], "0X") {
+		l.pos += 2
+		for l.pos < len(l.src) && strings.IndexByte("0123456789abcdefABCDEF", l.src[l.pos]) >= 0 {
+			l.pos++
+		}
+		v, err := strconv.ParseInt(l.src[start+2:l.pos], 16, 64)
+		if err != nil {
+			return tok, l.errorf("bad number %s", l.src[start:l.pos])
+		}
+		tok.kind, tok.ival, tok.text = tInt, v, l.src[start:l.pos]
+		return tok, nil
+	}
+
+	for l.pos < len(l.src) && l.src[l.pos] >= '0' && l.src[l.pos] <= '9' {
+		l.pos++
+	}
+	// A float, but not the start of a range "0..10"
+	if l.pos+1 < len(l.src) && l.src[l.pos] == '.' && l.src[l.pos+1] >= '0' && l.src[l.pos+1] <= '9' {
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] >= '0' && l.src[l.pos] <= '9' {
+			l.pos++
+		}
+		v, err := strconv.ParseFloat(l.src[start:l.pos], 64)
+		if err != nil {
+			return tok, l.errorf("bad number %s", l.src[start:l.pos])
+		}
+		tok.kind, tok.fval, tok.text = tFloat, v, l.src[start:l.pos]
+		return tok, nil
+	}
+
+	v, err := strconv.ParseInt(l.src[start:l.pos], 10, 64)
+	if err != nil {
+		return tok, l.errorf("bad number %s", l.src[start:l.pos])
+	}
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "KB"):
+		v *= 1024
+		l.pos += 2
+	case strings.HasPrefix(l.src[l.pos:], "MB"):
+		v *= 1024 * 1024
+		l.pos += 2
+	}
+	tok.kind, tok.ival, tok.text = tInt, v, l.src[start:l.pos]
+	return tok, nil
+}
+
+// quoted reads a double-quoted string, decoding YARA's escapes
+func (l *lexer) quoted() (string, error) {
+	l.pos++ // opening quote
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch c {
+		case '"':
+			l.pos++
+			return b.String(), nil
+		case '\n':
+			return "", l.errorf("unterminated string")
+		case '\\':
+			if l.pos+1 >= len(l.src) {
+				return "", l.errorf("unterminated string")
+			}
+			e := l.src[l.pos+1]
+			l.pos += 2
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '\\', '"':
+				b.WriteByte(e)
+			case 'x':
+				if l.pos+2 > len(l.src) {
+					return "", l.errorf("bad \\x escape")
+				}
+				v, err := strconv.ParseUint(l.src[l.pos:l.pos+2], 16, 8)
+				if err != nil {
+					return "", l.errorf("bad \\x escape")
+				}
+				b.WriteByte(byte(v))
+				l.pos += 2
+			default:
+				return "", l.errorf("unknown escape \\%c", e)
+			}
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return "", l.errorf("unterminated string")
+}
+
+// peekByte returns the next non-space byte without consuming it
+func (l *lexer) peekByte() (byte, error) {
+	if err := l.skipSpace(); err != nil {
+		return 0, err
+	}
+	if l.pos >= len(l.src) {
+		return 0, nil
+	}
+	return l.src[l.pos], nil
+}
+
+// rawHex reads a { ... } hex string body
+func (l *lexer) rawHex() (string, error) {
+	l.pos++ // {
+	end := strings.IndexByte(l.src[l.pos:], '}')
+	if end < 0 {
+		return "", l.errorf("unterminated hex string")
+	}
+	body := l.src[l.pos : l.pos+end]
+	l.line += strings.Count(body, "\n")
+	l.pos += end + 1
+	return body, nil
+}
+
+// rawRegexp reads a /.../flags regular expression, returning the pattern
+// and its trailing flags
+func (l *lexer) rawRegexp() (pattern, flags string, err error) {
+	l.pos++ // opening slash
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch c {
+		case '\n':
+			return "", "", l.errorf("unterminated regular expression")
+		case '\\':
+			if l.pos+1 < len(l.src) && l.src[l.pos+1] == '/' {
+				b.WriteByte('/')
+				l.pos += 2
+				continue
+			}
+			if l.pos+1 < len(l.src) {
+				b.WriteString(l.src[l.pos : l.pos+2])
+				l.pos += 2
+				continue
+			}
+		case '/':
+			l.pos++
+			start := l.pos
+			for l.pos < len(l.src) && (l.src[l.pos] == 'i' || l.src[l.pos] == 's') {
+				l.pos++
+			}
+			return b.String(), l.src[start:l.pos], nil
+		}
+		b.WriteByte(c)
+		l.pos++
+	}
+	return "", "", l.errorf("unterminated regular expression")
+}
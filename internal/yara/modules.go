@@ -0,0 +1,182 @@
+package yara
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"strings"
+)
+
+// modules are the importable modules and their functions. Functions over
+// data take either (offset, size) or a string.
+var modules = map[string]map[string]func(args []value, data []byte) value{
+	"hash": {
+		"md5": func(_ []value, d []byte) value {
+			sum := md5.Sum(d)
+			return hex.EncodeToString(sum[:])
+		},
+		"sha1": func(_ []value, d []byte) value {
+			sum := sha1.Sum(d)
+			return hex.EncodeToString(sum[:])
+		},
+		"sha256": func(_ []value, d []byte) value {
+			sum := sha256.Sum256(d)
+			return hex.EncodeToString(sum[:])
+		},
+		"crc32": func(_ []value, d []byte) value { return int64(crc32.ChecksumIEEE(d)) },
+	},
+	"math": {
+		"entropy": func(_ []value, d []byte) value { return entropy(d) },
+		"mean":    func(_ []value, d []byte) value { return mean(d) },
+		"deviation": func(args []value, d []byte) value {
+			m, ok := toFloat(args[len(args)-1])
+			if !ok {
+				return nil
+			}
+			return deviation(d, m)
+		},
+		"serial_correlation": func(_ []value, d []byte) value { return serialCorrelation(d) },
+	},
+}
+
+// extraArgs counts the arguments that follow the data selection, e.g. the
+// mean passed to math.deviation
+var extraArgs = map[string]int{"math.deviation": 1}
+
+// callModule evaluates a module function; results over the scanned data
+// are cached since rules tend to hash the whole file repeatedly
+func callModule(sc *scanContext, fn string, args []value) value {
+	if fn == "math.in_range" {
+		if len(args) != 3 {
+			return nil
+		}
+		x, ok1 := toFloat(args[0])
+		lo, ok2 := toFloat(args[1])
+		hi, ok3 := toFloat(args[2])
+		return ok1 && ok2 && ok3 && x >= lo && x <= hi
+	}
+
+	mod, name := splitModule(fn)
+	f := modules[mod][name]
+	dataArgs := len(args) - extraArgs[fn]
+
+	var data []byte
+	key := ""
+	switch dataArgs {
+	case 1:
+		s, ok := args[0].(string)
+		if !ok {
+			return nil
+		}
+		data = []byte(s)
+	case 2:
+		off, ok1 := args[0].(int64)
+		size, ok2 := args[1].(int64)
+		if !ok1 || !ok2 || off < 0 || size < 0 || off > int64(len(sc.data)) {
+			return nil
+		}
+		if size > int64(len(sc.data))-off {
+			size = int64(len(sc.data)) - off
+		}
+		data = sc.data[off : off+size]
+		key = fmt.Sprint(fn, args)
+	default:
+		return nil
+	}
+
+	if key != "" {
+		if v, ok := sc.cache[key]; ok {
+			return v
+		}
+	}
+	v := f(args, data)
+	if key != "" {
+		sc.cache[key] = v
+	}
+	return v
+}
+
+func splitModule(fn string) (string, string) {
+	mod, name, _ := strings.Cut(fn, ".")
+	return mod, name
+}
+
+// moduleFunc reports whether fn names a function, for the parser
+func moduleFunc(fn string) bool {
+	if fn == "math.in_range" {
+		return true
+	}
+	mod, name := splitModule(fn)
+	_, ok := modules[mod][name]
+	return ok
+}
+
+func entropy(d []byte) value {
+	if len(d) == 0 {
+		return 0.0
+	}
+	var counts [256]int
+	for _, c := range d {
+		counts[c]++
+	}
+	var e float64
+	n := float64(len(d))
+	for _, c := range counts {
+		if c > 0 {
+			p := float64(c) / n
+			e -= p * math.Log2(p)
+		}
+	}
+	return e
+}
+
+func mean(d []byte) value {
+	if len(d) == 0 {
+		return nil
+	}
+	var sum float64
+	for _, c := range d {
+		sum += float64(c)
+	}
+	return sum / float64(len(d))
+}
+
+func deviation(d []byte, m float64) value {
+	if len(d) == 0 {
+		return nil
+	}
+	var sum float64
+	for _, c := range d {
+		sum += math.Abs(float64(c) - m)
+	}
+	return sum / float64(len(d))
+}
+
+func serialCorrelation(d []byte) value {
+	n := float64(len(d))
+	if n < 2 {
+		return nil
+	}
+	var sccun, scclast, scct1, scct2, scct3 float64
+	first := float64(d[0])
+	for i, c := range d {
+		v := float64(c)
+		if i > 0 {
+			scct1 += scclast * v
+		}
+		scct2 += v
+		scct3 += v * v
+		scclast = v
+	}
+	scct1 += scclast * first
+	scct2 *= scct2
+	sccun = n*scct3 - scct2
+	if sccun == 0 {
+		return -100000.0
+	}
+	return (n*scct1 - scct2) / sccun
+}
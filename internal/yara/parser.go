@@ -0,0 +1,739 @@
+package yara
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// rule is a compiled YARA rule
+type rule struct {
+	name      string
+	tags      []string
+	meta      map[string]interface{}
+	global    bool
+	private   bool
+	strings   []*stringDef
+	byID      map[string]*stringDef
+	condition expr
+}
+
+type parser struct {
+	lex     *lexer
+	tok     token
+	imports map[string]bool
+	rules   []*rule
+	byName  map[string]*rule
+	cur     *rule
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", p.tok.line, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) advance() error {
+	t, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = t
+	return nil
+}
+
+func (p *parser) isPunct(s string) bool { return p.tok.kind == tPunct && p.tok.text == s }
+func (p *parser) isIdent(s string) bool { return p.tok.kind == tIdent && p.tok.text == s }
+
+func (p *parser) expectPunct(s string) error {
+	if !p.isPunct(s) {
+		return p.errorf("expected %q, found %q", s, p.tok.text)
+	}
+	return p.advance()
+}
+
+func (p *parser) expectIdent(s string) error {
+	if !p.isIdent(s) {
+		return p.errorf("expected %q, found %q", s, p.tok.text)
+	}
+	return p.advance()
+}
+
+// parse reads a whole rule file
+func (p *parser) parse() error {
+	if err := p.advance(); err != nil {
+		return err
+	}
+	for p.tok.kind != tEOF {
+		switch {
+		case p.isIdent("import"):
+			if err := p.advance(); err != nil {
+				return err
+			}
+			if p.tok.kind != tText {
+				return p.errorf("import needs a module name")
+			}
+			if _, ok := modules[p.tok.text]; !ok {
+				return p.errorf("module %q is not supported", p.tok.text)
+			}
+			p.imports[p.tok.text] = true
+			if err := p.advance(); err != nil {
+				return err
+			}
+		case p.isIdent("include"):
+			return p.errorf("include is not supported; pass a directory of rule files instead")
+		default:
+			if err := p.parseRule(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseRule() error {
+	r := &rule{meta: make(map[string]interface{}), byID: make(map[string]*stringDef)}
+	for p.isIdent("private") || p.isIdent("global") {
+		if p.tok.text == "private" {
+			r.private = true
+		} else {
+			r.global = true
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+	if err := p.expectIdent("rule"); err != nil {
+		return err
+	}
+	if p.tok.kind != tIdent {
+		return p.errorf("expected rule name")
+	}
+	r.name = p.tok.text
+	if _, dup := p.byName[r.name]; dup {
+		return p.errorf("duplicate rule %s", r.name)
+	}
+	if err := p.advance(); err != nil {
+		return err
+	}
+	if p.isPunct(":") {
+		if err := p.advance(); err != nil {
+			return err
+		}
+		for p.tok.kind == tIdent {
+			r.tags = append(r.tags, p.tok.text)
+			if err := p.advance(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := p.expectPunct("{"); err != nil {
+		return err
+	}
+	p.cur = r
+
+	if p.isIdent("meta") {
+		if err := p.parseMeta(r); err != nil {
+			return err
+		}
+	}
+	if p.isIdent("strings") {
+		if err := p.parseStrings(r); err != nil {
+			return err
+		}
+	}
+	if err := p.expectIdent("condition"); err != nil {
+		return err
+	}
+	if err := p.expectPunct(":"); err != nil {
+		return err
+	}
+	cond, err := p.parseOr()
+	if err != nil {
+		return fmt.Errorf("rule %s: %w", r.name, err)
+	}
+	r.condition = cond
+	if err := p.expectPunct("}"); err != nil {
+		return fmt.Errorf("rule %s: %w", r.name, err)
+	}
+
+	p.rules = append(p.rules, r)
+	p.byName[r.name] = r
+	return nil
+}
+
+func (p *parser) parseMeta(r *rule) error {
+	if err := p.advance(); err != nil {
+		return err
+	}
+	if err := p.expectPunct(":"); err != nil {
+		return err
+	}
+	for p.tok.kind == tIdent && !p.isIdent("strings") && !p.isIdent("condition") {
+		key := p.tok.text
+		if err := p.advance(); err != nil {
+			return err
+		}
+		if err := p.expectPunct("="); err != nil {
+			return err
+		}
+		negative := false
+		if p.isPunct("-") {
+			negative = true
+			if err := p.advance(); err != nil {
+				return err
+			}
+		}
+		switch {
+		case p.tok.kind == tText:
+			r.meta[key] = p.tok.text
+		case p.tok.kind == tInt:
+			v := p.tok.ival
+			if negative {
+				v = -v
+			}
+			r.meta[key] = v
+		case p.isIdent("true"), p.isIdent("false"):
+			r.meta[key] = p.tok.text == "true"
+		default:
+			return p.errorf("bad meta value for %s", key)
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *parser) parseStrings(r *rule) error {
+	if err := p.advance(); err != nil {
+		return err
+	}
+	if err := p.expectPunct(":"); err != nil {
+		return err
+	}
+	for p.tok.kind == tStringID {
+		s := &stringDef{id: p.tok.text}
+		if s.id == "$" {
+			s.id = fmt.Sprintf("$_%d", len(r.strings))
+		}
+		if strings.HasSuffix(s.id, "*") {
+			return p.errorf("bad string identifier %s", s.id)
+		}
+		if _, dup := r.byID[s.id]; dup {
+			return p.errorf("duplicate string %s", s.id)
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+		// The lexer stands just past "=", where the value begins
+		if !p.isPunct("=") {
+			return p.errorf("expected = after %s", s.id)
+		}
+		c, err := p.lex.peekByte()
+		if err != nil {
+			return err
+		}
+		var pattern, flags string
+		switch c {
+		case '"':
+			if err := p.advance(); err != nil {
+				return err
+			}
+			s.kind, s.text = textString, []byte(p.tok.text)
+		case '{':
+			body, err := p.lex.rawHex()
+			if err != nil {
+				return err
+			}
+			if s.hex, err = compileHex(body); err != nil {
+				return p.errorf("%s: %v", s.id, err)
+			}
+			s.kind = hexString
+		case '/':
+			if pattern, flags, err = p.lex.rawRegexp(); err != nil {
+				return err
+			}
+			s.kind = regexString
+		default:
+			return p.errorf("%s: expected a text, hex or regular expression string", s.id)
+		}
+		if err := p.advance(); err != nil {
+			return err
+		}
+
+	modifiers:
+		for p.tok.kind == tIdent {
+			switch p.tok.text {
+			case "nocase":
+				s.nocase = true
+			case "wide":
+				s.wide = true
+			case "ascii":
+				s.ascii = true
+			case "fullword":
+				s.fullword = true
+			case "private":
+				s.private = true
+			case "xor", "base64", "base64wide":
+				return p.errorf("%s: modifier %s is not supported", s.id, p.tok.text)
+			default:
+				break modifiers
+			}
+			if err := p.advance(); err != nil {
+				return err
+			}
+		}
+
+		if s.kind == hexString && (s.nocase || s.wide || s.ascii || s.fullword) {
+			return p.errorf("%s: hex strings only accept the private modifier", s.id)
+		}
+		if s.kind == regexString {
+			if s.wide {
+				return p.errorf("%s: wide regular expressions are not supported", s.id)
+			}
+			if s.nocase {
+				flags += "i"
+			}
+			if s.re, err = compileRegexp(pattern, flags); err != nil {
+				return p.errorf("%s: %v", s.id, err)
+			}
+		}
+
+		r.strings = append(r.strings, s)
+		r.byID[s.id] = s
+	}
+	return nil
+}
+
+// Expressions, lowest precedence first
+
+func (p *parser) parseOr() (expr, error) {
+	l, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isIdent("or") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		r, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l = orNode{l, r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	l, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isIdent("and") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		r, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l = andNode{l, r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseNot() (expr, error) {
+	if p.isIdent("not") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{x}, nil
+	}
+	return p.parseRelational()
+}
+
+var relationalOps = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+	"contains": true, "icontains": true, "startswith": true, "istartswith": true,
+	"endswith": true, "iendswith": true, "iequals": true,
+}
+
+func (p *parser) parseRelational() (expr, error) {
+	l, err := p.parseBinary(0)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if p.isIdent("matches") {
+			c, err := p.lex.peekByte()
+			if err != nil {
+				return nil, err
+			}
+			if c != '/' {
+				return nil, p.errorf("matches needs a regular expression")
+			}
+			pattern, flags, err := p.lex.rawRegexp()
+			if err != nil {
+				return nil, err
+			}
+			re, err := compileRegexp(pattern, flags)
+			if err != nil {
+				return nil, p.errorf("%v", err)
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			l = matchesNode{l, re}
+			continue
+		}
+		if (p.tok.kind == tPunct || p.tok.kind == tIdent) && relationalOps[p.tok.text] {
+			op := p.tok.text
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			r, err := p.parseBinary(0)
+			if err != nil {
+				return nil, err
+			}
+			l = cmpNode{op, l, r}
+			continue
+		}
+		return l, nil
+	}
+}
+
+// binaryLevels are the arithmetic and bitwise operators by increasing
+// precedence
+var binaryLevels = [][]string{
+	{"|"},
+	{"^"},
+	{"&"},
+	{"<<", ">>"},
+	{"+", "-"},
+	{"*", "\\", "%"},
+}
+
+func (p *parser) parseBinary(level int) (expr, error) {
+	if level == len(binaryLevels) {
+		return p.parseUnary()
+	}
+	l, err := p.parseBinary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == tPunct && containsOp(binaryLevels[level], p.tok.text) {
+		op := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		r, err := p.parseBinary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		l = arithNode{op, l, r}
+	}
+	return l, nil
+}
+
+func containsOp(ops []string, op string) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.isPunct("-") || p.isPunct("~") {
+		op := p.tok.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return negNode{op, x}, nil
+	}
+	return p.parsePrimary()
+}
+
+var intReaders = map[string]intReadNode{
+	"uint8": {size: 1}, "uint16": {size: 2}, "uint32": {size: 4},
+	"int8": {size: 1, signed: true}, "int16": {size: 2, signed: true}, "int32": {size: 4, signed: true},
+	"uint16be": {size: 2, bigEndian: true}, "uint32be": {size: 4, bigEndian: true},
+	"int16be": {size: 2, signed: true, bigEndian: true}, "int32be": {size: 4, signed: true, bigEndian: true},
+}
+
+func (p *parser) parsePrimary() (expr, error) {
+	tok := p.tok
+	switch tok.kind {
+	case tInt:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.isIdent("of") {
+			return p.parseOf(litNode{tok.ival}, false, false)
+		}
+		return litNode{tok.ival}, nil
+	case tFloat:
+		return litNode{tok.fval}, p.advance()
+	case tText:
+		return litNode{tok.text}, p.advance()
+	case tStringID:
+		s, err := p.stringRef(tok.text)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n := strNode{s: s}
+		switch {
+		case p.isIdent("at"):
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if n.at, err = p.parseBinary(0); err != nil {
+				return nil, err
+			}
+		case p.isIdent("in"):
+			if n.lo, n.hi, err = p.parseRange(); err != nil {
+				return nil, err
+			}
+		}
+		return n, nil
+	case tCount:
+		s, err := p.stringRef("$" + tok.text[1:])
+		if err != nil {
+			return nil, err
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n := countNode{s: s}
+		if p.isIdent("in") {
+			if n.lo, n.hi, err = p.parseRange(); err != nil {
+				return nil, err
+			}
+		}
+		return n, nil
+	case tOffset, tLength:
+		s, err := p.stringRef("$" + tok.text[1:])
+		if err != nil {
+			return nil, err
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n := offsetNode{s: s, length: tok.kind == tLength}
+		if p.isPunct("[") {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if n.index, err = p.parseBinary(0); err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct("]"); err != nil {
+				return nil, err
+			}
+		}
+		return n, nil
+	case tPunct:
+		if tok.text == "(" {
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expectPunct(")")
+		}
+	case tIdent:
+		return p.parseIdent()
+	case tEOF:
+		return nil, p.errorf("unexpected end of rule")
+	}
+	return nil, p.errorf("unexpected %q", tok.text)
+}
+
+func (p *parser) parseIdent() (expr, error) {
+	name := p.tok.text
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	switch name {
+	case "true", "false":
+		return litNode{name == "true"}, nil
+	case "filesize":
+		return filesizeNode{}, nil
+	case "any", "all", "none":
+		if !p.isIdent("of") {
+			return nil, p.errorf("expected of after %s", name)
+		}
+		return p.parseOf(litNode{int64(1)}, name == "all", name == "none")
+	case "for", "entrypoint":
+		return nil, p.errorf("%s is not supported", name)
+	}
+
+	if reader, ok := intReaders[name]; ok {
+		if err := p.expectPunct("("); err != nil {
+			return nil, err
+		}
+		off, err := p.parseBinary(0)
+		if err != nil {
+			return nil, err
+		}
+		reader.off = off
+		return reader, p.expectPunct(")")
+	}
+
+	if strings.Contains(name, ".") {
+		mod, _ := splitModule(name)
+		if !p.imports[mod] {
+			return nil, p.errorf("module %s is not imported", mod)
+		}
+		if !moduleFunc(name) || !p.isPunct("(") {
+			return nil, p.errorf("unknown module function %s", name)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		call := callNode{fn: name}
+		for !p.isPunct(")") {
+			arg, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			call.args = append(call.args, arg)
+			if p.isPunct(",") {
+				if err := p.advance(); err != nil {
+					return nil, err
+				}
+			} else if !p.isPunct(")") {
+				return nil, p.errorf("expected , or ) in call to %s", name)
+			}
+		}
+		return call, p.advance()
+	}
+
+	if _, ok := p.byName[name]; !ok {
+		return nil, p.errorf("unknown identifier %s", name)
+	}
+	return ruleRefNode{name}, nil
+}
+
+// parseOf parses the rest of "<quantifier> of (them | (<strings>))"
+func (p *parser) parseOf(count expr, all, none bool) (expr, error) {
+	if err := p.advance(); err != nil { // of
+		return nil, err
+	}
+	n := ofNode{count: count, all: all, none: none}
+	switch {
+	case p.isIdent("them"):
+		n.strings = p.cur.strings
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	case p.isPunct("("):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		seen := make(map[*stringDef]bool)
+		for {
+			if p.tok.kind != tStringID {
+				return nil, p.errorf("expected a string identifier, found %q", p.tok.text)
+			}
+			matched := false
+			for _, s := range p.cur.strings {
+				if ok, _ := path.Match(p.tok.text, s.id); ok {
+					matched = true
+					if !seen[s] {
+						seen[s] = true
+						n.strings = append(n.strings, s)
+					}
+				}
+			}
+			if !matched {
+				return nil, p.errorf("undefined string %s", p.tok.text)
+			}
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			if p.isPunct(")") {
+				break
+			}
+			if err := p.expectPunct(","); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, p.errorf("expected them or a string set")
+	}
+	if len(n.strings) == 0 {
+		return nil, p.errorf("rule %s has no strings", p.cur.name)
+	}
+
+	var err error
+	switch {
+	case p.isIdent("at"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n.at, err = p.parseBinary(0)
+	case p.isIdent("in"):
+		n.lo, n.hi, err = p.parseRange()
+	}
+	return n, err
+}
+
+// parseRange parses "in (<lo>..<hi>)"
+func (p *parser) parseRange() (expr, expr, error) {
+	if err := p.advance(); err != nil { // in
+		return nil, nil, err
+	}
+	if err := p.expectPunct("("); err != nil {
+		return nil, nil, err
+	}
+	lo, err := p.parseBinary(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := p.expectPunct(".."); err != nil {
+		return nil, nil, err
+	}
+	hi, err := p.parseBinary(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return lo, hi, p.expectPunct(")")
+}
+
+func (p *parser) stringRef(id string) (*stringDef, error) {
+	if id == "$" {
+		return nil, p.errorf("anonymous string references need for..of, which is not supported")
+	}
+	s, ok := p.cur.byID[id]
+	if !ok {
+		return nil, p.errorf("undefined string %s", id)
+	}
+	return s, nil
+}
+
+// compileRegexp compiles a YARA regular expression with its i and s flags
+func compileRegexp(pattern, flags string) (*regexp.Regexp, error) {
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	return regexp.Compile(pattern)
+}
@@ -0,0 +1,155 @@
+package yara
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+)
+
+// maxStringMatches bounds the matches recorded per string and file, which
+// also caps the value of #a
+const maxStringMatches = 10000
+
+const (
+	textString = iota
+	hexString
+	regexString
+)
+
+// stringDef is one entry of a rule's strings section
+type stringDef struct {
+	id       string
+	kind     int
+	text     []byte
+	hex      []hexElem
+	re       *regexp.Regexp
+	nocase   bool
+	wide     bool
+	ascii    bool
+	fullword bool
+	private  bool
+}
+
+type stringMatch struct {
+	offset int
+	length int
+}
+
+func (s *stringDef) find(sc *scanContext) []stringMatch {
+	var matches []stringMatch
+	switch s.kind {
+	case textString:
+		data := sc.data
+		if s.nocase {
+			data = sc.lowered()
+		}
+		var patterns [][]byte
+		pattern := s.text
+		if s.nocase {
+			pattern = asciiLower(pattern)
+		}
+		if s.ascii || !s.wide {
+			patterns = append(patterns, pattern)
+		}
+		if s.wide {
+			patterns = append(patterns, widen(pattern))
+		}
+		for _, p := range patterns {
+			matches = append(matches, s.findText(data, p)...)
+		}
+		if len(patterns) > 1 {
+			sort.Slice(matches, func(i, j int) bool { return matches[i].offset < matches[j].offset })
+		}
+	case hexString:
+		matches = s.findHex(sc.data)
+	case regexString:
+		for _, loc := range s.re.FindAllIndex(sc.data, maxStringMatches) {
+			m := stringMatch{loc[0], loc[1] - loc[0]}
+			if !s.fullword || isFullword(sc.data, m, false) {
+				matches = append(matches, m)
+			}
+		}
+	}
+	if len(matches) > maxStringMatches {
+		matches = matches[:maxStringMatches]
+	}
+	return matches
+}
+
+func (s *stringDef) findText(data, pattern []byte) []stringMatch {
+	var matches []stringMatch
+	if len(pattern) == 0 {
+		return nil
+	}
+	wide := len(pattern) > 1 && pattern[1] == 0 && s.wide
+	for off := 0; off < len(data) && len(matches) < maxStringMatches; {
+		i := bytes.Index(data[off:], pattern)
+		if i < 0 {
+			break
+		}
+		m := stringMatch{off + i, len(pattern)}
+		if !s.fullword || isFullword(data, m, wide) {
+			matches = append(matches, m)
+		}
+		off += i + 1
+	}
+	return matches
+}
+
+func (s *stringDef) findHex(data []byte) []stringMatch {
+	var matches []stringMatch
+	anchor, anchored := hexAnchor(s.hex)
+	scan := newHexScan(data)
+	for off := 0; off < len(data) && len(matches) < maxStringMatches; off++ {
+		if anchored {
+			i := bytes.IndexByte(data[off:], anchor)
+			if i < 0 {
+				break
+			}
+			off += i
+		}
+		if end, ok := scan.match(off, s.hex, nil); ok {
+			matches = append(matches, stringMatch{off, end - off})
+		}
+	}
+	return matches
+}
+
+// isFullword reports whether a match is delimited by non-alphanumeric
+// characters on both sides
+func isFullword(data []byte, m stringMatch, wide bool) bool {
+	before := m.offset - 1
+	if wide {
+		before = m.offset - 2
+	}
+	if before >= 0 && isAlnum(data[before]) {
+		return false
+	}
+	end := m.offset + m.length
+	return end >= len(data) || !isAlnum(data[end])
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// asciiLower lowercases A-Z only, keeping offsets into binary data intact
+func asciiLower(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i, c := range b {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		out[i] = c
+	}
+	return out
+}
+
+// widen interleaves zero bytes, as in UTF-16LE text
+func widen(b []byte) []byte {
+	out := make([]byte, 0, 2*len(b))
+	for _, c := range b {
+		out = append(out, c, 0)
+	}
+	return out
+}
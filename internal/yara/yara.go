@@ -0,0 +1,168 @@
+// Package yara is a pure-Go scanner for a subset of the YARA rule language:
+// text, hex and regular expression strings with the nocase, wide, ascii,
+// fullword and private modifiers, and conditions over string matches,
+// counts, offsets, lengths, filesize, integer reads and the hash and math
+// modules. for..of loops, the xor/base64 modifiers and other modules are
+// rejected when the rules are compiled.
+//
+// Regular expressions use Go's regexp syntax and match the scanned bytes as
+// text, so byte escapes above \x7f do not match raw bytes; use hex strings
+// for binary patterns.
+package yara
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Rules is a compiled rule set
+type Rules struct {
+	rules []*rule
+}
+
+// Match is a rule that matched, with the matches of its non-private strings
+type Match struct {
+	Rule    string
+	Tags    []string
+	Meta    map[string]interface{}
+	Strings []StringMatch
+}
+
+// StringMatch is one occurrence of a rule string
+type StringMatch struct {
+	ID     string
+	Offset int64
+	Data   []byte
+}
+
+// maxMatchData bounds the bytes kept per string match
+const maxMatchData = 64
+
+// Compile parses rule source
+func Compile(src string) (*Rules, error) {
+	p := &parser{lex: newLexer(src), imports: make(map[string]bool), byName: make(map[string]*rule)}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return &Rules{rules: p.rules}, nil
+}
+
+// Load compiles a .yar/.yara file or every such file under a directory.
+// Rule names must be unique across files.
+func Load(path string) (*Rules, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files = nil
+		err = filepath.WalkDir(path, func(p string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			ext := strings.ToLower(filepath.Ext(p))
+			if !d.IsDir() && (ext == ".yar" || ext == ".yara") {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(files)
+	}
+
+	all := &Rules{}
+	names := make(map[string]string)
+	for _, f := range files {
+		src, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		rules, err := Compile(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		for _, r := range rules.rules {
+			if prev, dup := names[r.name]; dup {
+				return nil, fmt.Errorf("%s: rule %s already defined in %s", f, r.name, prev)
+			}
+			names[r.name] = f
+		}
+		all.rules = append(all.rules, rules.rules...)
+	}
+	return all, nil
+}
+
+// Len returns the number of rules
+func (r *Rules) Len() int { return len(r.rules) }
+
+// Scan evaluates every rule against data. If a global rule does not match,
+// nothing does.
+func (r *Rules) Scan(data []byte) []Match {
+	sc := newScanContext(data)
+	for _, rl := range r.rules {
+		sc.rule = rl
+		matched := toBool(rl.condition.eval(sc))
+		sc.results[rl.name] = matched
+		if rl.global && !matched {
+			return nil
+		}
+	}
+
+	var matches []Match
+	for _, rl := range r.rules {
+		if rl.private || !sc.results[rl.name] {
+			continue
+		}
+		m := Match{Rule: rl.name, Tags: rl.tags, Meta: rl.meta}
+		for _, s := range rl.strings {
+			if s.private {
+				continue
+			}
+			sc.rule = rl
+			for _, sm := range sc.stringMatches(s) {
+				end := sm.offset + sm.length
+				if end-sm.offset > maxMatchData {
+					end = sm.offset + maxMatchData
+				}
+				m.Strings = append(m.Strings, StringMatch{ID: s.id, Offset: int64(sm.offset), Data: data[sm.offset:end]})
+			}
+		}
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// ErrFileTooLarge is returned by ScanFile for files over the size limit
+var ErrFileTooLarge = errors.New("file exceeds scan size limit")
+
+// ScanFile scans a regular file of at most maxSize bytes
+func (r *Rules) ScanFile(path string, maxSize int64) ([]Match, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	if info.Size() > maxSize {
+		return nil, ErrFileTooLarge
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return r.Scan(data), nil
+}
@@ -0,0 +1,316 @@
+package yara
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestOutOfRangeReads(t *testing.T) {
+	data := []byte("abcdefgh")
+	sum := md5.Sum(data[1:])
+	tests := []struct {
+		cond string
+		want bool
+	}{
+		{`hash.md5(1, 0x7FFFFFFFFFFFFFFF) == "` + hex.EncodeToString(sum[:]) + `"`, true},
+		{`hash.md5(9, 1) == "x"`, false},
+		{`uint32(0x7FFFFFFFFFFFFFFF) == 0`, false},
+		{`uint32(5) == 0`, false},
+		{`uint32(4) == 0x68676665`, true},
+		{`uint8(7) == 0x68`, true},
+		{`uint8(8) == 0`, false},
+	}
+	for _, tt := range tests {
+		rules, err := Compile(`import "hash" rule p { condition: ` + tt.cond + ` }`)
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", tt.cond, err)
+		}
+		got := len(rules.Scan(data)) == 1
+		if got != tt.want {
+			t.Errorf("%s: matched = %v, want %v", tt.cond, got, tt.want)
+		}
+	}
+}
+
+// scan compiles a single rule with the given strings and condition and
+// scans data with it
+func scan(t *testing.T, strs, cond string, data []byte) []Match {
+	t.Helper()
+	src := `import "math" rule r { `
+	if strs != "" {
+		src += `strings: ` + strs + ` `
+	}
+	rules, err := Compile(src + `condition: ` + cond + ` }`)
+	if err != nil {
+		t.Fatalf("Compile(%s / %s): %v", strs, cond, err)
+	}
+	return rules.Scan(data)
+}
+
+func TestTextStrings(t *testing.T) {
+	tests := []struct {
+		str  string
+		data string
+		want int
+	}{
+		{`$a = "abc"`, "xxabcxxabc", 2},
+		{`$a = "abc"`, "ABC", 0},
+		{`$a = "abc" nocase`, "xABcx aBC", 2},
+		{`$a = "abc" wide`, "a\x00b\x00c\x00", 1},
+		{`$a = "abc" wide`, "abc", 0},
+		{`$a = "abc" wide ascii`, "abc a\x00b\x00c\x00", 2},
+		{`$a = "abc" wide nocase`, "A\x00b\x00C\x00", 1},
+		{`$a = "abc" fullword`, "xabc abcx", 0},
+		{`$a = "abc" fullword`, "xabc.abc-", 1},
+		{`$a = "abc" fullword`, "abc", 1},
+		{`$a = "abc" wide fullword`, "x\x00a\x00b\x00c\x00", 0},
+		{`$a = "abc" wide fullword`, " \x00a\x00b\x00c\x00", 1},
+		{`$a = "aa"`, "aaaa", 3},
+		{`$a = "a\x00\"b"`, "a\x00\"b", 1},
+	}
+	for _, tt := range tests {
+		got := scan(t, tt.str, `#a == `+strconv.Itoa(tt.want), []byte(tt.data))
+		if len(got) != 1 {
+			t.Errorf("%s in %q: #a != %d", tt.str, tt.data, tt.want)
+		}
+	}
+}
+
+func TestHexStrings(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		data string
+		want []int // offsets of the matches
+	}{
+		{"bytes", "4D 5A", "xxMZMZ", []int{2, 4}},
+		{"bytes without spaces", "4D5A90", "MZ\x90", []int{0}},
+		{"nibble wildcard", "4? 5A", "MZLZmZ", []int{0, 2}},
+		{"byte wildcard", "4D ?? 5A", "M\xffZ", []int{0}},
+		{"negated byte", "~4D 5A", "MZNZ", []int{2}},
+		{"fixed jump", "4D [2] 5A", "MxZ MxxZ MxxxZ", []int{4}},
+		{"ranged jump", "4D [1-3] 5A", "MZ MxZ MxxxZ MxxxxZ", []int{3, 7}},
+		{"zero-length jump", "41 [0-1] 42", "AB AxB", []int{0, 3}},
+		{"unbounded jump", "4D [-] 5A", "M" + strings.Repeat("x", 5000) + "Z", []int{0}},
+		{"lower-bounded jump", "4D [2-] 5A", "MxZ", nil},
+		{"lower-bounded jump", "4D [2-] 5A", "MxxxxxxxZ", []int{0}},
+		{"shortest jump wins", "41 [1-4] 42", "AxBxB", []int{0}},
+		// Later starts must not reuse what an earlier start's jump memo
+		// learnt beyond its own reach
+		{"jump shared by starts", "41 [0-2] 42", "AAAB", []int{0, 1, 2}},
+		{"jump out of reach", "41 [0-2] 42", "AxxxAB", []int{4}},
+		{"unbounded jump shared by starts", "41 [-] 42", "AAAxB", []int{0, 1, 2}},
+		{"alternatives", "4D ( 5A | 4C 46 ) 00", "MZ\x00 MLF\x00 ML\x00", []int{0, 4}},
+		{"nested alternatives", "4D ( 5A | ( 4C | 4B ) 46 ) 21", "MKF! MLF! MJF!", []int{0, 5}},
+		{"alternative then jump", "4D ( 5A | 4C ) [1-2] 00", "MZx\x00 MLxx\x00 MZxxx\x00", []int{0, 5}},
+		{"jump inside alternative", "4D ( 5A [1] 41 | 4C ) 42", "MZxAB MLB MZAB", []int{0, 6}},
+		{"alternative of different lengths", "( 41 | 41 41 41 ) 42", "AAAB", []int{0, 2}},
+		{"alternative at the start", "( 41 | 42 ) 43", "ACBC", []int{0, 2}},
+		{"wildcard in alternative", "( 4? | 5A ) 21", "M!Z!!", []int{0, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := scan(t, `$a = { `+tt.hex+` }`, `$a or true`, []byte(tt.data))
+			var got []int
+			for _, sm := range matches[0].Strings {
+				got = append(got, int(sm.Offset))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("{ %s } in %q: offsets = %v, want %v", tt.hex, tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHexMatchLength(t *testing.T) {
+	// !a is the length of the match, which includes the jump taken
+	data := []byte("AxxB AB")
+	for cond, want := range map[string]bool{
+		`!a[1] == 4`: true,
+		`!a[2] == 2`: true,
+		`@a[2] == 5`: true,
+	} {
+		if got := len(scan(t, `$a = { 41 [-] 42 }`, cond, data)) == 1; got != want {
+			t.Errorf("%s: matched = %v, want %v", cond, got, want)
+		}
+	}
+}
+
+func TestBadHexStrings(t *testing.T) {
+	for _, hex := range []string{
+		"[1] 4D",
+		"4D [1]",
+		"4D ( ) 5A",
+		"4D ( 5A | ) 00",
+		"4D ( 5A",
+		"4D 5A )",
+		"4D [3-1] 5A",
+		"4D [x] 5A",
+		"4G",
+		"4D5",
+		"",
+	} {
+		if _, err := Compile(`rule r { strings: $a = { ` + hex + ` } condition: $a }`); err == nil {
+			t.Errorf("{ %s } compiled, want an error", hex)
+		}
+	}
+}
+
+func TestRegexStrings(t *testing.T) {
+	tests := []struct {
+		str  string
+		data string
+		want int
+	}{
+		{`$a = /ab+c/`, "abbbc ac abc", 2},
+		{`$a = /AB+C/ nocase`, "abbc", 1},
+		{`$a = /ab+c/i`, "ABC", 1},
+		{`$a = /a.c/`, "a\nc", 0},
+		{`$a = /a.c/s`, "a\nc", 1},
+		{`$a = /[0-9]{3}/ fullword`, "123 4567 x890", 1},
+		{`$a = /https?:\/\/[a-z]+/`, "see http://x and https://yz", 2},
+	}
+	for _, tt := range tests {
+		got := scan(t, tt.str, `#a == `+strconv.Itoa(tt.want), []byte(tt.data))
+		if len(got) != 1 {
+			t.Errorf("%s in %q: #a != %d", tt.str, tt.data, tt.want)
+		}
+	}
+}
+
+func TestConditions(t *testing.T) {
+	data := []byte("foo bar foo baz")
+	strs := `$a = "foo" $b = "bar" $c = "qux" $x1 = "ba" $x2 = "az"`
+	tests := []struct {
+		cond string
+		want bool
+	}{
+		{`$a and $b`, true},
+		{`$a and $c`, false},
+		{`$c or not $c`, true},
+		{`#a == 2`, true},
+		{`#c == 0`, true},
+		{`#a in (1..10) == 1`, true},
+		{`@a[1] == 0 and @a[2] == 8`, true},
+		{`@a == 0`, true},
+		{`@a[3] == 0`, false},
+		{`!a[1] == 3`, true},
+		{`!x1[2] == 2`, true},
+		{`$a at 8`, true},
+		{`$a at 4`, false},
+		{`$b in (0..4)`, true},
+		{`$b in (5..100)`, false},
+		{`$a in (1..7)`, false},
+		{`2 of ($a, $b, $c)`, true},
+		{`3 of ($a, $b, $c)`, false},
+		{`any of ($a, $c)`, true},
+		{`all of ($a, $b)`, true},
+		{`all of them`, false},
+		{`none of ($c)`, true},
+		{`none of them`, false},
+		{`all of ($x*)`, true},
+		{`2 of them`, true},
+		{`any of ($a, $b) at 4`, true},
+		{`any of ($a, $c) in (9..20)`, false},
+		{`filesize == 15`, true},
+		{`filesize > 1KB`, false},
+		{`1KB == 1024 and 1MB == 1048576`, true},
+		{`#a * 2 + 1 == 5`, true},
+		{`(#a + #b) % 2 == 1`, true},
+		{`uint8(0) == 0x66 and uint16be(0) == 0x666f`, true},
+	}
+	for _, tt := range tests {
+		if got := len(scan(t, strs, tt.cond, data)) == 1; got != tt.want {
+			t.Errorf("%s: matched = %v, want %v", tt.cond, got, tt.want)
+		}
+	}
+}
+
+func TestMathModule(t *testing.T) {
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+	tests := []struct {
+		cond string
+		data []byte
+		want bool
+	}{
+		{`math.entropy(0, filesize) == 0.0`, []byte("aaaa"), true},
+		{`math.entropy(0, filesize) == 1.0`, []byte("abab"), true},
+		{`math.entropy(0, filesize) == 8.0`, all, true},
+		{`math.entropy(0, 128) == 7.0`, all, true},
+		{`math.entropy(0, filesize) > 7.9`, []byte("aaaa"), false},
+		{`math.in_range(math.entropy(0, filesize), 7.9, 8.1)`, all, true},
+		{`math.mean(0, 2) == 97.5`, []byte("ab"), true},
+	}
+	for _, tt := range tests {
+		if got := len(scan(t, "", tt.cond, tt.data)) == 1; got != tt.want {
+			t.Errorf("%s: matched = %v, want %v", tt.cond, got, tt.want)
+		}
+	}
+}
+
+func TestPrivateStringsAndRules(t *testing.T) {
+	rules, err := Compile(`
+private rule helper { strings: $h = "bar" condition: $h }
+rule r : tag1 tag2 {
+	meta: author = "x" score = 5
+	strings: $a = "foo" $p = "baz" private
+	condition: $a and $p and helper
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	matches := rules.Scan([]byte("foo bar baz"))
+	if len(matches) != 1 || matches[0].Rule != "r" {
+		t.Fatalf("matches = %+v, want only r", matches)
+	}
+	m := matches[0]
+	if len(m.Strings) != 1 || m.Strings[0].ID != "$a" || string(m.Strings[0].Data) != "foo" {
+		t.Errorf("strings = %+v, want only $a", m.Strings)
+	}
+	if !reflect.DeepEqual(m.Tags, []string{"tag1", "tag2"}) {
+		t.Errorf("tags = %v", m.Tags)
+	}
+	if m.Meta["author"] != "x" || m.Meta["score"] != int64(5) {
+		t.Errorf("meta = %v", m.Meta)
+	}
+}
+
+func TestGlobalRule(t *testing.T) {
+	rules, err := Compile(`
+global rule big { condition: filesize > 3 }
+rule r { condition: true }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rules.Scan([]byte("ab")); len(got) != 0 {
+		t.Errorf("small file: matches = %+v, want none", got)
+	}
+	if got := rules.Scan([]byte("abcd")); len(got) != 2 {
+		t.Errorf("large file: matches = %+v, want big and r", got)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	for _, src := range []string{
+		`rule r { strings: $a = "x" xor condition: $a }`,
+		`rule r { strings: $a = "x" base64 condition: $a }`,
+		`rule r { strings: $a = { 41 } nocase condition: $a }`,
+		`rule r { strings: $a = /x/ wide condition: $a }`,
+		`rule r { strings: $a = "x" condition: for any of them : ( $ ) }`,
+		`rule r { condition: pe.is_dll() }`,
+		`rule r { condition: math.entropy(0, 1) > 1 }`,
+		`rule r { strings: $a = "x" $a = "y" condition: $a }`,
+		`rule r { condition: $a }`,
+	} {
+		if _, err := Compile(src); err == nil {
+			t.Errorf("Compile(%s) succeeded, want an error", src)
+		}
+	}
+}